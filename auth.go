@@ -0,0 +1,114 @@
+package pg
+
+import (
+	"fmt"
+	"sync"
+
+	"gopkg.in/pg.v5/internal/pool"
+)
+
+// AuthHandler handles an authentication request sent by the server.
+// data is the rest of the request after the authentication code, e.g.
+// the salt of AuthenticationMD5Password. The handler sends responses
+// using AuthConn.Send. Further requests are dispatched to the handler
+// registered for their code, or the handler can read them with
+// AuthConn.Receive.
+type AuthHandler func(cn *AuthConn, data []byte) error
+
+var authHandlers struct {
+	mu sync.RWMutex
+	m  map[int32]AuthHandler
+}
+
+// RegisterAuthHandler registers the handler for the authentication
+// request code, e.g. to support mechanisms used by custom proxies.
+// Registered handlers have priority over built-in cleartext, MD5 and
+// SASL authentication. Passing nil handler removes the registration.
+func RegisterAuthHandler(code int, fn AuthHandler) {
+	authHandlers.mu.Lock()
+	defer authHandlers.mu.Unlock()
+	if fn == nil {
+		delete(authHandlers.m, int32(code))
+		return
+	}
+	if authHandlers.m == nil {
+		authHandlers.m = make(map[int32]AuthHandler)
+	}
+	authHandlers.m[int32(code)] = fn
+}
+
+func authHandler(code int32) AuthHandler {
+	authHandlers.mu.RLock()
+	fn := authHandlers.m[code]
+	authHandlers.mu.RUnlock()
+	return fn
+}
+
+// AuthConn is the connection that is being authenticated. It is only
+// valid until the AuthHandler returns.
+type AuthConn struct {
+	cn       *pool.Conn
+	user     string
+	password string
+}
+
+// User returns the user name sent in the startup message.
+func (c *AuthConn) User() string {
+	return c.user
+}
+
+// Password returns the password from Password or PasswordFn options.
+func (c *AuthConn) Password() string {
+	return c.password
+}
+
+// Send sends the data in a message of type 'p', which is used by
+// PasswordMessage, SASLInitialResponse, SASLResponse and
+// GSSResponse.
+func (c *AuthConn) Send(data []byte) error {
+	c.cn.Wr.StartMessage(passwordMessageMsg)
+	c.cn.Wr.Write(data)
+	c.cn.Wr.FinishMessage()
+	return c.cn.FlushWriter()
+}
+
+// Receive reads the next authentication request and returns its code
+// and data. ErrorResponse is returned as Error.
+func (c *AuthConn) Receive() (code int, data []byte, err error) {
+	typ, msgLen, err := readMessageType(c.cn)
+	if err != nil {
+		return 0, nil, err
+	}
+	switch typ {
+	case authenticationOKMsg:
+		num, data, err := readAuthRequest(c.cn, msgLen)
+		return int(num), data, err
+	case errorResponseMsg:
+		e, err := readError(c.cn)
+		if err != nil {
+			return 0, nil, err
+		}
+		return 0, nil, e
+	default:
+		return 0, nil, fmt.Errorf("pg: unknown authentication message: %q", typ)
+	}
+}
+
+func readAuthRequest(cn *pool.Conn, msgLen int) (int32, []byte, error) {
+	num, err := readInt32(cn)
+	if err != nil {
+		return 0, nil, err
+	}
+	data, err := readAuthData(cn, msgLen-4)
+	return num, data, err
+}
+
+func readAuthData(cn *pool.Conn, n int) ([]byte, error) {
+	b, err := cn.ReadN(n)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, len(b))
+	copy(data, b)
+	return data, nil
+}
@@ -108,6 +108,26 @@ func BenchmarkQueryRowsGopgORM(b *testing.B) {
 	})
 }
 
+func BenchmarkQueryIDs(b *testing.B) {
+	db := benchmarkDB()
+	defer db.Close()
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			var ids []int64
+			_, err := db.Query(&ids, `SELECT generate_series(1, 1000)`)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(ids) != 1000 {
+				b.Fatalf("got %d, wanted 1000", len(ids))
+			}
+		}
+	})
+}
+
 func BenchmarkModelHasOneGopg(b *testing.B) {
 	seedDB()
 
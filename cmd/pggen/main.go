@@ -0,0 +1,450 @@
+// Pggen generates code that scans and appends the fields of structs
+// without reflection. Given the names of struct types, it creates a
+// file that implements orm.GeneratedModel for each type, e.g.
+//
+//    //go:generate pggen -type=User,Story
+//
+// in the package with
+//
+//    type User struct {
+//        Id     int64
+//        Name   string
+//        Emails []string `pg:",array"`
+//    }
+//
+// generates user_pggen.go with User.ScanColumn and
+// User.AppendColumnValue. Fields of basic types and time.Time are
+// handled by the generated code. Other fields, fields with pg tag
+// options and fields of embedded structs declared in other packages are
+// scanned and appended using reflection as before.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/pg.v5/internal"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of type names; must be set")
+	output    = flag.String("output", "", "output file name; default srcdir/<type>_pggen.go")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage of pggen:\n")
+	fmt.Fprintf(os.Stderr, "\tpggen -type T [directory]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("pggen: ")
+	flag.Usage = usage
+	flag.Parse()
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if args := flag.Args(); len(args) > 0 {
+		dir = args[0]
+	}
+
+	types := strings.Split(*typeNames, ",")
+	src, err := generate(dir, types)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	outputName := *output
+	if outputName == "" {
+		name := internal.Underscore(types[0]) + "_pggen.go"
+		outputName = filepath.Join(dir, name)
+	}
+	if err := ioutil.WriteFile(outputName, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generate returns the source of the file with the code for the types
+// declared in the package in the dir.
+func generate(dir string, typeNames []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkg *ast.Package
+	for _, p := range pkgs {
+		if pkg != nil {
+			return nil, fmt.Errorf("multiple packages in %s", dir)
+		}
+		pkg = p
+	}
+	if pkg == nil {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	g := &generator{
+		pkg:     pkg,
+		imports: make(map[string]bool),
+	}
+	for _, name := range typeNames {
+		if err := g.generate(name); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by \"pggen -type=%s\"; DO NOT EDIT.\n\n", strings.Join(typeNames, ","))
+	fmt.Fprintf(&buf, "package %s\n\n", pkg.Name)
+	buf.WriteString("import (\n")
+	var std, other []string
+	for path := range g.imports {
+		if strings.Contains(path, ".") {
+			other = append(other, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+	for _, path := range std {
+		fmt.Fprintf(&buf, "\t%q\n", path)
+	}
+	if len(std) > 0 {
+		buf.WriteString("\n")
+	}
+	for _, path := range other {
+		fmt.Fprintf(&buf, "\t%q\n", path)
+	}
+	buf.WriteString(")\n")
+	buf.Write(g.buf.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %s", err)
+	}
+	return src, nil
+}
+
+type generator struct {
+	pkg     *ast.Package
+	imports map[string]bool
+	buf     bytes.Buffer
+}
+
+// field is a struct field handled by the generated code.
+type field struct {
+	path    string // selector of the field, e.g. Base.Id
+	sqlName string
+	kind    string // name of the Go type, e.g. int64 or time.Time
+	notNull bool
+}
+
+func (g *generator) generate(typeName string) error {
+	st, file, err := g.lookupStruct(typeName)
+	if err != nil {
+		return err
+	}
+
+	var fields []field
+	seen := make(map[string]bool)
+	g.collectFields(&fields, seen, st, file, "", "", 0)
+
+	g.imports["gopkg.in/pg.v5/orm"] = true
+	if len(fields) > 0 {
+		g.imports["gopkg.in/pg.v5/types"] = true
+	}
+
+	recv := receiverName(typeName)
+
+	g.printf("\nvar _ orm.GeneratedModel = (*%s)(nil)\n", typeName)
+
+	g.printf("\n// ScanColumn implements orm.ColumnScanner.\n")
+	g.printf("func (%s *%s) ScanColumn(colIdx int, colName string, b []byte) error {\n", recv, typeName)
+	g.printf("var err error\n")
+	g.printf("switch colName {\n")
+	for _, f := range fields {
+		g.printf("case %q:\n", f.sqlName)
+		g.printScan(recv+"."+f.path, f.kind)
+	}
+	g.printf("default:\nreturn orm.ErrNotGenerated\n")
+	g.printf("}\n")
+	g.printf("return err\n")
+	g.printf("}\n")
+
+	g.printf("\n// AppendColumnValue implements orm.GeneratedModel.\n")
+	g.printf("func (%s *%s) AppendColumnValue(b []byte, colName string, quote int) ([]byte, bool) {\n", recv, typeName)
+	g.printf("switch colName {\n")
+	for _, f := range fields {
+		g.printf("case %q:\n", f.sqlName)
+		g.printAppend(recv+"."+f.path, f)
+	}
+	g.printf("}\n")
+	g.printf("return b, false\n")
+	g.printf("}\n")
+	return nil
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) lookupStruct(name string) (*ast.StructType, *ast.File, error) {
+	for _, file := range g.pkg.Files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if ts.Name.Name != name {
+					continue
+				}
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					return nil, nil, fmt.Errorf("%s is not a struct", name)
+				}
+				return st, file, nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("type %s is not found", name)
+}
+
+// collectFields collects the fields handled by the generated code in
+// the order they are added by the orm, so the first field wins when
+// several fields have the same SQL name. The sqlPrefix is the column
+// prefix of the fields of embedded structs, e.g. `pg:",prefix:addr_"`.
+func (g *generator) collectFields(
+	fields *[]field, seen map[string]bool, st *ast.StructType, file *ast.File,
+	prefix, sqlPrefix string, depth int,
+) {
+	if depth > 10 {
+		return
+	}
+	for _, f := range st.Fields.List {
+		tag := reflect.StructTag("")
+		if f.Tag != nil {
+			s, err := strconv.Unquote(f.Tag.Value)
+			if err == nil {
+				tag = reflect.StructTag(s)
+			}
+		}
+
+		fieldPrefix, extend := parseExtendTag(tag.Get("pg"))
+		if len(f.Names) == 0 || (extend && len(f.Names) == 1) { // embedded struct
+			ident, ok := f.Type.(*ast.Ident)
+			if !ok {
+				continue
+			}
+			embedded, embeddedFile, err := g.lookupStruct(ident.Name)
+			if err != nil {
+				continue
+			}
+			path := ident.Name
+			if len(f.Names) == 1 {
+				path = f.Names[0].Name
+			}
+			g.collectFields(
+				fields, seen, embedded, embeddedFile,
+				prefix+path+".", sqlPrefix+fieldPrefix, depth+1,
+			)
+			continue
+		}
+
+		for _, name := range f.Names {
+			if !name.IsExported() || name.Name == "TableName" {
+				continue
+			}
+
+			sqlName, notNull, skip := parseSQLTag(tag.Get("sql"))
+			if sqlName == "" || skip {
+				sqlName = internal.Underscore(name.Name)
+			}
+			sqlName = sqlPrefix + sqlName
+			if seen[sqlName] {
+				continue
+			}
+			seen[sqlName] = true
+
+			if skip || tag.Get("pg") != "" {
+				continue
+			}
+			kind := basicKind(f.Type, file)
+			if kind == "" {
+				continue
+			}
+
+			*fields = append(*fields, field{
+				path:    prefix + name.Name,
+				sqlName: sqlName,
+				kind:    kind,
+				notNull: notNull,
+			})
+		}
+	}
+}
+
+func parseSQLTag(tag string) (name string, notNull, skip bool) {
+	opts := strings.Split(tag, ",")
+	name = opts[0]
+	if name == "-" {
+		return "", false, true
+	}
+	for _, opt := range opts[1:] {
+		if opt == "notnull" {
+			notNull = true
+		}
+	}
+	return name, notNull, false
+}
+
+// parseExtendTag returns the column prefix of the struct field, which
+// fields are added to the model, e.g. `pg:",prefix:addr_"` or
+// `pg:",extend"`.
+func parseExtendTag(tag string) (prefix string, extend bool) {
+	for _, opt := range strings.Split(tag, ",")[1:] {
+		if strings.HasPrefix(opt, "prefix:") {
+			prefix = opt[len("prefix:"):]
+			extend = true
+		} else if opt == "extend" {
+			extend = true
+		}
+	}
+	return prefix, extend
+}
+
+var basicKinds = map[string]bool{
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true,
+	"string": true,
+	"bool":   true,
+}
+
+// basicKind returns the name of the type when it is handled by the
+// generated code.
+func basicKind(expr ast.Expr, file *ast.File) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if basicKinds[t.Name] && t.Obj == nil {
+			return t.Name
+		}
+	case *ast.SelectorExpr:
+		pkg, ok := t.X.(*ast.Ident)
+		if ok && t.Sel.Name == "Time" && importPath(file, pkg.Name) == "time" {
+			return "time.Time"
+		}
+	}
+	return ""
+}
+
+func importPath(file *ast.File, name string) string {
+	for _, imp := range file.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		if imp.Name != nil {
+			if imp.Name.Name == name {
+				return path
+			}
+			continue
+		}
+		if filepath.Base(path) == name {
+			return path
+		}
+	}
+	return ""
+}
+
+func receiverName(typeName string) string {
+	recv := strings.ToLower(typeName[:1])
+	switch recv {
+	case "b", "e", "q":
+		return "m"
+	}
+	return recv
+}
+
+func (g *generator) printScan(v, kind string) {
+	switch kind {
+	case "int64":
+		g.printf("%s, err = types.ScanInt64(b)\n", v)
+	case "int", "int8", "int16", "int32":
+		g.printf("var n int64\nn, err = types.ScanInt64(b)\n%s = %s(n)\n", v, kind)
+	case "uint64":
+		g.printf("%s, err = types.ScanUint64(b)\n", v)
+	case "uint", "uint8", "uint16", "uint32":
+		g.printf("var n uint64\nn, err = types.ScanUint64(b)\n%s = %s(n)\n", v, kind)
+	case "float64":
+		g.printf("%s, err = types.ScanFloat64(b)\n", v)
+	case "float32":
+		g.printf("var n float64\nn, err = types.ScanFloat64(b)\n%s = float32(n)\n", v)
+	case "string":
+		g.printf("%s = string(b)\n", v)
+	case "bool":
+		g.printf("%s = types.ScanBool(b)\n", v)
+	case "time.Time":
+		g.printf("%s, err = types.ScanTime(b)\n", v)
+	default:
+		panic("unsupported kind " + kind)
+	}
+}
+
+func (g *generator) printAppend(v string, f field) {
+	if !f.notNull {
+		var empty string
+		switch f.kind {
+		case "string":
+			empty = v + ` == ""`
+		case "bool":
+			empty = "!" + v
+		case "time.Time":
+			empty = v + ".IsZero()"
+		default:
+			empty = v + " == 0"
+		}
+		g.printf("if %s {\nreturn types.AppendNull(b, quote), true\n}\n", empty)
+	}
+
+	switch f.kind {
+	case "int", "int8", "int16", "int32", "int64":
+		g.imports["strconv"] = true
+		g.printf("return strconv.AppendInt(b, int64(%s), 10), true\n", v)
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		g.imports["strconv"] = true
+		g.printf("return strconv.AppendUint(b, uint64(%s), 10), true\n", v)
+	case "float32", "float64":
+		g.printf("return types.AppendFloat(b, float64(%s)), true\n", v)
+	case "string":
+		g.printf("return types.AppendString(b, %s, quote), true\n", v)
+	case "bool":
+		g.printf("return types.AppendBool(b, %s), true\n", v)
+	case "time.Time":
+		g.printf("return types.AppendTime(b, %s, quote), true\n", v)
+	default:
+		panic("unsupported kind " + f.kind)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	got, err := generate("testdata", []string{"Record"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wanted, err := ioutil.ReadFile("testdata/record_pggen.go.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, wanted) {
+		t.Fatalf("got\n%s\nwanted\n%s", got, wanted)
+	}
+}
+
+func TestGenerateNotStruct(t *testing.T) {
+	_, err := generate("testdata", []string{"Missing"})
+	if err == nil || err.Error() != "type Missing is not found" {
+		t.Fatalf("got %v, wanted type Missing is not found", err)
+	}
+}
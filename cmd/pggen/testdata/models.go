@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+type Base struct {
+	Id        int64
+	CreatedAt time.Time `sql:",notnull"`
+}
+
+type Address struct {
+	City string
+	Zip  string
+}
+
+type Record struct {
+	tableName struct{} `sql:"records"`
+
+	Base
+	Num     int32
+	Ratio   float64
+	Str     string `sql:"title"`
+	Flag    bool
+	Count   uint
+	Tags    []string `pg:",array"`
+	Home    Address  `pg:",prefix:home_"`
+	Skipped string   `sql:"-"`
+	hidden  int
+}
@@ -0,0 +1,300 @@
+package pg
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/pg.v5/internal"
+	"gopkg.in/pg.v5/internal/pool"
+	"gopkg.in/pg.v5/orm"
+	"gopkg.in/pg.v5/types"
+)
+
+// Conn represents a single database connection rather than a pool of
+// database connections. Session state such as temporary tables and
+// SET commands is preserved between queries made through the Conn.
+//
+// Conn is NOT safe for concurrent use by multiple goroutines.
+type Conn struct {
+	db *DB
+	cn *pool.Conn
+
+	lastErr error
+}
+
+var _ orm.DB = (*Conn)(nil)
+
+// RawConn takes a connection from the pool for packages that speak
+// the protocol directly, e.g. pgrepl. The connection belongs to
+// the caller until it is closed with CloseRawConn and is never returned
+// to the pool. Its type is internal, so it can be used only by
+// the packages of this module.
+func (db *DB) RawConn() (*pool.Conn, error) {
+	return db.conn()
+}
+
+// CloseRawConn closes the connection taken with RawConn and removes it
+// from the pool.
+func (db *DB) CloseRawConn(cn *pool.Conn, reason error) error {
+	return db.pool.Remove(cn, reason)
+}
+
+// WithSession runs fn using a single connection from the pool, which is
+// useful for workflows that depend on session state, e.g. creating a temp
+// table, copying data into it and joining against it. If fn returns an
+// error the connection is closed instead of being returned to the pool,
+// so temporary tables and other session state are discarded. Otherwise
+// the state is reset using Options.ResetSessionQuery. The connection is
+// also closed when fn panics.
+func (db *DB) WithSession(fn func(*Conn) error) error {
+	cn, err := db.conn()
+	if err != nil {
+		return err
+	}
+
+	c := &Conn{
+		db: db,
+		cn: cn,
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			_ = c.close(fmt.Errorf("pg: WithSession panicked: %v", v))
+			panic(v)
+		}
+	}()
+	if err := fn(c); err != nil {
+		_ = c.close(err)
+		return err
+	}
+	return c.close(nil)
+}
+
+// Conn reserves a single connection from the pool for running session
+// scoped statements, e.g. SET, temporary tables or advisory locks,
+// across multiple queries. The connection must be returned to the pool
+// with Release.
+func (db *DB) Conn() (*Conn, error) {
+	cn, err := db.conn()
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{
+		db: db,
+		cn: cn,
+	}, nil
+}
+
+// Release resets the session state using Options.ResetSessionQuery or
+// DISCARD ALL and returns the connection to the pool. The connection is
+// closed when the state can't be reset, e.g. because a transaction is
+// still open.
+func (c *Conn) Release() error {
+	if c.cn == nil {
+		return errConnClosed
+	}
+
+	query := c.db.opt.ResetSessionQuery
+	if query == "" {
+		query = "DISCARD ALL"
+	}
+	err := c.db.releaseConn(c.cn, query, c.lastErr)
+	c.cn = nil
+
+	return err
+}
+
+func (c *Conn) conn() (*pool.Conn, error) {
+	if c.cn == nil {
+		return nil, errConnClosed
+	}
+	c.cn.SetReadWriteTimeout(c.db.opt.ReadTimeout, c.db.opt.WriteTimeout)
+	return c.cn, nil
+}
+
+// setErr remembers the bad connection error, so the connection is
+// closed on release, and returns the error wrapped in ConnError.
+func (c *Conn) setErr(err error) error {
+	if isBadConn(err, false) {
+		err = newConnError(c.cn, err, true)
+		c.lastErr = err
+	}
+	return err
+}
+
+func (c *Conn) close(reason error) error {
+	if c.cn == nil {
+		return errConnClosed
+	}
+
+	var err error
+	if reason != nil {
+		err = c.db.pool.Remove(c.cn, reason)
+	} else {
+		err = c.db.releaseConn(c.cn, c.db.opt.ResetSessionQuery, c.lastErr)
+	}
+	c.cn = nil
+
+	return err
+}
+
+// Exec executes a query ignoring returned rows. The params are for any
+// placeholders in the query.
+func (c *Conn) Exec(query interface{}, params ...interface{}) (*types.Result, error) {
+	cn, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.db.simpleQuery(cn, query, params...)
+	err = c.setErr(err)
+	return res, err
+}
+
+// ExecOne acts like Exec, but query must affect only one row. It
+// returns ErrNoRows error when query returns zero rows or
+// ErrMultiRows when query returns multiple rows.
+func (c *Conn) ExecOne(query interface{}, params ...interface{}) (*types.Result, error) {
+	res, err := c.Exec(query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := internal.AssertOneRow(res.RowsAffected()); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Query executes a query that returns rows, typically a SELECT.
+// The params are for any placeholders in the query.
+func (c *Conn) Query(model, query interface{}, params ...interface{}) (*types.Result, error) {
+	cn, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	res, mod, err := c.db.simpleQueryData(cn, model, query, params...)
+	err = c.setErr(err)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.RowsReturned() > 0 && mod != nil {
+		if err = mod.AfterQuery(c); err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+// QueryOne acts like Query, but query must return only one row. It
+// returns ErrNoRows error when query returns zero rows or
+// ErrMultiRows when query returns multiple rows.
+func (c *Conn) QueryOne(model, query interface{}, params ...interface{}) (*types.Result, error) {
+	mod, err := orm.NewModel(model)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.Query(mod, query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := internal.AssertOneRow(res.RowsAffected()); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// CopyFrom copies data from reader to a table.
+func (c *Conn) CopyFrom(r io.Reader, query interface{}, params ...interface{}) (*types.Result, error) {
+	cn, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.db.copyFrom(cn, r, nil, query, params...)
+	err = c.setErr(err)
+	return res, err
+}
+
+// CopyFromWithOptions is like CopyFrom, but reports progress and limits
+// the rate of copied data using the options.
+func (c *Conn) CopyFromWithOptions(
+	r io.Reader, opt *CopyOptions, query interface{}, params ...interface{},
+) (*types.Result, error) {
+	cn, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.db.copyFrom(cn, r, opt, query, params...)
+	err = c.setErr(err)
+	return res, err
+}
+
+// CopyTo copies data from a table to writer.
+func (c *Conn) CopyTo(w io.Writer, query interface{}, params ...interface{}) (*types.Result, error) {
+	cn, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.db.copyTo(cn, w, nil, query, params...)
+	err = c.setErr(err)
+	return res, err
+}
+
+// CopyToWithOptions is like CopyTo, but reports progress and limits the
+// rate of copied data using the options.
+func (c *Conn) CopyToWithOptions(
+	w io.Writer, opt *CopyOptions, query interface{}, params ...interface{},
+) (*types.Result, error) {
+	cn, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.db.copyTo(cn, w, opt, query, params...)
+	err = c.setErr(err)
+	return res, err
+}
+
+// Model returns new query for the model.
+func (c *Conn) Model(model ...interface{}) *orm.Query {
+	return orm.NewQuery(c, model...)
+}
+
+// Select selects the model by primary key.
+func (c *Conn) Select(model interface{}) error {
+	return orm.Select(c, model)
+}
+
+// Insert inserts the model updating primary keys if they are empty.
+func (c *Conn) Insert(model ...interface{}) error {
+	return orm.Insert(c, model...)
+}
+
+// Update updates the model by primary key.
+func (c *Conn) Update(model interface{}) error {
+	return orm.Update(c, model)
+}
+
+// Delete deletes the model by primary key.
+func (c *Conn) Delete(model interface{}) error {
+	return orm.Delete(c, model)
+}
+
+// CreateTable creates table for the model. It recognizes following field tags:
+//   - notnull - sets NOT NULL constraint.
+//   - unique - sets UNIQUE constraint.
+func (c *Conn) CreateTable(model interface{}, opt *orm.CreateTableOptions) error {
+	_, err := orm.CreateTable(c, model, opt)
+	return err
+}
+
+func (c *Conn) FormatQuery(dst []byte, query string, params ...interface{}) []byte {
+	return c.db.FormatQuery(dst, query, params...)
+}
@@ -0,0 +1,83 @@
+package pg
+
+import (
+	"bytes"
+	"context"
+)
+
+// copyChunkSize is the largest n passed to RateLimiter.WaitN.
+const copyChunkSize = 4096
+
+// RateLimiter limits the rate of data copied by CopyFromWithOptions and
+// CopyToWithOptions. It is implemented by *rate.Limiter from
+// golang.org/x/time/rate.
+type RateLimiter interface {
+	// WaitN blocks until n bytes can be copied.
+	WaitN(ctx context.Context, n int) error
+}
+
+// CopyOptions are options of CopyFromWithOptions and CopyToWithOptions.
+type CopyOptions struct {
+	// Progress is called after each chunk of data is copied with the
+	// number of bytes and rows copied so far. CopyFrom counts rows as
+	// lines of the data, so CSV values containing quoted newlines are
+	// counted as several rows, and CopyTo as messages sent by the
+	// server, which are rows. The exact number of rows copied is
+	// returned in the Result.
+	Progress func(bytes, rows int64)
+
+	// RateLimiter limits the number of bytes per second. WaitN is
+	// called with the context of the DB set with WithContext and n no
+	// larger than 4096, so the burst of the limiter must be at least
+	// 4096.
+	RateLimiter RateLimiter
+}
+
+type copyState struct {
+	ctx   context.Context
+	opt   *CopyOptions
+	bytes int64
+	rows  int64
+}
+
+func newCopyState(ctx context.Context, opt *CopyOptions) *copyState {
+	if opt == nil {
+		return nil
+	}
+	return &copyState{
+		ctx: ctx,
+		opt: opt,
+	}
+}
+
+func (s *copyState) wait(n int) error {
+	if s == nil || s.opt.RateLimiter == nil {
+		return nil
+	}
+	for n > 0 {
+		chunk := n
+		if chunk > copyChunkSize {
+			chunk = copyChunkSize
+		}
+		if err := s.opt.RateLimiter.WaitN(s.ctx, chunk); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+func (s *copyState) copied(n int, rows int64) {
+	if s == nil {
+		return
+	}
+	s.bytes += int64(n)
+	s.rows += rows
+	if s.opt.Progress != nil {
+		s.opt.Progress(s.bytes, s.rows)
+	}
+}
+
+func countLines(b []byte) int64 {
+	return int64(bytes.Count(b, []byte{'\n'}))
+}
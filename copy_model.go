@@ -0,0 +1,305 @@
+package pg
+
+import (
+	"bytes"
+	"errors"
+
+	"gopkg.in/pg.v5/internal"
+	"gopkg.in/pg.v5/internal/pool"
+	"gopkg.in/pg.v5/orm"
+	"gopkg.in/pg.v5/types"
+)
+
+// CopyToModel copies data from a COPY ... TO STDOUT query to the model,
+// e.g. a slice of structs, which is faster than Query for large
+// results. Text and CSV formats with default delimiters are supported
+// and the format is detected from the options of the query. Columns
+// are matched with the model using the header of the data when the
+// query has the HEADER option and otherwise using the order of the
+// model fields, e.g.
+//
+//    var events []Event
+//    _, err := db.CopyToModel(&events, "COPY (SELECT * FROM events) TO STDOUT WITH CSV HEADER")
+func (db *DB) CopyToModel(model, query interface{}, params ...interface{}) (*types.Result, error) {
+	cn, err := db.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	res, mod, err := db.copyToModel(cn, model, query, params...)
+	err = db.freeConn(cn, err)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.RowsAffected() > 0 {
+		if err := mod.AfterQuery(db); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+func (db *DB) copyToModel(
+	cn *pool.Conn, model, query interface{}, params ...interface{},
+) (res *types.Result, mod orm.Model, err error) {
+	mod, err = newModel(model)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := writeQueryMsg(cn.Wr, db, query, params...); err != nil {
+		return nil, nil, err
+	}
+	cn.Queries++
+	b := cn.Wr.Bytes
+	csv, header, err := copyFormat(b[5 : len(b)-1]) // Skip message header and trailing 0.
+	if err != nil {
+		cn.Wr.Reset()
+		return nil, nil, err
+	}
+	if qlog := newQueryLog(db, cn.Wr); qlog != nil {
+		defer func() { qlog.done(cn, err) }()
+	}
+
+	if err := cn.FlushWriter(); err != nil {
+		return nil, nil, err
+	}
+
+	if err := readCopyOutResponse(cn); err != nil {
+		return nil, nil, err
+	}
+
+	w := &copyModelWriter{
+		model:  mod,
+		csv:    csv,
+		header: header,
+	}
+	if !header {
+		w.columns = modelColumns(mod)
+	}
+	res, err = readCopyData(cn, w, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if w.err != nil {
+		return nil, nil, w.err
+	}
+	return res, mod, nil
+}
+
+// copyFormat detects the format from the options following STDOUT.
+func copyFormat(query []byte) (csv, header bool, err error) {
+	q := bytes.ToUpper(query)
+	i := bytes.LastIndex(q, []byte("STDOUT"))
+	if i == -1 {
+		return false, false, errors.New("pg: CopyToModel requires COPY ... TO STDOUT query")
+	}
+	opts := q[i+len("STDOUT"):]
+	if bytes.Contains(opts, []byte("BINARY")) {
+		return false, false, errors.New("pg: CopyToModel does not support binary format")
+	}
+	csv = bytes.Contains(opts, []byte("CSV"))
+	header = bytes.Contains(opts, []byte("HEADER"))
+	return csv, header, nil
+}
+
+func modelColumns(mod orm.Model) []string {
+	m, ok := mod.(interface {
+		Table() *orm.Table
+	})
+	if !ok {
+		return nil
+	}
+	fields := m.Table().Fields
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = f.SQLName
+	}
+	return columns
+}
+
+// copyModelWriter scans rows sent by the server in CopyData messages
+// into the model. Each message contains exactly one row.
+type copyModelWriter struct {
+	model   orm.Model
+	csv     bool
+	header  bool
+	columns []string
+	err     error
+}
+
+func (w *copyModelWriter) setErr(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *copyModelWriter) Write(b []byte) (int, error) {
+	var fields [][]byte
+	if w.csv {
+		var err error
+		fields, err = parseCopyCSV(b)
+		if err != nil {
+			w.setErr(err)
+			return len(b), nil
+		}
+	} else {
+		fields = parseCopyText(b)
+	}
+
+	if w.header {
+		w.header = false
+		w.columns = make([]string, len(fields))
+		for i, f := range fields {
+			w.columns[i] = string(f)
+		}
+		return len(b), nil
+	}
+
+	row := w.model.NewModel()
+	var scanErr error
+	for i, f := range fields {
+		var column string
+		if i < len(w.columns) {
+			column = w.columns[i]
+		}
+		if err := row.ScanColumn(i, column, f); err != nil && scanErr == nil {
+			scanErr = err
+		}
+	}
+	if scanErr != nil {
+		w.setErr(scanErr)
+	} else if err := w.model.AddModel(row); err != nil {
+		w.setErr(err)
+	}
+	return len(b), nil
+}
+
+// parseCopyText splits the row in text format into columns. NULL is
+// returned as nil.
+func parseCopyText(b []byte) [][]byte {
+	b = bytes.TrimSuffix(b, []byte{'\n'})
+	fields := bytes.Split(b, []byte{'\t'})
+	for i, f := range fields {
+		if string(f) == `\N` {
+			fields[i] = nil
+		} else if bytes.IndexByte(f, '\\') != -1 {
+			fields[i] = unescapeCopyText(f)
+		}
+	}
+	return fields
+}
+
+func unescapeCopyText(b []byte) []byte {
+	dst := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		if c != '\\' || i+1 == len(b) {
+			dst = append(dst, c)
+			continue
+		}
+
+		i++
+		c = b[i]
+		switch {
+		case c == 'b':
+			dst = append(dst, '\b')
+		case c == 'f':
+			dst = append(dst, '\f')
+		case c == 'n':
+			dst = append(dst, '\n')
+		case c == 'r':
+			dst = append(dst, '\r')
+		case c == 't':
+			dst = append(dst, '\t')
+		case c == 'v':
+			dst = append(dst, '\v')
+		case c >= '0' && c <= '7':
+			var v byte
+			for j := 0; j < 3 && i < len(b) && b[i] >= '0' && b[i] <= '7'; j++ {
+				v = v<<3 | (b[i] - '0')
+				i++
+			}
+			i--
+			dst = append(dst, v)
+		case c == 'x' && i+1 < len(b) && isHexDigit(b[i+1]):
+			var v byte
+			for j := 0; j < 2 && i+1 < len(b) && isHexDigit(b[i+1]); j++ {
+				i++
+				v = v<<4 | hexDigit(b[i])
+			}
+			dst = append(dst, v)
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexDigit(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	default:
+		return c - '0'
+	}
+}
+
+// parseCopyCSV splits the row in CSV format into columns. Unquoted
+// empty value is NULL and is returned as nil.
+func parseCopyCSV(b []byte) ([][]byte, error) {
+	b = bytes.TrimSuffix(b, []byte{'\n'})
+	b = bytes.TrimSuffix(b, []byte{'\r'})
+
+	var fields [][]byte
+	for {
+		if len(b) > 0 && b[0] == '"' {
+			f := make([]byte, 0)
+			i := 1
+			for {
+				if i >= len(b) {
+					return nil, internal.Errorf("pg: unterminated quoted CSV value")
+				}
+				if b[i] == '"' {
+					if i+1 < len(b) && b[i+1] == '"' {
+						f = append(f, '"')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				f = append(f, b[i])
+				i++
+			}
+			fields = append(fields, f)
+			b = b[i:]
+		} else {
+			i := bytes.IndexByte(b, ',')
+			if i == -1 {
+				i = len(b)
+			}
+			if i == 0 {
+				fields = append(fields, nil)
+			} else {
+				fields = append(fields, b[:i])
+			}
+			b = b[i:]
+		}
+
+		if len(b) == 0 {
+			return fields, nil
+		}
+		if b[0] != ',' {
+			return nil, internal.Errorf("pg: unexpected %q after quoted CSV value", b[0])
+		}
+		b = b[1:]
+	}
+}
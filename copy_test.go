@@ -0,0 +1,104 @@
+package pg
+
+import (
+	"context"
+	"testing"
+)
+
+type recordingLimiter []int
+
+func (l *recordingLimiter) WaitN(ctx context.Context, n int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	*l = append(*l, n)
+	return nil
+}
+
+func TestCopyState(t *testing.T) {
+	var limiter recordingLimiter
+	var bytes, rows int64
+	ctx, cancel := context.WithCancel(context.Background())
+	state := newCopyState(ctx, &CopyOptions{
+		Progress: func(b, r int64) {
+			bytes, rows = b, r
+		},
+		RateLimiter: &limiter,
+	})
+
+	if err := state.wait(10000); err != nil {
+		t.Fatal(err)
+	}
+	wanted := []int{4096, 4096, 1808}
+	if len(limiter) != len(wanted) {
+		t.Fatalf("got %v, wanted %v", limiter, wanted)
+	}
+	for i := range wanted {
+		if limiter[i] != wanted[i] {
+			t.Fatalf("got %v, wanted %v", limiter, wanted)
+		}
+	}
+
+	data := []byte("1\n2\n3")
+	state.copied(len(data), countLines(data))
+	data = []byte("\n4\n")
+	state.copied(len(data), countLines(data))
+	if bytes != 8 || rows != 4 {
+		t.Errorf("got %d bytes and %d rows, wanted 8 and 4", bytes, rows)
+	}
+
+	cancel()
+	if err := state.wait(1); err != context.Canceled {
+		t.Fatalf("got %v, wanted context.Canceled", err)
+	}
+
+	// Copying without options is a no-op.
+	state = newCopyState(ctx, nil)
+	if err := state.wait(10000); err != nil {
+		t.Fatal(err)
+	}
+	state.copied(1, 1)
+}
+
+func TestParseCopyText(t *testing.T) {
+	fields := parseCopyText([]byte("1\t\\N\t\tline\\nbreak\\ttab\\\\\\101\\x42\n"))
+	wanted := []string{"1", "<nil>", "", "line\nbreak\ttab\\AB"}
+	if len(fields) != len(wanted) {
+		t.Fatalf("got %q, wanted %q", fields, wanted)
+	}
+	for i, f := range fields {
+		got := string(f)
+		if f == nil {
+			got = "<nil>"
+		}
+		if got != wanted[i] {
+			t.Errorf("field %d: got %q, wanted %q", i, got, wanted[i])
+		}
+	}
+}
+
+func TestParseCopyCSV(t *testing.T) {
+	fields, err := parseCopyCSV([]byte("1,,\"\",\"a,\"\"b\"\"\nc\",x\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := []string{"1", "<nil>", "", "a,\"b\"\nc", "x"}
+	if len(fields) != len(wanted) {
+		t.Fatalf("got %q, wanted %q", fields, wanted)
+	}
+	for i, f := range fields {
+		got := string(f)
+		if f == nil {
+			got = "<nil>"
+		}
+		if got != wanted[i] {
+			t.Errorf("field %d: got %q, wanted %q", i, got, wanted[i])
+		}
+	}
+
+	for _, s := range []string{`"unterminated`, `"quoted"x`} {
+		if _, err := parseCopyCSV([]byte(s)); err == nil {
+			t.Errorf("%s: expected an error", s)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package pg
+
+import (
+	"gopkg.in/pg.v5/orm"
+	"gopkg.in/pg.v5/types"
+)
+
+const defaultFetchSize = 1000
+
+// QueryCursor executes the query using a server-side cursor and fetches
+// rows in batches of fetchSize rows (1000 by default), which allows
+// processing large result sets without buffering all rows in memory.
+// Every batch is scanned into the model, which is usually a pointer to
+// a slice, and fn is called after each batch. Iteration stops when
+// there are no more rows or fn returns an error.
+//
+// The cursor is declared in a transaction on a single connection.
+// The query can be a string or orm.Query, e.g.
+//
+//    var books []Book
+//    err := db.QueryCursor(&books, db.Model(&books).Where("id > ?", 100), 100, func() error {
+//        // process books
+//        return nil
+//    })
+func (db *DB) QueryCursor(model, query interface{}, fetchSize int, fn func() error) error {
+	if fetchSize < 1 {
+		fetchSize = defaultFetchSize
+	}
+
+	if _, ok := query.(orm.FormatAppender); !ok {
+		b, err := appendQuery(nil, db, query)
+		if err != nil {
+			return err
+		}
+		query = types.Q(b)
+	}
+
+	return db.WithSession(func(cn *Conn) error {
+		if _, err := cn.Exec("BEGIN"); err != nil {
+			return err
+		}
+
+		_, err := cn.Exec("DECLARE _go_pg_cursor NO SCROLL CURSOR FOR ?", query)
+		if err != nil {
+			return err
+		}
+
+		for {
+			res, err := cn.Query(model, "FETCH FORWARD ? FROM _go_pg_cursor", fetchSize)
+			if err != nil {
+				return err
+			}
+			if res.RowsReturned() == 0 {
+				break
+			}
+
+			if err := fn(); err != nil {
+				return err
+			}
+
+			if res.RowsReturned() < fetchSize {
+				break
+			}
+		}
+
+		_, err = cn.Exec("COMMIT")
+		return err
+	})
+}
@@ -1,8 +1,11 @@
 package pg
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
 	"time"
 
 	"gopkg.in/pg.v5/internal"
@@ -17,19 +20,25 @@ import (
 // and maintains its own connection pool.
 func Connect(opt *Options) *DB {
 	opt.init()
-	return &DB{
-		opt:  opt,
-		pool: newConnPool(opt),
+	db := &DB{
+		ctx: context.Background(),
+		opt: opt,
 	}
+	db.pool = newConnPool(opt, db.initIdleConn)
+	return db
 }
 
 // DB is a database handle representing a pool of zero or more
 // underlying connections. It's safe for concurrent use by multiple
 // goroutines.
 type DB struct {
+	ctx   context.Context
 	opt   *Options
 	pool  *pool.ConnPool
 	fmter orm.Formatter
+
+	ctxValues map[string]interface{}
+	comment   string
 }
 
 var _ orm.DB = (*DB)(nil)
@@ -43,69 +52,286 @@ func (db *DB) Options() *Options {
 	return db.opt
 }
 
-// WithTimeout returns a DB that uses d as the read/write timeout.
+// PoolStats contains the stats of the connection pool.
+type PoolStats pool.Stats
+
+// PoolStats returns the stats of the connection pool, e.g. how often
+// and how long queries waited for a free connection.
+func (db *DB) PoolStats() *PoolStats {
+	return (*PoolStats)(db.pool.Stats())
+}
+
+// WithTimeout returns a DB that uses d as the read/write timeout. The
+// returned DB shares the connection pool with db, so it is cheap to
+// create per request.
 func (db *DB) WithTimeout(d time.Duration) *DB {
 	newopt := *db.opt
 	newopt.ReadTimeout = d
 	newopt.WriteTimeout = d
 	return &DB{
+		ctx:   db.ctx,
 		opt:   &newopt,
 		pool:  db.pool,
 		fmter: db.fmter,
+
+		ctxValues: db.ctxValues,
+		comment:   db.comment,
+	}
+}
+
+// WithContext returns a DB that stops waiting for a free connection
+// when the ctx is done. Queries that already got a connection are not
+// canceled.
+func (db *DB) WithContext(ctx context.Context) *DB {
+	return &DB{
+		ctx:   ctx,
+		opt:   db.opt,
+		pool:  db.pool,
+		fmter: db.fmter,
+
+		ctxValues: db.ctxValues,
+		comment:   db.comment,
 	}
 }
 
-// WithParam returns a DB that replaces the param with the value in queries.
+// WithParam returns a DB that replaces the param with the value in queries,
+// e.g. ?tenant. The params of db are not changed and the connection pool
+// is shared.
 func (db *DB) WithParam(param string, value interface{}) *DB {
 	return &DB{
+		ctx:   db.ctx,
 		opt:   db.opt,
 		pool:  db.pool,
 		fmter: db.fmter.WithParam(param, value),
+
+		ctxValues: db.ctxValues,
+		comment:   db.comment,
 	}
 }
 
-func (db *DB) conn() (*pool.Conn, error) {
-	cn, _, err := db.pool.Get()
-	if err != nil {
-		return nil, err
+// WithContextValues returns a DB that carries the values into every query
+// made through it, which is useful for per-request handles, e.g. with
+// request id and user id. Each value is available as a named param (?key)
+// and is added to the query as a leading comment /* key=value */, so it
+// shows up in the query log and in pg_stat_activity.
+func (db *DB) WithContextValues(values map[string]interface{}) *DB {
+	ctxValues := make(map[string]interface{}, len(db.ctxValues)+len(values))
+	for k, v := range db.ctxValues {
+		ctxValues[k] = v
 	}
 
-	cn.SetReadWriteTimeout(db.opt.ReadTimeout, db.opt.WriteTimeout)
+	fmter := db.fmter
+	for k, v := range values {
+		ctxValues[k] = v
+		fmter = fmter.WithParam(k, v)
+	}
 
-	if cn.InitedAt.IsZero() {
-		if err := db.initConn(cn); err != nil {
-			_ = db.pool.Remove(cn, err)
-			return nil, err
+	return &DB{
+		ctx:   db.ctx,
+		opt:   db.opt,
+		pool:  db.pool,
+		fmter: fmter,
+
+		ctxValues: ctxValues,
+		comment:   queryComment(ctxValues),
+	}
+}
+
+func queryComment(values map[string]interface{}) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b []byte
+	for i, k := range keys {
+		if i > 0 {
+			b = append(b, ", "...)
 		}
-		cn.InitedAt = time.Now()
+		b = append(b, k...)
+		b = append(b, '=')
+		b = append(b, fmt.Sprint(values[k])...)
 	}
 
-	return cn, nil
+	// PostgreSQL supports nested comments so values must neither open
+	// nor close a comment.
+	s := strings.Replace(string(b), "*", "_", -1)
+	return "/* " + s + " */ "
+}
+
+func (db *DB) conn() (*pool.Conn, error) {
+	for {
+		cn, isNew, err := db.pool.GetContext(db.ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		idle := !isNew && db.opt.IdleCheckThreshold > 0 &&
+			time.Since(cn.UsedAt) >= db.opt.IdleCheckThreshold
+
+		cn.SetReadWriteTimeout(db.opt.ReadTimeout, db.opt.WriteTimeout)
+
+		if cn.InitedAt.IsZero() {
+			if err := db.initConn(cn); err != nil {
+				_ = db.pool.Remove(cn, err)
+				return nil, err
+			}
+			cn.InitedAt = time.Now()
+		} else if idle {
+			// Half-open connection would block the ping forever without
+			// ReadTimeout, so don't wait longer than for a new connection.
+			cn.SetReadWriteTimeout(db.opt.DialTimeout, db.opt.DialTimeout)
+			err := pingConn(cn)
+			cn.SetReadWriteTimeout(db.opt.ReadTimeout, db.opt.WriteTimeout)
+			if err != nil {
+				// Try another connection; eventually a new one is dialed.
+				_ = db.pool.Remove(cn, err)
+				continue
+			}
+		}
+
+		if err := closeStmts(cn); err != nil {
+			_ = db.pool.Remove(cn, err)
+			continue
+		}
+
+		return cn, nil
+	}
 }
 
 func (db *DB) initConn(cn *pool.Conn) error {
-	if db.opt.TLSConfig != nil {
-		if err := enableSSL(cn, db.opt.TLSConfig); err != nil {
+	if db.opt.tlsErr != nil {
+		return db.opt.tlsErr
+	}
+	password, err := db.password()
+	if err != nil {
+		return err
+	}
+	db.traceConn(cn)
+	cn.ZeroCopy = db.opt.ZeroCopyScan
+
+	useSSL := db.opt.TLSConfig != nil && db.opt.SSLMode != SSLAllow
+	if useSSL {
+		err := enableSSL(cn, db.opt.TLSConfig, db.opt.DialTimeout)
+		// With prefer mode continue without SSL.
+		if err != nil && !(err == errSSLNotSupported && db.opt.SSLMode == SSLPrefer) {
 			return err
 		}
+		cn.SetReadWriteTimeout(db.opt.ReadTimeout, db.opt.WriteTimeout)
+	}
+
+	err = db.startup(cn, password)
+	if _, ok := err.(Error); ok && db.opt.SSLMode == SSLAllow && db.opt.TLSConfig != nil {
+		// The server rejected non-SSL connection, so try SSL
+		// using a new connection.
+		err = db.reconnectSSL(cn, password)
+	}
+	if err != nil {
+		return err
+	}
+
+	return db.onConnect(cn)
+}
+
+// password returns the password for a new connection. PasswordFn is
+// called for every connection, so rotated credentials are picked up.
+func (db *DB) password() (string, error) {
+	if db.opt.PasswordFn == nil {
+		return db.opt.Password, nil
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), db.opt.DialTimeout)
+	defer cancel()
+	return db.opt.PasswordFn(ctx)
+}
 
-	err := startup(cn, db.opt.User, db.opt.Password, db.opt.Database)
+func (db *DB) startup(cn *pool.Conn, password string) error {
+	return startup(cn, db.opt.User, password, db.opt.Database, db.opt.startupParams())
+}
+
+func (db *DB) reconnectSSL(cn *pool.Conn, password string) error {
+	netConn, err := db.opt.getDialer()()
 	if err != nil {
 		return err
 	}
+	_ = cn.NetConn().Close()
+	cn.SetNetConn(netConn)
+	db.traceConn(cn)
+	cn.SetReadWriteTimeout(db.opt.ReadTimeout, db.opt.WriteTimeout)
+
+	if err := enableSSL(cn, db.opt.TLSConfig, db.opt.DialTimeout); err != nil {
+		return err
+	}
+	cn.SetReadWriteTimeout(db.opt.ReadTimeout, db.opt.WriteTimeout)
+	return db.startup(cn, password)
+}
+
+func (db *DB) onConnect(cn *pool.Conn) error {
+	if db.opt.OnConnect == nil {
+		return nil
+	}
 
+	c := &Conn{
+		db: db,
+		cn: cn,
+	}
+	err := db.opt.OnConnect(c)
+	c.cn = nil
+	if err == nil {
+		err = c.lastErr
+	}
+	return err
+}
+
+// initIdleConn initializes connections that the pool dials in the
+// background to maintain Options.MinIdleConns.
+func (db *DB) initIdleConn(cn *pool.Conn) error {
+	cn.SetReadWriteTimeout(db.opt.ReadTimeout, db.opt.WriteTimeout)
+	if err := db.initConn(cn); err != nil {
+		return err
+	}
+	cn.InitedAt = time.Now()
 	return nil
 }
 
+// freeConn returns the connection to the pool or removes it from the
+// pool after the error. It returns the error wrapped in ConnError when
+// it is caused by the connection.
 func (db *DB) freeConn(cn *pool.Conn, err error) error {
 	if !isBadConn(err, false) {
-		return db.pool.Put(cn)
+		_ = db.pool.Put(cn)
+		return err
+	}
+	err = newConnError(cn, err, true)
+	_ = db.pool.Remove(cn, err)
+	return err
+}
+
+// releaseConn runs the query that resets session state and returns
+// the connection to the pool. The connection is closed when the state
+// can't be reset.
+func (db *DB) releaseConn(cn *pool.Conn, resetQuery string, err error) error {
+	if resetQuery == "" || isBadConn(err, false) {
+		_ = db.freeConn(cn, err)
+		return nil
+	}
+
+	cn.SetReadWriteTimeout(db.opt.ReadTimeout, db.opt.WriteTimeout)
+	if _, err := db.simpleQuery(cn, resetQuery); err != nil {
+		return db.pool.Remove(cn, err)
+	}
+	if err := db.onConnect(cn); err != nil {
+		return db.pool.Remove(cn, err)
 	}
-	return db.pool.Remove(cn, err)
+	return db.pool.Put(cn)
 }
 
-func (db *DB) shouldRetry(err error) bool {
+// shouldRetry is the default RetryPolicy.Retryable.
+func (db *DB) shouldRetry(err error, safe bool) bool {
 	if err == nil {
 		return false
 	}
@@ -115,13 +341,15 @@ func (db *DB) shouldRetry(err error) bool {
 			return true
 		case "55000": // attempted to delete invisible tuple
 			return true
+		case "57P01": // admin_shutdown terminated the connection
+			return true
 		case "57014": // statement_timeout
 			return db.opt.RetryStatementTimeout
 		default:
 			return false
 		}
 	}
-	return isNetworkError(err)
+	return safe && isNetworkError(err)
 }
 
 // Close closes the database client, releasing any open resources.
@@ -139,28 +367,39 @@ func (db *DB) Close() error {
 	return db.pool.Close()
 }
 
+// Shutdown gracefully closes the database client. It stops handing out
+// connections, so new queries fail with an error, waits until queries
+// in progress return their connections to the pool and then closes the
+// connections sending Terminate message to the server. When the ctx is
+// done before all connections are returned, the connections are closed
+// anyway and the error of the ctx is returned.
+func (db *DB) Shutdown(ctx context.Context) error {
+	return db.pool.Shutdown(ctx)
+}
+
 // Exec executes a query ignoring returned rows. The params are for any
 // placeholders in the query.
 func (db *DB) Exec(query interface{}, params ...interface{}) (res *types.Result, err error) {
+	db.opt.RetryPolicy.deposit()
 	for i := 0; ; i++ {
 		var cn *pool.Conn
 
 		cn, err = db.conn()
 		if err != nil {
+			if db.retry(i, err, true) {
+				continue
+			}
 			return nil, err
 		}
 
+		flushes := cn.Flushes()
 		res, err = db.simpleQuery(cn, query, params...)
-		db.freeConn(cn, err)
+		safe := cn.Flushes() == flushes || isIdempotent(query)
+		err = db.freeConn(cn, err)
 
-		if i >= db.opt.MaxRetries {
-			break
-		}
-		if !db.shouldRetry(err) {
+		if !db.retry(i, err, safe) {
 			break
 		}
-
-		time.Sleep(internal.RetryBackoff << uint(i))
 	}
 	return res, err
 }
@@ -184,25 +423,26 @@ func (db *DB) ExecOne(query interface{}, params ...interface{}) (*types.Result,
 // The params are for any placeholders in the query.
 func (db *DB) Query(model, query interface{}, params ...interface{}) (res *types.Result, err error) {
 	var mod orm.Model
-	for i := 0; i < 3; i++ {
+	db.opt.RetryPolicy.deposit()
+	for i := 0; ; i++ {
 		var cn *pool.Conn
 
 		cn, err = db.conn()
 		if err != nil {
+			if db.retry(i, err, true) {
+				continue
+			}
 			return nil, err
 		}
 
+		flushes := cn.Flushes()
 		res, mod, err = db.simpleQueryData(cn, model, query, params...)
-		db.freeConn(cn, err)
+		safe := cn.Flushes() == flushes || isIdempotent(query)
+		err = db.freeConn(cn, err)
 
-		if i >= db.opt.MaxRetries {
-			break
-		}
-		if !db.shouldRetry(err) {
+		if !db.retry(i, err, safe) {
 			break
 		}
-
-		time.Sleep(internal.RetryBackoff << uint(i))
 	}
 	if err != nil {
 		return nil, err
@@ -237,6 +477,84 @@ func (db *DB) QueryOne(model, query interface{}, params ...interface{}) (*types.
 	return res, nil
 }
 
+// ExecNamed is like Exec, but replaces :name placeholders in the query
+// with the fields of the struct or the values of the map, e.g.
+//
+//    _, err := db.ExecNamed(
+//        "UPDATE users SET status = :status WHERE id = :id",
+//        map[string]interface{}{"id": 1, "status": "active"},
+//    )
+//
+// The same name can be used multiple times.
+func (db *DB) ExecNamed(query string, arg interface{}) (*types.Result, error) {
+	return db.Exec(orm.Named(query, arg))
+}
+
+// QueryNamed is like Query, but replaces :name placeholders in the query
+// with the fields of the struct or the values of the map like ExecNamed.
+func (db *DB) QueryNamed(model interface{}, query string, arg interface{}) (*types.Result, error) {
+	return db.Query(model, orm.Named(query, arg))
+}
+
+// ExecMulti executes a query that contains several statements
+// separated by semicolons ignoring returned rows and returns the result
+// of each statement. The statements run in a single transaction unless
+// the query contains explicit transaction control commands.
+func (db *DB) ExecMulti(query interface{}, params ...interface{}) ([]*types.Result, error) {
+	return db.QueryMulti(nil, query, params...)
+}
+
+// QueryMulti acts like ExecMulti, but scans rows returned by the i-th
+// statement into models[i], e.g.
+//
+//    var users []User
+//    var count int
+//    results, err := db.QueryMulti(
+//        []interface{}{&users, pg.Scan(&count)},
+//        "SELECT * FROM users LIMIT 10; SELECT count(*) FROM users",
+//    )
+//
+// Rows of statements without a model or with a nil model are discarded.
+func (db *DB) QueryMulti(
+	models []interface{}, query interface{}, params ...interface{},
+) (results []*types.Result, err error) {
+	var mods []orm.Model
+	db.opt.RetryPolicy.deposit()
+	for i := 0; ; i++ {
+		var cn *pool.Conn
+
+		cn, err = db.conn()
+		if err != nil {
+			if db.retry(i, err, true) {
+				continue
+			}
+			return nil, err
+		}
+
+		flushes := cn.Flushes()
+		results, mods, err = db.simpleQueryMulti(cn, models, query, params...)
+		safe := cn.Flushes() == flushes || isIdempotent(query)
+		err = db.freeConn(cn, err)
+
+		if !db.retry(i, err, safe) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, res := range results {
+		if res.RowsReturned() > 0 && mods[i] != nil {
+			if err = mods[i].AfterQuery(db); err != nil {
+				return results, err
+			}
+		}
+	}
+
+	return results, nil
+}
+
 // Listen listens for notifications sent with NOTIFY command.
 func (db *DB) Listen(channels ...string) *Listener {
 	ln := &Listener{
@@ -246,6 +564,14 @@ func (db *DB) Listen(channels ...string) *Listener {
 	return ln
 }
 
+// Notify sends a notification with the payload to the channel. The
+// channel is quoted as an identifier, so it is case sensitive, e.g.
+// "MyChannel" is not the same as MyChannel in LISTEN MyChannel.
+func (db *DB) Notify(channel, payload string) error {
+	_, err := db.Exec("NOTIFY ?, ?", F(channel), payload)
+	return err
+}
+
 // CopyFrom copies data from reader to a table.
 func (db *DB) CopyFrom(reader io.Reader, query interface{}, params ...interface{}) (*types.Result, error) {
 	cn, err := db.conn()
@@ -253,41 +579,51 @@ func (db *DB) CopyFrom(reader io.Reader, query interface{}, params ...interface{
 		return nil, err
 	}
 
-	res, err := db.copyFrom(cn, reader, query, params...)
-	db.freeConn(cn, err)
+	res, err := db.copyFrom(cn, reader, nil, query, params...)
+	err = db.freeConn(cn, err)
 	return res, err
 }
 
-// CopyTo copies data from a table to writer.
-func (db *DB) CopyTo(writer io.Writer, query interface{}, params ...interface{}) (*types.Result, error) {
+// CopyFromWithOptions is like CopyFrom, but reports progress and limits
+// the rate of copied data using the options.
+func (db *DB) CopyFromWithOptions(
+	reader io.Reader, opt *CopyOptions, query interface{}, params ...interface{},
+) (*types.Result, error) {
 	cn, err := db.conn()
 	if err != nil {
 		return nil, err
 	}
 
-	if err := writeQueryMsg(cn.Wr, db, query, params...); err != nil {
-		db.pool.Put(cn)
-		return nil, err
-	}
+	res, err := db.copyFrom(cn, reader, opt, query, params...)
+	err = db.freeConn(cn, err)
+	return res, err
+}
 
-	if err := cn.FlushWriter(); err != nil {
-		db.freeConn(cn, err)
+// CopyTo copies data from a table to writer.
+func (db *DB) CopyTo(writer io.Writer, query interface{}, params ...interface{}) (*types.Result, error) {
+	cn, err := db.conn()
+	if err != nil {
 		return nil, err
 	}
 
-	if err := readCopyOutResponse(cn); err != nil {
-		db.freeConn(cn, err)
-		return nil, err
-	}
+	res, err := db.copyTo(cn, writer, nil, query, params...)
+	err = db.freeConn(cn, err)
+	return res, err
+}
 
-	res, err := readCopyData(cn, writer)
+// CopyToWithOptions is like CopyTo, but reports progress and limits the
+// rate of copied data using the options.
+func (db *DB) CopyToWithOptions(
+	writer io.Writer, opt *CopyOptions, query interface{}, params ...interface{},
+) (*types.Result, error) {
+	cn, err := db.conn()
 	if err != nil {
-		db.freeConn(cn, err)
 		return nil, err
 	}
 
-	db.pool.Put(cn)
-	return res, nil
+	res, err := db.copyTo(cn, writer, opt, query, params...)
+	err = db.freeConn(cn, err)
+	return res, err
 }
 
 // Model returns new query for the model.
@@ -318,20 +654,144 @@ func (db *DB) Delete(model interface{}) error {
 // CreateTable creates table for the model. It recognizes following field tags:
 //   - notnull - sets NOT NULL constraint.
 //   - unique - sets UNIQUE constraint.
+//
+// Indexes are created by CreateIndexes.
 func (db *DB) CreateTable(model interface{}, opt *orm.CreateTableOptions) error {
 	_, err := orm.CreateTable(db, model, opt)
 	return err
 }
 
+// CreateIndexes creates the indexes declared with index tags on the
+// model fields, e.g. `pg:",index"`. See orm.Index.
+func (db *DB) CreateIndexes(model interface{}, opt *orm.CreateIndexOptions) error {
+	return orm.CreateIndexes(db, model, opt)
+}
+
+// CreateExtension creates the extension, e.g.
+//
+//    err := db.CreateExtension("hstore", &orm.CreateExtensionOptions{IfNotExists: true})
+func (db *DB) CreateExtension(name string, opt *orm.CreateExtensionOptions) error {
+	_, err := orm.CreateExtension(db, name, opt)
+	return err
+}
+
+// CreateEnum creates enum type with the values, e.g.
+//
+//    err := db.CreateEnum("mood", "sad", "ok", "happy")
+func (db *DB) CreateEnum(name string, values ...string) error {
+	_, err := orm.CreateEnum(db, name, values)
+	return err
+}
+
+// AddEnumValue adds the value to enum type, e.g.
+//
+//    err := db.AddEnumValue("mood", "angry", &orm.AddEnumValueOptions{Before: "sad"})
+func (db *DB) AddEnumValue(name, value string, opt *orm.AddEnumValueOptions) error {
+	_, err := orm.AddEnumValue(db, name, value, opt)
+	return err
+}
+
+// CreateSchema creates extensions, enums, tables and indexes for the models in
+// a single transaction. orm.Extension and orm.Enum values create
+// extensions and enum types before any table is created. Tables are
+// created after the tables they reference, e.g.
+//
+//    err := db.CreateSchema(orm.Extension("hstore"), &Author{}, &Book{})
+func (db *DB) CreateSchema(models ...interface{}) error {
+	return db.RunInTransaction(func(tx *Tx) error {
+		return orm.CreateSchema(tx, models, nil)
+	})
+}
+
+// Sync reconciles the table with reference rows in the model, which
+// must be a pointer to a slice of structs, in a single transaction.
+// Missing rows are inserted, changed rows are updated and, when
+// opt.Delete is set, extra rows are deleted. It returns the changes
+// that were made, e.g.
+//
+//    roles := []Role{{Id: 1, Name: "admin"}, {Id: 2, Name: "user"}}
+//    changes, err := db.Sync(&roles, &orm.SyncOptions{Delete: true})
+func (db *DB) Sync(model interface{}, opt *orm.SyncOptions) ([]orm.SyncChange, error) {
+	var changes []orm.SyncChange
+	err := db.RunInTransaction(func(tx *Tx) error {
+		var err error
+		changes, err = orm.Sync(tx, model, opt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
 func (db *DB) FormatQuery(dst []byte, query string, params ...interface{}) []byte {
 	return db.fmter.Append(dst, query, params...)
 }
 
+// statementTimeout returns the timeout of the query set with
+// orm.Query.Timeout.
+func statementTimeout(query interface{}) time.Duration {
+	if q, ok := query.(interface {
+		StatementTimeout() time.Duration
+	}); ok {
+		return q.StatementTimeout()
+	}
+	return 0
+}
+
+// isIdempotent reports whether the query is marked with Idempotent or
+// orm.Query.Idempotent.
+func isIdempotent(query interface{}) bool {
+	if q, ok := query.(interface {
+		IsIdempotent() bool
+	}); ok {
+		return q.IsIdempotent()
+	}
+	return false
+}
+
+// cancelAfter cancels the statement running on the connection when it
+// takes longer than d. The read deadline is extended, so the server
+// has ReadTimeout to respond to the cancellation. The returned function
+// stops the timer and waits until the cancel request is sent. The
+// cancel request is processed by the server asynchronously, so when it
+// was sent but the statement did not fail with the query_canceled
+// error, the connection is marked as unhealthy; otherwise the request
+// could cancel the next statement run on the connection.
+func (db *DB) cancelAfter(cn *pool.Conn, d time.Duration) func(err error) {
+	if db.opt.ReadTimeout > 0 {
+		cn.SetReadWriteTimeout(d+db.opt.ReadTimeout, db.opt.WriteTimeout)
+	}
+
+	done := make(chan struct{})
+	t := time.AfterFunc(d, func() {
+		defer close(done)
+		if err := db.cancelRequest(cn.ProcessId, cn.SecretKey); err != nil {
+			internal.Logf("cancelRequest failed: %s", err)
+		}
+	})
+	return func(err error) {
+		if t.Stop() {
+			return
+		}
+		<-done
+		if !isQueryCanceled(err) {
+			cn.CancelPending = true
+		}
+	}
+}
+
+func isQueryCanceled(err error) bool {
+	pgErr, ok := err.(Error)
+	return ok && pgErr.Field('C') == "57014" // query_canceled
+}
+
 func (db *DB) cancelRequest(processId, secretKey int32) error {
 	cn, err := db.pool.NewConn()
 	if err != nil {
 		return err
 	}
+	db.traceConn(cn)
 
 	writeCancelRequestMsg(cn.Wr, processId, secretKey)
 	if err = cn.FlushWriter(); err != nil {
@@ -344,47 +804,121 @@ func (db *DB) cancelRequest(processId, secretKey int32) error {
 
 func (db *DB) simpleQuery(
 	cn *pool.Conn, query interface{}, params ...interface{},
-) (*types.Result, error) {
+) (res *types.Result, err error) {
 	if err := writeQueryMsg(cn.Wr, db, query, params...); err != nil {
 		return nil, err
 	}
+	cn.Queries++
+	if qlog := newQueryLog(db, cn.Wr); qlog != nil {
+		defer func() { qlog.done(cn, err) }()
+	}
 
 	if err := cn.FlushWriter(); err != nil {
 		return nil, err
 	}
+	if d := statementTimeout(query); d > 0 {
+		stop := db.cancelAfter(cn, d)
+		defer func() { stop(err) }()
+	}
 
 	return readSimpleQuery(cn)
 }
 
 func (db *DB) simpleQueryData(
 	cn *pool.Conn, model, query interface{}, params ...interface{},
-) (*types.Result, orm.Model, error) {
+) (res *types.Result, mod orm.Model, err error) {
+	params = withModelParam(model, query, params)
 	if err := writeQueryMsg(cn.Wr, db, query, params...); err != nil {
 		return nil, nil, err
 	}
+	cn.Queries++
+	if qlog := newQueryLog(db, cn.Wr); qlog != nil {
+		defer func() { qlog.done(cn, err) }()
+	}
 
 	if err := cn.FlushWriter(); err != nil {
 		return nil, nil, err
 	}
+	if d := statementTimeout(query); d > 0 {
+		stop := db.cancelAfter(cn, d)
+		defer func() { stop(err) }()
+	}
 
 	return readSimpleQueryData(cn, model)
 }
 
-func (db *DB) copyFrom(cn *pool.Conn, r io.Reader, query interface{}, params ...interface{}) (*types.Result, error) {
+// withModelParam adds the model of the struct or the slice of structs
+// to the params of the raw query, so the query can use placeholders
+// like ?TableName and ?Columns, e.g.
+//
+//    db.Query(&users, "SELECT ?Columns FROM ?TableName WHERE ?PKs IN (?)", pg.In(ids))
+func withModelParam(model, query interface{}, params []interface{}) []interface{} {
+	q, ok := query.(string)
+	if !ok || strings.IndexByte(q, '?') == -1 {
+		return params
+	}
+	m, ok := orm.TableModel(model)
+	if !ok {
+		return params
+	}
+	return append(params[:len(params):len(params)], m)
+}
+
+func (db *DB) simpleQueryMulti(
+	cn *pool.Conn, models []interface{}, query interface{}, params ...interface{},
+) (results []*types.Result, mods []orm.Model, err error) {
+	if err := writeQueryMsg(cn.Wr, db, query, params...); err != nil {
+		return nil, nil, err
+	}
+	cn.Queries++
+	if qlog := newQueryLog(db, cn.Wr); qlog != nil {
+		defer func() { qlog.done(cn, err) }()
+	}
+
+	if err := cn.FlushWriter(); err != nil {
+		return nil, nil, err
+	}
+	if d := statementTimeout(query); d > 0 {
+		stop := db.cancelAfter(cn, d)
+		defer func() { stop(err) }()
+	}
+
+	return readSimpleQueryMulti(cn, models)
+}
+
+func (db *DB) copyFrom(
+	cn *pool.Conn, r io.Reader, opt *CopyOptions, query interface{}, params ...interface{},
+) (res *types.Result, err error) {
 	if err := writeQueryMsg(cn.Wr, db, query, params...); err != nil {
 		return nil, err
 	}
+	cn.Queries++
+	if qlog := newQueryLog(db, cn.Wr); qlog != nil {
+		defer func() { qlog.done(cn, err) }()
+	}
 
 	if err := cn.FlushWriter(); err != nil {
 		return nil, err
 	}
+	if d := statementTimeout(query); d > 0 {
+		stop := db.cancelAfter(cn, d)
+		defer func() { stop(err) }()
+	}
 
 	if err := readCopyInResponse(cn); err != nil {
 		return nil, err
 	}
 
+	state := newCopyState(db.ctx, opt)
 	for {
-		if _, err := writeCopyData(cn.Wr, r); err != nil {
+		n, err := writeCopyData(cn.Wr, r)
+		if n > 0 && state != nil {
+			if err := state.wait(int(n)); err != nil {
+				return nil, err
+			}
+			state.copied(int(n), countLines(cn.Wr.Bytes[len(cn.Wr.Bytes)-int(n):]))
+		}
+		if err != nil {
 			if err == io.EOF {
 				break
 			}
@@ -403,3 +937,29 @@ func (db *DB) copyFrom(cn *pool.Conn, r io.Reader, query interface{}, params ...
 
 	return readReadyForQuery(cn)
 }
+
+func (db *DB) copyTo(
+	cn *pool.Conn, w io.Writer, opt *CopyOptions, query interface{}, params ...interface{},
+) (res *types.Result, err error) {
+	if err := writeQueryMsg(cn.Wr, db, query, params...); err != nil {
+		return nil, err
+	}
+	cn.Queries++
+	if qlog := newQueryLog(db, cn.Wr); qlog != nil {
+		defer func() { qlog.done(cn, err) }()
+	}
+
+	if err := cn.FlushWriter(); err != nil {
+		return nil, err
+	}
+	if d := statementTimeout(query); d > 0 {
+		stop := db.cancelAfter(cn, d)
+		defer func() { stop(err) }()
+	}
+
+	if err := readCopyOutResponse(cn); err != nil {
+		return nil, err
+	}
+
+	return readCopyData(cn, w, newCopyState(db.ctx, opt))
+}
@@ -2,22 +2,37 @@ package pg_test
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"gopkg.in/pg.v5"
 	"gopkg.in/pg.v5/orm"
+	"gopkg.in/pg.v5/pgtest"
+	"gopkg.in/pg.v5/types"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
 func init() {
-	//pg.SetLogger(log.New(os.Stderr, "pg: ", log.LstdFlags))
+	//pg.SetLogger(pg.StdLogger(log.New(os.Stderr, "pg: ", log.LstdFlags), pg.LogDebug))
 }
 
 func TestGinkgo(t *testing.T) {
@@ -41,7 +56,7 @@ func pgOptions() *pg.Options {
 		PoolSize:           10,
 		PoolTimeout:        30 * time.Second,
 		IdleTimeout:        10 * time.Second,
-		MaxAge:             10 * time.Second,
+		MaxConnAge:         10 * time.Second,
 		IdleCheckFrequency: 100 * time.Millisecond,
 	}
 }
@@ -278,63 +293,2359 @@ var _ = Describe("CopyFrom/CopyTo", func() {
 		Expect(st.FreeConns).To(Equal(uint32(1)))
 	})
 
+	It("reports progress and limits the rate", func() {
+		limiter := new(countingLimiter)
+		var progress [][2]int64
+		opt := &pg.CopyOptions{
+			Progress: func(bytes, rows int64) {
+				progress = append(progress, [2]int64{bytes, rows})
+			},
+			RateLimiter: limiter,
+		}
+
+		var buf bytes.Buffer
+		res, err := db.CopyToWithOptions(&buf, opt, "COPY copy_from TO STDOUT")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RowsAffected()).To(Equal(n))
+		Expect(progress).To(HaveLen(n))
+		Expect(progress[n-1]).To(Equal([2]int64{int64(buf.Len()), n}))
+		Expect(limiter.n).To(Equal(int64(buf.Len())))
+
+		size := int64(buf.Len())
+		progress = nil
+		limiter.n = 0
+		res, err = db.CopyFromWithOptions(&buf, opt, "COPY copy_to FROM STDIN")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RowsAffected()).To(Equal(n))
+		Expect(progress[len(progress)-1]).To(Equal([2]int64{size, n}))
+		Expect(limiter.n).To(Equal(size))
+	})
+
+	It("stops copying when the rate limiter fails", func() {
+		opt := &pg.CopyOptions{
+			RateLimiter: rateLimiterFunc(func(ctx context.Context, n int) error {
+				return errors.New("limit exceeded")
+			}),
+		}
+		_, err := db.CopyToWithOptions(ioutil.Discard, opt, "COPY copy_from TO STDOUT")
+		Expect(err).To(MatchError("limit exceeded"))
+	})
+
+	It("copies data to a model", func() {
+		type CopyModel struct {
+			Id   int
+			Name string
+			Note *string
+		}
+
+		_, err := db.Exec(`CREATE TEMP TABLE copy_models (id int, name text, note text)`)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = db.Exec(`INSERT INTO copy_models VALUES (1, 'a,"b"', NULL), (2, E'tab\tnewline\n', '')`)
+		Expect(err).NotTo(HaveOccurred())
+
+		empty := ""
+		wanted := []CopyModel{
+			{Id: 1, Name: `a,"b"`},
+			{Id: 2, Name: "tab\tnewline\n", Note: &empty},
+		}
+
+		for _, query := range []string{
+			"COPY (SELECT name, note, id FROM copy_models ORDER BY id) TO STDOUT WITH CSV HEADER",
+			"COPY (SELECT * FROM copy_models ORDER BY id) TO STDOUT WITH CSV",
+			"COPY (SELECT * FROM copy_models ORDER BY id) TO STDOUT",
+		} {
+			var models []CopyModel
+			res, err := db.CopyToModel(&models, query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.RowsAffected()).To(Equal(2))
+			Expect(models).To(Equal(wanted), query)
+		}
+
+		var models []CopyModel
+		_, err = db.CopyToModel(&models, "COPY copy_models TO STDOUT WITH BINARY")
+		Expect(err).To(MatchError("pg: CopyToModel does not support binary format"))
+	})
+
 	It("copies corrupted data to a table", func() {
 		buf := bytes.NewBufferString("corrupted data")
 		res, err := db.CopyFrom(buf, "COPY copy_to FROM STDIN")
 		Expect(err).To(MatchError(`ERROR #22P02 invalid input syntax for integer: "corrupted data" (addr="127.0.0.1:5432")`))
 		Expect(res).To(BeNil())
 
-		st := db.Pool().Stats()
-		Expect(st.Requests).To(Equal(uint32(4)))
-		Expect(st.Hits).To(Equal(uint32(3)))
-		Expect(st.Timeouts).To(Equal(uint32(0)))
-		Expect(st.TotalConns).To(Equal(uint32(1)))
-		Expect(st.FreeConns).To(Equal(uint32(1)))
+		st := db.Pool().Stats()
+		Expect(st.Requests).To(Equal(uint32(4)))
+		Expect(st.Hits).To(Equal(uint32(3)))
+		Expect(st.Timeouts).To(Equal(uint32(0)))
+		Expect(st.TotalConns).To(Equal(uint32(1)))
+		Expect(st.FreeConns).To(Equal(uint32(1)))
+	})
+})
+
+type countingLimiter struct {
+	n int64
+}
+
+func (l *countingLimiter) WaitN(ctx context.Context, n int) error {
+	l.n += int64(n)
+	return nil
+}
+
+type rateLimiterFunc func(ctx context.Context, n int) error
+
+func (fn rateLimiterFunc) WaitN(ctx context.Context, n int) error {
+	return fn(ctx, n)
+}
+
+var _ = Describe("WithSession", func() {
+	var db *pg.DB
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions())
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).NotTo(HaveOccurred())
+	})
+
+	It("runs queries on the same connection", func() {
+		err := db.WithSession(func(cn *pg.Conn) error {
+			_, err := cn.Exec("CREATE TEMP TABLE session_test(n int)")
+			if err != nil {
+				return err
+			}
+
+			_, err = cn.CopyFrom(bytes.NewBufferString("1\n2\n3\n"), "COPY session_test FROM STDIN")
+			if err != nil {
+				return err
+			}
+
+			var count int
+			_, err = cn.QueryOne(pg.Scan(&count), "SELECT count(*) FROM session_test")
+			if err != nil {
+				return err
+			}
+			Expect(count).To(Equal(3))
+
+			_, err = cn.Exec("DROP TABLE session_test")
+			return err
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		st := db.Pool().Stats()
+		Expect(st.TotalConns).To(Equal(uint32(1)))
+		Expect(st.FreeConns).To(Equal(uint32(1)))
+	})
+
+	It("closes the connection on error", func() {
+		err := db.WithSession(func(cn *pg.Conn) error {
+			_, err := cn.Exec("CREATE TEMP TABLE session_test(n int)")
+			if err != nil {
+				return err
+			}
+			return errors.New("fail")
+		})
+		Expect(err).To(MatchError("fail"))
+
+		st := db.Pool().Stats()
+		Expect(st.TotalConns).To(Equal(uint32(0)))
+		Expect(st.FreeConns).To(Equal(uint32(0)))
+	})
+
+	It("closes the connection on panic", func() {
+		srv := pgtest.NewServer(nil)
+		defer srv.Close()
+		srv.Handle("SELECT 1", &pgtest.Response{Tag: "SELECT 1"})
+
+		db := pg.Connect(&pg.Options{
+			User:   "postgres",
+			Dialer: srv.Dial,
+		})
+		defer db.Close()
+
+		Expect(func() {
+			db.WithSession(func(cn *pg.Conn) error {
+				_, err := cn.Exec("SELECT 1")
+				Expect(err).NotTo(HaveOccurred())
+				panic("fail")
+			})
+		}).To(PanicWith("fail"))
+
+		st := db.Pool().Stats()
+		Expect(st.TotalConns).To(Equal(uint32(0)))
+		Expect(st.FreeConns).To(Equal(uint32(0)))
+	})
+})
+
+var _ = Describe("Conn", func() {
+	var db *pg.DB
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions())
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).NotTo(HaveOccurred())
+	})
+
+	It("keeps session state until released", func() {
+		cn, err := db.Conn()
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = cn.Exec("SET application_name = 'conn_test'")
+		Expect(err).NotTo(HaveOccurred())
+
+		var name string
+		_, err = cn.QueryOne(pg.Scan(&name), "SHOW application_name")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(Equal("conn_test"))
+
+		Expect(cn.Release()).NotTo(HaveOccurred())
+		Expect(cn.Release()).To(MatchError("pg: connection is closed"))
+
+		_, err = db.QueryOne(pg.Scan(&name), "SHOW application_name")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(Equal(""))
+
+		st := db.Pool().Stats()
+		Expect(st.TotalConns).To(Equal(uint32(1)))
+		Expect(st.FreeConns).To(Equal(uint32(1)))
+	})
+
+	It("closes the connection when session can't be reset", func() {
+		cn, err := db.Conn()
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = cn.Exec("BEGIN")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = cn.Release()
+		Expect(err).NotTo(HaveOccurred())
+
+		st := db.Pool().Stats()
+		Expect(st.TotalConns).To(Equal(uint32(0)))
+	})
+
+	It("sends DISCARD ALL on release", func() {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		var buf bytes.Buffer
+		db := pg.Connect(&pg.Options{
+			User:      "postgres",
+			Database:  "postgres",
+			TraceWire: &buf,
+			Dialer:    srv.Dial,
+		})
+		defer db.Close()
+
+		cn, err := db.Conn()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cn.Release()).NotTo(HaveOccurred())
+		Expect(buf.String()).To(ContainSubstring(`Query "DISCARD ALL"`))
+		Expect(db.Pool().FreeLen()).To(Equal(1))
+	})
+})
+
+var _ = Describe("DB.WithContext", func() {
+	It("stops waiting for a free connection when the context is done", func() {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		db := pg.Connect(&pg.Options{
+			User:     "postgres",
+			Database: "postgres",
+			PoolSize: 1,
+			Dialer:   srv.Dial,
+		})
+		defer db.Close()
+
+		cn, err := db.Conn()
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = db.WithContext(ctx).Exec("SELECT 1")
+		Expect(err).To(Equal(context.DeadlineExceeded))
+
+		st := db.PoolStats()
+		Expect(st.WaitCount).To(Equal(uint32(1)))
+		Expect(st.WaitDuration).To(BeNumerically(">=", 10*time.Millisecond))
+
+		Expect(cn.Release()).NotTo(HaveOccurred())
+		_, err = db.WithContext(ctx).Exec("SELECT 1")
+		Expect(err).To(Equal(context.DeadlineExceeded))
+		_, err = db.Exec("SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
+
+var _ = Describe("DB.WithTimeout and DB.WithParam", func() {
+	var srv *pgtest.Server
+	var db *pg.DB
+
+	BeforeEach(func() {
+		srv = pgtest.NewServer(nil)
+		db = pg.Connect(&pg.Options{
+			User:   "postgres",
+			Dialer: srv.Dial,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).NotTo(HaveOccurred())
+		Expect(srv.Close()).NotTo(HaveOccurred())
+	})
+
+	It("applies the timeout only to the derived DB", func() {
+		srv.Handle("SELECT pg_sleep(0.05)", &pgtest.Response{
+			Delay: 50 * time.Millisecond,
+			Tag:   "SELECT 1",
+		})
+
+		_, err := db.WithTimeout(10 * time.Millisecond).Exec("SELECT pg_sleep(0.05)")
+		Expect(err).To(HaveOccurred())
+		Expect(err.(net.Error).Timeout()).To(BeTrue())
+
+		_, err = db.Exec("SELECT pg_sleep(0.05)")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("resolves the param only in the derived DB", func() {
+		srv.Handle("SELECT 'app'", &pgtest.Response{Tag: "SELECT 1"})
+		srv.Handle("SELECT ?schema", &pgtest.Response{Tag: "SELECT 1"})
+
+		_, err := db.WithParam("schema", "app").Exec("SELECT ?schema")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = db.Exec("SELECT ?schema")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(srv.Queries()).To(Equal([]string{"SELECT 'app'", "SELECT ?schema"}))
+		Expect(db.PoolStats().TotalConns).To(Equal(uint32(1)))
+	})
+})
+
+var _ = Describe("DB.Notify", func() {
+	It("quotes channel and payload", func() {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		var buf bytes.Buffer
+		db := pg.Connect(&pg.Options{
+			User:      "postgres",
+			Database:  "postgres",
+			TraceWire: &buf,
+			Dialer:    srv.Dial,
+		})
+		defer db.Close()
+
+		err := db.Notify(`my "chan"`, "it's")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(buf.String()).To(ContainSubstring(`Query "NOTIFY \"my \"\"chan\"\"\", 'it''s'"`))
+	})
+})
+
+var _ = Describe("ListenerPingInterval option", func() {
+	var buf bytes.Buffer
+
+	// pingServer completes queries with LISTEN, but answers empty
+	// queries with EmptyQueryResponse or ignores them when answer is
+	// false.
+	pingServer := func(cn net.Conn, answer bool) {
+		defer cn.Close()
+
+		writeMsg := func(c byte, b []byte) {
+			msg := []byte{c, 0, 0, 0, 0}
+			binary.BigEndian.PutUint32(msg[1:], uint32(len(b)+4))
+			cn.Write(append(msg, b...))
+		}
+		readN := func(n int) []byte {
+			b := make([]byte, n)
+			if _, err := io.ReadFull(cn, b); err != nil {
+				return nil
+			}
+			return b
+		}
+
+		b := readN(4)
+		if b == nil {
+			return
+		}
+		readN(int(binary.BigEndian.Uint32(b)) - 4)
+		writeMsg('R', []byte{0, 0, 0, 0})
+		writeMsg('Z', []byte{'I'})
+
+		for {
+			hdr := readN(5)
+			if hdr == nil || hdr[0] == 'X' {
+				return
+			}
+			body := readN(int(binary.BigEndian.Uint32(hdr[1:])) - 4)
+			if hdr[0] == 'Q' && string(body) == "\x00" {
+				if answer {
+					writeMsg('I', nil)
+					writeMsg('Z', []byte{'I'})
+				}
+				continue
+			}
+			writeMsg('C', []byte("LISTEN\x00"))
+			writeMsg('Z', []byte{'I'})
+		}
+	}
+
+	connect := func(answer bool) *pg.DB {
+		buf.Reset()
+		return pg.Connect(&pg.Options{
+			User:                 "postgres",
+			Database:             "postgres",
+			ListenerPingInterval: 50 * time.Millisecond,
+			TraceWire:            &buf,
+			Dialer: func(network, addr string) (net.Conn, error) {
+				client, server := net.Pipe()
+				go pingServer(server, answer)
+				return client, nil
+			},
+		})
+	}
+
+	It("keeps live connection", func() {
+		db := connect(true)
+		defer db.Close()
+		ln := db.Listen("test_channel")
+		defer ln.Close()
+
+		_, _, err := ln.ReceiveTimeout(300 * time.Millisecond)
+		Expect(err).To(HaveOccurred())
+		Expect(err.(net.Error).Timeout()).To(BeTrue())
+		Expect(strings.Count(buf.String(), `Query ""`)).To(BeNumerically(">=", 3))
+		Expect(buf.String()).To(ContainSubstring("EmptyQueryResponse"))
+	})
+
+	It("detects dead connection", func() {
+		db := connect(false)
+		defer db.Close()
+		ln := db.Listen("test_channel")
+		defer ln.Close()
+
+		start := time.Now()
+		_, _, err := ln.Receive()
+		Expect(err).To(MatchError("pg: listener ping timed out"))
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		Expect(strings.Count(buf.String(), `Query ""`)).To(Equal(1))
+	})
+})
+
+var _ = Describe("ResetSessionQuery option", func() {
+	var buf bytes.Buffer
+	var srv *pgtest.Server
+	var db *pg.DB
+
+	BeforeEach(func() {
+		srv = newFakeServer()
+		buf.Reset()
+		db = pg.Connect(&pg.Options{
+			User:              "postgres",
+			Database:          "postgres",
+			ResetSessionQuery: "RESET ALL",
+			TraceWire:         &buf,
+			Dialer:            srv.Dial,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).NotTo(HaveOccurred())
+		Expect(srv.Close()).NotTo(HaveOccurred())
+	})
+
+	queries := func() []string {
+		var qs []string
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if ind := strings.Index(line, " Query "); ind != -1 {
+				qs = append(qs, line[ind+len(" Query "):])
+			}
+		}
+		return qs
+	}
+
+	It("resets session after transaction", func() {
+		err := db.RunInTransaction(func(tx *pg.Tx) error {
+			_, err := tx.Exec("SET search_path = test")
+			return err
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(queries()).To(Equal([]string{
+			`"BEGIN"`, `"SET search_path = test"`, `"COMMIT"`, `"RESET ALL"`,
+		}))
+		Expect(db.Pool().FreeLen()).To(Equal(1))
+	})
+
+	It("resets session after WithSession", func() {
+		err := db.WithSession(func(cn *pg.Conn) error {
+			_, err := cn.Exec("SET search_path = test")
+			return err
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(queries()).To(Equal([]string{`"SET search_path = test"`, `"RESET ALL"`}))
+	})
+
+	It("is used by Conn.Release", func() {
+		cn, err := db.Conn()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cn.Release()).NotTo(HaveOccurred())
+		Expect(queries()).To(Equal([]string{`"RESET ALL"`}))
+	})
+
+	It("is not used by DB queries", func() {
+		_, err := db.Exec("SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(queries()).To(Equal([]string{`"SELECT 1"`}))
+	})
+})
+
+var _ = Describe("OnConnect option", func() {
+	It("initializes session", func() {
+		opt := pgOptions()
+		opt.OnConnect = func(cn *pg.Conn) error {
+			_, err := cn.Exec("SET application_name = 'on_connect_test'")
+			return err
+		}
+		db := pg.Connect(opt)
+		defer db.Close()
+
+		var name string
+		_, err := db.QueryOne(pg.Scan(&name), "SHOW application_name")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(Equal("on_connect_test"))
+	})
+
+	It("runs once per connection and after session reset", func() {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		var buf bytes.Buffer
+		var calls int
+		db := pg.Connect(&pg.Options{
+			User:      "postgres",
+			Database:  "postgres",
+			TraceWire: &buf,
+			OnConnect: func(cn *pg.Conn) error {
+				calls++
+				_, err := cn.Exec("SET search_path = test")
+				return err
+			},
+			Dialer: srv.Dial,
+		})
+		defer db.Close()
+
+		for i := 0; i < 2; i++ {
+			_, err := db.Exec("SELECT 1")
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(calls).To(Equal(1))
+
+		cn, err := db.Conn()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cn.Release()).NotTo(HaveOccurred())
+		Expect(calls).To(Equal(2))
+
+		Expect(strings.Count(buf.String(), `Query "SET search_path = test"`)).To(Equal(2))
+	})
+
+	It("discards connection on error", func() {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		db := pg.Connect(&pg.Options{
+			User:     "postgres",
+			Database: "postgres",
+			OnConnect: func(cn *pg.Conn) error {
+				return errors.New("on connect failed")
+			},
+			Dialer: srv.Dial,
+		})
+		defer db.Close()
+
+		_, err := db.Exec("SELECT 1")
+		Expect(err).To(MatchError("on connect failed"))
+		Expect(db.Pool().Len()).To(Equal(0))
+	})
+})
+
+var _ = Describe("QueryCursor", func() {
+	var db *pg.DB
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions())
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).NotTo(HaveOccurred())
+	})
+
+	It("fetches rows in batches", func() {
+		var nums []int
+		var batches [][]int
+		err := db.QueryCursor(&nums, "SELECT generate_series(1, 25)", 10, func() error {
+			batches = append(batches, append([]int(nil), nums...))
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(batches).To(HaveLen(3))
+		Expect(batches[0]).To(HaveLen(10))
+		Expect(batches[2]).To(Equal([]int{21, 22, 23, 24, 25}))
+
+		st := db.Pool().Stats()
+		Expect(st.TotalConns).To(Equal(uint32(1)))
+		Expect(st.FreeConns).To(Equal(uint32(1)))
+	})
+
+	It("stops when fn returns an error", func() {
+		var nums []int
+		var n int
+		err := db.QueryCursor(&nums, "SELECT generate_series(1, 25)", 10, func() error {
+			n++
+			return errors.New("stop")
+		})
+		Expect(err).To(MatchError("stop"))
+		Expect(n).To(Equal(1))
+	})
+})
+
+var _ = Describe("ForEach", func() {
+	type Num struct {
+		N int
+	}
+
+	var db *pg.DB
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions())
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).NotTo(HaveOccurred())
+	})
+
+	It("calls fn for every row", func() {
+		var nums []int
+		_, err := db.Query(pg.ForEach(func(n *Num) error {
+			nums = append(nums, n.N)
+			return nil
+		}), "SELECT generate_series(1, 3) AS n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nums).To(Equal([]int{1, 2, 3}))
+	})
+
+	It("stops calling fn on error", func() {
+		var calls int
+		_, err := db.Query(pg.ForEach(func(n *Num) error {
+			calls++
+			return errors.New("stop")
+		}), "SELECT generate_series(1, 3) AS n")
+		Expect(err).To(MatchError("stop"))
+		Expect(calls).To(Equal(1))
+	})
+})
+
+var _ = Describe("ServeCSV/ServeNDJSON", func() {
+	var db *pg.DB
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions())
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).NotTo(HaveOccurred())
+	})
+
+	It("streams rows as CSV", func() {
+		req, _ := http.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+
+		err := db.ServeCSV(w, req, "SELECT n, ? AS s FROM generate_series(1, 2) n", "a,b")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(w.Header().Get("Content-Type")).To(Equal("text/csv; charset=utf-8"))
+		Expect(w.Body.String()).To(Equal("n,s\n1,\"a,b\"\n2,\"a,b\"\n"))
+		Expect(w.Flushed).To(BeTrue())
+	})
+
+	It("streams rows as NDJSON", func() {
+		req, _ := http.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+
+		q := db.Model().ColumnExpr("n, ? AS s", `back\slash`).TableExpr("generate_series(1, 2) n")
+		err := db.ServeNDJSON(w, req, q)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(w.Body.String()).To(Equal(`{"n":1,"s":"back\\slash"}` + "\n" + `{"n":2,"s":"back\\slash"}` + "\n"))
+	})
+})
+
+var _ = Describe("Result.Columns", func() {
+	var db *pg.DB
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions())
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).NotTo(HaveOccurred())
+	})
+
+	It("returns column metadata", func() {
+		res, err := db.Query(pg.Discard, "SELECT 1::int AS num, 'foo'::varchar(10) AS str")
+		Expect(err).NotTo(HaveOccurred())
+
+		cols := res.Columns()
+		Expect(cols).To(HaveLen(2))
+		Expect(cols[0].Name).To(Equal("num"))
+		Expect(cols[0].DataType).To(Equal(int32(23)))
+		Expect(cols[0].DataTypeSize).To(Equal(int16(4)))
+		Expect(cols[1].Name).To(Equal("str"))
+		Expect(cols[1].DataType).To(Equal(int32(1043)))
+		Expect(cols[1].TypeModifier).To(Equal(int32(14)))
+	})
+
+	It("returns columns for prepared statements", func() {
+		stmt, err := db.Prepare("SELECT $1::bigint AS num")
+		Expect(err).NotTo(HaveOccurred())
+		defer stmt.Close()
+
+		res, err := stmt.Query(pg.Discard, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Columns()).To(HaveLen(1))
+		Expect(res.Columns()[0].DataType).To(Equal(int32(20)))
+	})
+})
+
+var _ = Describe("Result", func() {
+	var db *pg.DB
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions())
+
+		_, err := db.Exec("CREATE TEMP TABLE result_test (id serial PRIMARY KEY, name text)")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).NotTo(HaveOccurred())
+	})
+
+	It("distinguishes rows affected and returned", func() {
+		res, err := db.Exec("INSERT INTO result_test (name) VALUES ('a'), ('b')")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Command()).To(Equal("INSERT"))
+		Expect(res.RowsAffected()).To(Equal(2))
+		Expect(res.RowsReturned()).To(Equal(0))
+
+		res, err = db.Exec("UPDATE result_test SET name = 'c' WHERE id = 1 RETURNING id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Command()).To(Equal("UPDATE"))
+		Expect(res.RowsAffected()).To(Equal(1))
+		Expect(res.RowsReturned()).To(Equal(1))
+
+		res, err = db.Exec("CREATE TEMP TABLE result_test2 (id int)")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Command()).To(Equal("CREATE TABLE"))
+		Expect(res.RowsAffected()).To(Equal(-1))
+	})
+
+	It("returns LastInsertId of INSERT ... RETURNING", func() {
+		res, err := db.Exec("INSERT INTO result_test (name) VALUES ('a'), ('b') RETURNING id")
+		Expect(err).NotTo(HaveOccurred())
+		id, err := res.LastInsertId()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(id).To(Equal(int64(2)))
+
+		res, err = db.Exec("INSERT INTO result_test (name) VALUES ('c')")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = res.LastInsertId()
+		Expect(err).To(MatchError("pg: LastInsertId requires INSERT ... RETURNING of integer id"))
+	})
+
+	It("does not return LastInsertId of the previous statement", func() {
+		res, err := db.Exec(`
+			INSERT INTO result_test (name) VALUES ('a') RETURNING id;
+			UPDATE result_test SET name = 'b'
+		`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Command()).To(Equal("UPDATE"))
+		_, err = res.LastInsertId()
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("DB.QueryMulti", func() {
+	var db *pg.DB
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions())
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).NotTo(HaveOccurred())
+	})
+
+	It("returns the result of each statement", func() {
+		results, err := db.ExecMulti(`
+			CREATE TEMP TABLE multi_test (id int);
+			INSERT INTO multi_test VALUES (1), (2);
+			SELECT * FROM multi_test`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(HaveLen(3))
+		Expect(results[0].Command()).To(Equal("CREATE TABLE"))
+		Expect(results[1].RowsAffected()).To(Equal(2))
+		Expect(results[2].RowsReturned()).To(Equal(2))
+	})
+
+	It("scans rows of each statement into the model", func() {
+		var ids []int
+		var count int
+		results, err := db.QueryMulti(
+			[]interface{}{pg.Scan(&count), nil, &ids},
+			"SELECT 2; SELECT 3; SELECT generate_series(1, ?)", 3,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(HaveLen(3))
+		Expect(count).To(Equal(2))
+		Expect(results[1].RowsReturned()).To(Equal(1))
+		Expect(ids).To(Equal([]int{1, 2, 3}))
+	})
+
+	It("returns the error of the failed statement", func() {
+		_, err := db.ExecMulti("SELECT 1; SELECT 1/0")
+		Expect(err).To(HaveOccurred())
+		Expect(err.(pg.Error).Field('C')).To(Equal("22012"))
+	})
+
+	It("reads the result of each statement", func() {
+		msg := func(c byte, b string) []byte {
+			m := []byte{c, 0, 0, 0, 0}
+			binary.BigEndian.PutUint32(m[1:], uint32(len(b)+4))
+			return append(m, b...)
+		}
+
+		var reply []byte
+		rowDesc := "\x00\x01n\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x17\x00\x04\xff\xff\xff\xff\x00\x00"
+		reply = append(reply, msg('T', rowDesc)...)
+		reply = append(reply, msg('D', "\x00\x01\x00\x00\x00\x0242")...)
+		reply = append(reply, msg('C', "SELECT 1\x00")...)
+		reply = append(reply, msg('C', "INSERT 0 2\x00")...)
+		reply = append(reply, msg('Z', "I")...)
+
+		db := pg.Connect(&pg.Options{
+			User:     "postgres",
+			Database: "postgres",
+			Dialer: func(network, addr string) (net.Conn, error) {
+				client, server := net.Pipe()
+				go failingServer(server, reply)
+				return client, nil
+			},
+		})
+		defer db.Close()
+
+		var n int
+		results, err := db.QueryMulti([]interface{}{pg.Scan(&n)}, "SELECT 42; INSERT ...")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(42))
+		Expect(results).To(HaveLen(2))
+		Expect(results[0].Command()).To(Equal("SELECT"))
+		Expect(results[0].RowsReturned()).To(Equal(1))
+		Expect(results[0].Columns()[0].Name).To(Equal("n"))
+		Expect(results[1].Command()).To(Equal("INSERT"))
+		Expect(results[1].RowsAffected()).To(Equal(2))
+	})
+})
+
+var _ = Describe("Stmt", func() {
+	var db *pg.DB
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions())
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).NotTo(HaveOccurred())
+	})
+
+	It("is prepared on every connection that executes it", func() {
+		stmt, err := db.Prepare("SELECT $1::int")
+		Expect(err).NotTo(HaveOccurred())
+		defer stmt.Close()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				var n int
+				_, err := stmt.QueryOne(pg.Scan(&n), i)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(n).To(Equal(i))
+
+				_, err = db.Exec("SELECT pg_sleep(0.1)")
+				Expect(err).NotTo(HaveOccurred())
+			}(i)
+		}
+		wg.Wait()
+
+		Expect(db.Pool().Len()).To(BeNumerically(">", 1))
+	})
+
+	It("is prepared again after the statement is deallocated", func() {
+		opt := pgOptions()
+		opt.PoolSize = 1
+		db := pg.Connect(opt)
+		defer db.Close()
+
+		stmt, err := db.Prepare("SELECT $1::int")
+		Expect(err).NotTo(HaveOccurred())
+		defer stmt.Close()
+
+		_, err = db.Exec("DEALLOCATE ALL")
+		Expect(err).NotTo(HaveOccurred())
+
+		var n int
+		_, err = stmt.QueryOne(pg.Scan(&n), 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(1))
+	})
+
+	It("is prepared again after the connection is lost", func() {
+		opt := pgOptions()
+		opt.PoolSize = 1
+		opt.MaxRetries = 1
+		db := pg.Connect(opt)
+		defer db.Close()
+
+		stmt, err := db.Prepare("SELECT $1::int")
+		Expect(err).NotTo(HaveOccurred())
+		defer stmt.Close()
+
+		var pid int
+		_, err = db.QueryOne(pg.Scan(&pid), "SELECT pg_backend_pid()")
+		Expect(err).NotTo(HaveOccurred())
+
+		other := pg.Connect(pgOptions())
+		defer other.Close()
+		_, err = other.Exec("SELECT pg_terminate_backend(?)", pid)
+		Expect(err).NotTo(HaveOccurred())
+
+		var n int
+		_, err = stmt.QueryOne(pg.Scan(&n), 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(1))
+	})
+
+	It("describes param types", func() {
+		stmt, err := db.Prepare("SELECT $1::int, $2::uuid, $3::jsonb")
+		Expect(err).NotTo(HaveOccurred())
+		defer stmt.Close()
+
+		Expect(stmt.ParamTypes()).To(Equal([]int32{23, 2950, 3802}))
+
+		uuid := [16]byte{0x12, 0x3e, 0x45, 0x67}
+		var n int
+		var s, js string
+		_, err = stmt.QueryOne(pg.Scan(&n, &s, &js), 1, uuid, []byte(`{"a": 1}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(1))
+		Expect(s).To(Equal("123e4567-0000-0000-0000-000000000000"))
+		Expect(js).To(Equal(`{"a": 1}`))
+
+		_, err = stmt.Exec(1)
+		Expect(err).To(MatchError("pg: statement expects 3 params, got 1"))
+	})
+
+	It("is deallocated after Close", func() {
+		opt := pgOptions()
+		opt.PoolSize = 1
+		db := pg.Connect(opt)
+		defer db.Close()
+
+		stmt, err := db.Prepare("SELECT $1::int")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stmt.Close()).NotTo(HaveOccurred())
+
+		var count int
+		_, err = db.QueryOne(pg.Scan(&count), "SELECT count(*) FROM pg_prepared_statements")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(count).To(Equal(0))
+
+		_, err = stmt.Exec(1)
+		Expect(err).To(MatchError("pg: statement is closed"))
+	})
+})
+
+var _ = Describe("map model", func() {
+	var db *pg.DB
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions())
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).NotTo(HaveOccurred())
+	})
+
+	It("scans row into map", func() {
+		var m map[string]interface{}
+		_, err := db.QueryOne(&m, `SELECT 1 AS num, 'hello' AS str, true AS bool, NULL AS null, '{"foo":"bar"}'::jsonb AS json`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m).To(Equal(map[string]interface{}{
+			"num":  int64(1),
+			"str":  "hello",
+			"bool": true,
+			"null": nil,
+			"json": map[string]interface{}{"foo": "bar"},
+		}))
+	})
+
+	It("scans rows into slice of maps", func() {
+		var ms []map[string]interface{}
+		_, err := db.Query(&ms, "SELECT n, n * 1.5::float8 AS f FROM generate_series(1, 2) n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ms).To(Equal([]map[string]interface{}{
+			{"n": int64(1), "f": 1.5},
+			{"n": int64(2), "f": 3.0},
+		}))
+	})
+})
+
+var _ = Describe("WithContextValues", func() {
+	var db *pg.DB
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions()).WithContextValues(map[string]interface{}{
+			"request_id": 123,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).NotTo(HaveOccurred())
+	})
+
+	It("replaces params", func() {
+		var n int
+		_, err := db.QueryOne(pg.Scan(&n), "SELECT ?request_id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(123))
+	})
+
+	It("adds comment to the query", func() {
+		db := db.WithContextValues(map[string]interface{}{
+			"user": "*/ DROP TABLE users; /*",
+		})
+
+		var query string
+		_, err := db.QueryOne(pg.Scan(&query), "SELECT current_query()")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(query).To(Equal("/* request_id=123, user=_/ DROP TABLE users; /_ */ SELECT current_query()"))
+	})
+})
+
+var _ = Describe("CountEstimate", func() {
+	var db *pg.DB
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions())
+	})
+
+	It("works", func() {
+		count, err := db.Model().
+			TableExpr("generate_series(1, 10)").
+			CountEstimate(1000)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(count).To(Equal(10))
+	})
+
+	It("works when there are no results", func() {
+		count, err := db.Model().
+			TableExpr("generate_series(1, 0)").
+			CountEstimate(1000)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(count).To(Equal(0))
+	})
+
+	It("works with GROUP", func() {
+		count, err := db.Model().
+			TableExpr("generate_series(1, 10)").
+			Group("generate_series").
+			CountEstimate(1000)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(count).To(Equal(10))
+	})
+
+	It("works with GROUP when there are no results", func() {
+		count, err := db.Model().
+			TableExpr("generate_series(1, 0)").
+			Group("generate_series").
+			CountEstimate(1000)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(count).To(Equal(0))
+	})
+})
+
+var _ = Describe("Explain", func() {
+	var db *pg.DB
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions())
+	})
+
+	AfterEach(func() {
+		err := db.Close()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("returns text plan", func() {
+		plan, err := db.Model().
+			TableExpr("generate_series(1, 10)").
+			Explain(false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan).To(ContainSubstring("Function Scan on generate_series"))
+	})
+
+	It("returns json plan with analyze", func() {
+		plan, err := db.Model().
+			TableExpr("generate_series(1, 10)").
+			ExplainJSON(true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Plan.NodeType).To(Equal("Function Scan"))
+		Expect(plan.Plan.ActualRows).To(Equal(float64(10)))
+		Expect(plan.ExecutionTime).To(BeNumerically(">", 0))
+	})
+})
+
+var _ = Describe("extensions", func() {
+	var db *pg.DB
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions())
+	})
+
+	AfterEach(func() {
+		err := db.Close()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("creates and lists extensions", func() {
+		err := db.CreateExtension("hstore", &orm.CreateExtensionOptions{IfNotExists: true})
+		Expect(err).NotTo(HaveOccurred())
+
+		exts, err := db.ListExtensions()
+		Expect(err).NotTo(HaveOccurred())
+
+		var found bool
+		for _, ext := range exts {
+			if ext.Name == "hstore" {
+				found = true
+				Expect(ext.Version).NotTo(BeEmpty())
+				Expect(ext.Schema).NotTo(BeEmpty())
+			}
+		}
+		Expect(found).To(BeTrue())
+	})
+
+	It("requires installed extension", func() {
+		err := db.CreateExtension("hstore", &orm.CreateExtensionOptions{IfNotExists: true})
+		Expect(err).NotTo(HaveOccurred())
+
+		err = db.RequireExtension("hstore", "1.0")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = db.RequireExtension("hstore", "1000.0")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(`run ALTER EXTENSION "hstore" UPDATE`))
+	})
+
+	It("returns an error for unknown extension", func() {
+		err := db.RequireExtension("go_pg_unknown", "")
+		Expect(err).To(MatchError(
+			`pg: extension "go_pg_unknown" is not available on the server; install the package that provides it`,
+		))
+	})
+
+	It("compares versions", func() {
+		Expect(pg.CompareVersions("1.10", "1.9")).To(Equal(1))
+		Expect(pg.CompareVersions("1.4", "1.4.0")).To(Equal(0))
+		Expect(pg.CompareVersions("1.2", "1.2.1")).To(Equal(-1))
+		Expect(pg.CompareVersions("1.0beta", "1.0")).To(Equal(1))
+	})
+})
+
+var _ = Describe("bytea streaming", func() {
+	var db *pg.DB
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions())
+	})
+
+	AfterEach(func() {
+		err := db.Close()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("streams bytea from reader and into writer", func() {
+		data := bytes.Repeat([]byte{0, 1, 2, 0xff}, 1<<18)
+
+		var buf bytes.Buffer
+		_, err := db.QueryOne(
+			pg.Scan(pg.ByteaWriter(&buf)),
+			"SELECT ?::bytea",
+			pg.ByteaReader(bytes.NewReader(data), int64(len(data))),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(buf.Len()).To(Equal(len(data)))
+		Expect(bytes.Equal(buf.Bytes(), data)).To(BeTrue())
+	})
+})
+
+var _ = Describe("large objects", func() {
+	var db *pg.DB
+	var tx *pg.Tx
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions())
+
+		var err error
+		tx, err = db.Begin()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		err := tx.Rollback()
+		Expect(err).NotTo(HaveOccurred())
+
+		err = db.Close()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("writes, seeks and reads large object", func() {
+		los := tx.LargeObjects()
+
+		oid, err := los.Create(0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(oid).NotTo(BeZero())
+
+		lo, err := los.Open(oid, pg.LargeObjectModeRead|pg.LargeObjectModeWrite)
+		Expect(err).NotTo(HaveOccurred())
+
+		n, err := lo.Write([]byte("hello world"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(11))
+
+		pos, err := lo.Seek(6, io.SeekStart)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pos).To(Equal(int64(6)))
+
+		b, err := ioutil.ReadAll(lo)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal("world"))
+
+		pos, err = lo.Tell()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pos).To(Equal(int64(11)))
+
+		err = lo.Truncate(5)
+		Expect(err).NotTo(HaveOccurred())
+
+		pos, err = lo.Seek(0, io.SeekEnd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pos).To(Equal(int64(5)))
+
+		err = lo.Close()
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = lo.Read(b)
+		Expect(err).To(MatchError("pg: large object is closed"))
+
+		err = los.Unlink(oid)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
+
+type DBTestMood string
+
+func init() {
+	types.RegisterEnum("db_test_mood", DBTestMood(""), "sad", "ok", "happy")
+}
+
+var _ = Describe("enums", func() {
+	var db *pg.DB
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions())
+
+		_, err := db.Exec("DROP TYPE IF EXISTS db_test_mood")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := db.Exec("DROP TYPE IF EXISTS db_test_mood")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = db.Close()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("creates enum and adds values", func() {
+		err := db.CreateEnum("db_test_mood", "sad", "happy")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = db.RequireEnum(DBTestMood(""))
+		Expect(err).To(MatchError(
+			`pg: enum "db_test_mood" does not have labels 'ok'; run ALTER TYPE "db_test_mood" ADD VALUE for each of them`,
+		))
+
+		err = db.AddEnumValue("db_test_mood", "ok", &orm.AddEnumValueOptions{
+			Before: "happy",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		values, err := db.EnumValues("db_test_mood")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values).To(Equal([]string{"sad", "ok", "happy"}))
+
+		err = db.RequireEnum(DBTestMood(""))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("scans enum labels", func() {
+		err := db.CreateEnum("db_test_mood", "sad", "ok", "happy", "angry")
+		Expect(err).NotTo(HaveOccurred())
+
+		var mood DBTestMood
+		_, err = db.QueryOne(pg.Scan(&mood), "SELECT ?::db_test_mood", DBTestMood("ok"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mood).To(Equal(DBTestMood("ok")))
+
+		_, err = db.QueryOne(pg.Scan(&mood), "SELECT 'angry'::db_test_mood")
+		Expect(err).To(MatchError(`pg: unknown label "angry" for enum "db_test_mood"`))
+	})
+
+	It("returns an error for missing enum", func() {
+		err := db.RequireEnum(DBTestMood(""))
+		Expect(err).To(MatchError(
+			`pg: enum "db_test_mood" does not exist; run CREATE TYPE "db_test_mood" AS ENUM ('sad', 'ok', 'happy')`,
+		))
+	})
+})
+
+var _ = Describe("Sync", func() {
+	type SyncRole struct {
+		Id   int
+		Name string
+	}
+
+	var db *pg.DB
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions())
+
+		_, err := db.Exec("DROP TABLE IF EXISTS sync_roles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = db.Exec("CREATE TABLE sync_roles (id int PRIMARY KEY, name text)")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = db.Exec("INSERT INTO sync_roles VALUES (1, 'admin'), (2, 'usr'), (3, 'guest')")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := db.Exec("DROP TABLE sync_roles")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = db.Close()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("inserts and updates rows", func() {
+		roles := []SyncRole{
+			{Id: 1, Name: "admin"},
+			{Id: 2, Name: "user"},
+			{Id: 4, Name: "owner"},
+		}
+		changes, err := db.Sync(&roles, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes).To(HaveLen(2))
+		Expect(changes[0].Op).To(Equal(orm.SyncUpdate))
+		Expect(changes[0].Row).To(Equal(&roles[1]))
+		Expect(changes[0].Columns).To(Equal([]string{"name"}))
+		Expect(changes[1].Op).To(Equal(orm.SyncInsert))
+		Expect(changes[1].Row).To(Equal(&roles[2]))
+
+		var got []SyncRole
+		err = db.Model(&got).Order("id").Select()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got).To(Equal([]SyncRole{
+			{Id: 1, Name: "admin"},
+			{Id: 2, Name: "user"},
+			{Id: 3, Name: "guest"},
+			{Id: 4, Name: "owner"},
+		}))
+	})
+
+	It("deletes extra rows", func() {
+		roles := []SyncRole{
+			{Id: 1, Name: "admin"},
+			{Id: 2, Name: "usr"},
+		}
+		changes, err := db.Sync(&roles, &orm.SyncOptions{Delete: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].Op).To(Equal(orm.SyncDelete))
+		Expect(changes[0].Row).To(Equal(&SyncRole{Id: 3, Name: "guest"}))
+
+		n, err := db.Model(&SyncRole{}).Count()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(2))
+	})
+})
+
+var _ = Describe("protocol extensions", func() {
+	It("sends _pq_ options and accepts NegotiateProtocolVersion", func() {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		db := pg.Connect(&pg.Options{
+			User:     "postgres",
+			Database: "postgres",
+			ProtocolExtensions: map[string]string{
+				"foo": "bar",
+			},
+			Dialer: srv.Dial,
+		})
+		defer db.Close()
+
+		_, err := db.Exec("SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+
+		params := srv.StartupParams()
+		Expect(params).To(HaveLen(1))
+		Expect(params[0]).To(HaveKeyWithValue("_pq_.foo", "bar"))
+	})
+})
+
+var _ = Describe("TimeZone option", func() {
+	It("sends TimeZone on startup", func() {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		db := pg.Connect(&pg.Options{
+			User:     "postgres",
+			Database: "postgres",
+			TimeZone: "Europe/Berlin",
+			Dialer:   srv.Dial,
+		})
+		defer db.Close()
+
+		_, err := db.Exec("SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+
+		params := srv.StartupParams()
+		Expect(params).To(HaveLen(1))
+		Expect(params[0]).To(HaveKeyWithValue("TimeZone", "Europe/Berlin"))
+	})
+
+	It("sets session time zone", func() {
+		opt := pgOptions()
+		opt.TimeZone = "America/New_York"
+		db := pg.Connect(opt)
+		defer db.Close()
+
+		var tz string
+		_, err := db.QueryOne(pg.Scan(&tz), "SHOW TimeZone")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tz).To(Equal("America/New_York"))
+	})
+})
+
+var _ = Describe("StatementTimeout option", func() {
+	It("sends statement_timeout on startup", func() {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		db := pg.Connect(&pg.Options{
+			User:             "postgres",
+			Database:         "postgres",
+			StatementTimeout: 1500 * time.Millisecond,
+			Dialer:           srv.Dial,
+		})
+		defer db.Close()
+
+		_, err := db.Exec("SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+
+		params := srv.StartupParams()
+		Expect(params).To(HaveLen(1))
+		Expect(params[0]).To(HaveKeyWithValue("statement_timeout", "1500"))
+	})
+
+	It("cancels statements without closing the connection", func() {
+		opt := pgOptions()
+		opt.StatementTimeout = 100 * time.Millisecond
+		db := pg.Connect(opt)
+		defer db.Close()
+
+		_, err := db.Exec("SELECT pg_sleep(1)")
+		Expect(err).To(HaveOccurred())
+		Expect(err.(pg.Error).Field('C')).To(Equal("57014"))
+
+		_, err = db.Exec("SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db.Pool().Len()).To(Equal(1))
+	})
+})
+
+var _ = Describe("Query.Timeout", func() {
+	var db *pg.DB
+
+	BeforeEach(func() {
+		db = pg.Connect(pgOptions())
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).NotTo(HaveOccurred())
+	})
+
+	It("cancels the statement without closing the connection", func() {
+		var s string
+		err := db.Model().
+			ColumnExpr("pg_sleep(1)::text").
+			Timeout(100 * time.Millisecond).
+			Select(pg.Scan(&s))
+		Expect(err).To(HaveOccurred())
+		Expect(err.(pg.Error).Field('C')).To(Equal("57014"))
+
+		_, err = db.Exec("SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db.Pool().Len()).To(Equal(1))
+	})
+
+	It("does not cancel fast statements", func() {
+		var n int
+		err := db.Model().
+			ColumnExpr("1").
+			Timeout(time.Second).
+			Select(pg.Scan(&n))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(1))
+	})
+})
+
+var _ = Describe("RetryPolicy option", func() {
+	It("retries failed connects", func() {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		var dials int32
+		db := pg.Connect(&pg.Options{
+			User:     "postgres",
+			Database: "postgres",
+			RetryPolicy: &pg.RetryPolicy{
+				MaxRetries: 2,
+				MinBackoff: time.Millisecond,
+			},
+			Dialer: func(network, addr string) (net.Conn, error) {
+				if atomic.AddInt32(&dials, 1) < 3 {
+					return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+				}
+				return srv.Dial(network, addr)
+			},
+		})
+		defer db.Close()
+
+		_, err := db.Exec("SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&dials)).To(Equal(int32(3)))
+	})
+
+	It("does not retry queries that fail before the server responds", func() {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		var dials int32
+		db := pg.Connect(&pg.Options{
+			User:     "postgres",
+			Database: "postgres",
+			RetryPolicy: &pg.RetryPolicy{
+				MaxRetries: 1,
+				MinBackoff: time.Millisecond,
+			},
+			Dialer: func(network, addr string) (net.Conn, error) {
+				if atomic.AddInt32(&dials, 1) > 1 {
+					return srv.Dial(network, addr)
+				}
+				client, server := net.Pipe()
+				go failingServer(server, nil)
+				return client, nil
+			},
+		})
+		defer db.Close()
+
+		// The query was sent, so the server may have executed it.
+		_, err := db.Exec("SELECT 1")
+		Expect(err.(*pg.ConnError).Err).To(Equal(io.EOF))
+		Expect(atomic.LoadInt32(&dials)).To(Equal(int32(1)))
+	})
+
+	It("stops retrying when the context is done", func() {
+		db := pg.Connect(&pg.Options{
+			User: "postgres",
+			RetryPolicy: &pg.RetryPolicy{
+				MaxRetries: 10,
+				MinBackoff: time.Hour,
+			},
+			Dialer: func(network, addr string) (net.Conn, error) {
+				return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+			},
+		})
+		defer db.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := db.WithContext(ctx).Exec("SELECT 1")
+		Expect(err).To(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+	})
+
+	It("does not retry ambiguous failures by default", func() {
+		var dials int32
+		var safe []bool
+		retryable := func(err error, s bool) bool {
+			safe = append(safe, s)
+			return false
+		}
+		db := pg.Connect(&pg.Options{
+			User:     "postgres",
+			Database: "postgres",
+			RetryPolicy: &pg.RetryPolicy{
+				MaxRetries: 1,
+				MinBackoff: time.Millisecond,
+			},
+			Dialer: func(network, addr string) (net.Conn, error) {
+				atomic.AddInt32(&dials, 1)
+				client, server := net.Pipe()
+				go failingServer(server, []byte{'C'})
+				return client, nil
+			},
+		})
+		defer db.Close()
+
+		_, err := db.Exec("SELECT 1")
+		Expect(err.(*pg.ConnError).Err).To(Equal(io.EOF))
+		Expect(atomic.LoadInt32(&dials)).To(Equal(int32(1)))
+
+		db.Options().RetryPolicy.Retryable = retryable
+		_, err = db.Exec("SELECT 1")
+		Expect(err.(*pg.ConnError).Err).To(Equal(io.EOF))
+		Expect(safe).To(Equal([]bool{false}))
+	})
+
+	Context("idempotent queries", func() {
+		var srv *pgtest.Server
+		var db *pg.DB
+		var dials int32
+
+		BeforeEach(func() {
+			srv = newFakeServer()
+			dials = 0
+			db = pg.Connect(&pg.Options{
+				User:     "postgres",
+				Database: "postgres",
+				RetryPolicy: &pg.RetryPolicy{
+					MaxRetries: 1,
+					MinBackoff: time.Millisecond,
+				},
+				Dialer: func(network, addr string) (net.Conn, error) {
+					if atomic.AddInt32(&dials, 1) > 1 {
+						return srv.Dial(network, addr)
+					}
+					client, server := net.Pipe()
+					go failingServer(server, []byte{'C'})
+					return client, nil
+				},
+			})
+		})
+
+		AfterEach(func() {
+			Expect(db.Close()).NotTo(HaveOccurred())
+			Expect(srv.Close()).NotTo(HaveOccurred())
+		})
+
+		It("retries ambiguous failures of Idempotent", func() {
+			_, err := db.Exec(pg.Idempotent("SELECT ?"), 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(atomic.LoadInt32(&dials)).To(Equal(int32(2)))
+		})
+
+		It("retries ambiguous failures of Query.Idempotent", func() {
+			var ids []int
+			err := db.Model().ColumnExpr("1").Idempotent().Select(pg.Scan(&ids))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(atomic.LoadInt32(&dials)).To(Equal(int32(2)))
+		})
+	})
+
+	It("limits retries with the budget", func() {
+		var dials int32
+		db := pg.Connect(&pg.Options{
+			User:     "postgres",
+			Database: "postgres",
+			RetryPolicy: &pg.RetryPolicy{
+				MaxRetries: 100,
+				MinBackoff: time.Microsecond,
+				MaxBackoff: time.Microsecond,
+				Budget:     0.1,
+			},
+			Dialer: func(network, addr string) (net.Conn, error) {
+				atomic.AddInt32(&dials, 1)
+				return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+			},
+		})
+		defer db.Close()
+
+		_, err := db.Exec("SELECT 1")
+		Expect(err).To(HaveOccurred())
+		// The first attempt and 10 retries saved in the budget.
+		Expect(atomic.LoadInt32(&dials)).To(Equal(int32(11)))
+	})
+})
+
+var _ = Describe("ZeroCopyScan option", func() {
+	var db *pg.DB
+
+	BeforeEach(func() {
+		opt := pgOptions()
+		opt.ZeroCopyScan = true
+		db = pg.Connect(opt)
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).NotTo(HaveOccurred())
+	})
+
+	It("scans rows", func() {
+		var rows []struct {
+			Id   int
+			Name string
+			Data []byte
+		}
+		_, err := db.Query(&rows, `
+			SELECT n AS id, repeat('x', n * 1000) AS name, '\xdeadbeef'::bytea AS data
+			FROM generate_series(1, 10) AS n`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rows).To(HaveLen(10))
+		for i, row := range rows {
+			Expect(row.Id).To(Equal(i + 1))
+			Expect(row.Name).To(Equal(strings.Repeat("x", (i+1)*1000)))
+			Expect(row.Data).To(Equal([]byte{0xde, 0xad, 0xbe, 0xef}))
+		}
 	})
 })
 
-var _ = Describe("CountEstimate", func() {
-	var db *pg.DB
+var _ = Describe("MinIdleConns option", func() {
+	It("initializes idle connections in the background", func() {
+		srv := newFakeServer()
+		defer srv.Close()
 
-	BeforeEach(func() {
-		db = pg.Connect(pgOptions())
+		db := pg.Connect(&pg.Options{
+			User:         "postgres",
+			Database:     "postgres",
+			MinIdleConns: 2,
+			Dialer:       srv.Dial,
+		})
+		defer db.Close()
+
+		Eventually(db.Pool().FreeLen).Should(Equal(2))
+		Expect(srv.StartupParams()).To(HaveLen(2))
+
+		_, err := db.Exec("SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db.Pool().Stats().Hits).To(Equal(uint32(1)))
 	})
+})
 
-	It("works", func() {
-		count, err := db.Model().
-			TableExpr("generate_series(1, 10)").
-			CountEstimate(1000)
+var _ = Describe("IdleCheckThreshold option", func() {
+	It("replaces broken idle connections", func() {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		db := pg.Connect(&pg.Options{
+			User:               "postgres",
+			Database:           "postgres",
+			IdleCheckThreshold: time.Millisecond,
+			Dialer:             srv.Dial,
+		})
+		defer db.Close()
+
+		_, err := db.Exec("SELECT 1")
 		Expect(err).NotTo(HaveOccurred())
-		Expect(count).To(Equal(10))
+
+		time.Sleep(5 * time.Millisecond)
+		_, err = db.Exec("SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(srv.StartupParams()).To(HaveLen(1))
+
+		srv.CloseConns()
+		time.Sleep(5 * time.Millisecond)
+
+		_, err = db.Exec("SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(srv.StartupParams()).To(HaveLen(2))
+		Expect(db.Pool().Len()).To(Equal(1))
 	})
 
-	It("works when there are no results", func() {
-		count, err := db.Model().
-			TableExpr("generate_series(1, 0)").
-			CountEstimate(1000)
+	It("limits the ping of idle connections with DialTimeout", func() {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		var dials int32
+		db := pg.Connect(&pg.Options{
+			User:               "postgres",
+			Database:           "postgres",
+			IdleCheckThreshold: time.Millisecond,
+			DialTimeout:        50 * time.Millisecond,
+			Dialer: func(network, addr string) (net.Conn, error) {
+				cn, err := srv.Dial(network, addr)
+				if err != nil || atomic.AddInt32(&dials, 1) > 1 {
+					return cn, err
+				}
+				return syncDroppingConn{cn}, nil
+			},
+		})
+		defer db.Close()
+
+		_, err := db.Exec("SELECT 1")
 		Expect(err).NotTo(HaveOccurred())
-		Expect(count).To(Equal(0))
+
+		time.Sleep(5 * time.Millisecond)
+		start := time.Now()
+		_, err = db.Exec("SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		Expect(atomic.LoadInt32(&dials)).To(Equal(int32(2)))
 	})
+})
 
-	It("works with GROUP", func() {
-		count, err := db.Model().
-			TableExpr("generate_series(1, 10)").
-			Group("generate_series").
-			CountEstimate(1000)
+var _ = Describe("DialContext option", func() {
+	It("is used to dial connections", func() {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		var hasDeadline bool
+		db := pg.Connect(&pg.Options{
+			User:        "postgres",
+			Database:    "postgres",
+			DialTimeout: time.Minute,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				_, hasDeadline = ctx.Deadline()
+				return srv.Dial(network, addr)
+			},
+		})
+		defer db.Close()
+
+		_, err := db.Exec("SELECT 1")
 		Expect(err).NotTo(HaveOccurred())
-		Expect(count).To(Equal(10))
+		Expect(hasDeadline).To(BeTrue())
 	})
 
-	It("works with GROUP when there are no results", func() {
-		count, err := db.Model().
-			TableExpr("generate_series(1, 0)").
-			Group("generate_series").
-			CountEstimate(1000)
+	It("limits TLS handshake with DialTimeout", func() {
+		db := pg.Connect(&pg.Options{
+			User:        "postgres",
+			Database:    "postgres",
+			TLSConfig:   &tls.Config{InsecureSkipVerify: true},
+			DialTimeout: 50 * time.Millisecond,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				client, server := net.Pipe()
+				go func() {
+					// Accept SSLRequest and never answer the handshake.
+					b := make([]byte, 8)
+					io.ReadFull(server, b)
+					server.Write([]byte{'S'})
+					io.Copy(ioutil.Discard, server)
+				}()
+				return client, nil
+			},
+		})
+		defer db.Close()
+
+		_, err := db.Exec("SELECT 1")
+		Expect(err).To(HaveOccurred())
+		Expect(err.(net.Error).Timeout()).To(BeTrue())
+	})
+})
+
+var _ = Describe("PasswordFn option", func() {
+	It("is called for every new connection", func() {
+		passwords := make(chan []byte, 2)
+		var n int32
+		db := pg.Connect(&pg.Options{
+			User:     "postgres",
+			Password: "static",
+			Database: "postgres",
+			PasswordFn: func(ctx context.Context) (string, error) {
+				_, ok := ctx.Deadline()
+				Expect(ok).To(BeTrue())
+				return fmt.Sprintf("token%d", atomic.AddInt32(&n, 1)), nil
+			},
+			Dialer: func(network, addr string) (net.Conn, error) {
+				client, server := net.Pipe()
+				go fakeAuthServer(server, cleartextPasswordReq, passwords)
+				return client, nil
+			},
+		})
+		defer db.Close()
+
+		tx, err := db.Begin()
 		Expect(err).NotTo(HaveOccurred())
-		Expect(count).To(Equal(0))
+		_, err = db.Exec("SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tx.Rollback()).NotTo(HaveOccurred())
+
+		Expect(string(<-passwords)).To(Equal("token1\x00"))
+		Expect(string(<-passwords)).To(Equal("token2\x00"))
+	})
+
+	It("returns the error", func() {
+		db := pg.Connect(&pg.Options{
+			User:     "postgres",
+			Database: "postgres",
+			PasswordFn: func(ctx context.Context) (string, error) {
+				return "", errors.New("token expired")
+			},
+			Dialer: func(network, addr string) (net.Conn, error) {
+				client, server := net.Pipe()
+				go fakeAuthServer(server, cleartextPasswordReq, make(chan []byte, 1))
+				return client, nil
+			},
+		})
+		defer db.Close()
+
+		_, err := db.Exec("SELECT 1")
+		Expect(err).To(MatchError("token expired"))
+	})
+})
+
+var _ = Describe("RegisterAuthHandler", func() {
+	AfterEach(func() {
+		pg.RegisterAuthHandler(100, nil)
+	})
+
+	It("handles custom authentication request", func() {
+		var challenge []byte
+		pg.RegisterAuthHandler(100, func(cn *pg.AuthConn, data []byte) error {
+			challenge = data
+			return cn.Send([]byte(cn.User() + ":" + cn.Password()))
+		})
+
+		responses := make(chan []byte, 1)
+		db := pg.Connect(&pg.Options{
+			User:     "alice",
+			Password: "secret",
+			Database: "postgres",
+			Dialer: func(network, addr string) (net.Conn, error) {
+				client, server := net.Pipe()
+				go fakeAuthServer(server, append([]byte{0, 0, 0, 100}, "challenge"...), responses)
+				return client, nil
+			},
+		})
+		defer db.Close()
+
+		_, err := db.Exec("SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(challenge)).To(Equal("challenge"))
+		Expect(string(<-responses)).To(Equal("alice:secret"))
+	})
+
+	It("returns handler error", func() {
+		pg.RegisterAuthHandler(100, func(cn *pg.AuthConn, data []byte) error {
+			return errors.New("radius: access rejected")
+		})
+
+		db := pg.Connect(&pg.Options{
+			User:     "alice",
+			Database: "postgres",
+			Dialer: func(network, addr string) (net.Conn, error) {
+				client, server := net.Pipe()
+				go fakeAuthServer(server, []byte{0, 0, 0, 100}, make(chan []byte, 1))
+				return client, nil
+			},
+		})
+		defer db.Close()
+
+		_, err := db.Exec("SELECT 1")
+		Expect(err).To(MatchError("radius: access rejected"))
+	})
+})
+
+var _ = Describe("Unix socket", func() {
+	It("connects using socket directory", func() {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		dir, err := ioutil.TempDir("", "pg")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		ln, err := net.Listen("unix", filepath.Join(dir, ".s.PGSQL.5433"))
+		Expect(err).NotTo(HaveOccurred())
+		defer ln.Close()
+		go func() {
+			cn, err := ln.Accept()
+			if err == nil {
+				srv.ServeConn(cn)
+			}
+		}()
+
+		db := pg.Connect(&pg.Options{
+			User:     "postgres",
+			Database: "postgres",
+			Addr:     dir + ":5433",
+		})
+		defer db.Close()
+
+		_, err = db.Exec("SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
+
+var _ = Describe("SSLMode option", func() {
+	// rejectSSL answers SSLRequest with N.
+	rejectSSL := func(cn net.Conn) bool {
+		b := make([]byte, 8)
+		if _, err := io.ReadFull(cn, b); err != nil {
+			return false
+		}
+		Expect(binary.BigEndian.Uint32(b[4:])).To(Equal(uint32(80877103)))
+		_, err := cn.Write([]byte{'N'})
+		return err == nil
+	}
+
+	It("falls back to plaintext in prefer mode", func() {
+		// The server answers SSLRequest with N.
+		srv := newFakeServer()
+		defer srv.Close()
+
+		db := pg.Connect(&pg.Options{
+			User:     "postgres",
+			Database: "postgres",
+			SSLMode:  pg.SSLPrefer,
+			Dialer:   srv.Dial,
+		})
+		defer db.Close()
+
+		_, err := db.Exec("SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("requires SSL in require mode", func() {
+		db := pg.Connect(&pg.Options{
+			User:     "postgres",
+			Database: "postgres",
+			SSLMode:  pg.SSLRequire,
+			Dialer: func(network, addr string) (net.Conn, error) {
+				client, server := net.Pipe()
+				go func() {
+					rejectSSL(server)
+					server.Close()
+				}()
+				return client, nil
+			},
+		})
+		defer db.Close()
+
+		_, err := db.Exec("SELECT 1")
+		Expect(err).To(MatchError("pg: SSL is not enabled on the server"))
+	})
+
+	It("tries SSL after plaintext is rejected in allow mode", func() {
+		var dials int
+		db := pg.Connect(&pg.Options{
+			User:     "postgres",
+			Database: "postgres",
+			SSLMode:  pg.SSLAllow,
+			Dialer: func(network, addr string) (net.Conn, error) {
+				dials++
+				client, server := net.Pipe()
+				if dials == 1 {
+					go func() {
+						defer server.Close()
+						b := make([]byte, 4)
+						io.ReadFull(server, b)
+						io.ReadFull(server, make([]byte, binary.BigEndian.Uint32(b)-4))
+						msg := []byte("E\x00\x00\x00\x00SFATAL\x00C28000\x00Mno encryption\x00\x00")
+						binary.BigEndian.PutUint32(msg[1:], uint32(len(msg)-1))
+						server.Write(msg)
+					}()
+				} else {
+					go func() {
+						rejectSSL(server)
+						server.Close()
+					}()
+				}
+				return client, nil
+			},
+		})
+		defer db.Close()
+
+		_, err := db.Exec("SELECT 1")
+		Expect(err).To(MatchError("pg: SSL is not enabled on the server"))
+		Expect(dials).To(Equal(2))
+	})
+})
+
+var _ = Describe("TraceWire option", func() {
+	It("writes protocol messages", func() {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		var buf bytes.Buffer
+		db := pg.Connect(&pg.Options{
+			User:      "postgres",
+			Database:  "postgres",
+			TraceWire: &buf,
+			Dialer:    srv.Dial,
+		})
+
+		_, err := db.Exec("SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db.Close()).NotTo(HaveOccurred())
+
+		var msgs []string
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			// Skip date, time and pid.
+			msgs = append(msgs, strings.SplitN(line, " ", 4)[3])
+		}
+		Expect(msgs).To(Equal([]string{
+			`F 41 StartupMessage 3.0 user="postgres" database="postgres"`,
+			`B 8 AuthenticationOk`,
+			`B 12 BackendKeyData 1`,
+			`B 5 ReadyForQuery I`,
+			`F 13 Query "SELECT 1"`,
+			`B 13 CommandComplete "SELECT 1"`,
+			`B 5 ReadyForQuery I`,
+			`F 4 Terminate`,
+		}))
+	})
+})
+
+var _ = Describe("DB.ExecNamed", func() {
+	It("replaces named placeholders", func() {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		var buf bytes.Buffer
+		db := pg.Connect(&pg.Options{
+			User:      "postgres",
+			Database:  "postgres",
+			TraceWire: &buf,
+			Dialer:    srv.Dial,
+		})
+		defer db.Close()
+
+		_, err := db.ExecNamed(
+			"UPDATE users SET status = :status WHERE id = :id OR parent_id = :id",
+			map[string]interface{}{"id": 1, "status": "active"},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(buf.String()).To(ContainSubstring(
+			`Query "UPDATE users SET status = 'active' WHERE id = 1 OR parent_id = 1"`,
+		))
+	})
+})
+
+var _ = Describe("DB.Query model placeholders", func() {
+	It("formats raw queries using the model", func() {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		var buf bytes.Buffer
+		db := pg.Connect(&pg.Options{
+			User:      "postgres",
+			Database:  "postgres",
+			TraceWire: &buf,
+			Dialer:    srv.Dial,
+		})
+		defer db.Close()
+
+		type Book struct {
+			Id    int
+			Title string
+		}
+		var books []Book
+		_, err := db.Query(&books, "SELECT ?Columns FROM ?TableName WHERE ?PKs = ?", 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(buf.String()).To(ContainSubstring(
+			`Query "SELECT \"id\", \"title\" FROM \"books\" WHERE \"id\" = 1"`,
+		))
+	})
+})
+
+var _ = Describe("ConnError", func() {
+	It("describes the connection that failed", func() {
+		db := pg.Connect(&pg.Options{
+			User:     "postgres",
+			Database: "postgres",
+			Dialer: func(network, addr string) (net.Conn, error) {
+				client, server := net.Pipe()
+				go failingServer(server, []byte{'C'})
+				return client, nil
+			},
+		})
+		defer db.Close()
+
+		_, err := db.Exec("SELECT 1")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(HavePrefix("EOF (addr=pipe pid=0 age="))
+
+		connErr := err.(*pg.ConnError)
+		Expect(connErr.Err).To(Equal(io.EOF))
+		Expect(connErr.RemoteAddr).To(Equal("pipe"))
+		Expect(connErr.Age).To(BeNumerically(">", 0))
+		Expect(connErr.Queries).To(Equal(int64(1)))
+		Expect(connErr.Discarded).To(BeTrue())
+		Expect(connErr.Timeout()).To(BeFalse())
+		Expect(db.Pool().Len()).To(Equal(0))
+	})
+})
+
+var _ = Describe("DB.Shutdown", func() {
+	It("waits for queries in progress and terminates connections", func() {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		var buf bytes.Buffer
+		db := pg.Connect(&pg.Options{
+			User:      "postgres",
+			Database:  "postgres",
+			TraceWire: &buf,
+			Dialer:    srv.Dial,
+		})
+
+		cn, err := db.Conn()
+		Expect(err).NotTo(HaveOccurred())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- db.Shutdown(context.Background())
+		}()
+
+		Eventually(func() error {
+			_, err := db.Exec("SELECT 1")
+			return err
+		}).Should(MatchError("pg: database is closed"))
+		Consistently(done).ShouldNot(Receive())
+
+		_, err = cn.Exec("SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cn.Release()).NotTo(HaveOccurred())
+
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(buf.String()).To(HaveSuffix("F 4 Terminate\n"))
 	})
 })
 
+// newFakeServer returns a server that completes every query with
+// an empty result.
+func newFakeServer() *pgtest.Server {
+	srv := pgtest.NewServer(nil)
+	srv.HandleFunc(func(query string) *pgtest.Response {
+		return &pgtest.Response{Tag: "SELECT 1"}
+	})
+	return srv
+}
+
+// syncDroppingConn drops Sync messages, so the server never answers
+// them like on a half-open connection.
+type syncDroppingConn struct {
+	net.Conn
+}
+
+func (cn syncDroppingConn) Write(b []byte) (int, error) {
+	if bytes.Equal(b, []byte{'S', 0, 0, 0, 4}) {
+		return len(b), nil
+	}
+	return cn.Conn.Write(b)
+}
+
+// failingServer accepts the startup message, writes the reply to the
+// first query and closes the connection.
+func failingServer(cn net.Conn, reply []byte) {
+	defer cn.Close()
+
+	readMsg := func(hdrLen int) bool {
+		hdr := make([]byte, hdrLen)
+		if _, err := io.ReadFull(cn, hdr); err != nil {
+			return false
+		}
+		body := make([]byte, binary.BigEndian.Uint32(hdr[hdrLen-4:])-4)
+		_, err := io.ReadFull(cn, body)
+		return err == nil
+	}
+
+	if !readMsg(4) {
+		return
+	}
+	cn.Write([]byte{'R', 0, 0, 0, 8, 0, 0, 0, 0, 'Z', 0, 0, 0, 5, 'I'})
+	if !readMsg(5) {
+		return
+	}
+	cn.Write(reply)
+}
+
+var cleartextPasswordReq = []byte{0, 0, 0, 3}
+
+// fakeAuthServer sends the authentication request and sends the body
+// of the client response to responses before it completes every query
+// with an empty result.
+func fakeAuthServer(cn net.Conn, authReq []byte, responses chan<- []byte) {
+	defer cn.Close()
+
+	writeMsg := func(c byte, b []byte) {
+		msg := []byte{c, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(msg[1:], uint32(len(b)+4))
+		cn.Write(append(msg, b...))
+	}
+	readN := func(n int) []byte {
+		b := make([]byte, n)
+		if _, err := io.ReadFull(cn, b); err != nil {
+			return nil
+		}
+		return b
+	}
+
+	b := readN(4)
+	if b == nil {
+		return
+	}
+	readN(int(binary.BigEndian.Uint32(b)) - 4)
+	writeMsg('R', authReq)
+
+	hdr := readN(5)
+	if hdr == nil || hdr[0] != 'p' {
+		return
+	}
+	responses <- readN(int(binary.BigEndian.Uint32(hdr[1:])) - 4)
+	writeMsg('R', []byte{0, 0, 0, 0})
+	writeMsg('Z', []byte{'I'})
+
+	for {
+		hdr := readN(5)
+		if hdr == nil || hdr[0] == 'X' {
+			return
+		}
+		readN(int(binary.BigEndian.Uint32(hdr[1:])) - 4)
+		writeMsg('C', []byte("SELECT 1\x00"))
+		writeMsg('Z', []byte{'I'})
+	}
+}
+
 var _ = Describe("DB nulls", func() {
 	var db *pg.DB
 
@@ -392,6 +2703,71 @@ var _ = Describe("DB nulls", func() {
 		})
 	})
 
+	Describe("pg.NullInt64", func() {
+		type Test struct {
+			Id    int
+			Value pg.NullInt64
+		}
+
+		It("inserts null value", func() {
+			ins := Test{
+				Id: 1,
+			}
+			err := db.Insert(&ins)
+			Expect(err).NotTo(HaveOccurred())
+
+			sel := Test{
+				Id: 1,
+			}
+			err = db.Select(&sel)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sel.Value.Valid).To(BeFalse())
+
+			b, err := json.Marshal(sel.Value)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(b)).To(Equal("null"))
+		})
+
+		It("inserts non-null value", func() {
+			ins := Test{
+				Id:    1,
+				Value: pg.NullInt64{NullInt64: sql.NullInt64{Int64: 2, Valid: true}},
+			}
+			err := db.Insert(&ins)
+			Expect(err).NotTo(HaveOccurred())
+
+			sel := Test{
+				Id: 1,
+			}
+			err = db.Select(&sel)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sel.Value.Valid).To(BeTrue())
+			Expect(sel.Value.Int64).To(Equal(int64(2)))
+
+			b, err := json.Marshal(sel.Value)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(b)).To(Equal("2"))
+		})
+
+		It("is encoded as NULL param", func() {
+			var isNull bool
+			_, err := db.QueryOne(pg.Scan(&isNull), "SELECT ? IS NULL", pg.NullInt64{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(isNull).To(BeTrue())
+		})
+	})
+
+	Describe("pg.NullString", func() {
+		It("scans NULL and non-NULL values", func() {
+			var s1, s2 pg.NullString
+			_, err := db.QueryOne(pg.Scan(&s1, &s2), "SELECT NULL::text, 'hello'")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s1.Valid).To(BeFalse())
+			Expect(s2.Valid).To(BeTrue())
+			Expect(s2.String).To(Equal("hello"))
+		})
+	})
+
 	Context("nil ptr", func() {
 		type Test struct {
 			Id    int
@@ -0,0 +1,78 @@
+package pg
+
+import (
+	"fmt"
+	"reflect"
+
+	"gopkg.in/pg.v5/types"
+)
+
+// EnumValues returns values of the enum type in their sort order.
+func (db *DB) EnumValues(name string) ([]string, error) {
+	var values []string
+	_, err := db.Query(&values, `
+		SELECT e.enumlabel
+		FROM pg_enum AS e
+		JOIN pg_type AS t ON t.oid = e.enumtypid
+		WHERE t.typname = ?
+		ORDER BY e.enumsortorder
+	`, name)
+	return values, err
+}
+
+// RequireEnum checks that the enum type registered for v with
+// types.RegisterEnum exists in the database and has all registered
+// labels. It is meant to be called at startup and returns an error
+// that describes how to fix the database, e.g.
+//
+//    if err := db.RequireEnum(Mood("")); err != nil {
+//        log.Fatal(err)
+//    }
+func (db *DB) RequireEnum(v interface{}) error {
+	typ := reflect.Indirect(reflect.ValueOf(v)).Type()
+	name, labels, ok := types.LookupEnum(typ)
+	if !ok {
+		return fmt.Errorf("pg: %s is not registered with types.RegisterEnum", typ)
+	}
+
+	values, err := db.EnumValues(name)
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return fmt.Errorf(
+			"pg: enum %q does not exist; run CREATE TYPE %q AS ENUM (%s)",
+			name, name, quoteLabels(labels),
+		)
+	}
+
+	existing := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		existing[value] = struct{}{}
+	}
+	var missing []string
+	for _, label := range labels {
+		if _, ok := existing[label]; !ok {
+			missing = append(missing, label)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf(
+			"pg: enum %q does not have labels %s; run ALTER TYPE %q ADD VALUE for each of them",
+			name, quoteLabels(missing), name,
+		)
+	}
+
+	return nil
+}
+
+func quoteLabels(labels []string) string {
+	b := make([]byte, 0, 16*len(labels))
+	for i, label := range labels {
+		if i > 0 {
+			b = append(b, ", "...)
+		}
+		b = types.AppendString(b, label, 1)
+	}
+	return string(b)
+}
@@ -0,0 +1,192 @@
+package pg
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"gopkg.in/pg.v5/internal"
+)
+
+// OptionsFromEnv returns options configured using libpq environment
+// variables PGHOST, PGPORT, PGUSER, PGPASSWORD, PGDATABASE, PGSSLMODE,
+// PGSSLROOTCERT, PGSSLCERT, PGSSLKEY and PGTZ. When PGPASSWORD is not
+// set, the password is looked up in the file set by PGPASSFILE or in
+// ~/.pgpass. Unset variables have the same defaults as in libpq, e.g.
+// the user is the name of the OS user and the database is the user.
+func OptionsFromEnv() (*Options, error) {
+	opt := &Options{
+		User:        os.Getenv("PGUSER"),
+		Password:    os.Getenv("PGPASSWORD"),
+		Database:    os.Getenv("PGDATABASE"),
+		SSLMode:     os.Getenv("PGSSLMODE"),
+		SSLRootCert: os.Getenv("PGSSLROOTCERT"),
+		SSLCert:     os.Getenv("PGSSLCERT"),
+		SSLKey:      os.Getenv("PGSSLKEY"),
+		TimeZone:    os.Getenv("PGTZ"),
+	}
+	if opt.User == "" {
+		if u, err := user.Current(); err == nil {
+			opt.User = u.Username
+		} else {
+			opt.User = "postgres"
+		}
+	}
+	if opt.Database == "" {
+		opt.Database = opt.User
+	}
+	if opt.SSLMode == "" {
+		opt.SSLMode = SSLPrefer
+	}
+
+	host := os.Getenv("PGHOST")
+	if host == "" {
+		host = "localhost"
+	}
+	port := os.Getenv("PGPORT")
+	if port == "" {
+		port = "5432"
+	}
+	if strings.HasPrefix(host, "/") {
+		opt.Addr = host + ":" + port
+	} else {
+		opt.Addr = net.JoinHostPort(host, port)
+	}
+
+	if opt.Password == "" {
+		var err error
+		opt.Password, err = pgpassPassword(pgpassFile(), host, port, opt.Database, opt.User)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	opt.TLSConfig, err = newTLSConfig(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	return opt, nil
+}
+
+func pgpassFile() string {
+	if file := os.Getenv("PGPASSFILE"); file != "" {
+		return file
+	}
+	home := os.Getenv("HOME")
+	if home == "" {
+		u, err := user.Current()
+		if err != nil {
+			return ""
+		}
+		home = u.HomeDir
+	}
+	return filepath.Join(home, ".pgpass")
+}
+
+// pgpassPassword returns the password from the first line of the
+// password file that matches host, port, database and user. Each line
+// has the format hostname:port:database:username:password where the
+// first four fields can be * that matches anything. Like in libpq, Unix
+// socket connections match localhost and files with group or world
+// access are ignored.
+func pgpassPassword(file, host, port, database, user string) (string, error) {
+	if file == "" {
+		return "", nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if !fi.Mode().IsRegular() {
+		return "", nil
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0077 != 0 {
+		internal.Logf(
+			"pg: password file %s has group or world access; permissions should be u=rw (0600) or less",
+			file,
+		)
+		return "", nil
+	}
+
+	if strings.HasPrefix(host, "/") {
+		host = "localhost"
+	}
+	want := [4]string{host, port, database, user}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		fields, ok := splitPgpassLine(line)
+		if !ok {
+			continue
+		}
+		match := true
+		for i, s := range want {
+			if fields[i] != "*" && unescapePgpass(fields[i]) != s {
+				match = false
+				break
+			}
+		}
+		if match {
+			return unescapePgpass(fields[4]), nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// splitPgpassLine splits the line on colons that are not escaped with
+// a backslash. Fields are returned escaped, so a literal \* is not
+// a wildcard. The password is the rest of the line.
+func splitPgpassLine(line string) ([5]string, bool) {
+	var fields [5]string
+	var n, start int
+	for i := 0; i < len(line) && n < 4; i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case ':':
+			fields[n] = line[start:i]
+			n++
+			start = i + 1
+		}
+	}
+	if n < 4 {
+		return fields, false
+	}
+	fields[4] = line[start:]
+	return fields, true
+}
+
+func unescapePgpass(s string) string {
+	if strings.IndexByte(s, '\\') == -1 {
+		return s
+	}
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
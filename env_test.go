@@ -0,0 +1,136 @@
+package pg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePgpass(t *testing.T, dir, data string, perm os.FileMode) string {
+	file := filepath.Join(dir, "pgpass")
+	if err := ioutil.WriteFile(file, []byte(data), perm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(file, perm); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestPgpassPassword(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := writePgpass(t, dir, `# comment
+db.example.com:5432:mydb:alice:secret1
+db.example.com:*:*:bob:secret2
+localhost:5433:*:*:socket
+\*:5432:*:carol:literal
+*:*:*:carol:pass\:with\\colon:and more
+broken:line
+*:*:*:*:fallback
+`, 0600)
+
+	tests := []struct {
+		host, port, database, user string
+		password                   string
+	}{
+		{"db.example.com", "5432", "mydb", "alice", "secret1"},
+		{"db.example.com", "5432", "otherdb", "alice", "fallback"},
+		{"db.example.com", "6432", "otherdb", "bob", "secret2"},
+		{"localhost", "5433", "mydb", "alice", "socket"},
+		{"/var/run/postgresql", "5433", "mydb", "alice", "socket"},
+		{"*", "5432", "mydb", "carol", "literal"},
+		{"db.example.com", "5432", "mydb", "carol", `pass:with\colon:and more`},
+	}
+	for _, test := range tests {
+		got, err := pgpassPassword(file, test.host, test.port, test.database, test.user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.password {
+			t.Errorf("%s:%s:%s:%s: got %q, want %q",
+				test.host, test.port, test.database, test.user, got, test.password)
+		}
+	}
+
+	file = writePgpass(t, dir, "*:*:*:*:secret\n", 0644)
+	got, err := pgpassPassword(file, "localhost", "5432", "mydb", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "" {
+		t.Errorf("file with group or world access must be ignored, got %q", got)
+	}
+
+	got, err = pgpassPassword(filepath.Join(dir, "missing"), "localhost", "5432", "mydb", "alice")
+	if err != nil || got != "" {
+		t.Errorf("missing file must be ignored, got %q %v", got, err)
+	}
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	env := map[string]string{
+		"PGHOST":        "db.example.com",
+		"PGPORT":        "6432",
+		"PGUSER":        "alice",
+		"PGPASSWORD":    "",
+		"PGDATABASE":    "",
+		"PGSSLMODE":     "disable",
+		"PGSSLROOTCERT": "",
+		"PGSSLCERT":     "",
+		"PGSSLKEY":      "",
+		"PGTZ":          "UTC",
+		"PGPASSFILE":    writePgpass(t, dir, "db.example.com:6432:alice:alice:secret\n", 0600),
+	}
+	for name, value := range env {
+		old, ok := os.LookupEnv(name)
+		os.Setenv(name, value)
+		if ok {
+			defer os.Setenv(name, old)
+		} else {
+			defer os.Unsetenv(name)
+		}
+	}
+
+	opt, err := OptionsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opt.Addr != "db.example.com:6432" {
+		t.Errorf("got addr %q", opt.Addr)
+	}
+	if opt.User != "alice" || opt.Database != "alice" || opt.Password != "secret" {
+		t.Errorf("got user %q, database %q, password %q", opt.User, opt.Database, opt.Password)
+	}
+	if opt.SSLMode != SSLDisable || opt.TLSConfig != nil {
+		t.Errorf("got sslmode %q and TLSConfig %v", opt.SSLMode, opt.TLSConfig)
+	}
+	if opt.TimeZone != "UTC" {
+		t.Errorf("got time zone %q", opt.TimeZone)
+	}
+
+	os.Setenv("PGPASSWORD", "env")
+	os.Setenv("PGHOST", "/tmp")
+	opt, err = OptionsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opt.Password != "env" {
+		t.Errorf("PGPASSWORD must have priority over password file, got %q", opt.Password)
+	}
+	opt.init()
+	if opt.Network != "unix" || opt.Addr != "/tmp/.s.PGSQL.6432" {
+		t.Errorf("got %s %s", opt.Network, opt.Addr)
+	}
+}
@@ -1,10 +1,13 @@
 package pg
 
 import (
+	"fmt"
 	"io"
 	"net"
+	"time"
 
 	"gopkg.in/pg.v5/internal"
+	"gopkg.in/pg.v5/internal/pool"
 )
 
 var (
@@ -15,6 +18,7 @@ var (
 
 	errClosed         = internal.Errorf("pg: database is closed")
 	errTxDone         = internal.Errorf("pg: transaction has already been committed or rolled back")
+	errConnClosed     = internal.Errorf("pg: connection is closed")
 	errStmtClosed     = internal.Errorf("pg: statement is closed")
 	errListenerClosed = internal.Errorf("pg: listener is closed")
 )
@@ -26,7 +30,78 @@ type Error interface {
 
 var _ Error = (*internal.PGError)(nil)
 
+// ConnError is returned when a query fails because of the connection,
+// e.g. with io.EOF when the server closes the connection. It describes
+// the connection to make such errors easier to debug.
+type ConnError struct {
+	Err error
+
+	RemoteAddr string
+	ProcessId  int32         // backend process id
+	Age        time.Duration // time since the connection was established
+	Queries    int64         // number of queries sent on the connection
+	Discarded  bool          // whether the connection is removed from the pool
+}
+
+func newConnError(cn *pool.Conn, err error, discarded bool) error {
+	if err == nil || !isNetworkError(err) {
+		return err
+	}
+	if _, ok := err.(*ConnError); ok {
+		return err
+	}
+
+	e := &ConnError{
+		Err:       err,
+		ProcessId: cn.ProcessId,
+		Queries:   cn.Queries,
+		Discarded: discarded,
+	}
+	if addr := cn.RemoteAddr(); addr != nil {
+		e.RemoteAddr = addr.String()
+	}
+	if !cn.InitedAt.IsZero() {
+		e.Age = time.Since(cn.InitedAt)
+	}
+	return e
+}
+
+func (e *ConnError) Error() string {
+	return fmt.Sprintf(
+		"%s (addr=%s pid=%d age=%s queries=%d discarded=%t)",
+		e.Err, e.RemoteAddr, e.ProcessId, e.Age, e.Queries, e.Discarded,
+	)
+}
+
+// Unwrap returns the underlying error.
+func (e *ConnError) Unwrap() error {
+	return e.Err
+}
+
+// ConnError implements net.Error, so timeouts can be checked as before
+// with err.(net.Error).Timeout().
+var _ net.Error = (*ConnError)(nil)
+
+func (e *ConnError) Timeout() bool {
+	netErr, ok := e.Err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+func (e *ConnError) Temporary() bool {
+	netErr, ok := e.Err.(net.Error)
+	return ok && netErr.Temporary()
+}
+
+// unwrapConnError returns the error wrapped in ConnError.
+func unwrapConnError(err error) error {
+	if e, ok := err.(*ConnError); ok {
+		return e.Err
+	}
+	return err
+}
+
 func isBadConn(err error, allowTimeout bool) bool {
+	err = unwrapConnError(err)
 	if err == nil {
 		return false
 	}
@@ -45,6 +120,7 @@ func isBadConn(err error, allowTimeout bool) bool {
 }
 
 func isNetworkError(err error) bool {
+	err = unwrapConnError(err)
 	if err == io.EOF {
 		return true
 	}
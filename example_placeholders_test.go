@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"gopkg.in/pg.v5"
+	"gopkg.in/pg.v5/orm"
 )
 
 type Params struct {
@@ -61,3 +62,15 @@ func Example_placeholders() {
 	// named: 4
 	// global: 3
 }
+
+func ExampleIdent() {
+	column := `name"; DROP TABLE users; --`
+	q := pg.SafeQuery("SELECT * FROM users ORDER BY ?", pg.Ident(column))
+	fmt.Println(string(q.AppendFormat(nil, orm.Formatter{})))
+	// Output: SELECT * FROM users ORDER BY "name""; DROP TABLE users; --"
+}
+
+func ExampleQuoteLiteral() {
+	fmt.Println(pg.QuoteLiteral("it's"))
+	// Output: 'it''s'
+}
@@ -102,8 +102,8 @@ func ExampleListener() {
 	}()
 
 	notif := <-ch
-	fmt.Println(notif)
-	// Output: &{mychan hello world}
+	fmt.Println(notif.Channel, notif.Payload)
+	// Output: mychan hello world
 }
 
 func txExample() *pg.DB {
@@ -9,3 +9,7 @@ func (db *DB) Pool() *pool.ConnPool {
 func (ln *Listener) CurrentConn() *pool.Conn {
 	return ln._cn
 }
+
+func CompareVersions(v1, v2 string) int {
+	return compareVersions(v1, v2)
+}
@@ -0,0 +1,105 @@
+package pg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ExtensionInfo describes an extension installed in the database.
+type ExtensionInfo struct {
+	Name    string
+	Version string
+	Schema  string
+}
+
+// ListExtensions returns extensions installed in the database.
+func (db *DB) ListExtensions() ([]ExtensionInfo, error) {
+	var exts []ExtensionInfo
+	_, err := db.Query(&exts, `
+		SELECT e.extname AS name, e.extversion AS version, n.nspname AS schema
+		FROM pg_extension AS e
+		JOIN pg_namespace AS n ON n.oid = e.extnamespace
+		ORDER BY e.extname
+	`)
+	return exts, err
+}
+
+// RequireExtension checks that the extension is installed and its version
+// is at least minVersion. Empty minVersion matches any version. It is
+// meant to be called at startup and returns an error that describes how
+// to fix the database, e.g.
+//
+//    if err := db.RequireExtension("hstore", "1.4"); err != nil {
+//        log.Fatal(err)
+//    }
+func (db *DB) RequireExtension(name, minVersion string) error {
+	var version, available string
+	_, err := db.QueryOne(Scan(&version, &available), `
+		SELECT
+			coalesce((SELECT extversion FROM pg_extension WHERE extname = ?0), ''),
+			coalesce((SELECT default_version FROM pg_available_extensions WHERE name = ?0), '')
+	`, name)
+	if err != nil {
+		return err
+	}
+
+	if version == "" {
+		if available == "" {
+			return fmt.Errorf(
+				"pg: extension %q is not available on the server; install the package that provides it",
+				name,
+			)
+		}
+		return fmt.Errorf(
+			"pg: extension %q is not installed; run CREATE EXTENSION %q",
+			name, name,
+		)
+	}
+
+	if minVersion != "" && compareVersions(version, minVersion) < 0 {
+		return fmt.Errorf(
+			"pg: extension %q has version %s, but %s is required; run ALTER EXTENSION %q UPDATE",
+			name, version, minVersion, name,
+		)
+	}
+
+	return nil
+}
+
+// compareVersions compares dot separated versions like "1.10" and "1.9".
+// Numeric parts are compared as numbers and other parts as strings.
+// Missing parts are treated as 0.
+func compareVersions(v1, v2 string) int {
+	parts1 := strings.Split(v1, ".")
+	parts2 := strings.Split(v2, ".")
+	for i := 0; i < len(parts1) || i < len(parts2); i++ {
+		p1, p2 := "0", "0"
+		if i < len(parts1) {
+			p1 = parts1[i]
+		}
+		if i < len(parts2) {
+			p2 = parts2[i]
+		}
+
+		n1, err1 := strconv.Atoi(p1)
+		n2, err2 := strconv.Atoi(p2)
+		if err1 == nil && err2 == nil {
+			if n1 != n2 {
+				if n1 < n2 {
+					return -1
+				}
+				return 1
+			}
+			continue
+		}
+
+		if p1 != p2 {
+			if p1 < p2 {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
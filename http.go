@@ -0,0 +1,149 @@
+package pg
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"gopkg.in/pg.v5/internal"
+	"gopkg.in/pg.v5/orm"
+)
+
+const httpFlushInterval = 500 * time.Millisecond
+
+// ServeCSV streams rows returned by the query to the client as CSV with
+// a header row. The query can be a string or orm.Query. The response is
+// flushed periodically and the query is cancelled when the client
+// disconnects, e.g.
+//
+//    err := db.ServeCSV(w, req, "SELECT * FROM events WHERE day = ?", day)
+func (db *DB) ServeCSV(w http.ResponseWriter, req *http.Request, query interface{}, params ...interface{}) error {
+	q, err := httpQuery(query, params...)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	return db.serveCopy(w, req, "COPY (?) TO STDOUT WITH CSV HEADER", q)
+}
+
+// ServeNDJSON is like ServeCSV, but it encodes every row as a JSON object
+// on a separate line (newline delimited JSON).
+func (db *DB) ServeNDJSON(w http.ResponseWriter, req *http.Request, query interface{}, params ...interface{}) error {
+	q, err := httpQuery(query, params...)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	// CSV format does not escape backslashes like text format does and
+	// quote and delimiter chars can't appear in JSON unescaped.
+	return db.serveCopy(
+		w, req,
+		`COPY (SELECT row_to_json(t) FROM (?) AS t) TO STDOUT WITH (FORMAT csv, QUOTE E'\x01', DELIMITER E'\x02')`,
+		q,
+	)
+}
+
+func httpQuery(query interface{}, params ...interface{}) (orm.FormatAppender, error) {
+	switch query := query.(type) {
+	case string:
+		return orm.Q(query, params...), nil
+	case orm.FormatAppender:
+		return query, nil
+	default:
+		return nil, fmt.Errorf("pg: can't serve %T", query)
+	}
+}
+
+func (db *DB) serveCopy(w http.ResponseWriter, req *http.Request, query string, params ...interface{}) error {
+	cn, err := db.conn()
+	if err != nil {
+		return err
+	}
+
+	fw := newFlushWriter(w)
+	ctx := req.Context()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case <-ctx.Done():
+		case <-fw.failed:
+		case <-done:
+			return
+		}
+		if err := db.cancelRequest(cn.ProcessId, cn.SecretKey); err != nil {
+			internal.Logf("cancelRequest failed: %s", err)
+		}
+	}()
+
+	_, err = db.copyTo(cn, fw, nil, query, params...)
+	close(done)
+	wg.Wait()
+	err = db.freeConn(cn, err)
+
+	if fw.err != nil {
+		return fw.err
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+
+	fw.Flush()
+	return nil
+}
+
+// flushWriter flushes the response periodically. After the first write
+// error it discards the data, so the rest of the COPY stream is consumed
+// and the connection can be reused.
+type flushWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+
+	flushedAt time.Time
+
+	err    error
+	failed chan struct{}
+}
+
+func newFlushWriter(w http.ResponseWriter) *flushWriter {
+	flusher, _ := w.(http.Flusher)
+	return &flushWriter{
+		w:         w,
+		flusher:   flusher,
+		flushedAt: time.Now(),
+		failed:    make(chan struct{}),
+	}
+}
+
+func (fw *flushWriter) Write(b []byte) (int, error) {
+	if fw.err != nil {
+		return len(b), nil
+	}
+
+	if _, err := fw.w.Write(b); err != nil {
+		fw.err = err
+		close(fw.failed)
+		return len(b), nil
+	}
+
+	if time.Since(fw.flushedAt) >= httpFlushInterval {
+		fw.Flush()
+	}
+	return len(b), nil
+}
+
+func (fw *flushWriter) Flush() {
+	if fw.flusher != nil {
+		fw.flusher.Flush()
+	}
+	fw.flushedAt = time.Now()
+}
@@ -2,36 +2,43 @@ package internal
 
 import (
 	"fmt"
-	"log"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync/atomic"
 )
 
-var (
-	Logger      *log.Logger
-	QueryLogger *log.Logger
+// Log levels.
+const (
+	LevelDebug = iota
+	LevelInfo
+	LevelWarn
+	LevelError
 )
 
-func Logf(s string, args ...interface{}) {
-	if Logger == nil {
-		return
-	}
-	Logger.Output(2, fmt.Sprintf(s, args...))
+type logFunc func(level int, msg string, keyvals ...interface{})
+
+var log atomic.Value // logFunc
+
+// SetLog sets the function that is called with log records. Passing nil
+// disables logging.
+func SetLog(fn func(level int, msg string, keyvals ...interface{})) {
+	log.Store(logFunc(fn))
 }
 
-func LogQuery(query string) {
-	if QueryLogger == nil {
+func Logf(s string, args ...interface{}) {
+	fn, _ := log.Load().(logFunc)
+	if fn == nil {
 		return
 	}
-	file, line := fileLine(2)
-	QueryLogger.Printf("%s:%d: %s", file, line, strings.TrimRight(query, "\t\n"))
+	fn(LevelWarn, fmt.Sprintf(s, args...))
 }
 
 const packageName = "gopkg.in/pg.v5"
 
-func fileLine(depth int) (string, int) {
-	for i := depth; ; i++ {
+// Caller returns file and line of the first caller outside of the package.
+func Caller() (string, int) {
+	for i := 2; ; i++ {
 		_, file, line, ok := runtime.Caller(i)
 		if !ok {
 			break
@@ -52,8 +52,11 @@ func (p *ArrayParser) NextElem() ([]byte, error) {
 	}
 }
 
+// readElem reads nested array without the closing brace. Arrays of
+// multidimensional arrays, e.g. {{{1},{2}},{{3}}}, are nested as well.
 func (p *ArrayParser) readElem() []byte {
 	var b []byte
+	var depth int
 	for p.Valid() {
 		c := p.Read()
 		switch c {
@@ -70,8 +73,15 @@ func (p *ArrayParser) readElem() []byte {
 					break
 				}
 			}
+		case '{':
+			depth++
+			b = append(b, c)
 		case '}':
-			return b
+			depth--
+			if depth == 0 {
+				return b
+			}
+			b = append(b, c)
 		default:
 			b = append(b, c)
 		}
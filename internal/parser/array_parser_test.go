@@ -21,6 +21,7 @@ var arrayTests = []struct {
 	{`{"{1}","{2}"}`, []string{"{1}", "{2}"}},
 
 	{"{{1,2},{3}}", []string{"{1,2}", "{3}"}},
+	{"{{{1},{2}},{{3}}}", []string{"{{1},{2}}", "{{3}}"}},
 }
 
 func TestArrayParser(t *testing.T) {
@@ -0,0 +1,89 @@
+package parser
+
+import "fmt"
+
+// CompositeParser parses composite type (row) literals, e.g.
+// (1,"foo bar",). Empty unquoted elements are NULL.
+type CompositeParser struct {
+	*Parser
+
+	done      bool
+	stickyErr error
+}
+
+func NewCompositeParser(b []byte) *CompositeParser {
+	var err error
+	if len(b) < 2 || b[0] != '(' || b[len(b)-1] != ')' {
+		err = fmt.Errorf("pg: can't parse composite value: %s", string(b))
+	} else {
+		b = b[1 : len(b)-1]
+	}
+	return &CompositeParser{
+		Parser: New(b),
+
+		stickyErr: err,
+	}
+}
+
+func (p *CompositeParser) Valid() bool {
+	return !p.done
+}
+
+// NextElem returns the next element or nil if the element is NULL.
+func (p *CompositeParser) NextElem() ([]byte, error) {
+	if p.stickyErr != nil {
+		return nil, p.stickyErr
+	}
+
+	var b []byte
+	var quoted bool
+	for p.Parser.Valid() {
+		c := p.Read()
+		switch c {
+		case ',':
+			return elemOrNull(b, quoted), nil
+		case '"':
+			quoted = true
+			b = p.readQuoted(b)
+		case '\\':
+			if p.Parser.Valid() {
+				c = p.Read()
+			}
+			b = append(b, c)
+		default:
+			b = append(b, c)
+		}
+	}
+
+	p.done = true
+	if p.stickyErr != nil {
+		return nil, p.stickyErr
+	}
+	return elemOrNull(b, quoted), nil
+}
+
+func (p *CompositeParser) readQuoted(b []byte) []byte {
+	for p.Parser.Valid() {
+		c := p.Read()
+		switch c {
+		case '"':
+			if !p.Skip('"') {
+				return b
+			}
+		case '\\':
+			if p.Parser.Valid() {
+				c = p.Read()
+			}
+		}
+		b = append(b, c)
+	}
+	p.stickyErr = fmt.Errorf("pg: can't parse composite value: unterminated quoted string")
+	return b
+}
+
+func elemOrNull(b []byte, quoted bool) []byte {
+	if b == nil && quoted {
+		return []byte{}
+	}
+	return b
+}
@@ -0,0 +1,63 @@
+package parser_test
+
+import (
+	"testing"
+
+	"gopkg.in/pg.v5/internal/parser"
+)
+
+var compositeTests = []struct {
+	s   string
+	els []interface{}
+}{
+	{`()`, []interface{}{nil}},
+	{`(1)`, []interface{}{"1"}},
+	{`(1,foo)`, []interface{}{"1", "foo"}},
+	{`(1,)`, []interface{}{"1", nil}},
+	{`(,"")`, []interface{}{nil, ""}},
+	{`("foo bar","a,b")`, []interface{}{"foo bar", "a,b"}},
+	{`("a""b","c\"d","e\\f")`, []interface{}{`a"b`, `c"d`, `e\f`}},
+	{`("(1,2)",'x')`, []interface{}{"(1,2)", "'x'"}},
+	{`("{1,2}",NULL)`, []interface{}{"{1,2}", "NULL"}},
+}
+
+func TestCompositeParser(t *testing.T) {
+	for testi, test := range compositeTests {
+		p := parser.NewCompositeParser([]byte(test.s))
+
+		var got []interface{}
+		for p.Valid() {
+			b, err := p.NextElem()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b == nil {
+				got = append(got, nil)
+			} else {
+				got = append(got, string(b))
+			}
+		}
+
+		if len(got) != len(test.els) {
+			t.Fatalf("#%d got %q, wanted %q", testi, got, test.els)
+		}
+		for i, el := range test.els {
+			if got[i] != el {
+				t.Fatalf("#%d el #%d does not match: %q != %q", testi, i, got[i], el)
+			}
+		}
+	}
+}
+
+func TestCompositeParserError(t *testing.T) {
+	for _, s := range []string{``, `1,2`, `("foo)`} {
+		p := parser.NewCompositeParser([]byte(s))
+		var err error
+		for p.Valid() && err == nil {
+			_, err = p.NextElem()
+		}
+		if err == nil {
+			t.Errorf("parsing %q: expected an error", s)
+		}
+	}
+}
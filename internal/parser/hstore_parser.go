@@ -22,6 +22,9 @@ func (p *HstoreParser) NextKey() ([]byte, error) {
 	}
 
 	key := p.readSubstring()
+	if key == nil {
+		key = []byte{}
+	}
 	if !(p.Skip('=') && p.Skip('>')) {
 		return nil, fmt.Errorf("pg: can't parse hstore key: %q", p.Bytes())
 	}
@@ -29,12 +32,21 @@ func (p *HstoreParser) NextKey() ([]byte, error) {
 	return key, nil
 }
 
+// NextValue returns the next value or nil if the value is NULL.
 func (p *HstoreParser) NextValue() ([]byte, error) {
+	if p.SkipBytes(pgNull) {
+		p.SkipBytes([]byte(", "))
+		return nil, nil
+	}
+
 	if !p.Skip('"') {
 		return nil, fmt.Errorf("pg: can't parse hstore value: %q", p.Bytes())
 	}
 
 	value := p.readSubstring()
+	if value == nil {
+		value = []byte{}
+	}
 	p.SkipBytes([]byte(", "))
 	return value, nil
 }
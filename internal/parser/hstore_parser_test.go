@@ -17,6 +17,7 @@ var hstoreTests = []struct {
 
 	{`"foo"=>"bar"`, map[string]string{"foo": "bar"}},
 	{`"foo"=>"bar","k"=>"v"`, map[string]string{"foo": "bar", "k": "v"}},
+	{`"foo"=>"bar", "k"=>NULL, "v"=>""`, map[string]string{"foo": "bar", "k": "", "v": ""}},
 }
 
 func TestHstoreParser(t *testing.T) {
@@ -0,0 +1,53 @@
+package pool
+
+import "sync"
+
+// Default sizes of the read and write buffers of connections.
+const (
+	DefaultReadBufferSize  = 4096
+	DefaultWriteBufferSize = 4096
+)
+
+// maxBufClass is the size class of the largest pooled buffer (1GB),
+// which is the limit of the message size.
+const maxBufClass = 30
+
+// bufPools pools buffers by size classes that are powers of two.
+// Connections take buffers larger than their buffer sizes from the
+// pools when they read large values and return them when the
+// connection is released, so connections don't keep the memory used by
+// wide rows or huge COPY forever and don't allocate it again for every
+// large value either.
+var bufPools [maxBufClass + 1]sync.Pool
+
+// bufClass returns the smallest c such that 1<<c >= n.
+func bufClass(n int) int {
+	var c int
+	for 1<<uint(c) < n {
+		c++
+	}
+	return c
+}
+
+// getBuf returns a buffer of length n with the capacity of its size
+// class.
+func getBuf(n int) []byte {
+	c := bufClass(n)
+	if c > maxBufClass {
+		return make([]byte, n)
+	}
+	if v := bufPools[c].Get(); v != nil {
+		return (*v.(*[]byte))[:n]
+	}
+	return make([]byte, n, 1<<uint(c))
+}
+
+// putBuf returns the buffer taken with getBuf to the pool.
+func putBuf(b []byte) {
+	c := bufClass(cap(b))
+	if c > maxBufClass || cap(b) != 1<<uint(c) {
+		return
+	}
+	b = b[:0]
+	bufPools[c].Put(&b)
+}
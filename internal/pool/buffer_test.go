@@ -0,0 +1,51 @@
+package pool
+
+import "testing"
+
+func TestBufClass(t *testing.T) {
+	tests := []struct {
+		n, class int
+	}{
+		{1, 0},
+		{2, 1},
+		{3, 2},
+		{4096, 12},
+		{4097, 13},
+		{1 << 20, 20},
+	}
+	for _, test := range tests {
+		if got := bufClass(test.n); got != test.class {
+			t.Errorf("bufClass(%d) = %d, wanted %d", test.n, got, test.class)
+		}
+	}
+}
+
+func TestGetPutBuf(t *testing.T) {
+	b := getBuf(5000)
+	if len(b) != 5000 || cap(b) != 8192 {
+		t.Fatalf("got len=%d cap=%d, wanted len=5000 cap=8192", len(b), cap(b))
+	}
+	putBuf(b)
+
+	b = getBuf(6000)
+	if len(b) != 6000 || cap(b) != 8192 {
+		t.Fatalf("got len=%d cap=%d, wanted len=6000 cap=8192", len(b), cap(b))
+	}
+
+	// Buffers that were not taken from the pool are not pooled.
+	putBuf(make([]byte, 0, 5000))
+}
+
+func TestReleaseBuffers(t *testing.T) {
+	cn := NewConnSize(nil, 1024, 1024)
+	cn.buf = getBuf(1 << 16)
+	cn.Wr.Bytes = append(cn.Wr.Bytes, make([]byte, 2048)...)
+
+	cn.ReleaseBuffers()
+	if cap(cn.buf) > 1024 {
+		t.Fatalf("read buffer is not released: cap=%d", cap(cn.buf))
+	}
+	if cap(cn.Wr.Bytes) != 1024 || len(cn.Wr.Bytes) != 0 {
+		t.Fatalf("write buffer is not released: len=%d cap=%d", len(cn.Wr.Bytes), cap(cn.Wr.Bytes))
+	}
+}
@@ -3,6 +3,7 @@ package pool
 import (
 	"bufio"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -17,25 +18,73 @@ type Conn struct {
 
 	buf     []byte // read buffer
 	Rd      *bufio.Reader
-	Columns [][]byte
+	Columns []Column
 
 	Wr *WriteBuffer
 
 	InitedAt time.Time
 	UsedAt   time.Time
+	Queries  int64 // number of queries sent on the connection
 
 	ProcessId int32
 	SecretKey int32
 
+	// CancelPending is set when a cancel request was sent for
+	// the connection, but the server may not have processed it yet.
+	// Such connection is not returned to the pool.
+	CancelPending bool
+
+	trace   func(frontend bool, b []byte)
+	flushes int64
+
+	// ZeroCopy makes ReadValue return slices of the read buffer.
+	ZeroCopy bool
+
+	readBufSize  int
+	writeBufSize int
+
+	// Names of statements prepared on the connection by *pg.Stmt keyed
+	// by the statement and the generation of closed statements that
+	// were deallocated.
+	Stmts    map[interface{}]string
+	StmtsGen uint32
+
 	_lastId int64
 }
 
+// Column describes a column of the rows returned by the server.
+type Column struct {
+	Name         []byte
+	TableOID     int32
+	ColumnNum    int16
+	DataType     int32 // data type OID
+	DataTypeSize int16
+	TypeModifier int32
+	Format       int16
+}
+
 func NewConn(netConn net.Conn) *Conn {
+	return NewConnSize(netConn, DefaultReadBufferSize, DefaultWriteBufferSize)
+}
+
+// NewConnSize returns a connection with the read and write buffers of
+// the sizes. Larger buffers used for large messages are released to
+// the buffer pool by ReleaseBuffers.
+func NewConnSize(netConn net.Conn, readSize, writeSize int) *Conn {
+	if readSize <= 0 {
+		readSize = DefaultReadBufferSize
+	}
+	if writeSize <= 0 {
+		writeSize = DefaultWriteBufferSize
+	}
 	cn := &Conn{
 		buf:    make([]byte, 0, 512),
-		Rd:     bufio.NewReader(netConn),
-		Wr:     NewWriteBuffer(),
+		Rd:     bufio.NewReaderSize(netConn, readSize),
+		Wr:     &WriteBuffer{Bytes: make([]byte, 0, writeSize)},
 		UsedAt: time.Now(),
+
+		readBufSize:  readSize,
+		writeBufSize: writeSize,
 	}
 	cn.SetNetConn(netConn)
 	return cn
@@ -47,7 +96,34 @@ func (cn *Conn) RemoteAddr() net.Addr {
 
 func (cn *Conn) SetNetConn(netConn net.Conn) {
 	cn.netConn = netConn
-	cn.Rd.Reset(netConn)
+	cn.Rd.Reset(connReader{cn})
+}
+
+// SetTrace sets the function that is called with data written to and
+// read from the connection. It must be set before anything is read
+// from the connection.
+func (cn *Conn) SetTrace(fn func(frontend bool, b []byte)) {
+	cn.trace = fn
+	cn.SetNetConn(cn.netConn)
+}
+
+// connReader traces data read from the connection.
+type connReader struct {
+	cn *Conn
+}
+
+func (r connReader) Read(b []byte) (int, error) {
+	n, err := r.cn.netConn.Read(b)
+	if n > 0 && r.cn.trace != nil {
+		r.cn.trace(false, b[:n])
+	}
+	return n, err
+}
+
+// Flushes returns the number of successful FlushWriter calls. A query
+// that failed before it was flushed was not sent to the server.
+func (cn *Conn) Flushes() int64 {
+	return cn.flushes
 }
 
 func (cn *Conn) NetConn() net.Conn {
@@ -74,9 +150,8 @@ func (cn *Conn) SetReadWriteTimeout(rt, wt time.Duration) {
 }
 
 func (cn *Conn) ReadN(n int) ([]byte, error) {
-	if d := n - cap(cn.buf); d > 0 {
-		cn.buf = cn.buf[:cap(cn.buf)]
-		cn.buf = append(cn.buf, make([]byte, d)...)
+	if n > cap(cn.buf) {
+		cn.buf = getBuf(n)
 	} else {
 		cn.buf = cn.buf[:n]
 	}
@@ -84,17 +159,63 @@ func (cn *Conn) ReadN(n int) ([]byte, error) {
 	return cn.buf, err
 }
 
+// ReadValue reads a column value of n bytes. With ZeroCopy it returns
+// the slice of the read buffer when the value fits in it, which is
+// valid only until the next read, and otherwise copies the value like
+// ReadN.
+func (cn *Conn) ReadValue(n int) ([]byte, error) {
+	if !cn.ZeroCopy || n > cn.Rd.Size() {
+		return cn.ReadN(n)
+	}
+	b, err := cn.Rd.Peek(n)
+	if err != nil {
+		return nil, err
+	}
+	_, err = cn.Rd.Discard(n)
+	return b, err
+}
+
+// Write writes b to the connection bypassing the write buffer.
+func (cn *Conn) Write(b []byte) (int, error) {
+	if cn.trace != nil {
+		cn.trace(true, b)
+	}
+	return cn.netConn.Write(b)
+}
+
 func (cn *Conn) FlushWriter() error {
+	if cn.trace != nil {
+		cn.trace(true, cn.Wr.Bytes)
+	}
 	_, err := cn.netConn.Write(cn.Wr.Bytes)
 	cn.Wr.Reset()
+	if err == nil {
+		cn.flushes++
+	}
 	return err
 }
 
+// ReleaseBuffers returns buffers that grew larger than the buffer sizes
+// of the connection to the buffer pool.
+func (cn *Conn) ReleaseBuffers() {
+	if cap(cn.buf) > cn.readBufSize {
+		putBuf(cn.buf)
+		cn.buf = make([]byte, 0, 512)
+	}
+	if cap(cn.Wr.Bytes) > cn.writeBufSize {
+		putBuf(cn.Wr.Bytes)
+		cn.Wr.Bytes = make([]byte, 0, cn.writeBufSize)
+	}
+}
+
 func (cn *Conn) Close() error {
 	return cn.netConn.Close()
 }
 
 func (cn *Conn) CheckHealth() error {
+	if cn.CancelPending {
+		return errors.New("connection has pending cancel request")
+	}
 	if cn.Rd.Buffered() != 0 {
 		b, _ := cn.Rd.Peek(cn.Rd.Buffered())
 		err := fmt.Errorf("connection has unread data:\n%s", hex.Dump(b))
@@ -1,6 +1,7 @@
 package pool
 
 import (
+	"context"
 	"errors"
 	"net"
 	"sync"
@@ -30,46 +31,63 @@ type Stats struct {
 	Hits     uint32 // number of times free connection was found in the pool
 	Timeouts uint32 // number of times a wait timeout occurred
 
+	WaitCount    uint32        // number of times a request waited for a free connection
+	WaitDuration time.Duration // total time requests waited for a free connection
+
 	TotalConns uint32 // the number of total connections in the pool
 	FreeConns  uint32 // the number of free connections in the pool
 }
 
 type Pooler interface {
 	Get() (*Conn, bool, error)
+	GetContext(context.Context) (*Conn, bool, error)
 	Put(*Conn) error
 	Remove(*Conn, error) error
 	Len() int
 	FreeLen() int
 	Stats() *Stats
 	Close() error
+	Shutdown(context.Context) error
 	Closed() bool
 }
 
 type Options struct {
 	Dial    func() (net.Conn, error)
 	OnClose func(*Conn) error
+	// OnConnect initializes connections that are dialed in the
+	// background to maintain MinIdleConns.
+	OnConnect func(*Conn) error
 
 	PoolSize           int
+	PoolFIFO           bool
+	MinIdleConns       int
 	PoolTimeout        time.Duration
 	IdleTimeout        time.Duration
 	IdleCheckFrequency time.Duration
 	MaxAge             time.Duration
+
+	ReadBufferSize  int
+	WriteBufferSize int
 }
 
 type ConnPool struct {
+	waitDuration int64 // atomic
+
 	opt *Options
 
-	queue chan struct{}
+	queue *waitQueue
 
 	connsMu sync.Mutex
 	conns   []*Conn
+	dialing int // number of idle connections that are being dialed
 
 	freeConnsMu sync.Mutex
 	freeConns   []*Conn
 
 	stats Stats
 
-	_closed int32 // atomic
+	_closed   int32 // atomic
+	_draining int32 // atomic
 }
 
 var _ Pooler = (*ConnPool)(nil)
@@ -78,18 +96,75 @@ func NewConnPool(opt *Options) *ConnPool {
 	p := &ConnPool{
 		opt: opt,
 
-		queue:     make(chan struct{}, opt.PoolSize),
+		queue:     newWaitQueue(opt.PoolSize),
 		conns:     make([]*Conn, 0, opt.PoolSize),
 		freeConns: make([]*Conn, 0, opt.PoolSize),
 	}
 
-	if opt.IdleTimeout > 0 && opt.IdleCheckFrequency > 0 {
+	if (opt.IdleTimeout > 0 || opt.MaxAge > 0) && opt.IdleCheckFrequency > 0 {
 		go p.reaper(opt.IdleCheckFrequency)
 	}
+	p.checkMinIdleConns()
 
 	return p
 }
 
+// checkMinIdleConns dials connections in the background until there
+// are at least MinIdleConns free connections or the pool is full.
+func (p *ConnPool) checkMinIdleConns() {
+	if p.opt.MinIdleConns == 0 || p.Closed() || p.draining() {
+		return
+	}
+
+	free := p.FreeLen()
+	var n int
+	p.connsMu.Lock()
+	for len(p.conns)+p.dialing < p.opt.PoolSize && free+p.dialing < p.opt.MinIdleConns {
+		p.dialing++
+		n++
+	}
+	p.connsMu.Unlock()
+
+	for i := 0; i < n; i++ {
+		go p.addIdleConn()
+	}
+}
+
+func (p *ConnPool) addIdleConn() {
+	cn, err := p.NewConn()
+	if err == nil && p.opt.OnConnect != nil {
+		if err = p.opt.OnConnect(cn); err != nil {
+			_ = cn.Close()
+		}
+	}
+
+	if err != nil {
+		p.connsMu.Lock()
+		p.dialing--
+		p.connsMu.Unlock()
+		internal.Logf("pg: can't create idle connection: %s", err)
+		return
+	}
+
+	// Clients could fill the pool while the connection was dialed.
+	p.connsMu.Lock()
+	p.dialing--
+	added := !p.Closed() && !p.draining() && len(p.conns) < p.opt.PoolSize
+	if added {
+		p.conns = append(p.conns, cn)
+	}
+	p.connsMu.Unlock()
+
+	if !added {
+		_ = p.closeConn(cn, ErrClosed)
+		return
+	}
+
+	p.freeConnsMu.Lock()
+	p.freeConns = append(p.freeConns, cn)
+	p.freeConnsMu.Unlock()
+}
+
 func (p *ConnPool) dial() (net.Conn, error) {
 	cn, err := p.opt.Dial()
 	if err != nil {
@@ -103,7 +178,7 @@ func (p *ConnPool) NewConn() (*Conn, error) {
 	if err != nil {
 		return nil, err
 	}
-	return NewConn(netConn), nil
+	return NewConnSize(netConn, p.opt.ReadBufferSize, p.opt.WriteBufferSize), nil
 }
 
 func (p *ConnPool) isStaleConn(cn *Conn) bool {
@@ -123,18 +198,7 @@ func (p *ConnPool) isStaleConn(cn *Conn) bool {
 }
 
 func (p *ConnPool) PopFree() *Conn {
-	timer := timers.Get().(*time.Timer)
-	timer.Reset(p.opt.PoolTimeout)
-
-	select {
-	case p.queue <- struct{}{}:
-		if !timer.Stop() {
-			<-timer.C
-		}
-		timers.Put(timer)
-	case <-timer.C:
-		timers.Put(timer)
-		atomic.AddUint32(&p.stats.Timeouts, 1)
+	if err := p.wait(context.Background()); err != nil {
 		return nil
 	}
 
@@ -143,7 +207,9 @@ func (p *ConnPool) PopFree() *Conn {
 	p.freeConnsMu.Unlock()
 
 	if cn == nil {
-		<-p.queue
+		p.queue.release()
+	} else {
+		p.checkMinIdleConns()
 	}
 	return cn
 }
@@ -153,33 +219,54 @@ func (p *ConnPool) popFree() *Conn {
 		return nil
 	}
 
+	if p.opt.PoolFIFO {
+		cn := p.freeConns[0]
+		copy(p.freeConns, p.freeConns[1:])
+		p.freeConns = p.freeConns[:len(p.freeConns)-1]
+		return cn
+	}
+
 	idx := len(p.freeConns) - 1
 	cn := p.freeConns[idx]
 	p.freeConns = p.freeConns[:idx]
 	return cn
 }
 
+// wait waits for a free slot in the pool.
+func (p *ConnPool) wait(ctx context.Context) error {
+	start := time.Now()
+	waited, err := p.queue.acquire(ctx, p.opt.PoolTimeout)
+	if waited {
+		atomic.AddUint32(&p.stats.WaitCount, 1)
+		atomic.AddInt64(&p.waitDuration, int64(time.Since(start)))
+	}
+	if err == ErrPoolTimeout || err == context.DeadlineExceeded {
+		atomic.AddUint32(&p.stats.Timeouts, 1)
+	}
+	return err
+}
+
 // Get returns existed connection from the pool or creates a new one.
 func (p *ConnPool) Get() (*Conn, bool, error) {
-	if p.Closed() {
+	return p.GetContext(context.Background())
+}
+
+// GetContext is like Get, but stops waiting for a free connection when
+// the ctx is done. Clients wait for free connections in the FIFO order.
+func (p *ConnPool) GetContext(ctx context.Context) (*Conn, bool, error) {
+	if p.Closed() || p.draining() {
 		return nil, false, ErrClosed
 	}
 
 	atomic.AddUint32(&p.stats.Requests, 1)
 
-	timer := timers.Get().(*time.Timer)
-	timer.Reset(p.opt.PoolTimeout)
-
-	select {
-	case p.queue <- struct{}{}:
-		if !timer.Stop() {
-			<-timer.C
-		}
-		timers.Put(timer)
-	case <-timer.C:
-		timers.Put(timer)
-		atomic.AddUint32(&p.stats.Timeouts, 1)
-		return nil, false, ErrPoolTimeout
+	if err := p.wait(ctx); err != nil {
+		return nil, false, err
+	}
+	// The pool could be shut down while the client waited.
+	if p.draining() {
+		p.queue.release()
+		return nil, false, ErrClosed
 	}
 
 	for {
@@ -197,12 +284,13 @@ func (p *ConnPool) Get() (*Conn, bool, error) {
 		}
 
 		atomic.AddUint32(&p.stats.Hits, 1)
+		p.checkMinIdleConns()
 		return cn, false, nil
 	}
 
 	newcn, err := p.NewConn()
 	if err != nil {
-		<-p.queue
+		p.queue.release()
 		return nil, false, err
 	}
 
@@ -218,16 +306,18 @@ func (p *ConnPool) Put(cn *Conn) error {
 		internal.Logf(e.Error())
 		return p.Remove(cn, e)
 	}
+	cn.ReleaseBuffers()
 	p.freeConnsMu.Lock()
 	p.freeConns = append(p.freeConns, cn)
 	p.freeConnsMu.Unlock()
-	<-p.queue
+	p.queue.release()
 	return nil
 }
 
 func (p *ConnPool) Remove(cn *Conn, reason error) error {
 	p.remove(cn, reason)
-	<-p.queue
+	p.queue.release()
+	p.checkMinIdleConns()
 	return nil
 }
 
@@ -262,9 +352,13 @@ func (p *ConnPool) FreeLen() int {
 
 func (p *ConnPool) Stats() *Stats {
 	return &Stats{
-		Requests:   atomic.LoadUint32(&p.stats.Requests),
-		Hits:       atomic.LoadUint32(&p.stats.Hits),
-		Timeouts:   atomic.LoadUint32(&p.stats.Timeouts),
+		Requests: atomic.LoadUint32(&p.stats.Requests),
+		Hits:     atomic.LoadUint32(&p.stats.Hits),
+		Timeouts: atomic.LoadUint32(&p.stats.Timeouts),
+
+		WaitCount:    atomic.LoadUint32(&p.stats.WaitCount),
+		WaitDuration: time.Duration(atomic.LoadInt64(&p.waitDuration)),
+
 		TotalConns: uint32(p.Len()),
 		FreeConns:  uint32(p.FreeLen()),
 	}
@@ -274,6 +368,34 @@ func (p *ConnPool) Closed() bool {
 	return atomic.LoadInt32(&p._closed) == 1
 }
 
+func (p *ConnPool) draining() bool {
+	return atomic.LoadInt32(&p._draining) == 1
+}
+
+// Shutdown stops handing out connections, waits until the connections
+// in use are returned to the pool or the ctx is done and closes the
+// pool. It returns the error of the ctx when connections were closed
+// while in use.
+func (p *ConnPool) Shutdown(ctx context.Context) error {
+	if p.Closed() || !atomic.CompareAndSwapInt32(&p._draining, 0, 1) {
+		return ErrClosed
+	}
+
+	// Every connection in use holds a slot, so all connections are
+	// returned when all slots are taken.
+	var err error
+	for i := 0; i < p.opt.PoolSize; i++ {
+		if _, err = p.queue.acquire(ctx, 0); err != nil {
+			break
+		}
+	}
+
+	if closeErr := p.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
 func (p *ConnPool) Close() error {
 	if !atomic.CompareAndSwapInt32(&p._closed, 0, 1) {
 		return ErrClosed
@@ -303,35 +425,37 @@ func (p *ConnPool) closeConn(cn *Conn, reason error) error {
 	if p.opt.OnClose != nil {
 		_ = p.opt.OnClose(cn)
 	}
+	cn.ReleaseBuffers()
 	return cn.Close()
 }
 
 func (p *ConnPool) reapStaleConn() bool {
-	if len(p.freeConns) == 0 {
-		return false
-	}
+	// Free connections are ordered by the last usage, but not by age,
+	// so all of them are checked.
+	for i, cn := range p.freeConns {
+		if !p.isStaleConn(cn) {
+			continue
+		}
 
-	cn := p.freeConns[0]
-	if !p.isStaleConn(cn) {
-		return false
+		p.remove(cn, errConnStale)
+		p.freeConns = append(p.freeConns[:i], p.freeConns[i+1:]...)
+		return true
 	}
-
-	p.remove(cn, errConnStale)
-	p.freeConns = append(p.freeConns[:0], p.freeConns[1:]...)
-
-	return true
+	return false
 }
 
 func (p *ConnPool) ReapStaleConns() (int, error) {
 	var n int
 	for {
-		p.queue <- struct{}{}
+		if _, err := p.queue.acquire(context.Background(), p.opt.PoolTimeout); err != nil {
+			return n, err
+		}
 		p.freeConnsMu.Lock()
 
 		reaped := p.reapStaleConn()
 
 		p.freeConnsMu.Unlock()
-		<-p.queue
+		p.queue.release()
 
 		if reaped {
 			n++
@@ -355,6 +479,7 @@ func (p *ConnPool) reaper(frequency time.Duration) {
 			internal.Logf("ReapStaleConns failed: %s", err)
 			continue
 		}
+		p.checkMinIdleConns()
 		s := p.Stats()
 		internal.Logf(
 			"reaper: removed %d stale conns (TotalConns=%d FreeConns=%d Requests=%d Hits=%d Timeouts=%d)",
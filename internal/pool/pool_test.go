@@ -1,7 +1,11 @@
 package pool_test
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"net"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -82,6 +86,350 @@ var _ = Describe("ConnPool", func() {
 	})
 })
 
+var _ = Describe("PoolFIFO", func() {
+	var connPool *pool.ConnPool
+
+	AfterEach(func() {
+		connPool.Close()
+	})
+
+	getAndPut := func() []*pool.Conn {
+		var cns []*pool.Conn
+		for i := 0; i < 3; i++ {
+			cn, _, err := connPool.Get()
+			Expect(err).NotTo(HaveOccurred())
+			cns = append(cns, cn)
+		}
+		for _, cn := range cns {
+			Expect(connPool.Put(cn)).NotTo(HaveOccurred())
+		}
+		return cns
+	}
+
+	It("takes the most recently used connection by default", func() {
+		connPool = pool.NewConnPool(&pool.Options{
+			Dial:        dummyDialer,
+			PoolSize:    10,
+			PoolTimeout: time.Hour,
+		})
+		cns := getAndPut()
+
+		cn, _, err := connPool.Get()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cn).To(BeIdenticalTo(cns[2]))
+	})
+
+	It("takes the least recently used connection", func() {
+		connPool = pool.NewConnPool(&pool.Options{
+			Dial:        dummyDialer,
+			PoolSize:    10,
+			PoolFIFO:    true,
+			PoolTimeout: time.Hour,
+		})
+		cns := getAndPut()
+
+		for _, wanted := range cns {
+			cn, _, err := connPool.Get()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cn).To(BeIdenticalTo(wanted))
+		}
+	})
+})
+
+var _ = Describe("GetContext", func() {
+	var connPool *pool.ConnPool
+	var cn *pool.Conn
+
+	BeforeEach(func() {
+		connPool = pool.NewConnPool(&pool.Options{
+			Dial:        dummyDialer,
+			PoolSize:    1,
+			PoolTimeout: time.Hour,
+		})
+
+		var err error
+		cn, _, err = connPool.Get()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		connPool.Close()
+	})
+
+	It("stops waiting when the context is done", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, _, err := connPool.GetContext(ctx)
+		Expect(err).To(Equal(context.DeadlineExceeded))
+
+		st := connPool.Stats()
+		Expect(st.Timeouts).To(Equal(uint32(1)))
+		Expect(st.WaitCount).To(Equal(uint32(1)))
+		Expect(st.WaitDuration).To(BeNumerically(">=", 10*time.Millisecond))
+
+		// The slot of the canceled client is not lost.
+		Expect(connPool.Put(cn)).NotTo(HaveOccurred())
+		_, _, err = connPool.Get()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("does not wait when the context is already done", func() {
+		Expect(connPool.Put(cn)).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, _, err := connPool.GetContext(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(connPool.Stats().WaitCount).To(Equal(uint32(0)))
+	})
+
+	It("serves waiting clients in FIFO order", func() {
+		const n = 5
+		order := make(chan int, n)
+		for i := 0; i < n; i++ {
+			go func(i int) {
+				defer GinkgoRecover()
+
+				cn, _, err := connPool.GetContext(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				order <- i
+				Expect(connPool.Put(cn)).NotTo(HaveOccurred())
+			}(i)
+			// Let the client join the queue.
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		Expect(connPool.Put(cn)).NotTo(HaveOccurred())
+		for i := 0; i < n; i++ {
+			Eventually(order).Should(Receive(Equal(i)))
+		}
+		Expect(connPool.Stats().WaitCount).To(Equal(uint32(n)))
+	})
+})
+
+var _ = Describe("Shutdown", func() {
+	var connPool *pool.ConnPool
+	var cn *pool.Conn
+
+	BeforeEach(func() {
+		connPool = pool.NewConnPool(&pool.Options{
+			Dial:        dummyDialer,
+			PoolSize:    10,
+			PoolTimeout: time.Hour,
+		})
+
+		var err error
+		cn, _, err = connPool.Get()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("waits for connections in use", func() {
+		done := make(chan error, 1)
+		go func() {
+			done <- connPool.Shutdown(context.Background())
+		}()
+
+		Eventually(func() error {
+			cn, _, err := connPool.Get()
+			if err == nil {
+				_ = connPool.Put(cn)
+			}
+			return err
+		}).Should(Equal(pool.ErrClosed))
+		Consistently(done).ShouldNot(Receive())
+
+		Expect(connPool.Put(cn)).NotTo(HaveOccurred())
+		// Dummy connections fail to close, so the error is ignored.
+		Eventually(done).Should(Receive())
+		Expect(connPool.Closed()).To(BeTrue())
+		Expect(connPool.Len()).To(Equal(0))
+	})
+
+	It("closes connections in use when the context is done", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := connPool.Shutdown(ctx)
+		Expect(err).To(Equal(context.DeadlineExceeded))
+		Expect(connPool.Closed()).To(BeTrue())
+		Expect(connPool.Len()).To(Equal(0))
+	})
+
+	It("can't be called twice", func() {
+		Expect(connPool.Put(cn)).NotTo(HaveOccurred())
+		_ = connPool.Shutdown(context.Background())
+		Expect(connPool.Shutdown(context.Background())).To(Equal(pool.ErrClosed))
+	})
+})
+
+var _ = Describe("MaxAge", func() {
+	var connPool *pool.ConnPool
+
+	BeforeEach(func() {
+		connPool = pool.NewConnPool(&pool.Options{
+			Dial:               dummyDialer,
+			PoolSize:           10,
+			PoolTimeout:        time.Hour,
+			MaxAge:             time.Hour,
+			IdleCheckFrequency: time.Hour,
+		})
+	})
+
+	AfterEach(func() {
+		connPool.Close()
+	})
+
+	It("reaps aged connections that were used recently", func() {
+		var cns []*pool.Conn
+		for i := 0; i < 3; i++ {
+			cn, _, err := connPool.Get()
+			Expect(err).NotTo(HaveOccurred())
+			cns = append(cns, cn)
+		}
+		cns[0].InitedAt = time.Now()
+		cns[1].InitedAt = time.Now().Add(-2 * time.Hour)
+		cns[2].InitedAt = time.Now()
+		for _, cn := range cns {
+			Expect(connPool.Put(cn)).NotTo(HaveOccurred())
+		}
+
+		n, err := connPool.ReapStaleConns()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(1))
+		Expect(connPool.FreeLen()).To(Equal(2))
+	})
+
+	It("retires aged connection on Get", func() {
+		cn, _, err := connPool.Get()
+		Expect(err).NotTo(HaveOccurred())
+		cn.InitedAt = time.Now().Add(-2 * time.Hour)
+		Expect(connPool.Put(cn)).NotTo(HaveOccurred())
+
+		cn2, isNew, err := connPool.Get()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isNew).To(BeTrue())
+		Expect(cn2).NotTo(BeIdenticalTo(cn))
+		Expect(connPool.Len()).To(Equal(1))
+	})
+})
+
+var _ = Describe("MinIdleConns", func() {
+	var connPool *pool.ConnPool
+
+	newConnPool := func(onConnect func(*pool.Conn) error) *pool.ConnPool {
+		return pool.NewConnPool(&pool.Options{
+			Dial:               dummyDialer,
+			OnConnect:          onConnect,
+			PoolSize:           4,
+			MinIdleConns:       2,
+			PoolTimeout:        time.Hour,
+			IdleTimeout:        time.Hour,
+			IdleCheckFrequency: time.Hour,
+		})
+	}
+
+	AfterEach(func() {
+		connPool.Close()
+	})
+
+	It("dials idle connections on start", func() {
+		var inited int32
+		connPool = newConnPool(func(cn *pool.Conn) error {
+			atomic.AddInt32(&inited, 1)
+			return nil
+		})
+
+		Eventually(connPool.FreeLen).Should(Equal(2))
+		Expect(connPool.Len()).To(Equal(2))
+		Expect(atomic.LoadInt32(&inited)).To(Equal(int32(2)))
+	})
+
+	It("maintains idle connections", func() {
+		connPool = newConnPool(nil)
+		Eventually(connPool.FreeLen).Should(Equal(2))
+
+		cn, _, err := connPool.Get()
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(connPool.FreeLen).Should(Equal(2))
+		Expect(connPool.Len()).To(Equal(3))
+
+		Expect(connPool.Remove(cn, errors.New("test"))).NotTo(HaveOccurred())
+		Eventually(connPool.Len).Should(Equal(2))
+		Expect(connPool.FreeLen()).To(Equal(2))
+	})
+
+	It("does not exceed pool size", func() {
+		connPool = newConnPool(nil)
+		Eventually(connPool.FreeLen).Should(Equal(2))
+
+		var cns []*pool.Conn
+		for i := 0; i < 4; i++ {
+			cn, _, err := connPool.Get()
+			Expect(err).NotTo(HaveOccurred())
+			cns = append(cns, cn)
+		}
+		Consistently(connPool.Len).Should(Equal(4))
+		Expect(connPool.FreeLen()).To(Equal(0))
+
+		for _, cn := range cns {
+			Expect(connPool.Put(cn)).NotTo(HaveOccurred())
+		}
+		Expect(connPool.FreeLen()).To(Equal(4))
+	})
+
+	It("does not add connections that failed to initialize", func() {
+		connPool = newConnPool(func(cn *pool.Conn) error {
+			return errors.New("test")
+		})
+
+		Consistently(connPool.Len).Should(Equal(0))
+	})
+})
+
+var _ = Describe("Conn.ReadValue", func() {
+	read := func(zeroCopy bool, data []byte, n int) ([]byte, *pool.Conn) {
+		client, server := net.Pipe()
+		go func() {
+			server.Write(data)
+			server.Close()
+		}()
+		cn := pool.NewConn(client)
+		cn.ZeroCopy = zeroCopy
+		b, err := cn.ReadValue(n)
+		Expect(err).NotTo(HaveOccurred())
+		return b, cn
+	}
+
+	It("returns the slice of the read buffer with ZeroCopy", func() {
+		b, cn := read(true, []byte("foobar"), 3)
+		Expect(string(b)).To(Equal("foo"))
+
+		// The value is not copied to the buffer used by ReadN.
+		b2, err := cn.ReadN(3)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b2)).To(Equal("bar"))
+		Expect(string(b)).To(Equal("foo"))
+	})
+
+	It("copies values larger than the read buffer", func() {
+		data := bytes.Repeat([]byte("x"), 10000)
+		b, _ := read(true, data, len(data))
+		Expect(b).To(Equal(data))
+	})
+
+	It("copies values without ZeroCopy", func() {
+		b, cn := read(false, []byte("foobar"), 3)
+		Expect(string(b)).To(Equal("foo"))
+
+		b2, err := cn.ReadN(3)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b2)).To(Equal("bar"))
+		Expect(string(b)).To(Equal("bar"))
+	})
+})
+
 var _ = Describe("conns reaper", func() {
 	const idleTimeout = time.Minute
 	const maxAge = time.Hour
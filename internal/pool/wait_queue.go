@@ -0,0 +1,94 @@
+package pool
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// waitQueue limits the number of connections in use. When all of them
+// are in use, clients wait in the FIFO order, so a client can't be
+// starved by clients that came later.
+type waitQueue struct {
+	mu      sync.Mutex
+	size    int
+	used    int
+	waiters list.List // of chan struct{}
+}
+
+func newWaitQueue(size int) *waitQueue {
+	return &waitQueue{size: size}
+}
+
+// acquire takes a slot waiting up to the timeout or until the ctx is
+// done. Zero timeout waits until the ctx is done. It reports whether
+// the client had to wait.
+func (q *waitQueue) acquire(ctx context.Context, timeout time.Duration) (waited bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	q.mu.Lock()
+	if q.used < q.size && q.waiters.Len() == 0 {
+		q.used++
+		q.mu.Unlock()
+		return false, nil
+	}
+	ready := make(chan struct{})
+	elem := q.waiters.PushBack(ready)
+	q.mu.Unlock()
+
+	var timer *time.Timer
+	var timeoutC <-chan time.Time
+	if timeout > 0 {
+		timer = timers.Get().(*time.Timer)
+		timer.Reset(timeout)
+		timeoutC = timer.C
+	}
+	stopTimer := func(fired bool) {
+		if timer == nil {
+			return
+		}
+		if !fired && !timer.Stop() {
+			<-timer.C
+		}
+		timers.Put(timer)
+	}
+
+	select {
+	case <-ready:
+		stopTimer(false)
+		return true, nil
+	case <-timeoutC:
+		stopTimer(true)
+		err = ErrPoolTimeout
+	case <-ctx.Done():
+		stopTimer(false)
+		err = ctx.Err()
+	}
+
+	q.mu.Lock()
+	select {
+	case <-ready:
+		// The slot was handed over while the client gave up,
+		// so it is passed to the next client.
+		q.mu.Unlock()
+		q.release()
+	default:
+		q.waiters.Remove(elem)
+		q.mu.Unlock()
+	}
+	return true, err
+}
+
+// release returns the slot handing it over to the first waiting client.
+func (q *waitQueue) release() {
+	q.mu.Lock()
+	if elem := q.waiters.Front(); elem != nil {
+		q.waiters.Remove(elem)
+		close(elem.Value.(chan struct{}))
+	} else {
+		q.used--
+	}
+	q.mu.Unlock()
+}
@@ -0,0 +1,171 @@
+package pg
+
+import (
+	"errors"
+	"io"
+)
+
+// Modes used to open large objects.
+const (
+	LargeObjectModeWrite = 0x20000
+	LargeObjectModeRead  = 0x40000
+)
+
+// largeObjectChunkSize limits the amount of data sent or received
+// in a single round trip.
+const largeObjectChunkSize = 1 << 20
+
+var errLargeObjectClosed = errors.New("pg: large object is closed")
+
+// LargeObjects provides access to the large object facility. Large
+// objects can only be used inside a transaction, e.g.
+//
+//    err := db.RunInTransaction(func(tx *pg.Tx) error {
+//        los := tx.LargeObjects()
+//        oid, err := los.Create(0)
+//        if err != nil {
+//            return err
+//        }
+//        lo, err := los.Open(oid, pg.LargeObjectModeWrite)
+//        if err != nil {
+//            return err
+//        }
+//        _, err = io.Copy(lo, f)
+//        return err
+//    })
+type LargeObjects struct {
+	tx *Tx
+}
+
+// LargeObjects returns large objects accessor for the transaction.
+func (tx *Tx) LargeObjects() *LargeObjects {
+	return &LargeObjects{tx: tx}
+}
+
+// Create creates a new large object with the oid. When oid is 0 the
+// server assigns an unused oid. It returns oid of the created object.
+func (los *LargeObjects) Create(oid uint32) (uint32, error) {
+	_, err := los.tx.QueryOne(Scan(&oid), "SELECT lo_create(?)", oid)
+	return oid, err
+}
+
+// Open opens the large object with the mode, which is a combination of
+// LargeObjectModeRead and LargeObjectModeWrite. The object is closed
+// at the end of the transaction if it is not closed earlier.
+func (los *LargeObjects) Open(oid uint32, mode int) (*LargeObject, error) {
+	var fd int32
+	_, err := los.tx.QueryOne(Scan(&fd), "SELECT lo_open(?, ?)", oid, mode)
+	if err != nil {
+		return nil, err
+	}
+	return &LargeObject{tx: los.tx, fd: fd}, nil
+}
+
+// Unlink removes the large object.
+func (los *LargeObjects) Unlink(oid uint32) error {
+	_, err := los.tx.Exec("SELECT lo_unlink(?)", oid)
+	return err
+}
+
+// LargeObject is an open large object. It implements io.Reader,
+// io.Writer, io.Seeker and io.Closer. Every Read and Write transfers at
+// most 1MB per round trip to the server, so small reads and writes
+// should be buffered with bufio.
+type LargeObject struct {
+	tx     *Tx
+	fd     int32
+	closed bool
+}
+
+var _ io.ReadWriteSeeker = (*LargeObject)(nil)
+var _ io.Closer = (*LargeObject)(nil)
+
+func (lo *LargeObject) Read(p []byte) (int, error) {
+	if lo.closed {
+		return 0, errLargeObjectClosed
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if len(p) > largeObjectChunkSize {
+		p = p[:largeObjectChunkSize]
+	}
+
+	var b []byte
+	_, err := lo.tx.QueryOne(Scan(&b), "SELECT loread(?, ?)", lo.fd, len(p))
+	if err != nil {
+		return 0, err
+	}
+	n := copy(p, b)
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+func (lo *LargeObject) Write(p []byte) (int, error) {
+	if lo.closed {
+		return 0, errLargeObjectClosed
+	}
+
+	var written int
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > largeObjectChunkSize {
+			chunk = chunk[:largeObjectChunkSize]
+		}
+
+		var n int
+		_, err := lo.tx.QueryOne(Scan(&n), "SELECT lowrite(?, ?)", lo.fd, chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if n != len(chunk) {
+			return written, io.ErrShortWrite
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Seek sets the offset for the next Read or Write. Whence is one of
+// io.SeekStart, io.SeekCurrent and io.SeekEnd, which have the same
+// values as SEEK_SET, SEEK_CUR and SEEK_END used by the server.
+func (lo *LargeObject) Seek(offset int64, whence int) (int64, error) {
+	if lo.closed {
+		return 0, errLargeObjectClosed
+	}
+	var pos int64
+	_, err := lo.tx.QueryOne(Scan(&pos), "SELECT lo_lseek64(?, ?, ?)", lo.fd, offset, whence)
+	return pos, err
+}
+
+// Tell returns the current offset.
+func (lo *LargeObject) Tell() (int64, error) {
+	if lo.closed {
+		return 0, errLargeObjectClosed
+	}
+	var pos int64
+	_, err := lo.tx.QueryOne(Scan(&pos), "SELECT lo_tell64(?)", lo.fd)
+	return pos, err
+}
+
+// Truncate truncates the large object to the size.
+func (lo *LargeObject) Truncate(size int64) error {
+	if lo.closed {
+		return errLargeObjectClosed
+	}
+	_, err := lo.tx.Exec("SELECT lo_truncate64(?, ?)", lo.fd, size)
+	return err
+}
+
+// Close closes the large object. It does not remove the object.
+func (lo *LargeObject) Close() error {
+	if lo.closed {
+		return errLargeObjectClosed
+	}
+	lo.closed = true
+	_, err := lo.tx.Exec("SELECT lo_close(?)", lo.fd)
+	return err
+}
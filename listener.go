@@ -1,6 +1,9 @@
 package pg
 
 import (
+	"context"
+	"errors"
+	"net"
 	"sync"
 	"time"
 
@@ -8,10 +11,44 @@ import (
 	"gopkg.in/pg.v5/internal/pool"
 )
 
+// errPingTimeout is not an internal.Error, so the connection is
+// treated as bad and is closed.
+var errPingTimeout = errors.New("pg: listener ping timed out")
+
 // A notification received with LISTEN command.
 type Notification struct {
 	Channel string
 	Payload string
+	// Process id of the server process that sent the notification.
+	PID int32
+}
+
+// DropPolicy controls what happens with notifications that don't fit
+// into the buffer of the channel returned by Listener.ChannelContext.
+type DropPolicy int
+
+const (
+	// DropNone blocks until the notification fits into the buffer.
+	// Notifications are not lost, but the server buffers them
+	// meanwhile and slow receivers delay NOTIFY transactions when the
+	// server queue fills up.
+	DropNone DropPolicy = iota
+	// DropNewest discards the notification that doesn't fit.
+	DropNewest
+	// DropOldest discards the oldest buffered notification to make
+	// room for the new one.
+	DropOldest
+)
+
+// ChannelOptions configures the channel returned by
+// Listener.ChannelContext.
+type ChannelOptions struct {
+	// Size of the channel buffer.
+	// Default is 100 notifications.
+	Size int
+	// Policy for notifications that don't fit into the buffer.
+	// Default is DropNone.
+	DropPolicy DropPolicy
 }
 
 // Listener listens for notifications sent with NOTIFY command.
@@ -56,23 +93,76 @@ func (ln *Listener) conn(readTimeout time.Duration) (*pool.Conn, error) {
 // Channel returns a channel for concurrently receiving notifications.
 // The channel is closed with Listener.
 func (ln *Listener) Channel() <-chan *Notification {
-	ch := make(chan *Notification, 100)
+	return ln.ChannelContext(context.Background(), nil)
+}
+
+// ChannelContext is like Channel, but the channel is also closed when
+// the context is done. The listener is polled once a second, so it can
+// take up to a second for the channel to close. Notifications must not
+// be read with Receive while the channel is used.
+func (ln *Listener) ChannelContext(ctx context.Context, opt *ChannelOptions) <-chan *Notification {
+	size := 100
+	var policy DropPolicy
+	if opt != nil {
+		if opt.Size > 0 {
+			size = opt.Size
+		}
+		policy = opt.DropPolicy
+	}
+
+	ch := make(chan *Notification, size)
 	go func() {
-		for {
-			channel, payload, err := ln.ReceiveTimeout(5 * time.Second)
+		defer close(ch)
+		for ctx.Err() == nil {
+			notif, err := ln.receiveNotification(time.Second)
 			if err != nil {
 				if err == errListenerClosed {
-					break
+					return
 				}
 				continue
 			}
-			ch <- &Notification{channel, payload}
+			if !sendNotification(ctx, ch, notif, policy) {
+				return
+			}
 		}
-		close(ch)
 	}()
 	return ch
 }
 
+// sendNotification sends the notification using the drop policy.
+// It returns false when the context is done.
+func sendNotification(
+	ctx context.Context, ch chan *Notification, notif *Notification, policy DropPolicy,
+) bool {
+	switch policy {
+	case DropNewest:
+		select {
+		case ch <- notif:
+		default:
+		}
+		return true
+	case DropOldest:
+		for {
+			select {
+			case ch <- notif:
+				return true
+			default:
+			}
+			select {
+			case <-ch:
+			default:
+			}
+		}
+	default:
+		select {
+		case ch <- notif:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
 // Listen starts listening for notifications on channels.
 func (ln *Listener) Listen(channels ...string) error {
 	cn, err := ln.conn(ln.db.opt.ReadTimeout)
@@ -105,21 +195,93 @@ func (ln *Listener) Receive() (channel string, payload string, err error) {
 	return ln.ReceiveTimeout(0)
 }
 
+// ReceiveNotification is like ReceiveTimeout, but returns the
+// notification with the process id of the sender, e.g. to ignore
+// notifications sent by the same session.
+func (ln *Listener) ReceiveNotification(timeout time.Duration) (*Notification, error) {
+	return ln.receiveNotification(timeout)
+}
+
 // ReceiveTimeout waits for a notification until timeout is reached.
 func (ln *Listener) ReceiveTimeout(timeout time.Duration) (channel, payload string, err error) {
-	channel, payload, err = ln.receiveTimeout(timeout)
+	notif, err := ln.receiveNotification(timeout)
 	if err != nil {
-		ln.freeConn(err)
+		return "", "", err
 	}
-	return channel, payload, err
+	return notif.Channel, notif.Payload, nil
 }
 
-func (ln *Listener) receiveTimeout(readTimeout time.Duration) (channel, payload string, err error) {
-	cn, err := ln.conn(readTimeout)
+func (ln *Listener) receiveNotification(timeout time.Duration) (*Notification, error) {
+	notif, err := ln.receiveTimeout(timeout)
 	if err != nil {
-		return "", "", err
+		ln.freeConn(err)
 	}
-	return readNotification(cn)
+	return notif, err
+}
+
+func (ln *Listener) receiveTimeout(timeout time.Duration) (*Notification, error) {
+	interval := ln.db.opt.ListenerPingInterval
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	var pinged bool
+	for {
+		readTimeout := timeout
+		if timeout > 0 {
+			readTimeout = deadline.Sub(time.Now())
+			if readTimeout <= 0 {
+				// Zero timeout disables the deadline.
+				readTimeout = time.Nanosecond
+			}
+		}
+		if interval > 0 && (timeout <= 0 || interval < readTimeout) {
+			readTimeout = interval
+		}
+
+		cn, err := ln.conn(readTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		notif, err := readNotification(cn)
+		if err == nil {
+			if notif != nil {
+				return notif, nil
+			}
+			// Response to the ping.
+			pinged = false
+			continue
+		}
+
+		if interval <= 0 {
+			return nil, err
+		}
+		if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+			return nil, err
+		}
+		if timeout > 0 && !time.Now().Before(deadline) {
+			return nil, err
+		}
+		if pinged {
+			return nil, errPingTimeout
+		}
+		if err := ln.ping(cn); err != nil {
+			return nil, err
+		}
+		pinged = true
+	}
+}
+
+// ping sends an empty query. The server responds with
+// EmptyQueryResponse, which is returned by readNotification as nil
+// notification.
+func (ln *Listener) ping(cn *pool.Conn) error {
+	cn.Wr.StartMessage(queryMsg)
+	cn.Wr.WriteString("")
+	cn.Wr.FinishMessage()
+	return cn.FlushWriter()
 }
 
 func (ln *Listener) freeConn(err error) (retErr error) {
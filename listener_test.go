@@ -1,6 +1,7 @@
 package pg_test
 
 import (
+	"context"
 	"net"
 	"time"
 
@@ -124,6 +125,78 @@ var _ = Context("Listener", func() {
 		Expect(payload).To(Equal(""))
 	})
 
+	It("receives notification with sender pid", func() {
+		err := db.Notify("test_channel", "hello")
+		Expect(err).NotTo(HaveOccurred())
+
+		notif, err := ln.ReceiveNotification(3 * time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(notif.Channel).To(Equal("test_channel"))
+		Expect(notif.Payload).To(Equal("hello"))
+		Expect(notif.PID).NotTo(BeZero())
+	})
+
+	It("receives notifications using channel", func() {
+		ch := ln.Channel()
+
+		_, err := db.Exec("NOTIFY test_channel, 'hello'")
+		Expect(err).NotTo(HaveOccurred())
+
+		var notif *pg.Notification
+		Eventually(ch, 3*time.Second).Should(Receive(&notif))
+		Expect(notif.Channel).To(Equal("test_channel"))
+		Expect(notif.Payload).To(Equal("hello"))
+		Expect(notif.PID).NotTo(BeZero())
+	})
+
+	It("closes channel when context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := ln.ChannelContext(ctx, nil)
+
+		cancel()
+		Eventually(ch, 3*time.Second).Should(BeClosed())
+	})
+
+	It("drops newest notifications when buffer is full", func() {
+		ch := ln.ChannelContext(context.Background(), &pg.ChannelOptions{
+			Size:       1,
+			DropPolicy: pg.DropNewest,
+		})
+
+		for _, payload := range []string{"1", "2", "3"} {
+			_, err := db.Exec("NOTIFY test_channel, ?", payload)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		time.Sleep(time.Second)
+
+		var notif *pg.Notification
+		Expect(ch).To(Receive(&notif))
+		Expect(notif.Payload).To(Equal("1"))
+		Consistently(ch).ShouldNot(Receive())
+	})
+
+	It("drops oldest notifications when buffer is full", func() {
+		ch := ln.ChannelContext(context.Background(), &pg.ChannelOptions{
+			Size:       1,
+			DropPolicy: pg.DropOldest,
+		})
+
+		for _, payload := range []string{"1", "2", "3"} {
+			_, err := db.Exec("NOTIFY test_channel, ?", payload)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		var notif *pg.Notification
+		Eventually(func() string {
+			select {
+			case notif = <-ch:
+				return notif.Payload
+			default:
+				return ""
+			}
+		}, 3*time.Second).Should(Equal("3"))
+	})
+
 	It("reconnects on listen error", func() {
 		cn := ln.CurrentConn()
 		Expect(cn).NotTo(BeNil())
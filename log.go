@@ -0,0 +1,340 @@
+package pg
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/pg.v5/internal"
+	"gopkg.in/pg.v5/internal/pool"
+	"gopkg.in/pg.v5/types"
+)
+
+// LogLevel is the severity of a log record.
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "debug"
+	case LogInfo:
+		return "info"
+	case LogWarn:
+		return "warn"
+	case LogError:
+		return "error"
+	}
+	return "level(" + strconv.Itoa(int(l)) + ")"
+}
+
+// Logger is a structured logger. Keyvals are alternating keys and values
+// like in slog, zap's SugaredLogger or logrus.Fields, so adapters for
+// these libraries are a few lines of code.
+//
+// Queries are logged at LogDebug level with the following keys:
+//   - query - the query text;
+//   - params - formatted query params unless redaction is enabled;
+//   - duration - time.Duration the query took;
+//   - pid - process id of the server backend that executed the query;
+//   - caller - file:line of the code that executed the query;
+//   - error - error returned by the query, if any;
+//
+// followed by the values set with DB.WithContextValues sorted by key.
+type Logger interface {
+	Log(level LogLevel, msg string, keyvals ...interface{})
+}
+
+// LevelLogger is a Logger that reports whether records of the level
+// are logged. Records of disabled levels are not built, so e.g. queries
+// are neither copied nor formatted for a logger that only logs
+// warnings.
+type LevelLogger interface {
+	Logger
+	Enabled(level LogLevel) bool
+}
+
+type logConfig struct {
+	logger Logger
+	redact bool
+}
+
+func (c *logConfig) enabled(level LogLevel) bool {
+	if c.logger == nil {
+		return false
+	}
+	if l, ok := c.logger.(LevelLogger); ok {
+		return l.Enabled(level)
+	}
+	return true
+}
+
+var (
+	logMu  sync.Mutex   // serializes SetLogger and SetLogRedaction
+	logCfg atomic.Value // *logConfig
+)
+
+func getLogConfig() *logConfig {
+	if c, ok := logCfg.Load().(*logConfig); ok {
+		return c
+	}
+	return &logConfig{}
+}
+
+// SetLogger sets the logger for queries and internal events like
+// connection errors. Passing nil disables logging. It is safe to call
+// SetLogger while queries are running.
+func SetLogger(l Logger) {
+	logMu.Lock()
+	defer logMu.Unlock()
+
+	c := *getLogConfig()
+	c.logger = l
+	logCfg.Store(&c)
+
+	if l == nil {
+		internal.SetLog(nil)
+		return
+	}
+	internal.SetLog(func(level int, msg string, keyvals ...interface{}) {
+		if c.enabled(LogLevel(level)) {
+			l.Log(LogLevel(level), msg, keyvals...)
+		}
+	})
+}
+
+// SetLogRedaction enables redaction of logged queries: string literals
+// in queries are replaced with '?' and params are not logged.
+func SetLogRedaction(redact bool) {
+	logMu.Lock()
+	defer logMu.Unlock()
+
+	c := *getLogConfig()
+	c.redact = redact
+	logCfg.Store(&c)
+}
+
+// SetQueryLogger sets a logger that will be used to log generated queries.
+//
+// Deprecated: use SetLogger(StdLogger(logger, LogDebug)).
+func SetQueryLogger(l *log.Logger) {
+	SetLogger(StdLogger(l, LogDebug))
+}
+
+// StdLogger returns Logger that writes records with level minLevel and
+// above to the standard library logger in logfmt-like format.
+func StdLogger(l *log.Logger, minLevel LogLevel) Logger {
+	return stdLogger{
+		log:      l,
+		minLevel: minLevel,
+	}
+}
+
+type stdLogger struct {
+	log      *log.Logger
+	minLevel LogLevel
+}
+
+var _ LevelLogger = stdLogger{}
+
+func (l stdLogger) Enabled(level LogLevel) bool {
+	return level >= l.minLevel
+}
+
+func (l stdLogger) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	var b bytes.Buffer
+	b.WriteString("level=")
+	b.WriteString(level.String())
+	b.WriteString(" msg=")
+	b.WriteString(strconv.Quote(msg))
+	for i := 0; i < len(keyvals); i += 2 {
+		b.WriteByte(' ')
+		fmt.Fprint(&b, keyvals[i])
+		b.WriteByte('=')
+		if i+1 < len(keyvals) {
+			s := fmt.Sprint(keyvals[i+1])
+			if s == "" || bytes.ContainsAny([]byte(s), " \"=\t\n") {
+				s = strconv.Quote(s)
+			}
+			b.WriteString(s)
+		}
+	}
+	l.log.Output(3, b.String())
+}
+
+// SlowQuery describes a query that took longer than
+// Options.SlowQueryThreshold. ProcessId can be used to find the query
+// in pg_stat_activity.
+type SlowQuery struct {
+	Query     string
+	Params    []interface{}
+	Duration  time.Duration
+	ProcessId int32
+	Err       error
+}
+
+// queryLog collects the query that is being executed so it can be
+// logged with the duration when the query is finished.
+type queryLog struct {
+	db     *DB
+	log    *logConfig
+	start  time.Time
+	query  string
+	params []interface{}
+}
+
+func shouldLogQuery(db *DB, cfg *logConfig) bool {
+	return cfg.enabled(LogDebug) || db.opt.SlowQueryThreshold > 0
+}
+
+// newQueryLog returns nil when logging is disabled. The query is
+// copied from the message written to the buffer by writeQueryMsg.
+// Params are already formatted into such queries.
+func newQueryLog(db *DB, buf *pool.WriteBuffer) *queryLog {
+	cfg := getLogConfig()
+	if !shouldLogQuery(db, cfg) {
+		return nil
+	}
+	b := buf.Bytes
+	if len(b) < 6 {
+		return nil
+	}
+	return &queryLog{
+		db:    db,
+		log:   cfg,
+		start: time.Now(),
+		query: string(b[5 : len(b)-1]), // Skip message header and trailing 0.
+	}
+}
+
+func newStmtLog(db *DB, query string, params []interface{}) *queryLog {
+	cfg := getLogConfig()
+	if !shouldLogQuery(db, cfg) {
+		return nil
+	}
+	return &queryLog{
+		db:     db,
+		log:    cfg,
+		start:  time.Now(),
+		query:  query,
+		params: params,
+	}
+}
+
+func (q *queryLog) done(cn *pool.Conn, err error) {
+	if q == nil {
+		return
+	}
+
+	dur := time.Since(q.start)
+	if q.log.enabled(LogDebug) {
+		q.log.logger.Log(LogDebug, "query", q.keyvals(cn, dur, err)...)
+	}
+
+	threshold := q.db.opt.SlowQueryThreshold
+	if threshold <= 0 || dur < threshold {
+		return
+	}
+	if fn := q.db.opt.OnSlowQuery; fn != nil {
+		fn(&SlowQuery{
+			Query:     q.query,
+			Params:    q.params,
+			Duration:  dur,
+			ProcessId: cn.ProcessId,
+			Err:       err,
+		})
+		return
+	}
+	if q.log.enabled(LogWarn) {
+		q.log.logger.Log(LogWarn, "slow query", q.keyvals(cn, dur, err)...)
+	}
+}
+
+func (q *queryLog) keyvals(cn *pool.Conn, dur time.Duration, err error) []interface{} {
+	query := q.query
+	if q.log.redact {
+		query = redactQuery(query)
+	}
+	keyvals := make([]interface{}, 0, 12+2*len(q.db.ctxValues))
+	keyvals = append(keyvals, "query", query)
+	if len(q.params) > 0 && !q.log.redact {
+		keyvals = append(keyvals, "params", formatParams(q.params))
+	}
+	keyvals = append(keyvals, "duration", dur)
+	keyvals = append(keyvals, "pid", cn.ProcessId)
+	if file, line := internal.Caller(); file != "" {
+		keyvals = append(keyvals, "caller", file+":"+strconv.Itoa(line))
+	}
+	if err != nil {
+		keyvals = append(keyvals, "error", err)
+	}
+	return appendContextValues(keyvals, q.db.ctxValues)
+}
+
+func appendContextValues(keyvals []interface{}, values map[string]interface{}) []interface{} {
+	if len(values) == 0 {
+		return keyvals
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		keyvals = append(keyvals, k, values[k])
+	}
+	return keyvals
+}
+
+func formatParams(params []interface{}) []string {
+	ss := make([]string, len(params))
+	for i, param := range params {
+		// Don't consume the reader, which is appended to the query.
+		if _, ok := param.(*types.ByteaReader); ok {
+			ss[i] = "<ByteaReader>"
+			continue
+		}
+		ss[i] = string(types.Append(nil, param, 1))
+	}
+	return ss
+}
+
+// redactQuery replaces string literals in the query with '?'.
+func redactQuery(query string) string {
+	b := make([]byte, 0, len(query))
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c != '\'' {
+			b = append(b, c)
+			continue
+		}
+
+		b = append(b, "'?'"...)
+		for i++; i < len(query); i++ {
+			if query[i] != '\'' {
+				continue
+			}
+			if i+1 < len(query) && query[i+1] == '\'' {
+				i++
+				continue
+			}
+			break
+		}
+	}
+	return string(b)
+}
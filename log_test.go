@@ -0,0 +1,197 @@
+package pg
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/pg.v5/internal/pool"
+	"gopkg.in/pg.v5/types"
+)
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		query  string
+		wanted string
+	}{
+		{`SELECT 1`, `SELECT 1`},
+		{`SELECT 'secret'`, `SELECT '?'`},
+		{`SELECT 'it''s', "col" FROM t WHERE a = 'b'`, `SELECT '?', "col" FROM t WHERE a = '?'`},
+		{`SELECT ''`, `SELECT '?'`},
+		{`SELECT 'unterminated`, `SELECT '?'`},
+	}
+	for _, test := range tests {
+		got := redactQuery(test.query)
+		if got != test.wanted {
+			t.Errorf("redactQuery(%q) = %q, wanted %q", test.query, got, test.wanted)
+		}
+	}
+}
+
+func TestFormatParamsByteaReader(t *testing.T) {
+	r := types.NewByteaReader(strings.NewReader("data"), 4)
+	if got := formatParams([]interface{}{r}); got[0] != "<ByteaReader>" {
+		t.Fatalf("got %q", got[0])
+	}
+	// The reader is not consumed by logging.
+	if got := types.Append(nil, r, 1); string(got) != `'\x64617461'` {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := StdLogger(log.New(&buf, "", 0), LogInfo)
+
+	l.Log(LogDebug, "query", "query", "SELECT 1")
+	if buf.Len() != 0 {
+		t.Fatalf("got %q, wanted debug record to be skipped", buf.String())
+	}
+
+	l.Log(LogWarn, "conn error", "pid", 123, "error", "read: connection reset", "empty", "")
+	wanted := `level=warn msg="conn error" pid=123 error="read: connection reset" empty=""` + "\n"
+	if buf.String() != wanted {
+		t.Fatalf("got %q, wanted %q", buf.String(), wanted)
+	}
+}
+
+type recordLogger struct {
+	level   LogLevel
+	msg     string
+	keyvals []interface{}
+}
+
+func (l *recordLogger) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	l.level = level
+	l.msg = msg
+	l.keyvals = keyvals
+}
+
+func (l *recordLogger) get(key string) (interface{}, bool) {
+	for i := 0; i+1 < len(l.keyvals); i += 2 {
+		if l.keyvals[i] == key {
+			return l.keyvals[i+1], true
+		}
+	}
+	return nil, false
+}
+
+func TestStmtLog(t *testing.T) {
+	l := new(recordLogger)
+	SetLogger(l)
+	defer SetLogger(nil)
+
+	db := &DB{opt: &Options{}}
+	q := "SELECT $1::text WHERE 'a' = 'a'"
+	newStmtLog(db, q, []interface{}{"secret", 1}).done(new(pool.Conn), nil)
+	if l.level != LogDebug || l.msg != "query" {
+		t.Fatalf("got level=%s msg=%q", l.level, l.msg)
+	}
+	if v, _ := l.get("query"); v != q {
+		t.Fatalf("got query %q", v)
+	}
+	v, _ := l.get("params")
+	if params, _ := v.([]string); len(params) != 2 || params[0] != "'secret'" || params[1] != "1" {
+		t.Fatalf("got params %v", v)
+	}
+	if _, ok := l.get("duration"); !ok {
+		t.Fatalf("duration is missing")
+	}
+	if _, ok := l.get("pid"); !ok {
+		t.Fatalf("pid is missing")
+	}
+
+	SetLogRedaction(true)
+	defer SetLogRedaction(false)
+
+	newStmtLog(db, q, []interface{}{"secret"}).done(new(pool.Conn), nil)
+	if v, _ := l.get("query"); v != "SELECT $1::text WHERE '?' = '?'" {
+		t.Fatalf("got query %q", v)
+	}
+	if v, ok := l.get("params"); ok {
+		t.Fatalf("got params %v, wanted none", v)
+	}
+}
+
+func TestSlowQuery(t *testing.T) {
+	var slow []*SlowQuery
+	db := &DB{opt: &Options{
+		SlowQueryThreshold: time.Millisecond,
+		OnSlowQuery: func(q *SlowQuery) {
+			slow = append(slow, q)
+		},
+	}}
+	cn := &pool.Conn{ProcessId: 42}
+
+	newStmtLog(db, "SELECT 1", nil).done(cn, nil)
+	if len(slow) != 0 {
+		t.Fatalf("got %d slow queries, wanted 0", len(slow))
+	}
+
+	qlog := newStmtLog(db, "SELECT pg_sleep($1)", []interface{}{1})
+	qlog.start = qlog.start.Add(-time.Second)
+	qlog.done(cn, nil)
+	if len(slow) != 1 {
+		t.Fatalf("got %d slow queries, wanted 1", len(slow))
+	}
+	q := slow[0]
+	if q.Query != "SELECT pg_sleep($1)" || len(q.Params) != 1 || q.ProcessId != 42 || q.Duration < time.Second {
+		t.Fatalf("got %+v", q)
+	}
+
+	l := new(recordLogger)
+	SetLogger(l)
+	defer SetLogger(nil)
+
+	db.opt.OnSlowQuery = nil
+	qlog = newStmtLog(db, "SELECT pg_sleep(1)", nil)
+	qlog.start = qlog.start.Add(-time.Second)
+	qlog.done(cn, nil)
+	if l.level != LogWarn || l.msg != "slow query" {
+		t.Fatalf("got level=%s msg=%q", l.level, l.msg)
+	}
+	if v, _ := l.get("pid"); v != int32(42) {
+		t.Fatalf("got pid %v", v)
+	}
+}
+
+func TestLevelLogger(t *testing.T) {
+	SetLogger(StdLogger(log.New(new(bytes.Buffer), "", 0), LogWarn))
+	defer SetLogger(nil)
+
+	db := &DB{opt: &Options{}}
+	if qlog := newStmtLog(db, "SELECT 1", nil); qlog != nil {
+		t.Fatalf("query is collected for a logger without debug level")
+	}
+
+	db.opt.SlowQueryThreshold = time.Second
+	if qlog := newStmtLog(db, "SELECT 1", nil); qlog == nil {
+		t.Fatalf("query is not collected for slow query log")
+	}
+}
+
+func TestQueryLogContextValues(t *testing.T) {
+	l := new(recordLogger)
+	SetLogger(l)
+	defer SetLogger(nil)
+
+	db := (&DB{opt: &Options{}}).WithContextValues(map[string]interface{}{
+		"user_id":    7,
+		"request_id": "abc",
+	})
+	newStmtLog(db, "SELECT 1", nil).done(new(pool.Conn), nil)
+
+	n := len(l.keyvals)
+	if n < 4 {
+		t.Fatalf("got %v", l.keyvals)
+	}
+	got := l.keyvals[n-4:]
+	wanted := []interface{}{"request_id", "abc", "user_id", 7}
+	for i := range wanted {
+		if got[i] != wanted[i] {
+			t.Fatalf("got %v, wanted %v", got, wanted)
+		}
+	}
+}
@@ -8,6 +8,10 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"mellium.im/sasl"
 
@@ -25,9 +29,11 @@ const (
 	parameterStatusMsg  = 'S'
 	authenticationOKMsg = 'R'
 	backendKeyDataMsg   = 'K'
-	noDataMsg           = 'n'
-	passwordMessageMsg  = 'p'
-	terminateMsg        = 'X'
+
+	negotiateProtocolVersionMsg = 'v'
+	noDataMsg                   = 'n'
+	passwordMessageMsg          = 'p'
+	terminateMsg                = 'X'
 
 	saslInitialResponseMsg        = 'p'
 	authenticationSASLContinueMsg = 'R'
@@ -70,8 +76,8 @@ const (
 	copyDoneMsg        = 'c'
 )
 
-func startup(cn *pool.Conn, user, password, database string) error {
-	writeStartupMsg(cn.Wr, user, database)
+func startup(cn *pool.Conn, user, password, database string, params map[string]string) error {
+	writeStartupMsg(cn.Wr, user, database, params)
 	if err := cn.FlushWriter(); err != nil {
 		return err
 	}
@@ -97,8 +103,12 @@ func startup(cn *pool.Conn, user, password, database string) error {
 			if err := logParameterStatus(cn, msgLen); err != nil {
 				return err
 			}
+		case negotiateProtocolVersionMsg:
+			if err := readNegotiateProtocolVersion(cn); err != nil {
+				return err
+			}
 		case authenticationOKMsg:
-			if err := authenticate(cn, user, password); err != nil {
+			if err := authenticate(cn, msgLen, user, password); err != nil {
 				return err
 			}
 		case readyForQueryMsg:
@@ -116,7 +126,9 @@ func startup(cn *pool.Conn, user, password, database string) error {
 	}
 }
 
-func enableSSL(cn *pool.Conn, tlsConf *tls.Config) error {
+// enableSSL upgrades the connection to TLS. The handshake must finish
+// within the timeout, because read and write timeouts can be disabled.
+func enableSSL(cn *pool.Conn, tlsConf *tls.Config, timeout time.Duration) error {
 	writeSSLMsg(cn.Wr)
 	if err := cn.FlushWriter(); err != nil {
 		return err
@@ -130,15 +142,35 @@ func enableSSL(cn *pool.Conn, tlsConf *tls.Config) error {
 		return errSSLNotSupported
 	}
 
-	cn.SetNetConn(tls.Client(cn.NetConn(), tlsConf))
+	tlsCn := tls.Client(cn.NetConn(), tlsConf)
+	if timeout > 0 {
+		tlsCn.SetDeadline(time.Now().Add(timeout))
+	}
+	if err := tlsCn.Handshake(); err != nil {
+		return err
+	}
+	cn.SetNetConn(tlsCn)
 	return nil
 }
 
-func authenticate(cn *pool.Conn, user, password string) error {
+func authenticate(cn *pool.Conn, msgLen int, user, password string) error {
 	num, err := readInt32(cn)
 	if err != nil {
 		return err
 	}
+
+	if fn := authHandler(num); fn != nil {
+		data, err := readAuthData(cn, msgLen-4)
+		if err != nil {
+			return err
+		}
+		return fn(&AuthConn{
+			cn:       cn,
+			user:     user,
+			password: password,
+		}, data)
+	}
+
 	switch num {
 	case authenticationOK:
 		return nil
@@ -303,13 +335,24 @@ func md5s(s string) string {
 	return hex.EncodeToString(h.Sum(nil))
 }
 
-func writeStartupMsg(buf *pool.WriteBuffer, user, database string) {
+func writeStartupMsg(buf *pool.WriteBuffer, user, database string, params map[string]string) {
 	buf.StartMessage(0)
 	buf.WriteInt32(196608)
 	buf.WriteString("user")
 	buf.WriteString(user)
 	buf.WriteString("database")
 	buf.WriteString(database)
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		buf.WriteString(name)
+		buf.WriteString(params[name])
+	}
+
 	buf.WriteString("")
 	buf.FinishMessage()
 }
@@ -339,16 +382,16 @@ func writeCancelRequestMsg(buf *pool.WriteBuffer, processId, secretKey int32) {
 	buf.FinishMessage()
 }
 
-func writeQueryMsg(buf *pool.WriteBuffer, fmter orm.QueryFormatter, query interface{}, params ...interface{}) error {
+func writeQueryMsg(buf *pool.WriteBuffer, db *DB, query interface{}, params ...interface{}) error {
 	buf.StartMessage(queryMsg)
-	bytes, err := appendQuery(buf.Bytes, fmter, query, params...)
+	buf.Bytes = append(buf.Bytes, db.comment...)
+	start := len(buf.Bytes)
+	bytes, err := appendQuery(buf.Bytes, db, query, params...)
 	if err != nil {
 		buf.Reset()
 		return err
 	}
-	if internal.QueryLogger != nil {
-		internal.LogQuery(string(bytes[5:]))
-	}
+	bytes = orm.ReplaceNow(bytes, start)
 	buf.Bytes = bytes
 	buf.WriteByte(0x0)
 	buf.FinishMessage()
@@ -361,6 +404,10 @@ func appendQuery(dst []byte, fmter orm.QueryFormatter, query interface{}, params
 		return query.AppendQuery(dst, params...)
 	case string:
 		return fmter.FormatQuery(dst, query, params...), nil
+	case orm.FormatAppender:
+		return query.AppendFormat(dst, fmter), nil
+	case idempotentQuery:
+		return appendQuery(dst, fmter, query.query, params...)
 	default:
 		return nil, fmt.Errorf("pg: can't append %T", query)
 	}
@@ -386,69 +433,104 @@ func writeParseDescribeSyncMsg(buf *pool.WriteBuffer, name, q string) {
 	writeSyncMsg(buf)
 }
 
-func readParseDescribeSync(cn *pool.Conn) ([][]byte, error) {
-	var columns [][]byte
+func readParseDescribeSync(cn *pool.Conn) ([]int32, []pool.Column, error) {
+	var paramTypes []int32
+	var columns []pool.Column
 	for {
 		c, msgLen, err := readMessageType(cn)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		switch c {
 		case parseCompleteMsg:
 			_, err := cn.ReadN(msgLen)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 		case rowDescriptionMsg: // Response to the DESCRIBE message.
 			columns, err = readRowDescription(cn, nil)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 		case parameterDescriptionMsg: // Response to the DESCRIBE message.
-			_, err := cn.ReadN(msgLen)
+			paramTypes, err = readParameterDescription(cn)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 		case noDataMsg: // Response to the DESCRIBE message.
 			_, err := cn.ReadN(msgLen)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 		case readyForQueryMsg:
 			_, err := cn.ReadN(msgLen)
-			return columns, err
+			return paramTypes, columns, err
 		case errorResponseMsg:
 			e, err := readError(cn)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
-			return nil, e
+			return nil, nil, e
 		case noticeResponseMsg:
 			if err := logNotice(cn, msgLen); err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 		case parameterStatusMsg:
 			if err := logParameterStatus(cn, msgLen); err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 		default:
-			return nil, fmt.Errorf("pg: readParseDescribeSync: unexpected message %#x", c)
+			return nil, nil, fmt.Errorf("pg: readParseDescribeSync: unexpected message %#x", c)
+		}
+	}
+}
+
+func readParameterDescription(cn *pool.Conn) ([]int32, error) {
+	num, err := readInt16(cn)
+	if err != nil {
+		return nil, err
+	}
+	paramTypes := make([]int32, num)
+	for i := range paramTypes {
+		paramTypes[i], err = readInt32(cn)
+		if err != nil {
+			return nil, err
 		}
 	}
+	return paramTypes, nil
 }
 
-// Writes BIND, EXECUTE and SYNC messages.
-func writeBindExecuteMsg(buf *pool.WriteBuffer, name string, params ...interface{}) error {
+// Writes BIND, EXECUTE and SYNC messages. When paramTypes are known,
+// the number of params is checked and params are converted to the
+// types.
+func writeBindExecuteMsg(
+	buf *pool.WriteBuffer, name string, paramTypes []int32, params ...interface{},
+) error {
 	const paramLenWidth = 4
 
+	if paramTypes != nil && len(params) != len(paramTypes) {
+		return internal.Errorf(
+			"pg: statement expects %d params, got %d", len(paramTypes), len(params))
+	}
+
 	buf.StartMessage(bindMsg)
 	buf.WriteString("")
 	buf.WriteString(name)
 	buf.WriteInt16(0)
 	buf.WriteInt16(int16(len(params)))
-	for _, param := range params {
+	for i, param := range params {
 		buf.StartParam()
-		bytes := types.Append(buf.Bytes, param, 0)
+		var bytes []byte
+		if paramTypes != nil {
+			var err error
+			bytes, err = appendParam(buf.Bytes, param, paramTypes[i])
+			if err != nil {
+				buf.Reset()
+				return internal.Errorf("pg: param $%d: %s", i+1, err)
+			}
+		} else {
+			bytes = types.Append(buf.Bytes, param, 0)
+		}
 		if bytes != nil {
 			buf.Bytes = bytes
 			buf.FinishParam()
@@ -549,6 +631,8 @@ func readSimpleQuery(cn *pool.Conn) (res *types.Result, retErr error) {
 	}
 
 	var rows int
+	var idBuf [maxIdLen]byte
+	idCol := idBuf[:0]
 	for {
 		c, msgLen, err := readMessageType(cn)
 		if err != nil {
@@ -562,6 +646,10 @@ func readSimpleQuery(cn *pool.Conn) (res *types.Result, retErr error) {
 				return nil, err
 			}
 			res = types.NewResult(b, rows)
+			if id, ok := parseLastInsertId(idCol); ok {
+				res.SetLastInsertId(id)
+			}
+			idCol = idCol[:0]
 		case readyForQueryMsg:
 			_, err := cn.ReadN(msgLen)
 			if err != nil {
@@ -574,11 +662,12 @@ func readSimpleQuery(cn *pool.Conn) (res *types.Result, retErr error) {
 				return nil, err
 			}
 		case dataRowMsg:
-			_, err := cn.ReadN(msgLen)
+			b, err := cn.ReadN(msgLen)
 			if err != nil {
 				return nil, err
 			}
 			rows++
+			idCol = appendIdColumn(idCol[:0], b)
 		case errorResponseMsg:
 			e, err := readError(cn)
 			if err != nil {
@@ -607,6 +696,8 @@ func readExtQuery(cn *pool.Conn) (res *types.Result, retErr error) {
 	}
 
 	var rows int
+	var idBuf [maxIdLen]byte
+	idCol := idBuf[:0]
 	for {
 		c, msgLen, err := readMessageType(cn)
 		if err != nil {
@@ -620,17 +711,22 @@ func readExtQuery(cn *pool.Conn) (res *types.Result, retErr error) {
 				return nil, err
 			}
 		case dataRowMsg:
-			_, err := cn.ReadN(msgLen)
+			b, err := cn.ReadN(msgLen)
 			if err != nil {
 				return nil, err
 			}
 			rows++
+			idCol = appendIdColumn(idCol[:0], b)
 		case commandCompleteMsg: // Response to the EXECUTE message.
 			b, err := cn.ReadN(msgLen)
 			if err != nil {
 				return nil, err
 			}
 			res = types.NewResult(b, rows)
+			if id, ok := parseLastInsertId(idCol); ok {
+				res.SetLastInsertId(id)
+			}
+			idCol = idCol[:0]
 		case readyForQueryMsg: // Response to the SYNC message.
 			_, err := cn.ReadN(msgLen)
 			if err != nil {
@@ -657,36 +753,98 @@ func readExtQuery(cn *pool.Conn) (res *types.Result, retErr error) {
 	}
 }
 
-func readRowDescription(cn *pool.Conn, columns [][]byte) ([][]byte, error) {
+// maxIdLen is the length of the longest int64 in text format,
+// i.e. -9223372036854775808.
+const maxIdLen = 20
+
+// appendIdColumn appends the first column of the DataRow message to
+// dst, e.g. the id returned by INSERT ... RETURNING id. The message is
+// overwritten by the next read, so the column is copied, but only when
+// it is short enough to be an integer.
+func appendIdColumn(dst, b []byte) []byte {
+	if len(b) < 6 || binary.BigEndian.Uint16(b) == 0 {
+		return dst
+	}
+	l := int32(binary.BigEndian.Uint32(b[2:]))
+	if l <= 0 || l > maxIdLen || int(l) > len(b)-6 {
+		return dst
+	}
+	return append(dst, b[6:6+l]...)
+}
+
+// parseLastInsertId parses the column appended by appendIdColumn for
+// the last row of the command.
+func parseLastInsertId(b []byte) (int64, bool) {
+	if len(b) == 0 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(internal.BytesToString(b), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+func readRowDescription(cn *pool.Conn, columns []pool.Column) ([]pool.Column, error) {
 	colNum, err := readInt16(cn)
 	if err != nil {
 		return nil, err
 	}
 
-	columns = setByteSliceLen(columns, int(colNum))
+	columns = setColumnsLen(columns, int(colNum))
 	for i := 0; i < int(colNum); i++ {
-		columns[i], err = readBytes(cn, columns[i][:0])
+		col := &columns[i]
+
+		col.Name, err = readBytes(cn, col.Name[:0])
 		if err != nil {
 			return nil, err
 		}
-		if _, err := cn.ReadN(18); err != nil {
+
+		b, err := cn.ReadN(18)
+		if err != nil {
 			return nil, err
 		}
+		col.TableOID = int32(binary.BigEndian.Uint32(b))
+		col.ColumnNum = int16(binary.BigEndian.Uint16(b[4:]))
+		col.DataType = int32(binary.BigEndian.Uint32(b[6:]))
+		col.DataTypeSize = int16(binary.BigEndian.Uint16(b[10:]))
+		col.TypeModifier = int32(binary.BigEndian.Uint32(b[12:]))
+		col.Format = int16(binary.BigEndian.Uint16(b[16:]))
 	}
 
 	return columns, nil
 }
 
-func setByteSliceLen(b [][]byte, n int) [][]byte {
-	if n <= cap(b) {
-		return b[:n]
+func resultColumns(columns []pool.Column) []types.Column {
+	if len(columns) == 0 {
+		return nil
 	}
-	b = b[:cap(b)]
-	b = append(b, make([][]byte, n-cap(b))...)
-	return b
+	cols := make([]types.Column, len(columns))
+	for i := range columns {
+		col := &columns[i]
+		cols[i] = types.Column{
+			Name:         string(col.Name),
+			TableOID:     col.TableOID,
+			ColumnNum:    col.ColumnNum,
+			DataType:     col.DataType,
+			DataTypeSize: col.DataTypeSize,
+			TypeModifier: col.TypeModifier,
+			Format:       col.Format,
+		}
+	}
+	return cols
 }
 
-func readDataRow(cn *pool.Conn, scanner orm.ColumnScanner, columns [][]byte) (retErr error) {
+func setColumnsLen(columns []pool.Column, n int) []pool.Column {
+	if n <= cap(columns) {
+		return columns[:n]
+	}
+	columns = columns[:cap(columns)]
+	columns = append(columns, make([]pool.Column, n-cap(columns))...)
+	return columns
+}
+
+func readDataRow(cn *pool.Conn, scanner orm.ColumnScanner, columns []pool.Column) (retErr error) {
 	setErr := func(err error) {
 		if retErr == nil {
 			retErr = err
@@ -706,14 +864,20 @@ func readDataRow(cn *pool.Conn, scanner orm.ColumnScanner, columns [][]byte) (re
 
 		var b []byte
 		if l != -1 { // NULL
-			b, err = cn.ReadN(int(l))
+			b, err = cn.ReadValue(int(l))
 			if err != nil {
 				return err
 			}
 		}
 
-		column := internal.BytesToString(columns[colIdx])
-		if err := scanner.ScanColumn(int(colIdx), column, b); err != nil {
+		col := &columns[colIdx]
+		column := internal.BytesToString(col.Name)
+		if s, ok := scanner.(orm.ColumnTypeScanner); ok {
+			err = s.ScanColumnType(int(colIdx), column, col.DataType, b)
+		} else {
+			err = scanner.ScanColumn(int(colIdx), column, b)
+		}
+		if err != nil {
 			setErr(err)
 		}
 
@@ -744,6 +908,8 @@ func readSimpleQueryData(
 		}
 	}
 
+	cn.Columns = cn.Columns[:0]
+
 	var rows int
 	for {
 		c, msgLen, err := readMessageType(cn)
@@ -783,6 +949,7 @@ func readSimpleQueryData(
 				return nil, nil, err
 			}
 			res = types.NewResult(b, rows)
+			res.SetColumns(resultColumns(cn.Columns))
 		case readyForQueryMsg:
 			_, err := cn.ReadN(msgLen)
 			if err != nil {
@@ -809,8 +976,100 @@ func readSimpleQueryData(
 	}
 }
 
+// readSimpleQueryMulti reads the results of the statements of the
+// simple query scanning the rows of the i-th statement into models[i].
+func readSimpleQueryMulti(
+	cn *pool.Conn, models []interface{},
+) (results []*types.Result, mods []orm.Model, retErr error) {
+	setErr := func(err error) {
+		if retErr == nil {
+			retErr = err
+		}
+	}
+
+	cn.Columns = cn.Columns[:0]
+
+	var model orm.Model
+	var rows int
+	for {
+		c, msgLen, err := readMessageType(cn)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch c {
+		case rowDescriptionMsg:
+			cn.Columns, err = readRowDescription(cn, cn.Columns[:0])
+			if err != nil {
+				return nil, nil, err
+			}
+
+			model = Discard
+			if i := len(results); i < len(models) && models[i] != nil {
+				var err error
+				model, err = newModel(models[i])
+				if err != nil {
+					setErr(err)
+					model = Discard
+				}
+			}
+		case dataRowMsg:
+			m := model.NewModel()
+			if err := readDataRow(cn, m, cn.Columns); err != nil {
+				setErr(err)
+			} else {
+				if err := model.AddModel(m); err != nil {
+					setErr(err)
+				}
+			}
+
+			rows++
+		case commandCompleteMsg:
+			b, err := cn.ReadN(msgLen)
+			if err != nil {
+				return nil, nil, err
+			}
+			res := types.NewResult(b, rows)
+			if model != nil {
+				res.SetColumns(resultColumns(cn.Columns))
+			}
+			results = append(results, res)
+			mods = append(mods, model)
+			model = nil
+			rows = 0
+		case emptyQueryResponseMsg:
+			_, err := cn.ReadN(msgLen)
+			if err != nil {
+				return nil, nil, err
+			}
+		case readyForQueryMsg:
+			_, err := cn.ReadN(msgLen)
+			if err != nil {
+				return nil, nil, err
+			}
+			return results, mods, retErr
+		case errorResponseMsg:
+			e, err := readError(cn)
+			if err != nil {
+				return nil, nil, err
+			}
+			setErr(e)
+		case noticeResponseMsg:
+			if err := logNotice(cn, msgLen); err != nil {
+				return nil, nil, err
+			}
+		case parameterStatusMsg:
+			if err := logParameterStatus(cn, msgLen); err != nil {
+				return nil, nil, err
+			}
+		default:
+			return nil, nil, fmt.Errorf("pg: readSimpleQueryMulti: unexpected message %#x", c)
+		}
+	}
+}
+
 func readExtQueryData(
-	cn *pool.Conn, mod interface{}, columns [][]byte,
+	cn *pool.Conn, mod interface{}, columns []pool.Column,
 ) (res *types.Result, model orm.Model, retErr error) {
 	setErr := func(err error) {
 		if retErr == nil {
@@ -857,6 +1116,7 @@ func readExtQueryData(
 				return nil, nil, err
 			}
 			res = types.NewResult(b, rows)
+			res.SetColumns(resultColumns(columns))
 		case readyForQueryMsg: // Response to the SYNC message.
 			_, err := cn.ReadN(msgLen)
 			if err != nil {
@@ -945,7 +1205,7 @@ func readCopyOutResponse(cn *pool.Conn) error {
 	}
 }
 
-func readCopyData(cn *pool.Conn, w io.Writer) (*types.Result, error) {
+func readCopyData(cn *pool.Conn, w io.Writer, state *copyState) (*types.Result, error) {
 	var res *types.Result
 	for {
 		c, msgLen, err := readMessageType(cn)
@@ -960,10 +1220,15 @@ func readCopyData(cn *pool.Conn, w io.Writer) (*types.Result, error) {
 				return nil, err
 			}
 
+			if err := state.wait(len(b)); err != nil {
+				return nil, err
+			}
+
 			_, err = w.Write(b)
 			if err != nil {
 				return nil, err
 			}
+			state.copied(len(b), 1)
 		case copyDoneMsg:
 			_, err := cn.ReadN(msgLen)
 			if err != nil {
@@ -1050,59 +1315,83 @@ func readReadyForQuery(cn *pool.Conn) (res *types.Result, retErr error) {
 	}
 }
 
-func readNotification(cn *pool.Conn) (channel, payload string, err error) {
+// readNotification reads the next notification. It returns nil
+// notification when the server responds to the empty query sent by
+// Listener.ping.
+func readNotification(cn *pool.Conn) (*Notification, error) {
 	for {
 		c, msgLen, err := readMessageType(cn)
 		if err != nil {
-			return "", "", err
+			return nil, err
 		}
 
 		switch c {
 		case commandCompleteMsg:
 			_, err := cn.ReadN(msgLen)
 			if err != nil {
-				return "", "", err
+				return nil, err
 			}
 		case readyForQueryMsg:
 			_, err := cn.ReadN(msgLen)
 			if err != nil {
-				return "", "", err
+				return nil, err
 			}
 		case errorResponseMsg:
 			e, err := readError(cn)
 			if err != nil {
-				return "", "", err
+				return nil, err
 			}
-			return "", "", e
+			return nil, e
 		case noticeResponseMsg:
 			if err := logNotice(cn, msgLen); err != nil {
-				return "", "", err
+				return nil, err
+			}
+		case emptyQueryResponseMsg:
+			_, err := cn.ReadN(msgLen)
+			if err != nil {
+				return nil, err
 			}
+			return nil, nil
 		case notificationResponseMsg:
-			_, err := readInt32(cn)
+			pid, err := readInt32(cn)
 			if err != nil {
-				return "", "", err
+				return nil, err
 			}
-			channel, err = readString(cn)
+			channel, err := readString(cn)
 			if err != nil {
-				return "", "", err
+				return nil, err
 			}
-			payload, err = readString(cn)
+			payload, err := readString(cn)
 			if err != nil {
-				return "", "", err
+				return nil, err
 			}
-			return channel, payload, nil
+			return &Notification{
+				Channel: channel,
+				Payload: payload,
+				PID:     pid,
+			}, nil
 		default:
-			return "", "", fmt.Errorf("pg: unexpected message %q", c)
+			return nil, fmt.Errorf("pg: unexpected message %q", c)
 		}
 	}
 }
 
 var terminateMessage = []byte{terminateMsg, 0, 0, 0, 4}
 
+// pingConn checks that the connection is alive using a Sync message,
+// which is answered with ReadyForQuery.
+func pingConn(cn *pool.Conn) error {
+	writeSyncMsg(cn.Wr)
+	if err := cn.FlushWriter(); err != nil {
+		return err
+	}
+	_, err := readReadyForQuery(cn)
+	return err
+}
+
 func terminateConn(cn *pool.Conn) error {
 	// Don't use cn.Buf because it is racy with user code.
-	_, err := cn.NetConn().Write(terminateMessage)
+	_, err := cn.Write(terminateMessage)
 	return err
 }
 
@@ -1190,6 +1479,36 @@ func logParameterStatus(cn *pool.Conn, msgLen int) error {
 	return err
 }
 
+// readNegotiateProtocolVersion reads the message that is sent by servers
+// that don't support the requested protocol minor version or some of
+// the requested protocol extensions. The connection proceeds using
+// the protocol version and extensions supported by the server.
+func readNegotiateProtocolVersion(cn *pool.Conn) error {
+	minorVersion, err := readInt32(cn)
+	if err != nil {
+		return err
+	}
+
+	num, err := readInt32(cn)
+	if err != nil {
+		return err
+	}
+
+	unsupported := make([]string, num)
+	for i := range unsupported {
+		unsupported[i], err = readString(cn)
+		if err != nil {
+			return err
+		}
+	}
+
+	internal.Logf(
+		"server supports protocol minor version %d and does not support options: %s",
+		minorVersion, strings.Join(unsupported, ", "),
+	)
+	return nil
+}
+
 func readAuthSASLFinal(cn *pool.Conn, client *sasl.Negotiator) error {
 	c, n, err := readMessageType(cn)
 	if err != nil {
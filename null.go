@@ -0,0 +1,107 @@
+package pg
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+)
+
+// NullBool is a sql.NullBool wrapper that marshals invalid value as
+// JSON null. Invalid value is appended as PostgreSQL NULL.
+type NullBool struct {
+	sql.NullBool
+}
+
+var _ json.Marshaler = (*NullBool)(nil)
+var _ json.Unmarshaler = (*NullBool)(nil)
+
+func (n NullBool) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return jsonNull, nil
+	}
+	return json.Marshal(n.Bool)
+}
+
+func (n *NullBool) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, jsonNull) {
+		n.Bool, n.Valid = false, false
+		return nil
+	}
+	n.Valid = true
+	return json.Unmarshal(b, &n.Bool)
+}
+
+// NullFloat64 is a sql.NullFloat64 wrapper that marshals invalid value as
+// JSON null. Invalid value is appended as PostgreSQL NULL.
+type NullFloat64 struct {
+	sql.NullFloat64
+}
+
+var _ json.Marshaler = (*NullFloat64)(nil)
+var _ json.Unmarshaler = (*NullFloat64)(nil)
+
+func (n NullFloat64) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return jsonNull, nil
+	}
+	return json.Marshal(n.Float64)
+}
+
+func (n *NullFloat64) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, jsonNull) {
+		n.Float64, n.Valid = 0, false
+		return nil
+	}
+	n.Valid = true
+	return json.Unmarshal(b, &n.Float64)
+}
+
+// NullInt64 is a sql.NullInt64 wrapper that marshals invalid value as
+// JSON null. Invalid value is appended as PostgreSQL NULL.
+type NullInt64 struct {
+	sql.NullInt64
+}
+
+var _ json.Marshaler = (*NullInt64)(nil)
+var _ json.Unmarshaler = (*NullInt64)(nil)
+
+func (n NullInt64) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return jsonNull, nil
+	}
+	return json.Marshal(n.Int64)
+}
+
+func (n *NullInt64) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, jsonNull) {
+		n.Int64, n.Valid = 0, false
+		return nil
+	}
+	n.Valid = true
+	return json.Unmarshal(b, &n.Int64)
+}
+
+// NullString is a sql.NullString wrapper that marshals invalid value as
+// JSON null. Invalid value is appended as PostgreSQL NULL.
+type NullString struct {
+	sql.NullString
+}
+
+var _ json.Marshaler = (*NullString)(nil)
+var _ json.Unmarshaler = (*NullString)(nil)
+
+func (n NullString) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return jsonNull, nil
+	}
+	return json.Marshal(n.String)
+}
+
+func (n *NullString) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, jsonNull) {
+		n.String, n.Valid = "", false
+		return nil
+	}
+	n.Valid = true
+	return json.Unmarshal(b, &n.String)
+}
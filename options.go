@@ -1,11 +1,14 @@
 package pg
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
-	"fmt"
+	"io"
 	"net"
 	"net/url"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,25 +18,77 @@ import (
 // Database connection options.
 type Options struct {
 	// Network type, either tcp or unix.
-	// Default is tcp.
+	// Default is unix when Addr is an absolute path and tcp otherwise.
 	Network string
-	// TCP host:port or Unix socket depending on Network.
+	// TCP host:port or Unix socket depending on Network. Unix socket
+	// can be specified using the directory that contains it, e.g.
+	// /var/run/postgresql or /tmp:5433, like in libpq. Unix sockets
+	// allow peer authentication and have lower latency.
 	Addr string
 
 	// Dialer creates new network connection and has priority over
 	// Network and Addr options.
 	Dialer func(network, addr string) (net.Conn, error)
+	// DialContext is like Dialer, but the context is cancelled when
+	// DialTimeout expires. It can be used to connect through SSH
+	// tunnels or SOCKS proxies or to resolve addresses with custom
+	// DNS logic. It has priority over Dialer.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
 
 	User     string
 	Password string
 	Database string
 
-	// TLS config for secure connections.
+	// PasswordFn returns the password and has priority over Password.
+	// It is called every time a new connection is established, so
+	// short-lived credentials like AWS RDS IAM tokens, Cloud SQL IAM
+	// tokens or Vault dynamic secrets can rotate without recreating
+	// the DB. The context is cancelled when DialTimeout expires.
+	PasswordFn func(ctx context.Context) (string, error)
+
+	// TLS config for secure connections. When SSLMode is empty, SSL
+	// is required.
 	TLSConfig *tls.Config
+	// SSLMode has the same meaning as libpq sslmode, e.g. pg.SSLPrefer.
+	// TLS config is created from the mode when TLSConfig is nil.
+	// Default is to use TLSConfig.
+	SSLMode string
+	// File with PEM encoded root certificates that are used to verify
+	// the server certificate in verify-ca and verify-full modes.
+	// Default is to use system roots.
+	SSLRootCert string
+	// Files with PEM encoded client certificate and key that are used
+	// for certificate authentication.
+	SSLCert string
+	SSLKey  string
+	// Password that decrypts SSLKey when the key is encrypted.
+	SSLPassword string
+
+	tlsErr error
+
+	// TimeZone is sent on startup and sets the session time zone,
+	// e.g. "UTC" or "Europe/Berlin". It changes the offset of
+	// timestamptz values sent by the server; use pg.SetTimeLocation
+	// to control the location of scanned time values.
+	// Default is the server time zone.
+	TimeZone string
 
-	// Maximum number of retries before giving up.
+	// Run-time parameters sent in the startup message, e.g.
+	// application_name or replication=database.
+	RuntimeParams map[string]string
+
+	// Protocol extensions that are requested on startup using
+	// _pq_.<name> parameters. Extensions that are not supported by
+	// the server are ignored.
+	ProtocolExtensions map[string]string
+
+	// Maximum number of retries before giving up. It is ignored when
+	// RetryPolicy is set.
 	// Default is to not retry failed queries.
 	MaxRetries int
+	// RetryPolicy configures backoff and which failures are retried.
+	// Default is the policy with MaxRetries.
+	RetryPolicy *RetryPolicy
 	// Whether to retry queries cancelled because of statement_timeout.
 	RetryStatementTimeout bool
 
@@ -46,10 +101,50 @@ type Options struct {
 	// Timeout for socket writes. If reached, commands will fail
 	// with a timeout instead of blocking.
 	WriteTimeout time.Duration
+	// Server-side statement_timeout that is sent on startup. Statements
+	// that run longer are canceled by the server and fail with an
+	// error, but unlike ReadTimeout the connection remains usable.
+	// Query.Timeout can only make the timeout shorter.
+	// Default is the server setting.
+	StatementTimeout time.Duration
+
+	// Queries that take longer than the threshold are logged at warn
+	// level with the full query, params, duration and server process
+	// id, or passed to OnSlowQuery when it is set.
+	// Default is to not report slow queries.
+	SlowQueryThreshold time.Duration
+	// OnSlowQuery is called with queries that exceed SlowQueryThreshold.
+	// Redaction set by SetLogRedaction is not applied.
+	OnSlowQuery func(*SlowQuery)
+
+	// TraceWire receives every protocol message sent and received by
+	// the client with its type, length and a decoded summary, similar
+	// to libpq PQtrace. Passwords are not written.
+	// Default is to not trace messages.
+	TraceWire io.Writer
+
+	// ZeroCopyScan passes column values to ScanColumn as slices of the
+	// read buffer of the connection instead of copying them first. The
+	// value is valid only during ScanColumn, so scanners must copy it
+	// to retain it, which all scanners of the package do. Values larger
+	// than ReadBufferSize are still copied.
+	// Default is to copy values.
+	ZeroCopyScan bool
 
 	// Maximum number of socket connections.
 	// Default is 20 connections.
 	PoolSize int
+	// Whether free connections are taken from the pool in FIFO order,
+	// which spreads load across connections and keeps them from going
+	// stale behind load balancers. LIFO order keeps a smaller working
+	// set of connections hot, which is better for latency.
+	// Default is LIFO.
+	PoolFIFO bool
+	// Minimum number of idle connections that are dialed and
+	// initialized in the background, which avoids connection storms
+	// and latency of new connections after idle periods.
+	// Default is to not maintain idle connections.
+	MinIdleConns int
 	// Time for which client waits for free connection if all
 	// connections are busy before returning an error.
 	// Default is 5 seconds.
@@ -57,13 +152,58 @@ type Options struct {
 	// Time after which client closes idle connections.
 	// Default is to not close idle connections.
 	IdleTimeout time.Duration
-	// Connection age at which client retires (closes) the connection.
-	// Primarily useful with proxies like HAProxy.
+	// Connection age at which client retires (closes) the connection
+	// regardless of idleness, so connections are rebalanced across
+	// backends behind proxies like pgbouncer or HAProxy and session
+	// state is eventually cleared. Connections in use are retired when
+	// they are returned to the pool and requested again.
 	// Default is to not close aged connections.
+	MaxConnAge time.Duration
+	// Deprecated: use MaxConnAge.
 	MaxAge time.Duration
 	// Frequency of idle checks.
 	// Default is 1 minute.
 	IdleCheckFrequency time.Duration
+	// Connections that were idle longer than the threshold are checked
+	// with a Sync round-trip before they are used, so the first query
+	// after a network failure doesn't fail with a broken connection.
+	// Default is to not check idle connections.
+	IdleCheckThreshold time.Duration
+
+	// Size of the buffer for reading from connections and the largest
+	// buffer for values kept by connections between queries. Larger
+	// buffers are returned to a buffer pool shared by connections.
+	// Default is 4096 bytes.
+	ReadBufferSize int
+	// Size of the buffer for writing messages kept by connections
+	// between queries. Larger buffers are returned to the buffer pool.
+	// Default is 4096 bytes.
+	WriteBufferSize int
+
+	// Listener sends a ping to the server when it has not received
+	// anything for the interval while waiting for notifications. When
+	// the ping is not answered within another interval, the connection
+	// is considered dead, Receive returns an error and the next call
+	// reconnects, so dead NAT mappings and half-open connections are
+	// detected within two intervals.
+	// Default is to not ping.
+	ListenerPingInterval time.Duration
+
+	// OnConnect is called once for every new connection before it is
+	// used, e.g. to set role, search_path or statement_timeout. It is
+	// called again after the session state is reset with
+	// ResetSessionQuery or Conn.Release. The Conn must not be used
+	// after OnConnect returns.
+	OnConnect func(*Conn) error
+
+	// Query that resets session state, e.g. "DISCARD ALL" or
+	// "RESET ALL; DEALLOCATE ALL; UNLISTEN *". It runs before
+	// connections used by transactions, WithSession and Conn are
+	// returned to the pool, so SET commands and temporary tables don't
+	// leak to other users of the connection. Connections are closed
+	// when the state can't be reset.
+	// Default is to not reset session state.
+	ResetSessionQuery string
 
 	// When true Tx does not issue BEGIN, COMMIT, or ROLLBACK.
 	// Also underlying database connection is immediately returned to the pool.
@@ -74,7 +214,11 @@ type Options struct {
 
 func (opt *Options) init() {
 	if opt.Network == "" {
-		opt.Network = "tcp"
+		if strings.HasPrefix(opt.Addr, "/") {
+			opt.Network = "unix"
+		} else {
+			opt.Network = "tcp"
+		}
 	}
 
 	if opt.Addr == "" {
@@ -86,6 +230,17 @@ func (opt *Options) init() {
 		}
 	}
 
+	if opt.Network == "unix" {
+		opt.Addr = unixSocketPath(opt.Addr)
+		// PostgreSQL does not support SSL over Unix sockets.
+		opt.TLSConfig = nil
+		opt.SSLMode = SSLDisable
+	}
+
+	if opt.TLSConfig == nil && opt.SSLMode != "" {
+		opt.TLSConfig, opt.tlsErr = newTLSConfig(opt)
+	}
+
 	if opt.PoolSize == 0 {
 		opt.PoolSize = 20
 	}
@@ -102,9 +257,41 @@ func (opt *Options) init() {
 		opt.DialTimeout = 5 * time.Second
 	}
 
+	if opt.ReadBufferSize == 0 {
+		opt.ReadBufferSize = pool.DefaultReadBufferSize
+	}
+	if opt.WriteBufferSize == 0 {
+		opt.WriteBufferSize = pool.DefaultWriteBufferSize
+	}
+
 	if opt.IdleCheckFrequency == 0 {
 		opt.IdleCheckFrequency = time.Minute
 	}
+
+	if opt.MaxConnAge == 0 {
+		opt.MaxConnAge = opt.MaxAge
+	}
+
+	if opt.RetryPolicy == nil {
+		opt.RetryPolicy = &RetryPolicy{MaxRetries: opt.MaxRetries}
+	}
+}
+
+// unixSocketPath returns the path of the socket in the directory using
+// PostgreSQL naming convention, e.g. /tmp:5433 is /tmp/.s.PGSQL.5433.
+// Paths to socket files are returned as is.
+func unixSocketPath(addr string) string {
+	if strings.HasPrefix(path.Base(addr), ".s.PGSQL.") {
+		return addr
+	}
+
+	dir, port := addr, "5432"
+	if ind := strings.LastIndexByte(addr, ':'); ind != -1 {
+		if _, err := strconv.Atoi(addr[ind+1:]); err == nil {
+			dir, port = addr[:ind], addr[ind+1:]
+		}
+	}
+	return path.Join(dir, ".s.PGSQL."+port)
 }
 
 // ParseURL parses an URL into options that can be used to connect to PostgreSQL.
@@ -153,30 +340,59 @@ func ParseURL(sURL string) (*Options, error) {
 		return nil, err
 	}
 
+	options.SSLMode = SSLPrefer
 	if sslMode, ok := query["sslmode"]; ok && len(sslMode) > 0 {
-		switch sslMode[0] {
-		case "allow":
-			fallthrough
-		case "prefer":
-			options.TLSConfig = &tls.Config{InsecureSkipVerify: true}
-		case "disable":
-			options.TLSConfig = nil
-		default:
-			return nil, errors.New(fmt.Sprintf("pg: sslmode '%v' is not supported", sslMode[0]))
-		}
-	} else {
-		options.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+		options.SSLMode = sslMode[0]
+	}
+	options.SSLRootCert = query.Get("sslrootcert")
+	options.SSLCert = query.Get("sslcert")
+	options.SSLKey = query.Get("sslkey")
+	options.SSLPassword = query.Get("sslpassword")
+	options.TLSConfig, err = newTLSConfig(options)
+	if err != nil {
+		return nil, err
 	}
 
-	delete(query, "sslmode")
+	for _, name := range []string{"sslmode", "sslrootcert", "sslcert", "sslkey", "sslpassword"} {
+		delete(query, name)
+	}
 	if len(query) > 0 {
-		return nil, errors.New("pg: options other than 'sslmode' are not supported")
+		return nil, errors.New("pg: options other than 'sslmode', 'sslrootcert', 'sslcert', 'sslkey' and 'sslpassword' are not supported")
 	}
 
 	return options, nil
 }
 
+// startupParams returns run-time parameters sent in the startup message.
+func (opt *Options) startupParams() map[string]string {
+	params := make(map[string]string, len(opt.RuntimeParams)+len(opt.ProtocolExtensions)+1)
+	for name, value := range opt.RuntimeParams {
+		params[name] = value
+	}
+	if opt.TimeZone != "" {
+		params["TimeZone"] = opt.TimeZone
+	}
+	if opt.StatementTimeout > 0 {
+		ms := int64(opt.StatementTimeout / time.Millisecond)
+		if ms == 0 {
+			ms = 1
+		}
+		params["statement_timeout"] = strconv.FormatInt(ms, 10)
+	}
+	for name, value := range opt.ProtocolExtensions {
+		params["_pq_."+name] = value
+	}
+	return params
+}
+
 func (opt *Options) getDialer() func() (net.Conn, error) {
+	if opt.DialContext != nil {
+		return func() (net.Conn, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), opt.DialTimeout)
+			defer cancel()
+			return opt.DialContext(ctx, opt.Network, opt.Addr)
+		}
+	}
 	if opt.Dialer != nil {
 		return func() (net.Conn, error) {
 			return opt.Dialer(opt.Network, opt.Addr)
@@ -187,13 +403,19 @@ func (opt *Options) getDialer() func() (net.Conn, error) {
 	}
 }
 
-func newConnPool(opt *Options) *pool.ConnPool {
+func newConnPool(opt *Options, onConnect func(*pool.Conn) error) *pool.ConnPool {
 	return pool.NewConnPool(&pool.Options{
 		Dial:               opt.getDialer(),
+		OnConnect:          onConnect,
 		PoolSize:           opt.PoolSize,
+		PoolFIFO:           opt.PoolFIFO,
+		MinIdleConns:       opt.MinIdleConns,
 		PoolTimeout:        opt.PoolTimeout,
 		IdleTimeout:        opt.IdleTimeout,
+		MaxAge:             opt.MaxConnAge,
 		IdleCheckFrequency: opt.IdleCheckFrequency,
+		ReadBufferSize:     opt.ReadBufferSize,
+		WriteBufferSize:    opt.WriteBufferSize,
 		OnClose: func(cn *pool.Conn) error {
 			return terminateConn(cn)
 		},
@@ -0,0 +1,48 @@
+package orm
+
+import "gopkg.in/pg.v5/types"
+
+// arenaModel is implemented by models that can allocate scanned values
+// in the arena.
+type arenaModel interface {
+	setArena(*types.Arena)
+}
+
+func setArena(model Model, arena *types.Arena) {
+	if m, ok := model.(arenaModel); ok {
+		m.setArena(arena)
+	}
+}
+
+// WithArena wraps the values, so the model created by NewModel for
+// them allocates scanned string and []byte values in the arena. Struct,
+// slice of structs and []string models support the arena and other
+// models allocate values as usual.
+func WithArena(arena *types.Arena, values ...interface{}) interface{} {
+	return arenaValues{
+		arena:  arena,
+		values: values,
+	}
+}
+
+type arenaValues struct {
+	arena  *types.Arena
+	values []interface{}
+}
+
+func newArenaModel(v arenaValues) (Model, error) {
+	model, err := NewModel(v.values...)
+	if err != nil {
+		return nil, err
+	}
+	setArena(model, v.arena)
+	return model, nil
+}
+
+func (m *structTableModel) setArena(arena *types.Arena) {
+	m.arena = arena
+}
+
+func (m *stringSliceModel) setArena(arena *types.Arena) {
+	m.arena = arena
+}
@@ -0,0 +1,104 @@
+package orm_test
+
+import (
+	"unsafe"
+
+	"gopkg.in/pg.v5/orm"
+	"gopkg.in/pg.v5/pgmock"
+	"gopkg.in/pg.v5/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithArena", func() {
+	var arena types.Arena
+
+	AfterEach(func() {
+		arena.Release()
+	})
+
+	It("scans strings into the arena", func() {
+		var strs []string
+		m, err := orm.NewModel(orm.WithArena(&arena, &strs))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(m.NewModel().ScanColumn(0, "", []byte("foo"))).NotTo(HaveOccurred())
+		Expect(strs).To(Equal([]string{"foo"}))
+	})
+
+	It("scans struct fields into the arena", func() {
+		type ArenaTest struct {
+			Id   int
+			Name string
+			Data []byte
+		}
+
+		var rows []ArenaTest
+		m, err := orm.NewModel(orm.WithArena(&arena, &rows))
+		Expect(err).NotTo(HaveOccurred())
+
+		row := m.NewModel()
+		Expect(row.ScanColumn(0, "id", []byte("1"))).NotTo(HaveOccurred())
+		Expect(row.ScanColumn(1, "name", []byte("foo"))).NotTo(HaveOccurred())
+		Expect(row.ScanColumn(2, "data", []byte(`\xdead`))).NotTo(HaveOccurred())
+		Expect(m.AddModel(row)).NotTo(HaveOccurred())
+
+		Expect(rows).To(Equal([]ArenaTest{{Id: 1, Name: "foo", Data: []byte{0xde, 0xad}}}))
+	})
+})
+
+// allocatedLast reports whether s is the last value allocated in
+// the arena.
+func allocatedLast(arena *types.Arena, s string) bool {
+	next := arena.Alloc(1)
+	end := uintptr(unsafe.Pointer(unsafe.StringData(s))) + uintptr(len(s))
+	return end == uintptr(unsafe.Pointer(&next[0]))
+}
+
+var _ = Describe("Query.Arena", func() {
+	type ArenaUser struct {
+		Id   int
+		Name string
+	}
+
+	var arena types.Arena
+	var db *pgmock.DB
+
+	BeforeEach(func() {
+		db = pgmock.New()
+	})
+
+	AfterEach(func() {
+		arena.Release()
+	})
+
+	It("is used only by Select", func() {
+		db.ExpectQuery(pgmock.Regexp(`^SELECT`)).
+			WillReturnRows(pgmock.NewRows("id", "name").AddRow(1, "foo"))
+		db.ExpectQuery(pgmock.Regexp(`^UPDATE .* RETURNING name$`)).
+			WillReturnRows(pgmock.NewRows("name").AddRow("bar"))
+
+		user := ArenaUser{Id: 1}
+		q := db.Model(&user).Arena(&arena)
+		Expect(q.Select()).NotTo(HaveOccurred())
+		Expect(user.Name).To(Equal("foo"))
+		Expect(allocatedLast(&arena, user.Name)).To(BeTrue())
+
+		_, err := q.Returning("name").Update()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Name).To(Equal("bar"))
+		Expect(allocatedLast(&arena, user.Name)).To(BeFalse())
+	})
+
+	It("is not copied by Copy", func() {
+		db.ExpectQuery(pgmock.Regexp(`^SELECT`)).
+			WillReturnRows(pgmock.NewRows("id", "name").AddRow(1, "foo"))
+
+		user := ArenaUser{Id: 1}
+		q := db.Model(&user).Arena(&arena).Copy()
+		Expect(q.Select()).NotTo(HaveOccurred())
+		Expect(user.Name).To(Equal("foo"))
+		Expect(allocatedLast(&arena, user.Name)).To(BeFalse())
+	})
+})
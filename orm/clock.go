@@ -0,0 +1,261 @@
+package orm
+
+import (
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/pg.v5/internal"
+	"gopkg.in/pg.v5/types"
+)
+
+// Clock provides the current time to the ORM.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// clock is a *Clock or nil when the system clock is used.
+var clock atomic.Value
+
+func getClock() Clock {
+	if c, ok := clock.Load().(*Clock); ok && c != nil {
+		return *c
+	}
+	return nil
+}
+
+// SetClock sets the clock that is used to populate created_at and
+// updated_at columns. With a custom clock now() in queries is replaced
+// with the clock time so time dependent queries are deterministic in
+// tests. Passing nil restores the system clock.
+func SetClock(c Clock) {
+	if c == nil {
+		clock.Store((*Clock)(nil))
+		return
+	}
+	clock.Store(&c)
+}
+
+// Now returns the current time using the clock set by SetClock.
+func Now() time.Time {
+	if c := getClock(); c != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// ReplaceNow replaces now() calls in the query that starts at b[start:]
+// with the time returned by the clock set by SetClock. It does nothing
+// when the system clock is used or the query is not SELECT, INSERT,
+// UPDATE, DELETE, WITH or VALUES, so column defaults in DDL are not
+// changed. String literals, quoted identifiers, dollar-quoted strings
+// and comments are skipped.
+func ReplaceNow(b []byte, start int) []byte {
+	c := getClock()
+	if c == nil || !isDML(b[start:]) {
+		return b
+	}
+
+	const now = "now()"
+	var tm []byte
+	for i := start; i < len(b); i++ {
+		s := internal.BytesToString(b)
+		switch ch := b[i]; {
+		case ch == '\'' && isEscapeString(b, start, i):
+			i = skipEscapeString(s, i)
+			continue
+		case ch == '\'' || ch == '"':
+			i = skipQuoted(s, i, ch)
+			continue
+		case ch == '-' && i+1 < len(b) && b[i+1] == '-':
+			i = skipUntil(s, i+2, "\n")
+			continue
+		case ch == '/' && i+1 < len(b) && b[i+1] == '*':
+			i = skipUntil(s, i+2, "*/")
+			continue
+		case ch == '$' && (i == start || !isIdentChar(b[i-1])):
+			if tag := dollarTag(b[i:]); tag != "" {
+				i = skipUntil(s, i+len(tag), tag)
+				continue
+			}
+		}
+		if !hasPrefixFold(b[i:], now) {
+			continue
+		}
+		if i > start && isIdentChar(b[i-1]) {
+			continue
+		}
+
+		if tm == nil {
+			tm = types.AppendTime(nil, c.Now(), 1)
+			tm = append(tm, "::timestamptz"...)
+		}
+		b = append(b[:i], append(tm, b[i+len(now):]...)...)
+		i += len(tm) - 1
+	}
+	return b
+}
+
+var dmlKeywords = []string{"select", "insert", "update", "delete", "with", "values"}
+
+func isDML(b []byte) bool {
+	for len(b) > 0 && (b[0] == ' ' || b[0] == '\t' || b[0] == '\n' || b[0] == '(') {
+		b = b[1:]
+	}
+	for _, kw := range dmlKeywords {
+		if hasPrefixFold(b, kw) && (len(b) == len(kw) || !isIdentChar(b[len(kw)])) {
+			return true
+		}
+	}
+	return false
+}
+
+// isEscapeString reports whether the string literal at b[i] has
+// the E prefix, e.g. E'\n'.
+func isEscapeString(b []byte, start, i int) bool {
+	if i == start || (b[i-1] != 'E' && b[i-1] != 'e') {
+		return false
+	}
+	return i-1 == start || !isIdentChar(b[i-2])
+}
+
+// skipEscapeString returns the index of the quote that ends the string
+// literal with the E prefix started at s[i].
+func skipEscapeString(s string, i int) int {
+	for i++; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '\'':
+			if i+1 < len(s) && s[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return len(s)
+}
+
+// dollarTag returns the tag of the dollar-quoted string that starts
+// b, e.g. $$ or $body$, or an empty string.
+func dollarTag(b []byte) string {
+	for i := 1; i < len(b); i++ {
+		c := b[i]
+		if c == '$' {
+			return string(b[:i+1])
+		}
+		if c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' ||
+			i > 1 && '0' <= c && c <= '9' {
+			continue
+		}
+		return ""
+	}
+	return ""
+}
+
+func hasPrefixFold(b []byte, prefix string) bool {
+	if len(b) < len(prefix) {
+		return false
+	}
+	for i := 0; i < len(prefix); i++ {
+		c := b[i]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if c != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '.' || c == '"' ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// setTimestamps populates empty created_at and updated_at fields on
+// insert and updated_at field on update. Fields with DefaultNowFlag are
+// populated by the server.
+func setTimestamps(table *Table, v reflect.Value, insert bool) {
+	created := clientTimestamp(table.createdAt)
+	updated := clientTimestamp(table.updatedAt)
+	if created == nil && updated == nil {
+		return
+	}
+
+	tm := Now()
+	walkStructs(v, func(strct reflect.Value) {
+		if insert && created != nil {
+			setTimeField(created.Value(strct), tm, true)
+		}
+		if updated != nil {
+			setTimeField(updated.Value(strct), tm, insert)
+		}
+	})
+}
+
+func clientTimestamp(f *Field) *Field {
+	if f == nil || f.Has(DefaultNowFlag) {
+		return nil
+	}
+	return f
+}
+
+func walkStructs(v reflect.Value, fn func(reflect.Value)) {
+	if v.Kind() == reflect.Struct {
+		fn(v)
+		return
+	}
+	for i := 0; i < v.Len(); i++ {
+		el := v.Index(i)
+		if el.Kind() == reflect.Interface {
+			el = el.Elem()
+		}
+		fn(reflect.Indirect(el))
+	}
+}
+
+// setTimeField sets time.Time, *time.Time or a struct that embeds
+// time.Time, e.g. pg.NullTime. When onlyEmpty is set, non-zero time
+// is not changed.
+func setTimeField(v reflect.Value, tm time.Time, onlyEmpty bool) {
+	if unwrapType(indirectType(v.Type())) != timeType || !v.CanSet() {
+		return
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if onlyEmpty && !v.IsNil() && !isZeroTime(v.Elem()) {
+			return
+		}
+		// Don't change the time the pointer points to.
+		ptr := reflect.New(v.Type().Elem())
+		timeValue(ptr.Elem()).Set(reflect.ValueOf(tm))
+		v.Set(ptr)
+		return
+	}
+
+	if onlyEmpty && !isZeroTime(v) {
+		return
+	}
+	timeValue(v).Set(reflect.ValueOf(tm))
+}
+
+// timeValue returns time.Time value embedded by wrappers like pg.NullTime.
+func timeValue(v reflect.Value) reflect.Value {
+	for v.Type() != timeType {
+		v = v.Field(0)
+	}
+	return v
+}
+
+func isZeroTime(v reflect.Value) bool {
+	return timeValue(v).Interface().(time.Time).IsZero()
+}
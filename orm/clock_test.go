@@ -0,0 +1,154 @@
+package orm
+
+import (
+	"reflect"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+type ClockTest struct {
+	Id        int
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+}
+
+var _ = Describe("Clock", func() {
+	tm := time.Date(2001, time.February, 3, 4, 5, 6, 0, time.UTC)
+
+	BeforeEach(func() {
+		SetClock(fixedClock(tm))
+	})
+
+	AfterEach(func() {
+		SetClock(nil)
+	})
+
+	It("replaces now() outside of string literals", func() {
+		b := []byte(`prefix SELECT now(), NOW(), 'now()', tomorrow_now(), "t".now()`)
+		b = ReplaceNow(b, len("prefix "))
+		Expect(string(b)).To(Equal(`prefix SELECT '2001-02-03 04:05:06+00:00:00'::timestamptz, '2001-02-03 04:05:06+00:00:00'::timestamptz, 'now()', tomorrow_now(), "t".now()`))
+	})
+
+	It("skips comments, quoted identifiers and dollar-quoted strings", func() {
+		b := []byte(`SELECT now() -- now()
+/* now() */ , E'it\'s now()', "now()", $$now()$$, $f$now()$f$, $1`)
+		b = ReplaceNow(b, 0)
+		Expect(string(b)).To(Equal(`SELECT '2001-02-03 04:05:06+00:00:00'::timestamptz -- now()
+/* now() */ , E'it\'s now()', "now()", $$now()$$, $f$now()$f$, $1`))
+	})
+
+	It("does not replace now() in DDL", func() {
+		b := []byte(`CREATE TABLE clock_tests (created_at timestamptz DEFAULT now())`)
+		Expect(string(ReplaceNow(b, 0))).To(Equal(string(b)))
+	})
+
+	It("does not replace now() with system clock", func() {
+		SetClock(nil)
+		b := ReplaceNow([]byte(`SELECT now()`), 0)
+		Expect(string(b)).To(Equal(`SELECT now()`))
+	})
+
+	It("populates timestamps on insert", func() {
+		created := tm.Add(-time.Hour)
+		models := []ClockTest{{}, {CreatedAt: created}}
+		table := Tables.Get(reflect.TypeOf(ClockTest{}))
+
+		setTimestamps(table, reflect.ValueOf(models), true)
+		Expect(models[0].CreatedAt).To(Equal(tm))
+		Expect(*models[0].UpdatedAt).To(Equal(tm))
+		Expect(models[1].CreatedAt).To(Equal(created))
+		Expect(*models[1].UpdatedAt).To(Equal(tm))
+	})
+
+	It("populates updated_at on update", func() {
+		created := tm.Add(-time.Hour)
+		model := &ClockTest{CreatedAt: created, UpdatedAt: &created}
+		table := Tables.Get(reflect.TypeOf(ClockTest{}))
+
+		setTimestamps(table, reflect.ValueOf(model).Elem(), false)
+		Expect(model.CreatedAt).To(Equal(created))
+		Expect(*model.UpdatedAt).To(Equal(tm))
+	})
+})
+
+type ServerTimestampsTest struct {
+	tableName struct{} `pg:",timestamps:server"`
+
+	Id        int
+	Title     string
+	CreatedAt time.Time `sql:",notnull"`
+	UpdatedAt time.Time
+}
+
+type TaggedTimestampsTest struct {
+	Id         int
+	InsertedAt time.Time `pg:",created_at"`
+	ModifiedAt time.Time `pg:",updated_at,default_now"`
+}
+
+type NoTimestampsTest struct {
+	tableName struct{} `pg:",timestamps:off"`
+
+	Id        int
+	CreatedAt time.Time
+}
+
+var _ = Describe("Timestamps", func() {
+	tm := time.Date(2001, time.February, 3, 4, 5, 6, 0, time.UTC)
+
+	BeforeEach(func() {
+		SetClock(fixedClock(tm))
+	})
+
+	AfterEach(func() {
+		SetClock(nil)
+	})
+
+	It("populates server timestamps with now()", func() {
+		model := &ServerTimestampsTest{Id: 1, Title: "title"}
+		setTimestamps(Tables.Get(reflect.TypeOf(*model)), reflect.ValueOf(model).Elem(), true)
+		Expect(model.CreatedAt.IsZero()).To(BeTrue())
+		Expect(model.UpdatedAt.IsZero()).To(BeTrue())
+
+		q := NewQuery(nil, model)
+
+		b, err := insertQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`INSERT INTO "server_timestamps_tests" ("id", "title", "created_at", "updated_at") VALUES (1, 'title', DEFAULT, DEFAULT) RETURNING "created_at", "updated_at"`))
+
+		b, err = updateQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`UPDATE "server_timestamps_tests" AS "server_timestamps_test" SET "title" = 'title', "created_at" = '0001-01-01 00:00:00+00:00:00', "updated_at" = now() WHERE "server_timestamps_test"."id" = 1 RETURNING "updated_at"`))
+
+		b, err = createTableQuery{model: model}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`CREATE TABLE "server_timestamps_tests" (id bigserial, title text, created_at timestamptz NOT NULL DEFAULT now(), updated_at timestamptz DEFAULT now(), PRIMARY KEY (id))`))
+	})
+
+	It("recognizes tagged timestamps", func() {
+		model := &TaggedTimestampsTest{Id: 1}
+		table := Tables.Get(reflect.TypeOf(*model))
+
+		setTimestamps(table, reflect.ValueOf(model).Elem(), true)
+		Expect(model.InsertedAt).To(Equal(tm))
+		Expect(model.ModifiedAt.IsZero()).To(BeTrue())
+
+		b, err := updateQuery{Query: NewQuery(nil, model)}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`UPDATE "tagged_timestamps_tests" AS "tagged_timestamps_test" SET "inserted_at" = '2001-02-03 04:05:06+00:00:00', "modified_at" = now() WHERE "tagged_timestamps_test"."id" = 1 RETURNING "modified_at"`))
+	})
+
+	It("does not populate timestamps when they are off", func() {
+		model := &NoTimestampsTest{}
+		setTimestamps(Tables.Get(reflect.TypeOf(*model)), reflect.ValueOf(model).Elem(), true)
+		Expect(model.CreatedAt.IsZero()).To(BeTrue())
+	})
+})
@@ -0,0 +1,99 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+
+	"gopkg.in/pg.v5/internal"
+	"gopkg.in/pg.v5/internal/parser"
+	"gopkg.in/pg.v5/types"
+)
+
+// compositeAppender returns appender that encodes struct as composite
+// type (row) literal, e.g. '(1,"foo bar",)'. Struct fields are mapped
+// to composite attributes in the order they are defined.
+func compositeAppender(typ reflect.Type) types.AppenderFunc {
+	return func(b []byte, v reflect.Value, quote int) []byte {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return types.AppendNull(b, quote)
+			}
+			v = v.Elem()
+		}
+
+		table := Tables.Get(v.Type())
+		row := make([]byte, 0, 64)
+		row = append(row, '(')
+		elem := make([]byte, 0, 32)
+		for i, f := range table.Fields {
+			if i > 0 {
+				row = append(row, ',')
+			}
+			// With quote 0 NULL is appended as nil.
+			bb := f.AppendValue(elem[:0], v, 0)
+			if bb == nil {
+				continue
+			}
+			elem = bb
+			row = appendCompositeElem(row, elem)
+		}
+		row = append(row, ')')
+
+		return types.AppendString(b, internal.BytesToString(row), quote)
+	}
+}
+
+func appendCompositeElem(b, elem []byte) []byte {
+	b = append(b, '"')
+	for _, c := range elem {
+		if c == '"' || c == '\\' {
+			b = append(b, c)
+		}
+		b = append(b, c)
+	}
+	return append(b, '"')
+}
+
+// compositeScanner returns scanner that decodes composite type (row)
+// literal into struct.
+func compositeScanner(typ reflect.Type) types.ScannerFunc {
+	return func(v reflect.Value, b []byte) error {
+		if !v.CanSet() {
+			return internal.Errorf("pg: Scan(non-pointer %s)", v.Type())
+		}
+
+		if b == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+
+		table := Tables.Get(v.Type())
+		p := parser.NewCompositeParser(b)
+		var i int
+		for p.Valid() {
+			elem, err := p.NextElem()
+			if err != nil {
+				return err
+			}
+
+			if i >= len(table.Fields) {
+				return fmt.Errorf(
+					"pg: %s has %d fields, but composite value has more attributes: %q",
+					v.Type(), len(table.Fields), b,
+				)
+			}
+			if err := table.Fields[i].ScanValue(v, elem); err != nil {
+				return err
+			}
+			i++
+		}
+		return nil
+	}
+}
@@ -0,0 +1,77 @@
+package orm
+
+import (
+	"reflect"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type CompositeAddress struct {
+	Street string
+	City   string
+	Zip    *string
+	Rank   int `sql:",notnull"`
+}
+
+type CompositeTest struct {
+	Id      int
+	Address CompositeAddress  `pg:",composite:address_t"`
+	Billing *CompositeAddress `pg:",composite:address_t"`
+}
+
+var _ = Describe("Composite", func() {
+	table := Tables.Get(reflect.TypeOf(CompositeTest{}))
+
+	It("uses composite type name as SQL type", func() {
+		Expect(table.FieldsMap["address"].SQLType).To(Equal("address_t"))
+		Expect(table.FieldsMap["billing"].SQLType).To(Equal("address_t"))
+		Expect(table.FieldsMap).NotTo(HaveKey("address__street"))
+	})
+
+	It("formats struct as row literal", func() {
+		zip := ""
+		q := NewQuery(nil, &CompositeTest{
+			Id: 1,
+			Address: CompositeAddress{
+				Street: `it's "main", 1\2`,
+				City:   "(city)",
+				Zip:    &zip,
+			},
+		})
+
+		b, err := insertQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`INSERT INTO "composite_tests" ("id", "address", "billing") VALUES (1, '("it''s ""main"", 1\\2","(city)","","0")', DEFAULT) RETURNING "billing"`))
+	})
+
+	It("scans row literal into struct", func() {
+		var v CompositeTest
+		strct := reflect.ValueOf(&v).Elem()
+
+		err := table.FieldsMap["address"].ScanValue(strct, []byte(`("it's ""main"", 1\\2","(city)",,5)`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.Address).To(Equal(CompositeAddress{
+			Street: `it's "main", 1\2`,
+			City:   "(city)",
+			Rank:   5,
+		}))
+
+		err = table.FieldsMap["billing"].ScanValue(strct, []byte(`(street,city,12345,0)`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.Billing.Street).To(Equal("street"))
+		Expect(*v.Billing.Zip).To(Equal("12345"))
+
+		err = table.FieldsMap["billing"].ScanValue(strct, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.Billing).To(BeNil())
+	})
+
+	It("returns an error when row has more attributes than struct", func() {
+		var v CompositeTest
+		strct := reflect.ValueOf(&v).Elem()
+
+		err := table.FieldsMap["address"].ScanValue(strct, []byte(`(a,b,c,1,extra)`))
+		Expect(err).To(MatchError(`pg: orm.CompositeAddress has 4 fields, but composite value has more attributes: "(a,b,c,1,extra)"`))
+	})
+})
@@ -94,22 +94,22 @@ func (q *Query) SelectAndCountEstimate(threshold int, values ...interface{}) (co
 	var wg sync.WaitGroup
 	wg.Add(2)
 
+	var selectErr, countErr error
+
 	go func() {
 		defer wg.Done()
-		if e := q.Select(values...); e != nil {
-			err = e
-		}
+		selectErr = q.Select(values...)
 	}()
 
 	go func() {
 		defer wg.Done()
-		var e error
-		count, e = q.CountEstimate(threshold)
-		if e != nil {
-			err = e
-		}
+		count, countErr = q.CountEstimate(threshold)
 	}()
 
 	wg.Wait()
-	return count, err
+
+	if selectErr != nil {
+		return count, selectErr
+	}
+	return count, countErr
 }
@@ -0,0 +1,66 @@
+package orm
+
+import "gopkg.in/pg.v5/types"
+
+// CreateEnum creates enum type with the values.
+func CreateEnum(db DB, name string, values []string) (*types.Result, error) {
+	return db.Exec(createEnumQuery{enum: Enum{Name: name, Values: values}})
+}
+
+type createEnumQuery struct {
+	enum Enum
+}
+
+func (q createEnumQuery) AppendQuery(b []byte, params ...interface{}) ([]byte, error) {
+	b = append(b, "CREATE TYPE "...)
+	b = types.AppendField(b, q.enum.Name, 1)
+	b = append(b, " AS ENUM ("...)
+	for i, value := range q.enum.Values {
+		if i > 0 {
+			b = append(b, ", "...)
+		}
+		b = types.AppendString(b, value, 1)
+	}
+	b = append(b, ")"...)
+	return b, nil
+}
+
+type AddEnumValueOptions struct {
+	IfNotExists bool
+	// Before or After place the value relative to an existing value.
+	// By default the value is added at the end.
+	Before string
+	After  string
+}
+
+// AddEnumValue adds the value to enum type. Before PostgreSQL 12
+// it can't be executed inside a transaction.
+func AddEnumValue(db DB, name, value string, opt *AddEnumValueOptions) (*types.Result, error) {
+	return db.Exec(addEnumValueQuery{name: name, value: value, opt: opt})
+}
+
+type addEnumValueQuery struct {
+	name  string
+	value string
+	opt   *AddEnumValueOptions
+}
+
+func (q addEnumValueQuery) AppendQuery(b []byte, params ...interface{}) ([]byte, error) {
+	b = append(b, "ALTER TYPE "...)
+	b = types.AppendField(b, q.name, 1)
+	b = append(b, " ADD VALUE "...)
+	if q.opt != nil && q.opt.IfNotExists {
+		b = append(b, "IF NOT EXISTS "...)
+	}
+	b = types.AppendString(b, q.value, 1)
+	if q.opt != nil {
+		if q.opt.Before != "" {
+			b = append(b, " BEFORE "...)
+			b = types.AppendString(b, q.opt.Before, 1)
+		} else if q.opt.After != "" {
+			b = append(b, " AFTER "...)
+			b = types.AppendString(b, q.opt.After, 1)
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,30 @@
+package orm
+
+import "gopkg.in/pg.v5/types"
+
+type CreateExtensionOptions struct {
+	IfNotExists bool
+	Schema      string
+}
+
+func CreateExtension(db DB, name string, opt *CreateExtensionOptions) (*types.Result, error) {
+	return db.Exec(createExtensionQuery{name: name, opt: opt})
+}
+
+type createExtensionQuery struct {
+	name string
+	opt  *CreateExtensionOptions
+}
+
+func (q createExtensionQuery) AppendQuery(b []byte, params ...interface{}) ([]byte, error) {
+	b = append(b, "CREATE EXTENSION "...)
+	if q.opt != nil && q.opt.IfNotExists {
+		b = append(b, "IF NOT EXISTS "...)
+	}
+	b = types.AppendField(b, q.name, 1)
+	if q.opt != nil && q.opt.Schema != "" {
+		b = append(b, " SCHEMA "...)
+		b = types.AppendField(b, q.opt.Schema, 1)
+	}
+	return b, nil
+}
@@ -0,0 +1,83 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+
+	"gopkg.in/pg.v5/types"
+)
+
+// Index is a secondary index of the table. Indexes are declared with
+// field tags, e.g.
+//
+//    Title string `pg:",index"`
+//    ISBN  string `pg:",unique_index"`
+//
+// Fields with the same index name form a composite index, e.g.
+//
+//    AuthorId int    `pg:",index:books_author_title_idx"`
+//    Title    string `pg:",index:books_author_title_idx"`
+//
+// Composite indexes can also be declared with the tableName field tag
+// listing the columns, e.g.
+//
+//    tableName struct{} `pg:",index:author_id|title,unique_index:isbn|edition"`
+type Index struct {
+	Name    string
+	Unique  bool
+	Columns []string
+}
+
+type CreateIndexOptions struct {
+	IfNotExists bool
+}
+
+// CreateIndexes creates the indexes of the model.
+func CreateIndexes(db DB, model interface{}, opt *CreateIndexOptions) error {
+	typ := indirectType(reflect.TypeOf(model))
+	if typ.Kind() != reflect.Struct {
+		return fmt.Errorf("pg: Model(unsupported %s)", typ)
+	}
+
+	table := Tables.Get(typ)
+	for _, index := range table.Indexes {
+		_, err := db.Exec(createIndexQuery{table: table, index: index, opt: opt})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type createIndexQuery struct {
+	table *Table
+	index *Index
+	opt   *CreateIndexOptions
+}
+
+func (q createIndexQuery) AppendQuery(b []byte, params ...interface{}) ([]byte, error) {
+	b = append(b, "CREATE "...)
+	if q.index.Unique {
+		b = append(b, "UNIQUE "...)
+	}
+	b = append(b, "INDEX "...)
+	if q.opt != nil && q.opt.IfNotExists {
+		b = append(b, "IF NOT EXISTS "...)
+	}
+	b = types.AppendField(b, q.index.Name, 1)
+	b = append(b, " ON "...)
+	b = append(b, q.table.Name...)
+	b = append(b, " ("...)
+	for i, col := range q.index.Columns {
+		field, err := q.table.GetField(col)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			b = append(b, ", "...)
+		}
+		b = append(b, field.ColName...)
+	}
+	b = append(b, ")"...)
+	return b, nil
+}
@@ -0,0 +1,134 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Extension is a PostgreSQL extension created by CreateSchema,
+// e.g. orm.Extension("hstore").
+type Extension string
+
+// Enum is an enum type created by CreateSchema.
+type Enum struct {
+	Name   string
+	Values []string
+}
+
+// CreateSchema creates extensions, enums, tables and their indexes for
+// the models. Extensions and enums are created first. Tables are
+// ordered so that a table is created after the tables it references by
+// foreign key; otherwise models keep their order. It does not start
+// a transaction.
+func CreateSchema(db DB, models []interface{}, opt *CreateTableOptions) error {
+	var tables []interface{}
+	for _, model := range models {
+		var err error
+		switch model := model.(type) {
+		case Extension:
+			_, err = CreateExtension(db, string(model), &CreateExtensionOptions{
+				IfNotExists: true,
+			})
+			if err != nil {
+				err = fmt.Errorf("pg: can't create extension %q: %s", model, err)
+			}
+		case Enum:
+			_, err = CreateEnum(db, model.Name, model.Values)
+			if err != nil {
+				err = fmt.Errorf("pg: can't create enum %q: %s", model.Name, err)
+			}
+		default:
+			tables = append(tables, model)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	tables, err := orderByDependencies(tables)
+	if err != nil {
+		return err
+	}
+
+	for _, model := range tables {
+		table := Tables.Get(indirectType(reflect.TypeOf(model)))
+		_, err := CreateTable(db, model, opt)
+		if err != nil {
+			return fmt.Errorf("pg: can't create table %s: %s", table.Name, err)
+		}
+		err = CreateIndexes(db, model, nil)
+		if err != nil {
+			return fmt.Errorf("pg: can't create indexes of table %s: %s", table.Name, err)
+		}
+	}
+	return nil
+}
+
+// orderByDependencies sorts models so every model follows the models
+// it has foreign keys to. The sort is stable.
+func orderByDependencies(models []interface{}) ([]interface{}, error) {
+	tables := make([]*Table, len(models))
+	for i, model := range models {
+		typ := indirectType(reflect.TypeOf(model))
+		if typ.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("pg: Model(unsupported %s)", typ)
+		}
+		tables[i] = Tables.Get(typ)
+	}
+
+	deps := make([][]int, len(tables))
+	for i, table := range tables {
+		for _, rel := range table.Relations {
+			for j, other := range tables {
+				if i == j || other != rel.JoinTable {
+					continue
+				}
+				switch rel.Type {
+				case HasOneRelation:
+					deps[i] = append(deps[i], j)
+				case BelongsToRelation, HasManyRelation:
+					deps[j] = append(deps[j], i)
+				}
+			}
+		}
+	}
+
+	ordered := make([]interface{}, 0, len(models))
+	done := make([]bool, len(models))
+	for len(ordered) < len(models) {
+		next := -1
+		for i := range tables {
+			if !done[i] && allDone(deps[i], done) {
+				next = i
+				break
+			}
+		}
+
+		if next == -1 {
+			var names []string
+			for i, table := range tables {
+				if !done[i] {
+					names = append(names, string(table.Name))
+				}
+			}
+			return nil, fmt.Errorf(
+				"pg: can't order tables with cyclic foreign keys: %s",
+				strings.Join(names, ", "),
+			)
+		}
+
+		done[next] = true
+		ordered = append(ordered, models[next])
+	}
+	return ordered, nil
+}
+
+func allDone(inds []int, done []bool) bool {
+	for _, ind := range inds {
+		if !done[ind] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package orm
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type SchemaAuthor struct {
+	Id    int
+	Books []*SchemaBook
+}
+
+type SchemaBook struct {
+	Id             int
+	SchemaAuthorId int
+	SchemaAuthor   *SchemaAuthor
+	SchemaGenreId  int
+	SchemaGenre    *SchemaGenre
+}
+
+type SchemaGenre struct {
+	Id int
+}
+
+type SchemaCycleA struct {
+	Id             int
+	SchemaCycleBId int
+	SchemaCycleB   *SchemaCycleB
+}
+
+type SchemaCycleB struct {
+	Id             int
+	SchemaCycleAId int
+	SchemaCycleA   *SchemaCycleA
+}
+
+var _ = Describe("CreateSchema", func() {
+	It("orders tables by foreign keys", func() {
+		models, err := orderByDependencies([]interface{}{
+			&SchemaBook{}, &SchemaAuthor{}, SchemaGenre{},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(models).To(Equal([]interface{}{
+			&SchemaAuthor{}, SchemaGenre{}, &SchemaBook{},
+		}))
+	})
+
+	It("keeps order of independent tables", func() {
+		models, err := orderByDependencies([]interface{}{
+			SchemaGenre{}, &SchemaAuthor{},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(models).To(Equal([]interface{}{
+			SchemaGenre{}, &SchemaAuthor{},
+		}))
+	})
+
+	It("returns an error on cyclic foreign keys", func() {
+		_, err := orderByDependencies([]interface{}{
+			&SchemaGenre{}, &SchemaCycleA{}, &SchemaCycleB{},
+		})
+		Expect(err).To(MatchError(`pg: can't order tables with cyclic foreign keys: "schema_cycleas", "schema_cyclebs"`))
+	})
+
+	It("creates extension", func() {
+		b, err := createExtensionQuery{name: "hstore"}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`CREATE EXTENSION "hstore"`))
+	})
+
+	It("creates extension with options", func() {
+		b, err := createExtensionQuery{
+			name: "hstore",
+			opt: &CreateExtensionOptions{
+				IfNotExists: true,
+				Schema:      "public",
+			},
+		}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`CREATE EXTENSION IF NOT EXISTS "hstore" SCHEMA "public"`))
+	})
+
+	It("creates enum", func() {
+		b, err := createEnumQuery{enum: Enum{
+			Name:   "mood",
+			Values: []string{"sad", "it's ok"},
+		}}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`CREATE TYPE "mood" AS ENUM ('sad', 'it''s ok')`))
+	})
+
+	It("adds enum value", func() {
+		b, err := addEnumValueQuery{name: "mood", value: "happy"}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`ALTER TYPE "mood" ADD VALUE 'happy'`))
+	})
+
+	It("adds enum value with options", func() {
+		b, err := addEnumValueQuery{
+			name:  "mood",
+			value: "it's ok",
+			opt: &AddEnumValueOptions{
+				IfNotExists: true,
+				After:       "sad",
+			},
+		}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`ALTER TYPE "mood" ADD VALUE IF NOT EXISTS 'it''s ok' AFTER 'sad'`))
+	})
+})
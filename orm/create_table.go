@@ -50,6 +50,9 @@ func (c createTableQuery) AppendQuery(b []byte, params ...interface{}) ([]byte,
 		if field.Has(UniqueFlag) {
 			b = append(b, " UNIQUE"...)
 		}
+		if field.Has(DefaultNowFlag) {
+			b = append(b, " DEFAULT now()"...)
+		}
 
 		if i != len(table.Fields)-1 {
 			b = append(b, ", "...)
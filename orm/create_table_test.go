@@ -2,8 +2,11 @@ package orm
 
 import (
 	"database/sql"
+	"reflect"
 	"time"
 
+	"gopkg.in/pg.v5/types"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -34,6 +37,31 @@ type CreateTableModel struct {
 	Struct      struct{}
 }
 
+type createTableNullInt64 struct {
+	sql.NullInt64
+}
+
+type CreateTableWrapperModel struct {
+	Id        int
+	NullInt64 createTableNullInt64
+}
+
+type CreateTableHstoreModel struct {
+	Id    int
+	Attrs map[string]*string `pg:",hstore"`
+}
+
+type createTableMood string
+
+func init() {
+	types.RegisterEnum("mood", createTableMood(""), "sad", "happy")
+}
+
+type CreateTableEnumModel struct {
+	Id   int
+	Mood createTableMood
+}
+
 type CreateTableWithoutPKModel struct {
 	String string
 }
@@ -45,9 +73,87 @@ var _ = Describe("CreateTable", func() {
 		Expect(string(b)).To(Equal(`CREATE TABLE "create_table_models" (id bigserial, int8 smallint, uint8 smallint, int16 smallint, uint16 integer, int32 integer, uint32 bigint, int64 bigint, uint64 decimal, float32 real, float64 double precision, string text, varchar varchar(500), time timestamptz, not_null bigint NOT NULL, unique bigint UNIQUE, null_bool boolean, null_float64 double precision, null_int64 bigint, null_string text, slice jsonb, map jsonb, struct jsonb, PRIMARY KEY (id))`))
 	})
 
+	It("uses type of the embedded field for wrapper types", func() {
+		b, err := createTableQuery{model: CreateTableWrapperModel{}}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`CREATE TABLE "create_table_wrapper_models" (id bigserial, null_int64 bigint, PRIMARY KEY (id))`))
+	})
+
+	It("uses hstore type for hstore fields", func() {
+		b, err := createTableQuery{model: CreateTableHstoreModel{}}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`CREATE TABLE "create_table_hstore_models" (id bigserial, attrs hstore, PRIMARY KEY (id))`))
+	})
+
+	It("uses enum name for registered enum types", func() {
+		b, err := createTableQuery{model: CreateTableEnumModel{}}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`CREATE TABLE "create_table_enum_models" (id bigserial, mood mood, PRIMARY KEY (id))`))
+	})
+
 	It("creates new table without primary key", func() {
 		b, err := createTableQuery{model: CreateTableWithoutPKModel{}}.AppendQuery(nil)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(string(b)).To(Equal(`CREATE TABLE "create_table_without_pk_models" (string text)`))
 	})
 })
+
+type CreateIndexModel struct {
+	tableName struct{} `pg:",unique_index:isbn|edition"`
+
+	Id       int
+	Title    string `pg:",index"`
+	Slug     string `pg:",unique"`
+	AuthorId int    `pg:",index:author_title_idx"`
+	Subtitle string `pg:",index:author_title_idx"`
+	ISBN     string `sql:"isbn"`
+	Edition  int
+}
+
+var _ = Describe("CreateIndexes", func() {
+	var table *Table
+
+	BeforeEach(func() {
+		table = Tables.Get(reflect.TypeOf(CreateIndexModel{}))
+	})
+
+	It("declares indexes with tags", func() {
+		Expect(table.Indexes).To(Equal([]*Index{
+			{Name: "create_index_models_isbn_edition_idx", Unique: true, Columns: []string{"isbn", "edition"}},
+			{Name: "create_index_models_title_idx", Columns: []string{"title"}},
+			{Name: "author_title_idx", Columns: []string{"author_id", "subtitle"}},
+		}))
+	})
+
+	It("creates indexes", func() {
+		var queries []string
+		for _, index := range table.Indexes {
+			b, err := createIndexQuery{
+				table: table,
+				index: index,
+				opt:   &CreateIndexOptions{IfNotExists: true},
+			}.AppendQuery(nil)
+			Expect(err).NotTo(HaveOccurred())
+			queries = append(queries, string(b))
+		}
+		Expect(queries).To(Equal([]string{
+			`CREATE UNIQUE INDEX IF NOT EXISTS "create_index_models_isbn_edition_idx" ON "create_index_models" ("isbn", "edition")`,
+			`CREATE INDEX IF NOT EXISTS "create_index_models_title_idx" ON "create_index_models" ("title")`,
+			`CREATE INDEX IF NOT EXISTS "author_title_idx" ON "create_index_models" ("author_id", "subtitle")`,
+		}))
+	})
+
+	It("sets UNIQUE constraint with pg tag", func() {
+		b, err := createTableQuery{model: CreateIndexModel{}}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`CREATE TABLE "create_index_models" (id bigserial, title text, slug text UNIQUE, author_id bigint, subtitle text, isbn text, edition bigint, PRIMARY KEY (id))`))
+	})
+
+	It("returns an error for unknown columns", func() {
+		_, err := createIndexQuery{
+			table: table,
+			index: &Index{Name: "idx", Columns: []string{"missing"}},
+		}.AppendQuery(nil)
+		Expect(err).To(MatchError(`can't find column=missing in table="create_index_models"`))
+	})
+})
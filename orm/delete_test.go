@@ -19,4 +19,14 @@ var _ = Describe("Delete", func() {
 		Expect(err).NotTo(HaveOccurred())
 		Expect(string(b)).To(Equal(`WITH "wrapper" AS (SELECT  FROM "delete_tests" AS "delete_test") DELETE FROM "delete_tests" AS "delete_test" USING "wrapper" WHERE (delete_test.id = wrapper.id)`))
 	})
+
+	It("supports RETURNING", func() {
+		q := NewQuery(nil, &DeleteTest{}).
+			Where("id = ?", 1).
+			Returning("id, created_at")
+
+		b, err := deleteQuery{q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`DELETE FROM "delete_tests" AS "delete_test" WHERE (id = 1) RETURNING id, created_at`))
+	})
 })
@@ -0,0 +1,96 @@
+package orm
+
+import "strings"
+
+// ExplainPlan is the plan returned by EXPLAIN (FORMAT JSON).
+// Times are in milliseconds and are set only with ANALYZE.
+type ExplainPlan struct {
+	Plan          ExplainNode `json:"Plan"`
+	PlanningTime  float64     `json:"Planning Time"`
+	ExecutionTime float64     `json:"Execution Time"`
+}
+
+// ExplainNode is a node of the plan tree. Only the most commonly used
+// properties are decoded.
+type ExplainNode struct {
+	NodeType     string  `json:"Node Type"`
+	RelationName string  `json:"Relation Name"`
+	Alias        string  `json:"Alias"`
+	IndexName    string  `json:"Index Name"`
+	JoinType     string  `json:"Join Type"`
+	Filter       string  `json:"Filter"`
+	IndexCond    string  `json:"Index Cond"`
+	StartupCost  float64 `json:"Startup Cost"`
+	TotalCost    float64 `json:"Total Cost"`
+	PlanRows     float64 `json:"Plan Rows"`
+	PlanWidth    int     `json:"Plan Width"`
+
+	ActualStartupTime float64 `json:"Actual Startup Time"`
+	ActualTotalTime   float64 `json:"Actual Total Time"`
+	ActualRows        float64 `json:"Actual Rows"`
+	ActualLoops       float64 `json:"Actual Loops"`
+
+	Plans []ExplainNode `json:"Plans"`
+}
+
+// Explain returns the plan of the select query in text format. With
+// analyze the query is executed and the plan contains actual times
+// and row counts.
+func (q *Query) Explain(analyze bool) (string, error) {
+	if q.stickyErr != nil {
+		return "", q.stickyErr
+	}
+
+	var lines []string
+	_, err := q.db.Query(&lines, explainQuery{
+		query:   selectQuery{Query: q},
+		analyze: analyze,
+	}, q.model)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// ExplainJSON is like Explain, but returns the plan parsed from
+// EXPLAIN (FORMAT JSON) output.
+func (q *Query) ExplainJSON(analyze bool) (*ExplainPlan, error) {
+	if q.stickyErr != nil {
+		return nil, q.stickyErr
+	}
+
+	var plans []ExplainPlan
+	_, err := q.db.QueryOne(Scan(&plans), explainQuery{
+		query:   selectQuery{Query: q},
+		analyze: analyze,
+		json:    true,
+	}, q.model)
+	if err != nil {
+		return nil, err
+	}
+	if len(plans) == 0 {
+		return nil, nil
+	}
+	return &plans[0], nil
+}
+
+type explainQuery struct {
+	query   QueryAppender
+	analyze bool
+	json    bool
+}
+
+var _ QueryAppender = (*explainQuery)(nil)
+
+func (q explainQuery) AppendQuery(b []byte, params ...interface{}) ([]byte, error) {
+	b = append(b, "EXPLAIN "...)
+	switch {
+	case q.analyze && q.json:
+		b = append(b, "(ANALYZE, FORMAT JSON) "...)
+	case q.analyze:
+		b = append(b, "ANALYZE "...)
+	case q.json:
+		b = append(b, "(FORMAT JSON) "...)
+	}
+	return q.query.AppendQuery(b, params...)
+}
@@ -0,0 +1,32 @@
+package orm
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Explain", func() {
+	It("prefixes select query", func() {
+		q := NewQuery(nil, &SelectModel{}).Where("id = ?", 1)
+
+		b, err := explainQuery{query: selectQuery{Query: q}}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`EXPLAIN SELECT "select_model"."id", "select_model"."name", "select_model"."has_one_id" FROM "select_models" AS "select_model" WHERE (id = 1)`))
+	})
+
+	It("supports analyze and json format", func() {
+		q := NewQuery(nil).Table("test")
+
+		b, err := explainQuery{query: selectQuery{Query: q}, analyze: true}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`EXPLAIN ANALYZE SELECT * FROM "test"`))
+
+		b, err = explainQuery{query: selectQuery{Query: q}, json: true}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`EXPLAIN (FORMAT JSON) SELECT * FROM "test"`))
+
+		b, err = explainQuery{query: selectQuery{Query: q}, analyze: true, json: true}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`EXPLAIN (ANALYZE, FORMAT JSON) SELECT * FROM "test"`))
+	})
+})
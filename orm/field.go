@@ -11,6 +11,7 @@ const (
 	ForeignKeyFlag
 	NotNullFlag
 	UniqueFlag
+	DefaultNowFlag
 )
 
 type Field struct {
@@ -22,10 +23,12 @@ type Field struct {
 	SQLType string
 	Index   []int
 
-	flags uint8
+	flags     uint8
+	generated bool
 
-	append types.AppenderFunc
-	scan   types.ScannerFunc
+	append    types.AppenderFunc
+	scan      types.ScannerFunc
+	arenaScan types.ArenaScannerFunc
 
 	isEmpty func(reflect.Value) bool
 }
@@ -54,6 +57,14 @@ func (f *Field) OmitEmpty(strct reflect.Value) bool {
 }
 
 func (f *Field) AppendValue(b []byte, strct reflect.Value, quote int) []byte {
+	if f.generated {
+		if m, ok := generatedModel(strct); ok {
+			if b, ok := m.AppendColumnValue(b, f.SQLName, quote); ok {
+				return b
+			}
+		}
+	}
+
 	fv := f.Value(strct)
 	if !f.Has(NotNullFlag) && f.isEmpty(fv) {
 		return types.AppendNull(b, quote)
@@ -62,10 +73,29 @@ func (f *Field) AppendValue(b []byte, strct reflect.Value, quote int) []byte {
 }
 
 func (f *Field) ScanValue(strct reflect.Value, b []byte) error {
+	if f.generated {
+		if m, ok := generatedModel(strct); ok {
+			err := m.ScanColumn(0, f.SQLName, b)
+			if err != ErrNotGenerated {
+				return err
+			}
+		}
+	}
+
 	fv := fieldByIndex(strct, f.Index)
 	return f.scan(fv, b)
 }
 
+// ScanValueArena acts like ScanValue, but allocates string and []byte
+// values in the arena.
+func (f *Field) ScanValueArena(strct reflect.Value, b []byte, arena *types.Arena) error {
+	if arena == nil || f.arenaScan == nil {
+		return f.ScanValue(strct, b)
+	}
+	fv := fieldByIndex(strct, f.Index)
+	return f.arenaScan(fv, b, arena)
+}
+
 type Method struct {
 	Index int
 
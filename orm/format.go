@@ -70,6 +70,11 @@ func (a fieldAppender) AppendFormat(b []byte, f QueryFormatter) []byte {
 	return types.AppendField(b, a.field, 1)
 }
 
+// columnSetAppender is a field added with Query.ColumnSet.
+type columnSetAppender struct {
+	fieldAppender
+}
+
 //------------------------------------------------------------------------------
 
 type Formatter struct {
@@ -198,7 +203,7 @@ func (f Formatter) append(dst []byte, p *parser.Parser, params []interface{}) []
 			}
 
 			if model != nil {
-				dst, ok = model.AppendParam(dst, id)
+				dst, ok = f.appendModelParam(dst, model, id)
 				if ok {
 					continue
 				}
@@ -228,6 +233,15 @@ func (f Formatter) append(dst []byte, p *parser.Parser, params []interface{}) []
 	return dst
 }
 
+// appendModelParam formats the table name, because it can contain
+// placeholders, e.g. ?schema.users.
+func (f Formatter) appendModelParam(b []byte, model tableModel, name string) ([]byte, bool) {
+	if name == "TableName" {
+		return f.Append(b, string(model.Table().Name)), true
+	}
+	return model.AppendParam(b, name)
+}
+
 func (f Formatter) appendParam(b []byte, param interface{}) []byte {
 	if fa, ok := param.(FormatAppender); ok {
 		return fa.AppendFormat(b, f)
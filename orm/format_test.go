@@ -85,6 +85,7 @@ var formatTests = []formatTest{
 	{q: "?", params: params{uint64(math.MaxUint64)}, wanted: "18446744073709551615"},
 	{q: "?", params: params{orm.Q("query")}, wanted: "query"},
 	{q: "?", params: params{types.F("field")}, wanted: `"field"`},
+	{q: "?", params: params{types.Ident(`my "table".name`)}, wanted: `"my ""table"".name"`},
 	{q: "?", params: params{structv}, wanted: `'{"String":"string_value","NotNull":"","Iface":"iface_value"}'`},
 
 	{q: `\? ?`, params: params{1}, wanted: "? 1"},
@@ -104,6 +105,7 @@ var formatTests = []formatTest{
 	{q: "?", params: params{types.F("?string")}, paramsMap: paramsMap{"string": types.Q("my_value")}, wanted: `"?string"`},
 	{q: "?", params: params{orm.Q("?string")}, paramsMap: paramsMap{"string": "my_value"}, wanted: "'my_value'"},
 	{q: "?MethodParam", params: params{structv}, paramsMap: paramsMap{"string": "my_value"}, wanted: "?string"},
+	{q: "SELECT * FROM ?TableName", paramsMap: paramsMap{"TableName": types.Ident("users; --")}, wanted: `SELECT * FROM "users; --"`},
 }
 
 func TestFormatQuery(t *testing.T) {
@@ -165,3 +167,46 @@ func BenchmarkFormatQueryStructMethod(b *testing.B) {
 		_ = orm.Q("SELECT * FROM my_table WHERE id = ?Method", &param)
 	}
 }
+
+type ModelParams struct {
+	tableName struct{} `sql:"?schema.model_params,alias:mp"`
+
+	Id   int
+	Name string
+}
+
+func TestFormatModelParams(t *testing.T) {
+	var f orm.Formatter
+	f.SetParam("schema", types.F("tenant"))
+
+	tests := []struct {
+		model  interface{}
+		q      string
+		wanted string
+	}{
+		{
+			&ModelParams{Id: 1},
+			"SELECT ?Columns FROM ?TableName WHERE ?PKs = ?id",
+			`SELECT "id", "name" FROM "tenant".model_params WHERE "id" = 1`,
+		},
+		{
+			&[]ModelParams{},
+			"SELECT ?TableColumns FROM ?TableName AS ?TableAlias WHERE ?PKs = ?",
+			`SELECT mp."id", mp."name" FROM "tenant".model_params AS mp WHERE "id" = 2`,
+		},
+	}
+	for _, test := range tests {
+		model, ok := orm.TableModel(test.model)
+		if !ok {
+			t.Fatalf("%T is not a table model", test.model)
+		}
+		got := f.Append(nil, test.q, 2, model)
+		if string(got) != test.wanted {
+			t.Errorf("got %q, wanted %q", got, test.wanted)
+		}
+	}
+
+	if _, ok := orm.TableModel(&[]int{}); ok {
+		t.Fatal("[]int is a table model")
+	}
+}
@@ -0,0 +1,39 @@
+package orm
+
+import (
+	"errors"
+	"reflect"
+)
+
+// ErrNotGenerated is returned by ScanColumn of GeneratedModel for
+// columns that are not handled by the generated code, which are
+// scanned using reflection instead.
+var ErrNotGenerated = errors.New("pg: column is not handled by generated code")
+
+// GeneratedModel is implemented by structs with code generated by
+// pggen. Fields of such structs are scanned and appended by the
+// generated code instead of reflection:
+//
+//    //go:generate pggen -type=User
+//    type User struct {
+//        Id   int64
+//        Name string
+//    }
+type GeneratedModel interface {
+	ColumnScanner
+
+	// AppendColumnValue appends the value of the field of the column
+	// and returns false when the column is not handled by the
+	// generated code.
+	AppendColumnValue(b []byte, colName string, quote int) ([]byte, bool)
+}
+
+var generatedModelType = reflect.TypeOf((*GeneratedModel)(nil)).Elem()
+
+func generatedModel(strct reflect.Value) (GeneratedModel, bool) {
+	if !strct.CanAddr() {
+		return nil, false
+	}
+	m, ok := strct.Addr().Interface().(GeneratedModel)
+	return m, ok
+}
@@ -0,0 +1,80 @@
+package orm
+
+import (
+	"strconv"
+
+	"gopkg.in/pg.v5/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type GeneratedTest struct {
+	Id   int64
+	Name string
+	Tags []string `pg:",array"`
+
+	scanned  []string
+	appended []string
+}
+
+var _ GeneratedModel = (*GeneratedTest)(nil)
+
+func (g *GeneratedTest) ScanColumn(colIdx int, colName string, b []byte) error {
+	var err error
+	switch colName {
+	case "id":
+		g.Id, err = types.ScanInt64(b)
+	case "name":
+		g.Name = string(b)
+	default:
+		return ErrNotGenerated
+	}
+	g.scanned = append(g.scanned, colName)
+	return err
+}
+
+func (g *GeneratedTest) AppendColumnValue(b []byte, colName string, quote int) ([]byte, bool) {
+	switch colName {
+	case "id":
+		g.appended = append(g.appended, colName)
+		if g.Id == 0 {
+			return types.AppendNull(b, quote), true
+		}
+		return strconv.AppendInt(b, g.Id, 10), true
+	case "name":
+		g.appended = append(g.appended, colName)
+		if g.Name == "" {
+			return types.AppendNull(b, quote), true
+		}
+		return types.AppendString(b, g.Name, quote), true
+	}
+	return b, false
+}
+
+var _ = Describe("GeneratedModel", func() {
+	It("appends values with generated code", func() {
+		strct := &GeneratedTest{Id: 1, Name: "foo", Tags: []string{"a"}}
+		q := NewQuery(nil, strct)
+
+		b, err := insertQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`INSERT INTO "generated_tests" ("id", "name", "tags") VALUES (1, 'foo', '{"a"}')`))
+		Expect(strct.appended).To(Equal([]string{"id", "name"}))
+	})
+
+	It("scans values with generated code", func() {
+		strct := new(GeneratedTest)
+		m, err := newStructTableModel(strct)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(m.ScanColumn(0, "id", []byte("5"))).NotTo(HaveOccurred())
+		Expect(m.ScanColumn(1, "name", []byte("bar"))).NotTo(HaveOccurred())
+		Expect(m.ScanColumn(2, "tags", []byte("{a,b}"))).NotTo(HaveOccurred())
+
+		Expect(strct.Id).To(Equal(int64(5)))
+		Expect(strct.Name).To(Equal("bar"))
+		Expect(strct.Tags).To(Equal([]string{"a", "b"}))
+		Expect(strct.scanned).To(Equal([]string{"id", "name"}))
+	})
+})
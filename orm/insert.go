@@ -26,6 +26,18 @@ func (q insertQuery) AppendQuery(b []byte, params ...interface{}) ([]byte, error
 	table := q.model.Table()
 	value := q.model.Value()
 
+	fields := table.Fields
+	if names := q.getColumnSetFields(); len(names) > 0 {
+		fields = make([]*Field, len(names))
+		for i, name := range names {
+			f, err := table.GetField(name)
+			if err != nil {
+				return nil, err
+			}
+			fields[i] = f
+		}
+	}
+
 	b = append(b, "INSERT INTO "...)
 	if q.onConflict != nil {
 		b = q.appendTableNameWithAlias(b)
@@ -35,7 +47,7 @@ func (q insertQuery) AppendQuery(b []byte, params ...interface{}) ([]byte, error
 	b = append(b, " ("...)
 
 	start := len(b)
-	for _, f := range table.Fields {
+	for _, f := range fields {
 		b = append(b, f.ColName...)
 		b = append(b, ", "...)
 	}
@@ -45,14 +57,14 @@ func (q insertQuery) AppendQuery(b []byte, params ...interface{}) ([]byte, error
 
 	b = append(b, ") VALUES ("...)
 	if value.Kind() == reflect.Struct {
-		b = q.appendValues(b, table.Fields, value)
+		b = q.appendValues(b, fields, value)
 	} else {
 		for i := 0; i < value.Len(); i++ {
 			el := value.Index(i)
 			if el.Kind() == reflect.Interface {
 				el = el.Elem()
 			}
-			b = q.appendValues(b, table.Fields, reflect.Indirect(el))
+			b = q.appendValues(b, fields, reflect.Indirect(el))
 			if i != value.Len()-1 {
 				b = append(b, "), ("...)
 			}
@@ -78,7 +90,7 @@ func (q insertQuery) AppendQuery(b []byte, params ...interface{}) ([]byte, error
 	if len(q.returning) > 0 {
 		b = q.appendReturning(b)
 	} else if len(q.returningFields) > 0 {
-		b = q.appendReturningFields(b, q.returningFields)
+		b = appendReturningFields(b, q.returningFields)
 	}
 
 	return b, nil
@@ -93,7 +105,7 @@ func (q *insertQuery) appendValues(b []byte, fields []*Field, v reflect.Value) [
 		if i > 0 {
 			b = append(b, ", "...)
 		}
-		if f.OmitEmpty(v) {
+		if f.OmitEmpty(v) || f.Has(DefaultNowFlag) && f.IsEmpty(v) {
 			b = append(b, "DEFAULT"...)
 			q.addReturningField(f)
 		} else {
@@ -112,7 +124,7 @@ func (ins *insertQuery) addReturningField(field *Field) {
 	ins.returningFields = append(ins.returningFields, field)
 }
 
-func (insertQuery) appendReturningFields(b []byte, fields []*Field) []byte {
+func appendReturningFields(b []byte, fields []*Field) []byte {
 	b = append(b, " RETURNING "...)
 	for i, f := range fields {
 		if i > 0 {
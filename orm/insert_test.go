@@ -1,6 +1,12 @@
 package orm
 
 import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
 	"gopkg.in/pg.v5/types"
 
 	. "github.com/onsi/ginkgo"
@@ -38,6 +44,65 @@ type InsertQTest struct {
 	Geo types.Q
 }
 
+type InsertTimeTest struct {
+	Time    time.Time  `pg:",utc,usec"`
+	TimePtr *time.Time `pg:",utc"`
+}
+
+type InsertIntervalTest struct {
+	Duration time.Duration `pg:",interval"`
+	Interval types.Interval
+}
+
+type InsertJSONTest struct {
+	Id     int
+	Attrs  InsertJSONAttrs   `pg:",json"`
+	Upper  map[string]string `pg:",json:insert_upper"`
+	Labels []string          `pg:",json"`
+}
+
+type InsertJSONAttrs struct {
+	Color string
+}
+
+type InsertColumnSetTest struct {
+	Id        int
+	Name      string    `pg:",set:summary|full"`
+	Bio       string    `pg:",set:full"`
+	CreatedAt time.Time `sql:",default:now()"`
+}
+
+type insertUpperJSON struct{}
+
+func (insertUpperJSON) Marshal(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	return bytes.ToUpper(b), err
+}
+
+func (insertUpperJSON) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(bytes.ToLower(data), v)
+}
+
+func init() {
+	types.RegisterJSONProvider("insert_upper", insertUpperJSON{})
+
+	types.RegisterCodec(
+		"upper",
+		func(b []byte, v reflect.Value, quote int) []byte {
+			return types.AppendString(b, strings.ToUpper(v.String()), quote)
+		},
+		func(v reflect.Value, b []byte) error {
+			v.SetString(strings.ToLower(string(b)))
+			return nil
+		},
+	)
+}
+
+type InsertCodecTest struct {
+	Name    string `pg:",codec:upper,set:name"`
+	Unknown string `pg:",codec:unknown"`
+}
+
 var _ = Describe("Insert", func() {
 	It("supports ON CONFLICT DO UPDATE", func() {
 		q := NewQuery(nil, &InsertTest{}).
@@ -85,6 +150,14 @@ var _ = Describe("Insert", func() {
 		Expect(string(b)).To(Equal(`INSERT INTO "insert_null_tests" ("f1", "f2", "f3", "f4") VALUES (DEFAULT, 0, DEFAULT, 0) RETURNING "f1", "f3"`))
 	})
 
+	It("replaces default RETURNING columns", func() {
+		q := NewQuery(nil, &InsertNullTest{}).Returning("*")
+
+		b, err := insertQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`INSERT INTO "insert_null_tests" ("f1", "f2", "f3", "f4") VALUES (DEFAULT, 0, DEFAULT, 0) RETURNING *`))
+	})
+
 	It("inserts types.Q", func() {
 		q := NewQuery(nil, &InsertQTest{
 			Geo: types.Q("ST_GeomFromText('POLYGON((75.150000 29.530000, 77.000000 29.000000, 77.600000 29.500000, 75.150000 29.530000))')"),
@@ -94,4 +167,85 @@ var _ = Describe("Insert", func() {
 		Expect(err).NotTo(HaveOccurred())
 		Expect(string(b)).To(Equal(`INSERT INTO "insert_q_tests" ("geo") VALUES (ST_GeomFromText('POLYGON((75.150000 29.530000, 77.000000 29.000000, 77.600000 29.500000, 75.150000 29.530000))'))`))
 	})
+
+	It("formats time using field tags", func() {
+		tm := time.Date(2001, time.February, 3, 4, 5, 6, 123456789, time.FixedZone("", 3*3600))
+		q := NewQuery(nil, &InsertTimeTest{
+			Time:    tm,
+			TimePtr: &tm,
+		})
+
+		b, err := insertQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`INSERT INTO "insert_time_tests" ("time", "time_ptr") VALUES ('2001-02-03 01:05:06.123456+00:00:00', '2001-02-03 01:05:06.123456789+00:00:00')`))
+	})
+
+	It("formats duration as interval using field tag", func() {
+		q := NewQuery(nil, &InsertIntervalTest{
+			Duration: 90 * time.Minute,
+			Interval: types.Interval{Months: 1},
+		})
+
+		b, err := insertQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`INSERT INTO "insert_interval_tests" ("duration", "interval") VALUES ('0 mons 0 days 1:30:00.000000', '1 mons 0 days 0:00:00.000000')`))
+
+		table := Tables.Get(reflect.TypeOf(InsertIntervalTest{}))
+		Expect(table.FieldsMap["duration"].SQLType).To(Equal("interval"))
+		Expect(table.FieldsMap["interval"].SQLType).To(Equal("interval"))
+	})
+
+	It("formats fields with json tag using JSON provider", func() {
+		q := NewQuery(nil, &InsertJSONTest{
+			Id:     1,
+			Attrs:  InsertJSONAttrs{Color: "red"},
+			Upper:  map[string]string{"foo": "bar"},
+			Labels: []string{"a"},
+		})
+
+		b, err := insertQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`INSERT INTO "insert_json_tests" ("id", "attrs", "upper", "labels") VALUES (1, '{"Color":"red"}', '{"FOO":"BAR"}', '["a"]')`))
+
+		table := Tables.Get(reflect.TypeOf(InsertJSONTest{}))
+		Expect(table.FieldsMap["attrs"].SQLType).To(Equal("jsonb"))
+		Expect(table.FieldsMap["upper"].SQLType).To(Equal("jsonb"))
+		Expect(table.FieldsMap).NotTo(HaveKey("attrs__color"))
+
+		var v InsertJSONTest
+		err = table.FieldsMap["upper"].ScanValue(reflect.ValueOf(&v).Elem(), []byte(`{"FOO":"BAR"}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.Upper).To(Equal(map[string]string{"foo": "bar"}))
+	})
+
+	It("inserts only columns from column set", func() {
+		q := NewQuery(nil, &InsertColumnSetTest{Name: "name", Bio: "bio"}).ColumnSet("full")
+
+		b, err := insertQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`INSERT INTO "insert_column_set_tests" ("name", "bio") VALUES ('name', 'bio')`))
+	})
+
+	It("inserts all columns when only Column is used", func() {
+		q := NewQuery(nil, &InsertColumnSetTest{Name: "name", Bio: "bio"}).Column("name")
+
+		b, err := insertQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`INSERT INTO "insert_column_set_tests" ("id", "name", "bio", "created_at") VALUES (DEFAULT, 'name', 'bio', DEFAULT) RETURNING "id", "created_at"`))
+	})
+
+	It("uses codec registered for the field", func() {
+		q := NewQuery(nil, &InsertCodecTest{Name: "name"}).ColumnSet("name")
+
+		b, err := insertQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`INSERT INTO "insert_codec_tests" ("name") VALUES ('NAME')`))
+
+		var dst InsertCodecTest
+		m, err := NewModel(&dst)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.ScanColumn(0, "name", []byte("NAME"))).NotTo(HaveOccurred())
+		Expect(dst.Name).To(Equal("name"))
+		Expect(m.ScanColumn(1, "unknown", []byte("x"))).To(MatchError("pg: codec=unknown is not registered"))
+	})
 })
@@ -12,18 +12,18 @@ type join struct {
 	Columns []string
 }
 
-func (j *join) Select(db DB) error {
+func (j *join) Select(db DB, schema string) error {
 	switch j.Rel.Type {
 	case HasManyRelation:
-		return j.selectMany(db)
+		return j.selectMany(db, schema)
 	case Many2ManyRelation:
-		return j.selectM2M(db)
+		return j.selectM2M(db, schema)
 	}
 	panic("not reached")
 }
 
-func (j *join) selectMany(db DB) error {
-	q, err := j.manyQuery(db)
+func (j *join) selectMany(db DB, schema string) error {
+	q, err := j.manyQuery(db, schema)
 	if err != nil {
 		return err
 	}
@@ -36,12 +36,12 @@ func (j *join) selectMany(db DB) error {
 	return nil
 }
 
-func (j *join) manyQuery(db DB) (*Query, error) {
+func (j *join) manyQuery(db DB, schema string) (*Query, error) {
 	root := j.JoinModel.Root()
 	index := j.JoinModel.ParentIndex()
 
 	manyModel := newManyModel(j)
-	q := NewQuery(db, manyModel)
+	q := NewQuery(db, manyModel).Schema(schema)
 	if j.ApplyQuery != nil {
 		var err error
 		q, err = j.ApplyQuery(q)
@@ -53,10 +53,7 @@ func (j *join) manyQuery(db DB) (*Query, error) {
 	q.columns = append(q.columns, hasManyColumnsAppender{j})
 
 	baseTable := j.BaseModel.Table()
-	cols := columns(j.JoinModel.Table().Alias, "", j.Rel.FKs)
 	vals := values(root, index, baseTable.PKs)
-	q = q.Where(`(?) IN (?)`, types.Q(cols), types.Q(vals))
-
 	if j.Rel.Polymorphic {
 		q = q.Where(
 			`? IN (?, ?)`,
@@ -65,11 +62,49 @@ func (j *join) manyQuery(db DB) (*Query, error) {
 		)
 	}
 
+	joinAlias := j.JoinModel.Table().Alias
+	if perParent(q) {
+		for _, fk := range j.Rel.FKs {
+			q = q.Where("?.? = ?.?", joinAlias, fk.ColName, lateralAlias, fk.ColName)
+		}
+		return lateralQuery(q, columns("", "", j.Rel.FKs), vals, joinAlias), nil
+	}
+
+	cols := columns(joinAlias, "", j.Rel.FKs)
+	q = q.Where(`(?) IN (?)`, types.Q(cols), types.Q(vals))
+
 	return q, nil
 }
 
-func (j *join) selectM2M(db DB) error {
-	q, err := j.m2mQuery(db)
+// lateralAlias is the alias of the parent keys in the LATERAL query.
+const lateralAlias = types.Q(`"_parent"`)
+
+// perParent reports whether the relation query limits the rows of
+// every parent model.
+func perParent(q *Query) bool {
+	return q.perParent && (q.limit != 0 || q.offset != 0)
+}
+
+// lateralQuery wraps the relation query, which selects the rows of the
+// parent with the keys in the lateralAlias table, so it is executed for
+// every parent, e.g.
+//
+//    SELECT "comment".* FROM (SELECT DISTINCT * FROM (VALUES (1), (2)) AS v) AS "_parent" ("post_id"),
+//    LATERAL (SELECT ... WHERE "comment"."post_id" = "_parent"."post_id" LIMIT 5) AS "comment"
+func lateralQuery(q *Query, keys, vals []byte, alias types.Q) *Query {
+	lq := q.New()
+	lq.schema = q.schema
+	lq = lq.ColumnExpr("?.*", alias).
+		TableExpr(
+			"(SELECT DISTINCT * FROM (VALUES ?) AS v) AS ? (?)",
+			types.Q(vals), lateralAlias, types.Q(keys),
+		).
+		TableExpr("LATERAL (?) AS ?", q, alias)
+	return lq
+}
+
+func (j *join) selectM2M(db DB, schema string) error {
+	q, err := j.m2mQuery(db, schema)
 	if err != nil {
 		return err
 	}
@@ -82,7 +117,7 @@ func (j *join) selectM2M(db DB) error {
 	return nil
 }
 
-func (j *join) m2mQuery(db DB) (*Query, error) {
+func (j *join) m2mQuery(db DB, schema string) (*Query, error) {
 	index := j.JoinModel.ParentIndex()
 
 	baseTable := j.BaseModel.Table()
@@ -90,7 +125,7 @@ func (j *join) m2mQuery(db DB) (*Query, error) {
 	m2mVals := values(j.BaseModel.Root(), index, baseTable.PKs)
 
 	m2mModel := newM2MModel(j)
-	q := NewQuery(db, m2mModel)
+	q := NewQuery(db, m2mModel).Schema(schema)
 	if j.ApplyQuery != nil {
 		var err error
 		q, err = j.ApplyQuery(q)
@@ -100,11 +135,17 @@ func (j *join) m2mQuery(db DB) (*Query, error) {
 	}
 
 	q.columns = append(q.columns, hasManyColumnsAppender{j})
-	q = q.Join(
-		"JOIN ? ON (?) IN (?)",
-		j.Rel.M2MTableName,
-		types.Q(m2mCols), types.Q(m2mVals),
-	)
+	m2mTable := types.Q(q.appendSchemaTable(nil, j.Rel.M2MTableName))
+	lateral := perParent(q)
+	if lateral {
+		q = q.Join("JOIN ? ON ?", m2mTable, types.Q(m2mLateralCond(j, baseTable)))
+	} else {
+		q = q.Join(
+			"JOIN ? ON (?) IN (?)",
+			m2mTable,
+			types.Q(m2mCols), types.Q(m2mVals),
+		)
+	}
 
 	joinAlias := j.JoinModel.Table().Alias
 	for _, pk := range j.JoinModel.Table().PKs {
@@ -115,9 +156,31 @@ func (j *join) m2mQuery(db DB) (*Query, error) {
 		)
 	}
 
+	if lateral {
+		keys := columns("", j.Rel.BasePrefix, baseTable.PKs)
+		return lateralQuery(q, keys, m2mVals, joinAlias), nil
+	}
 	return q, nil
 }
 
+func m2mLateralCond(j *join, baseTable *Table) []byte {
+	var b []byte
+	for i, pk := range baseTable.PKs {
+		if i > 0 {
+			b = append(b, " AND "...)
+		}
+		col := types.AppendField(nil, j.Rel.BasePrefix+pk.SQLName, 1)
+		b = append(b, j.Rel.M2MTableName...)
+		b = append(b, '.')
+		b = append(b, col...)
+		b = append(b, " = "...)
+		b = append(b, lateralAlias...)
+		b = append(b, '.')
+		b = append(b, col...)
+	}
+	return b
+}
+
 func (j *join) hasParent() bool {
 	if j.Parent != nil {
 		switch j.Parent.Rel.Type {
@@ -195,9 +258,9 @@ func (j *join) appendHasOneColumns(b []byte) []byte {
 	return b
 }
 
-func (j *join) appendHasOneJoin(b []byte) []byte {
+func (j *join) appendHasOneJoin(q *Query, b []byte) []byte {
 	b = append(b, "LEFT JOIN "...)
-	b = append(b, j.JoinModel.Table().Name...)
+	b = q.appendSchemaTable(b, j.JoinModel.Table().Name)
 	b = append(b, " AS "...)
 	b = j.appendAlias(b)
 
@@ -53,6 +53,11 @@ func NewModel(values ...interface{}) (Model, error) {
 		return v0, nil
 	case sql.Scanner:
 		return Scan(v0), nil
+	case arenaValues:
+		return newArenaModel(v0)
+	}
+	if m := newScalarSliceModel(v0); m != nil {
+		return m, nil
 	}
 
 	v := reflect.ValueOf(v0)
@@ -67,8 +72,20 @@ func NewModel(values ...interface{}) (Model, error) {
 	switch v.Kind() {
 	case reflect.Struct:
 		return newStructTableModel(v)
+	case reflect.Map:
+		if v.Type() == mapType {
+			return &mapModel{
+				m: v,
+			}, nil
+		}
 	case reflect.Slice:
 		typ := v.Type()
+		if typ.Elem() == mapType {
+			return &mapSliceModel{
+				slice: v,
+			}, nil
+		}
+
 		structType := indirectType(typ.Elem())
 		if structType.Kind() == reflect.Struct && structType != timeType {
 			m := sliceTableModel{
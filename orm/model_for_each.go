@@ -0,0 +1,81 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+type forEachModel struct {
+	hookStubs
+	model *structTableModel
+	fn    reflect.Value
+	err   error
+
+	stickyErr error
+}
+
+var _ Model = (*forEachModel)(nil)
+
+// ForEach returns a Model that scans every row into the same struct and
+// passes it to fn, which must have the form func(*Struct) error. It allows
+// processing large result sets using constant memory. When fn returns an
+// error it is not called for remaining rows and the error is returned by
+// the query. When fn has another form, the query returns an error.
+func ForEach(fn interface{}) Model {
+	v := reflect.ValueOf(fn)
+	if !isForEachFunc(v) {
+		return &forEachModel{
+			stickyErr: fmt.Errorf("pg: ForEach(unsupported %T)", fn),
+		}
+	}
+
+	strct := reflect.New(v.Type().In(0).Elem()).Elem()
+	return &forEachModel{
+		model: &structTableModel{
+			table: Tables.Get(strct.Type()),
+			root:  strct,
+			strct: strct,
+		},
+		fn: v,
+	}
+}
+
+func isForEachFunc(v reflect.Value) bool {
+	if v.Kind() != reflect.Func {
+		return false
+	}
+	typ := v.Type()
+	return typ.NumIn() == 1 && typ.NumOut() == 1 &&
+		typ.In(0).Kind() == reflect.Ptr &&
+		typ.In(0).Elem().Kind() == reflect.Struct &&
+		typ.Out(0) == errorType
+}
+
+func (m *forEachModel) Reset() error {
+	m.err = nil
+	return m.stickyErr
+}
+
+func (m *forEachModel) NewModel() ColumnScanner {
+	m.model.strct.Set(m.model.table.zeroStruct)
+	return m.model.NewModel()
+}
+
+func (m *forEachModel) AddModel(_ ColumnScanner) error {
+	if m.err != nil {
+		return nil
+	}
+
+	out := m.fn.Call([]reflect.Value{m.model.strct.Addr()})
+	if err, ok := out[0].Interface().(error); ok {
+		m.err = err
+		return err
+	}
+	return nil
+}
+
+func (m *forEachModel) ScanColumn(colIdx int, colName string, b []byte) error {
+	return m.model.ScanColumn(colIdx, colName, b)
+}
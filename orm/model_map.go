@@ -0,0 +1,143 @@
+package orm
+
+import (
+	"reflect"
+	"time"
+
+	"gopkg.in/pg.v5/types"
+)
+
+// PostgreSQL data type OIDs.
+const (
+	pgBool        = 16
+	pgBytea       = 17
+	pgInt8        = 20
+	pgInt2        = 21
+	pgInt4        = 23
+	pgOID         = 26
+	pgJSON        = 114
+	pgFloat4      = 700
+	pgFloat8      = 701
+	pgDate        = 1082
+	pgTimestamp   = 1114
+	pgTimestamptz = 1184
+	pgInterval    = 1186
+	pgJSONB       = 3802
+)
+
+var mapType = reflect.TypeOf(map[string]interface{}(nil))
+
+type mapModel struct {
+	hookStubs
+	m reflect.Value
+}
+
+var _ Model = (*mapModel)(nil)
+var _ ColumnTypeScanner = (*mapModel)(nil)
+
+func (mapModel) useQueryOne() bool {
+	return true
+}
+
+func (m *mapModel) Reset() error {
+	m.m.Set(reflect.MakeMap(mapType))
+	return nil
+}
+
+func (m *mapModel) NewModel() ColumnScanner {
+	return m
+}
+
+func (mapModel) AddModel(_ ColumnScanner) error {
+	return nil
+}
+
+func (m *mapModel) ScanColumn(colIdx int, colName string, b []byte) error {
+	return m.ScanColumnType(colIdx, colName, 0, b)
+}
+
+func (m *mapModel) ScanColumnType(colIdx int, colName string, dataType int32, b []byte) error {
+	return scanMapColumn(m.m, colName, dataType, b)
+}
+
+type mapSliceModel struct {
+	hookStubs
+	slice reflect.Value
+	m     reflect.Value
+}
+
+var _ Model = (*mapSliceModel)(nil)
+var _ ColumnTypeScanner = (*mapSliceModel)(nil)
+
+func (m *mapSliceModel) Reset() error {
+	if m.slice.IsValid() && m.slice.Len() > 0 {
+		m.slice.Set(m.slice.Slice(0, 0))
+	}
+	return nil
+}
+
+func (m *mapSliceModel) NewModel() ColumnScanner {
+	m.m = reflect.MakeMap(mapType)
+	return m
+}
+
+func (m *mapSliceModel) AddModel(_ ColumnScanner) error {
+	m.slice.Set(reflect.Append(m.slice, m.m))
+	return nil
+}
+
+func (m *mapSliceModel) ScanColumn(colIdx int, colName string, b []byte) error {
+	return m.ScanColumnType(colIdx, colName, 0, b)
+}
+
+func (m *mapSliceModel) ScanColumnType(colIdx int, colName string, dataType int32, b []byte) error {
+	return scanMapColumn(m.m, colName, dataType, b)
+}
+
+func scanMapColumn(m reflect.Value, colName string, dataType int32, b []byte) error {
+	v, err := scanMapValue(dataType, b)
+	if err != nil {
+		return err
+	}
+	m.Interface().(map[string]interface{})[colName] = v
+	return nil
+}
+
+func scanMapValue(dataType int32, b []byte) (interface{}, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	switch dataType {
+	case pgBool:
+		var v bool
+		err := types.Scan(&v, b)
+		return v, err
+	case pgInt2, pgInt4, pgInt8, pgOID:
+		var v int64
+		err := types.Scan(&v, b)
+		return v, err
+	case pgFloat4, pgFloat8:
+		var v float64
+		err := types.Scan(&v, b)
+		return v, err
+	case pgBytea:
+		var v []byte
+		err := types.Scan(&v, b)
+		return v, err
+	case pgDate, pgTimestamp, pgTimestamptz:
+		var v time.Time
+		err := types.Scan(&v, b)
+		return v, err
+	case pgInterval:
+		var v types.Interval
+		err := v.Scan(b)
+		return v, err
+	case pgJSON, pgJSONB:
+		var v interface{}
+		err := types.UnmarshalJSON(b, &v)
+		return v, err
+	default:
+		return string(b), nil
+	}
+}
@@ -0,0 +1,241 @@
+package orm
+
+import (
+	"strconv"
+	"time"
+
+	"gopkg.in/pg.v5/internal"
+	"gopkg.in/pg.v5/types"
+)
+
+// newScalarSliceModel returns a model that scans a single column into
+// the slice of the basic type without reflection or nil when the type
+// of the slice is not supported.
+func newScalarSliceModel(v interface{}) Model {
+	switch v := v.(type) {
+	case *[]int64:
+		return &int64SliceModel{slice: v}
+	case *[]int:
+		return &intSliceModel{slice: v}
+	case *[]int32:
+		return &int32SliceModel{slice: v}
+	case *[]float64:
+		return &float64SliceModel{slice: v}
+	case *[]string:
+		return &stringSliceModel{slice: v}
+	case *[]bool:
+		return &boolSliceModel{slice: v}
+	case *[]time.Time:
+		return &timeSliceModel{slice: v}
+	}
+	return nil
+}
+
+type scalarSliceModel struct {
+	hookStubs
+}
+
+func (scalarSliceModel) AddModel(_ ColumnScanner) error {
+	return nil
+}
+
+func parseInt(b []byte, bitSize int) (int64, error) {
+	if b == nil {
+		return 0, nil
+	}
+	return strconv.ParseInt(internal.BytesToString(b), 10, bitSize)
+}
+
+//------------------------------------------------------------------------------
+
+type int64SliceModel struct {
+	scalarSliceModel
+	slice *[]int64
+}
+
+var _ Model = (*int64SliceModel)(nil)
+
+func (m *int64SliceModel) Reset() error {
+	*m.slice = (*m.slice)[:0]
+	return nil
+}
+
+func (m *int64SliceModel) NewModel() ColumnScanner {
+	return m
+}
+
+func (m *int64SliceModel) ScanColumn(colIdx int, _ string, b []byte) error {
+	n, err := parseInt(b, 64)
+	if err != nil {
+		return err
+	}
+	*m.slice = append(*m.slice, n)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type intSliceModel struct {
+	scalarSliceModel
+	slice *[]int
+}
+
+var _ Model = (*intSliceModel)(nil)
+
+func (m *intSliceModel) Reset() error {
+	*m.slice = (*m.slice)[:0]
+	return nil
+}
+
+func (m *intSliceModel) NewModel() ColumnScanner {
+	return m
+}
+
+func (m *intSliceModel) ScanColumn(colIdx int, _ string, b []byte) error {
+	n, err := parseInt(b, 0)
+	if err != nil {
+		return err
+	}
+	*m.slice = append(*m.slice, int(n))
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type int32SliceModel struct {
+	scalarSliceModel
+	slice *[]int32
+}
+
+var _ Model = (*int32SliceModel)(nil)
+
+func (m *int32SliceModel) Reset() error {
+	*m.slice = (*m.slice)[:0]
+	return nil
+}
+
+func (m *int32SliceModel) NewModel() ColumnScanner {
+	return m
+}
+
+func (m *int32SliceModel) ScanColumn(colIdx int, _ string, b []byte) error {
+	n, err := parseInt(b, 32)
+	if err != nil {
+		return err
+	}
+	*m.slice = append(*m.slice, int32(n))
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type float64SliceModel struct {
+	scalarSliceModel
+	slice *[]float64
+}
+
+var _ Model = (*float64SliceModel)(nil)
+
+func (m *float64SliceModel) Reset() error {
+	*m.slice = (*m.slice)[:0]
+	return nil
+}
+
+func (m *float64SliceModel) NewModel() ColumnScanner {
+	return m
+}
+
+func (m *float64SliceModel) ScanColumn(colIdx int, _ string, b []byte) error {
+	var n float64
+	if b != nil {
+		var err error
+		n, err = strconv.ParseFloat(internal.BytesToString(b), 64)
+		if err != nil {
+			return err
+		}
+	}
+	*m.slice = append(*m.slice, n)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type stringSliceModel struct {
+	scalarSliceModel
+	slice *[]string
+	arena *types.Arena
+}
+
+var _ Model = (*stringSliceModel)(nil)
+
+func (m *stringSliceModel) Reset() error {
+	*m.slice = (*m.slice)[:0]
+	return nil
+}
+
+func (m *stringSliceModel) NewModel() ColumnScanner {
+	return m
+}
+
+func (m *stringSliceModel) ScanColumn(colIdx int, _ string, b []byte) error {
+	if m.arena != nil {
+		*m.slice = append(*m.slice, m.arena.String(b))
+	} else {
+		*m.slice = append(*m.slice, string(b))
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type boolSliceModel struct {
+	scalarSliceModel
+	slice *[]bool
+}
+
+var _ Model = (*boolSliceModel)(nil)
+
+func (m *boolSliceModel) Reset() error {
+	*m.slice = (*m.slice)[:0]
+	return nil
+}
+
+func (m *boolSliceModel) NewModel() ColumnScanner {
+	return m
+}
+
+func (m *boolSliceModel) ScanColumn(colIdx int, _ string, b []byte) error {
+	*m.slice = append(*m.slice, len(b) == 1 && (b[0] == 't' || b[0] == '1'))
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type timeSliceModel struct {
+	scalarSliceModel
+	slice *[]time.Time
+}
+
+var _ Model = (*timeSliceModel)(nil)
+
+func (m *timeSliceModel) Reset() error {
+	*m.slice = (*m.slice)[:0]
+	return nil
+}
+
+func (m *timeSliceModel) NewModel() ColumnScanner {
+	return m
+}
+
+func (m *timeSliceModel) ScanColumn(colIdx int, _ string, b []byte) error {
+	var tm time.Time
+	if b != nil {
+		var err error
+		tm, err = types.ParseTime(b)
+		if err != nil {
+			return err
+		}
+	}
+	*m.slice = append(*m.slice, tm)
+	return nil
+}
@@ -0,0 +1,63 @@
+package orm_test
+
+import (
+	"time"
+
+	"gopkg.in/pg.v5/orm"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("scalar slice model", func() {
+	scan := func(v interface{}, values ...[]byte) error {
+		m, err := orm.NewModel(v)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Reset()).NotTo(HaveOccurred())
+		for _, b := range values {
+			row := m.NewModel()
+			if err := row.ScanColumn(0, "", b); err != nil {
+				return err
+			}
+			if err := m.AddModel(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	It("scans ints", func() {
+		ids := []int64{100}
+		Expect(scan(&ids, []byte("1"), nil, []byte("-3"))).NotTo(HaveOccurred())
+		Expect(ids).To(Equal([]int64{1, 0, -3}))
+
+		var ints []int
+		Expect(scan(&ints, []byte("1"), []byte("2"))).NotTo(HaveOccurred())
+		Expect(ints).To(Equal([]int{1, 2}))
+
+		var int32s []int32
+		Expect(scan(&int32s, []byte("4294967296"))).To(HaveOccurred())
+	})
+
+	It("scans strings, floats and bools", func() {
+		var strs []string
+		Expect(scan(&strs, []byte("foo"), nil)).NotTo(HaveOccurred())
+		Expect(strs).To(Equal([]string{"foo", ""}))
+
+		var floats []float64
+		Expect(scan(&floats, []byte("1.5"), nil)).NotTo(HaveOccurred())
+		Expect(floats).To(Equal([]float64{1.5, 0}))
+
+		var bools []bool
+		Expect(scan(&bools, []byte("t"), []byte("f"), nil)).NotTo(HaveOccurred())
+		Expect(bools).To(Equal([]bool{true, false, false}))
+	})
+
+	It("scans times", func() {
+		var tms []time.Time
+		Expect(scan(&tms, []byte("2001-02-03 04:05:06+00"), nil)).NotTo(HaveOccurred())
+		Expect(tms).To(HaveLen(2))
+		Expect(tms[0].Equal(time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC))).To(BeTrue())
+		Expect(tms[1].IsZero()).To(BeTrue())
+	})
+})
@@ -27,6 +27,21 @@ type tableModel interface {
 	scanColumn(int, string, []byte) (bool, error)
 }
 
+// TableModel returns the model of the struct or the slice of structs.
+// When it is the last param of the query, the model is used to format
+// placeholders like ?TableName, ?Columns and ?PKs, e.g.
+//
+//    f.FormatQuery(b, "SELECT ?Columns FROM ?TableName WHERE ?PKs = ?", id, model)
+//
+// It returns false for other values.
+func TableModel(v interface{}) (Model, bool) {
+	m, err := newTableModel(v)
+	if err != nil {
+		return nil, false
+	}
+	return m, true
+}
+
 func newTableModel(v interface{}) (tableModel, error) {
 	switch v := v.(type) {
 	case tableModel:
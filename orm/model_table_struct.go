@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+
+	"gopkg.in/pg.v5/types"
 )
 
 type structTableModel struct {
@@ -16,6 +18,8 @@ type structTableModel struct {
 	index []int
 
 	strct reflect.Value
+
+	arena *types.Arena
 }
 
 var _ tableModel = (*structTableModel)(nil)
@@ -60,16 +64,34 @@ func (m *structTableModel) Relation() *Relation {
 	return m.rel
 }
 
+// AppendParam appends the value of the field or the method of the
+// struct or the placeholder describing the table: ?TableName,
+// ?TableAlias, ?TableColumns (columns prefixed with the alias),
+// ?Columns and ?PKs.
 func (m *structTableModel) AppendParam(dst []byte, name string) ([]byte, bool) {
-	dst, ok := m.table.AppendParam(dst, m.strct, name)
-	if ok {
-		return dst, true
+	if m.strct.IsValid() {
+		dst, ok := m.table.AppendParam(dst, m.strct, name)
+		if ok {
+			return dst, true
+		}
 	}
 
 	switch name {
+	case "TableName":
+		dst = append(dst, m.table.Name...)
+		return dst, true
 	case "TableAlias":
 		dst = append(dst, m.table.Alias...)
 		return dst, true
+	case "TableColumns":
+		dst = append(dst, columns(m.table.Alias, "", m.table.Fields)...)
+		return dst, true
+	case "Columns":
+		dst = append(dst, columns("", "", m.table.Fields)...)
+		return dst, true
+	case "PKs":
+		dst = append(dst, columns("", "", m.table.PKs)...)
+		return dst, true
 	}
 
 	return dst, false
@@ -217,7 +239,7 @@ func (m *structTableModel) scanColumn(colIdx int, colName string, b []byte) (boo
 	}
 
 	m.initStruct(false)
-	return true, field.ScanValue(m.strct, b)
+	return true, field.ScanValueArena(m.strct, b, m.arena)
 }
 
 func (m *structTableModel) GetJoin(name string) *join {
@@ -0,0 +1,150 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+
+	"gopkg.in/pg.v5/types"
+)
+
+type namedParams interface {
+	AppendParam(dst []byte, name string) ([]byte, bool)
+}
+
+type namedQuery struct {
+	query  string
+	params namedParams
+	err    error
+}
+
+var _ FormatAppender = (*namedQuery)(nil)
+
+// Named returns the query with :name placeholders that are replaced
+// with the fields of the struct or the values of the map with string
+// keys, e.g.
+//
+//    Named("SELECT * FROM users WHERE id = :id AND status = :status", user)
+//
+// Struct fields are referenced by SQL names. Placeholders without
+// a value, type casts (::) and placeholders in string literals, quoted
+// identifiers and comments are left as is.
+func Named(query string, arg interface{}) FormatAppender {
+	params, err := newNamedParams(arg)
+	return namedQuery{
+		query:  query,
+		params: params,
+		err:    err,
+	}
+}
+
+func newNamedParams(arg interface{}) (namedParams, error) {
+	v := reflect.Indirect(reflect.ValueOf(arg))
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("pg: Named(unsupported %s)", v.Type())
+		}
+		return mapParams{v}, nil
+	case reflect.Struct:
+		return tableParams{
+			table: Tables.Get(v.Type()),
+			strct: v,
+		}, nil
+	case reflect.Invalid:
+		return nil, fmt.Errorf("pg: Named(nil)")
+	default:
+		return nil, fmt.Errorf("pg: Named(unsupported %s)", v.Type())
+	}
+}
+
+func (q namedQuery) AppendFormat(b []byte, f QueryFormatter) []byte {
+	if q.err != nil {
+		return types.AppendError(b, q.err)
+	}
+
+	s := q.query
+	var start int
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\'', '"':
+			i = skipQuoted(s, i, c)
+		case '-':
+			if i+1 < len(s) && s[i+1] == '-' {
+				i = skipUntil(s, i+2, "\n")
+			}
+		case '/':
+			if i+1 < len(s) && s[i+1] == '*' {
+				i = skipUntil(s, i+2, "*/")
+			}
+		case ':':
+			if i+1 < len(s) && s[i+1] == ':' {
+				i++
+				continue
+			}
+
+			j := i + 1
+			if j >= len(s) || !isNameStart(s[j]) {
+				continue
+			}
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+
+			name := s[i+1 : j]
+			b = f.FormatQuery(b, s[start:i])
+			if dst, ok := q.params.AppendParam(b, name); ok {
+				b = dst
+			} else {
+				b = append(b, s[i:j]...)
+			}
+			start = j
+			i = j - 1
+		}
+	}
+	return f.FormatQuery(b, s[start:])
+}
+
+// skipQuoted returns the index of the closing quote.
+func skipQuoted(s string, i int, quote byte) int {
+	for i++; i < len(s); i++ {
+		if s[i] != quote {
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == quote {
+			i++
+			continue
+		}
+		return i
+	}
+	return len(s)
+}
+
+// skipUntil returns the index of the last byte of the end.
+func skipUntil(s string, i int, end string) int {
+	for ; i+len(end) <= len(s); i++ {
+		if s[i:i+len(end)] == end {
+			return i + len(end) - 1
+		}
+	}
+	return len(s)
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
+
+type mapParams struct {
+	v reflect.Value
+}
+
+func (m mapParams) AppendParam(dst []byte, name string) ([]byte, bool) {
+	value := m.v.MapIndex(reflect.ValueOf(name).Convert(m.v.Type().Key()))
+	if !value.IsValid() {
+		return dst, false
+	}
+	return types.Append(dst, value.Interface(), 1), true
+}
@@ -0,0 +1,42 @@
+package orm_test
+
+import (
+	"testing"
+
+	"gopkg.in/pg.v5/orm"
+)
+
+type NamedParams struct {
+	Id     int
+	Status string
+}
+
+func TestNamed(t *testing.T) {
+	user := &NamedParams{Id: 1, Status: "active"}
+	var fmter orm.Formatter
+	fmter.SetParam("schema", "public")
+
+	tests := []struct {
+		q      string
+		arg    interface{}
+		wanted string
+	}{
+		{"id = :id AND status = :status", user, "id = 1 AND status = 'active'"},
+		{":id, :id", user, "1, 1"},
+		{":id::text", user, "1::text"},
+		{"':id' \":id\" -- :id\n/* :id */ :id", user, "':id' \":id\" -- :id\n/* :id */ 1"},
+		{"'it''s :id' :id", user, "'it''s :id' 1"},
+		{"arr[1:2] arr[:lo] :missing", user, "arr[1:2] arr[:lo] :missing"},
+		{"?schema.users WHERE id = :id", user, "'public'.users WHERE id = 1"},
+		{":status", map[string]interface{}{"status": "it's"}, "'it''s'"},
+		{":status", map[string]string{}, ":status"},
+		{":id", 1, "?!(pg: Named(unsupported int))"},
+		{":id", nil, "?!(pg: Named(nil))"},
+	}
+	for _, test := range tests {
+		got := orm.Named(test.q, test.arg).AppendFormat(nil, fmter)
+		if string(got) != test.wanted {
+			t.Errorf("got %q, wanted %q (q=%q)", got, test.wanted, test.q)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package orm
+
+import (
+	"gopkg.in/pg.v5/internal"
+
+	"github.com/jinzhu/inflection"
+)
+
+// NamingStrategy converts names of Go types and struct fields to names
+// of tables and columns. Names set with tags, e.g. `sql:"name"` on a
+// field or on the tableName field, are used as is.
+type NamingStrategy interface {
+	// TableName returns the table name for the struct type name.
+	TableName(typeName string) string
+	// ColumnName returns the column name for the struct field name.
+	// It is also used for prefixes of foreign keys, e.g. "Author_".
+	ColumnName(fieldName string) string
+}
+
+var (
+	// SnakeCase underscores names and pluralizes table names, e.g.
+	// BookGenre becomes book_genres. It is the default strategy.
+	SnakeCase NamingStrategy = snakeCase{plural: true}
+
+	// SnakeCaseSingular underscores names without pluralizing table
+	// names, e.g. BookGenre becomes book_genre.
+	SnakeCaseSingular NamingStrategy = snakeCase{}
+)
+
+var naming = SnakeCase
+
+// SetNamingStrategy sets the strategy that is used for the models that
+// are not used yet, so it should be called before the models are used,
+// e.g. in init. Passing nil restores SnakeCase.
+func SetNamingStrategy(s NamingStrategy) {
+	if s == nil {
+		s = SnakeCase
+	}
+	naming = s
+}
+
+type snakeCase struct {
+	plural bool
+}
+
+func (s snakeCase) TableName(typeName string) string {
+	name := internal.Underscore(typeName)
+	if s.plural {
+		name = inflection.Plural(name)
+	}
+	return name
+}
+
+func (snakeCase) ColumnName(fieldName string) string {
+	return internal.Underscore(fieldName)
+}
+
+// NamingFuncs is an adapter to use functions as NamingStrategy. Nil
+// functions fall back to SnakeCase.
+type NamingFuncs struct {
+	Table  func(typeName string) string
+	Column func(fieldName string) string
+}
+
+var _ NamingStrategy = NamingFuncs{}
+
+func (n NamingFuncs) TableName(typeName string) string {
+	if n.Table == nil {
+		return SnakeCase.TableName(typeName)
+	}
+	return n.Table(typeName)
+}
+
+func (n NamingFuncs) ColumnName(fieldName string) string {
+	if n.Column == nil {
+		return SnakeCase.ColumnName(fieldName)
+	}
+	return n.Column(fieldName)
+}
@@ -1,6 +1,10 @@
 package orm
 
-import "gopkg.in/pg.v5/types"
+import (
+	"io"
+
+	"gopkg.in/pg.v5/types"
+)
 
 // ColumnScanner is used to scan column values.
 type ColumnScanner interface {
@@ -11,6 +15,12 @@ type ColumnScanner interface {
 	ScanColumn(colIdx int, colName string, b []byte) error
 }
 
+// ColumnTypeScanner is an optional interface implemented by column
+// scanners that need column data type OID to scan the value, e.g. maps.
+type ColumnTypeScanner interface {
+	ScanColumnType(colIdx int, colName string, dataType int32, b []byte) error
+}
+
 type QueryAppender interface {
 	AppendQuery(dst []byte, params ...interface{}) ([]byte, error)
 }
@@ -19,7 +29,8 @@ type QueryFormatter interface {
 	FormatQuery(dst []byte, query string, params ...interface{}) []byte
 }
 
-// DB is a common interface for pg.DB and pg.Tx types.
+// DB is a common interface for pg.DB, pg.Tx and pg.Conn types, so code
+// can be written against DB and run both in and outside of transactions.
 type DB interface {
 	Model(model ...interface{}) *Query
 	Select(model interface{}) error
@@ -32,5 +43,8 @@ type DB interface {
 	Query(coll, query interface{}, params ...interface{}) (*types.Result, error)
 	QueryOne(model, query interface{}, params ...interface{}) (*types.Result, error)
 
+	CopyFrom(r io.Reader, query interface{}, params ...interface{}) (*types.Result, error)
+	CopyTo(w io.Writer, query interface{}, params ...interface{}) (*types.Result, error)
+
 	QueryFormatter
 }
@@ -1,8 +1,10 @@
 package orm
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -24,6 +26,7 @@ type Query struct {
 	ignoreModel bool
 
 	with       []withQuery
+	distinctOn []FormatAppender
 	tables     []FormatAppender
 	columns    []FormatAppender
 	set        []FormatAppender
@@ -36,8 +39,20 @@ type Query struct {
 	returning  []queryParamsAppender
 	limit      int
 	offset     int
+	selFor     FormatAppender
+	timeout    time.Duration
+	idempotent bool
+	perParent  bool
+	arena      *types.Arena
+	schema     string
+
+	// fmter is the formatter of the outer query when the Query is
+	// appended as a subquery.
+	fmter QueryFormatter
 }
 
+var _ FormatAppender = (*Query)(nil)
+
 func NewQuery(db DB, model ...interface{}) *Query {
 	return (&Query{}).DB(db).Model(model...)
 }
@@ -60,6 +75,7 @@ func (q *Query) Copy() *Query {
 		model:       q.model,
 		ignoreModel: q.ignoreModel,
 
+		distinctOn: q.distinctOn[:],
 		tables:     q.tables[:],
 		columns:    q.columns[:],
 		set:        q.set[:],
@@ -72,6 +88,11 @@ func (q *Query) Copy() *Query {
 		returning:  q.returning[:],
 		limit:      q.limit,
 		offset:     q.offset,
+		selFor:     q.selFor,
+		timeout:    q.timeout,
+		idempotent: q.idempotent,
+		perParent:  q.perParent,
+		schema:     q.schema,
 	}
 	for _, with := range q.with {
 		copy = copy.With(with.name, with.query.Copy())
@@ -169,16 +190,68 @@ func (q *Query) ColumnExpr(expr string, params ...interface{}) *Query {
 	return q
 }
 
+// ColumnSet adds columns from the named column set to the Query.
+// Column sets are declared using the set tag on model fields, e.g.
+//
+//    Name string `pg:",set:summary|full"`
+//    Bio  string `pg:",set:full"`
+//
+// Insert uses only the columns from column sets and Update uses only
+// the columns specified with Column or ColumnSet when any are present.
+func (q *Query) ColumnSet(name string) *Query {
+	if q.model == nil {
+		return q.err(errors.New("pg: Model(nil)"))
+	}
+
+	fields, err := q.model.Table().GetColumnSet(name)
+	if err != nil {
+		return q.err(err)
+	}
+
+	for _, f := range fields {
+		q.columns = append(q.columns, columnSetAppender{fieldAppender{f.SQLName}})
+	}
+	return q
+}
+
 func (q *Query) getFields() []string {
 	var fields []string
 	for _, col := range q.columns {
-		if f, ok := col.(fieldAppender); ok {
-			fields = append(fields, f.field)
+		switch col := col.(type) {
+		case fieldAppender:
+			fields = append(fields, col.field)
+		case columnSetAppender:
+			fields = append(fields, col.field)
+		}
+	}
+	return fields
+}
+
+// getColumnSetFields returns the fields added with ColumnSet.
+func (q *Query) getColumnSetFields() []string {
+	var fields []string
+	for _, col := range q.columns {
+		if col, ok := col.(columnSetAppender); ok {
+			fields = append(fields, col.field)
 		}
 	}
 	return fields
 }
 
+// Relation selects the model relation with the name, e.g. "Author" or
+// "Author.Country" for nested relations. Has one and belongs to
+// relations are loaded in the same query with LEFT JOIN selecting the
+// columns of the relation with the prefix, e.g. "author__name", which
+// are scanned into the nested struct. Has many and many to many
+// relations are loaded with a separate query per relation, which can
+// be customized with the apply func, e.g.
+//
+//    Relation("Comments", func(q *orm.Query) (*orm.Query, error) {
+//        return q.Order("created_at DESC").Limit(5).PerParent(), nil
+//    })
+//
+// Limit and Offset set by the apply func apply to the rows of all
+// parent models unless PerParent is used.
 func (q *Query) Relation(name string, apply func(*Query) (*Query, error)) *Query {
 	if _, j := q.model.Join(name, apply); j == nil {
 		return q.err(fmt.Errorf(
@@ -189,21 +262,75 @@ func (q *Query) Relation(name string, apply func(*Query) (*Query, error)) *Query
 	return q
 }
 
+// Set adds the SET expression, e.g. Set("name = ?name"), so Update
+// changes only the specified columns. Model fields can be referenced
+// with ?field_name placeholders.
 func (q *Query) Set(set string, params ...interface{}) *Query {
 	q.set = append(q.set, queryParamsAppender{set, params})
 	return q
 }
 
+// Where adds the condition joined with AND, e.g.
+// Where("id = ?", 1).
 func (q *Query) Where(where string, params ...interface{}) *Query {
 	q.where = append(q.where, &whereAppender{"AND", where, params})
 	return q
 }
 
+// WhereOr adds the condition joined with OR.
 func (q *Query) WhereOr(where string, params ...interface{}) *Query {
 	q.where = append(q.where, &whereAppender{"OR", where, params})
 	return q
 }
 
+// WherePK adds the condition on the primary keys of the model, which
+// can be a struct or a slice of structs.
+func (q *Query) WherePK() *Query {
+	if q.model == nil {
+		return q.err(errors.New("pg: Model(nil)"))
+	}
+	if err := q.model.Table().checkPKs(); err != nil {
+		return q.err(err)
+	}
+	q.where = append(q.where, wherePKQuery{q})
+	return q
+}
+
+// WhereGroup adds the conditions added by the fn in parentheses joined
+// with AND, e.g.
+//
+//    q.Where("active").WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+//        return q.Where("role = ?", "admin").WhereOr("role = ?", "owner"), nil
+//    })
+//
+// produces WHERE (active) AND ((role = 'admin') OR (role = 'owner')).
+func (q *Query) WhereGroup(fn func(*Query) (*Query, error)) *Query {
+	return q.whereGroup("AND", fn)
+}
+
+// WhereOrGroup is like WhereGroup, but joins the group with OR.
+func (q *Query) WhereOrGroup(fn func(*Query) (*Query, error)) *Query {
+	return q.whereGroup("OR", fn)
+}
+
+func (q *Query) whereGroup(conj string, fn func(*Query) (*Query, error)) *Query {
+	saved := q.where
+	q.where = nil
+
+	qq, err := fn(q)
+	if err != nil {
+		q.where = saved
+		return q.err(err)
+	}
+
+	group := qq.where
+	q.where = saved
+	if len(group) > 0 {
+		q.where = append(q.where, whereGroupAppender{conj, group})
+	}
+	return q
+}
+
 // WhereIn is a shortcut for Where and pg.In to work with IN operator:
 //
 //    WhereIn("id IN (?)", 1, 2, 3)
@@ -216,6 +343,22 @@ func (q *Query) Join(join string, params ...interface{}) *Query {
 	return q
 }
 
+// Distinct adds DISTINCT clause to the Query.
+func (q *Query) Distinct() *Query {
+	if q.distinctOn == nil {
+		q.distinctOn = make([]FormatAppender, 0)
+	}
+	return q
+}
+
+// DistinctOn adds DISTINCT ON (expr) clause to the Query, e.g.
+//
+//    DistinctOn("?TableAlias.author_id")
+func (q *Query) DistinctOn(expr string, params ...interface{}) *Query {
+	q.distinctOn = append(q.distinctOn, queryParamsAppender{expr, params})
+	return q
+}
+
 func (q *Query) Group(columns ...string) *Query {
 	for _, column := range columns {
 		q.group = append(q.group, fieldAppender{column})
@@ -274,16 +417,118 @@ func (q *Query) Offset(n int) *Query {
 	return q
 }
 
+// PerParent makes Limit and Offset of the has many or many to many
+// relation query, which is customized with the Relation apply func,
+// apply to the rows of every parent model, e.g. up to 5 comments are
+// loaded per post, using LATERAL subquery.
+func (q *Query) PerParent() *Query {
+	q.perParent = true
+	return q
+}
+
+// Timeout sets the timeout of the statements run by the Query, but not
+// of prepared statements. When the timeout is reached, the client
+// sends a cancel request to the server, so the statement fails with
+// the query_canceled error, but unlike ReadTimeout the connection
+// remains usable. If the statement completes before the cancel request
+// is processed, the connection is closed instead of being returned to
+// the pool, because the request could cancel the next statement; the
+// next statement run by pg.Tx or pg.Conn on the same connection may
+// still be canceled.
+func (q *Query) Timeout(d time.Duration) *Query {
+	q.timeout = d
+	return q
+}
+
+// StatementTimeout returns the timeout set with Timeout.
+func (q *Query) StatementTimeout() time.Duration {
+	return q.timeout
+}
+
+// Idempotent marks the statements run by the Query as safe to execute
+// more than once, so they are retried even when they fail after being
+// sent to the server and may have been applied, e.g. when the
+// connection is lost while waiting for the response.
+func (q *Query) Idempotent() *Query {
+	q.idempotent = true
+	return q
+}
+
+// Arena makes Select allocate string and []byte values of the model
+// fields in the arena. The arena is not used by other methods and is
+// not copied by Copy, e.g.
+//
+//    var arena types.Arena
+//    defer arena.Release()
+//    err := db.Model(&events).Arena(&arena).Select()
+func (q *Query) Arena(arena *types.Arena) *Query {
+	q.arena = arena
+	return q
+}
+
+// IsIdempotent reports whether the Query is marked with Idempotent.
+func (q *Query) IsIdempotent() bool {
+	return q.idempotent
+}
+
+const (
+	pagerMaxLimit  = 1000
+	pagerMaxOffset = 1000000
+)
+
+// Pager sets LIMIT and OFFSET like Limit and Offset, but also checks
+// that they don't exceed 1000 and 1000000 respectively so values that
+// come from API clients can be used as is. Zero values are ignored.
+func (q *Query) Pager(limit, offset int) *Query {
+	if limit > pagerMaxLimit {
+		return q.err(fmt.Errorf("pg: limit=%d is bigger than %d", limit, pagerMaxLimit))
+	}
+	if offset > pagerMaxOffset {
+		return q.err(fmt.Errorf("pg: offset=%d can't be bigger than %d", offset, pagerMaxOffset))
+	}
+	if limit > 0 {
+		q.limit = limit
+	}
+	if offset > 0 {
+		q.offset = offset
+	}
+	return q
+}
+
+// For sets locking clause for the select query, e.g.
+//
+//    For("UPDATE")
+//    For("NO KEY UPDATE OF ?TableAlias SKIP LOCKED")
+//    For("SHARE NOWAIT")
+func (q *Query) For(s string, params ...interface{}) *Query {
+	q.selFor = queryParamsAppender{s, params}
+	return q
+}
+
 func (q *Query) OnConflict(s string, params ...interface{}) *Query {
 	q.onConflict = queryParamsAppender{s, params}
 	return q
 }
 
+// Returning adds the RETURNING clause to Insert, Update and Delete, e.g.
+// Returning("*") or Returning("id, created_at"). The returned columns
+// are scanned into the model. It replaces the columns that are returned
+// by default, e.g. columns of empty fields that are inserted as DEFAULT.
 func (q *Query) Returning(s string, params ...interface{}) *Query {
 	q.returning = append(q.returning, queryParamsAppender{s, params})
 	return q
 }
 
+// Schema qualifies the tables of the model and its relations with the
+// schema, e.g. Schema("tenant_42") selects from "tenant_42"."users".
+// Table names that are already qualified, e.g. `sql:"public.users"`,
+// are not changed and ?schema placeholder in the table names, e.g.
+// `sql:"?schema.users"`, is replaced with the schema.
+func (q *Query) Schema(name string) *Query {
+	q.schema = name
+	return q
+}
+
 // Apply calls the fn passing the Query as an argument.
 func (q *Query) Apply(fn func(*Query) (*Query, error)) *Query {
 	qq, err := fn(q)
@@ -310,7 +555,7 @@ func (q *Query) Count() (int, error) {
 }
 
 func (q *Query) countQuery() *Query {
-	if len(q.group) > 0 {
+	if len(q.group) > 0 || q.distinctOn != nil {
 		return q.Copy().WrapWith("wrapper").Table("wrapper")
 	}
 	return q
@@ -347,6 +592,14 @@ func (q *Query) Select(values ...interface{}) error {
 		return err
 	}
 
+	// The arena is set only while the rows are scanned, so values
+	// scanned into the model later are not allocated in the arena that
+	// may have been released.
+	if q.arena != nil {
+		setArena(model, q.arena)
+		defer setArena(model, nil)
+	}
+
 	res, err := q.query(model, selectQuery{Query: q})
 	if err != nil {
 		return err
@@ -354,7 +607,7 @@ func (q *Query) Select(values ...interface{}) error {
 
 	if res.RowsReturned() > 0 {
 		if q.model != nil {
-			if err := selectJoins(q.db, q.model.GetJoins()); err != nil {
+			if err := selectJoins(q.db, q.schema, q.model.GetJoins()); err != nil {
 				return err
 			}
 		}
@@ -390,28 +643,28 @@ func (q *Query) SelectAndCount(values ...interface{}) (count int, err error) {
 	var wg sync.WaitGroup
 	wg.Add(2)
 
+	var selectErr, countErr error
+
 	go func() {
 		defer wg.Done()
-		if e := q.Select(values...); e != nil {
-			err = e
-		}
+		selectErr = q.Select(values...)
 	}()
 
 	go func() {
 		defer wg.Done()
-		var e error
-		count, e = q.Count()
-		if e != nil {
-			err = e
-		}
+		count, countErr = q.Count()
 	}()
 
 	wg.Wait()
-	return count, err
+
+	if selectErr != nil {
+		return count, selectErr
+	}
+	return count, countErr
 }
 
 func (q *Query) forEachHasOneJoin(fn func(*join)) {
-	if q.model == nil {
+	if !q.hasModel() {
 		return
 	}
 	q._forEachHasOneJoin(fn, q.model.GetJoins())
@@ -428,14 +681,14 @@ func (q *Query) _forEachHasOneJoin(fn func(*join), joins []join) {
 	}
 }
 
-func selectJoins(db DB, joins []join) error {
+func selectJoins(db DB, schema string, joins []join) error {
 	var err error
 	for i := range joins {
 		j := &joins[i]
 		if j.Rel.Type == HasOneRelation || j.Rel.Type == BelongsToRelation {
-			err = selectJoins(db, j.JoinModel.GetJoins())
+			err = selectJoins(db, schema, j.JoinModel.GetJoins())
 		} else {
-			err = j.Select(db)
+			err = j.Select(db, schema)
 		}
 		if err != nil {
 			return err
@@ -461,6 +714,10 @@ func (q *Query) Insert(values ...interface{}) (*types.Result, error) {
 		}
 	}
 
+	if q.model != nil {
+		setTimestamps(q.model.Table(), q.model.Value(), true)
+	}
+
 	res, err := q.db.Query(model, insertQuery{Query: q}, q.model)
 	if err != nil {
 		return nil, err
@@ -521,8 +778,19 @@ func (q *Query) SelectOrInsert(values ...interface{}) (inserted bool, err error)
 	return false, err
 }
 
-// Update updates the model.
+// Update updates the model. Only the columns specified with Set,
+// Column or ColumnSet are updated when any are present.
 func (q *Query) Update(values ...interface{}) (*types.Result, error) {
+	return q.update(values, false)
+}
+
+// UpdateNotNull updates the model omitting columns of fields with zero
+// values unless the field is marked with notnull tag.
+func (q *Query) UpdateNotNull(values ...interface{}) (*types.Result, error) {
+	return q.update(values, true)
+}
+
+func (q *Query) update(values []interface{}, omitEmpty bool) (*types.Result, error) {
 	if q.stickyErr != nil {
 		return nil, q.stickyErr
 	}
@@ -538,7 +806,11 @@ func (q *Query) Update(values ...interface{}) (*types.Result, error) {
 		}
 	}
 
-	res, err := q.db.Query(model, updateQuery{q}, q.model)
+	if q.model != nil {
+		setTimestamps(q.model.Table(), q.model.Value(), false)
+	}
+
+	res, err := q.db.Query(model, updateQuery{Query: q, omitEmpty: omitEmpty}, q.model)
 	if err != nil {
 		return nil, err
 	}
@@ -580,12 +852,32 @@ func (q *Query) Delete() (*types.Result, error) {
 
 func (q *Query) FormatQuery(dst []byte, query string, params ...interface{}) []byte {
 	params = append(params, q.model)
+	if q.fmter != nil {
+		return q.fmter.FormatQuery(dst, query, params...)
+	}
 	if q.db != nil {
 		return q.db.FormatQuery(dst, query, params...)
 	}
 	return Formatter{}.Append(dst, query, params...)
 }
 
+// AppendFormat implements FormatAppender so the Query can be used as a
+// parameter, e.g. Where("id IN (?)", subq) or TableExpr("(?) AS t", subq).
+// The subquery is formatted with f, so it uses the params of the outer
+// query.
+func (q *Query) AppendFormat(b []byte, f QueryFormatter) []byte {
+	if q.stickyErr != nil {
+		return types.AppendError(b, q.stickyErr)
+	}
+	subq := q.Copy()
+	subq.fmter = f
+	bb, err := selectQuery{Query: subq}.AppendQuery(b)
+	if err != nil {
+		return types.AppendError(b, err)
+	}
+	return bb
+}
+
 func (q *Query) hasModel() bool {
 	return !q.ignoreModel && q.model != nil
 }
@@ -595,7 +887,30 @@ func (q *Query) hasTables() bool {
 }
 
 func (q *Query) appendTableName(b []byte) []byte {
-	return q.FormatQuery(b, string(q.model.Table().Name))
+	return q.appendSchemaTable(b, q.model.Table().Name)
+}
+
+// appendSchemaTable appends the table name qualifying it with the schema
+// set by Schema unless the name is already qualified. The ?schema
+// placeholder is replaced with the schema too. When no schema is set and
+// the formatter has no schema param, the ?schema prefix is omitted.
+func (q *Query) appendSchemaTable(b []byte, name types.Q) []byte {
+	s := string(name)
+	if q.schema != "" {
+		s = strings.TrimPrefix(s, "?schema.")
+		if strings.IndexByte(s, '.') == -1 {
+			b = types.AppendField(b, q.schema, 1)
+			b = append(b, '.')
+		}
+		return q.FormatQuery(b, s)
+	}
+
+	n := len(b)
+	b = q.FormatQuery(b, s)
+	if bytes.HasPrefix(b[n:], []byte("?schema.")) {
+		b = append(b[:n], b[n+len("?schema."):]...)
+	}
+	return b
 }
 
 func (q *Query) appendTableNameWithAlias(b []byte) []byte {
@@ -742,5 +1057,38 @@ func (wherePKQuery) AppendSep(b []byte) []byte {
 
 func (q wherePKQuery) AppendFormat(b []byte, f QueryFormatter) []byte {
 	table := q.model.Table()
-	return appendColumnAndValue(b, q.model.Value(), table, table.PKs)
+	v := q.model.Value()
+	if v.Kind() == reflect.Struct {
+		return appendColumnAndValue(b, v, table, table.PKs)
+	}
+
+	b = append(b, '(')
+	b = append(b, columns(table.Alias, "", table.PKs)...)
+	b = append(b, ") IN ("...)
+	b = append(b, values(v, nil, table.PKs)...)
+	b = append(b, ')')
+	return b
+}
+
+type whereGroupAppender struct {
+	conj  string
+	where []sepFormatAppender
+}
+
+func (q whereGroupAppender) AppendSep(b []byte) []byte {
+	return append(b, q.conj...)
+}
+
+func (q whereGroupAppender) AppendFormat(b []byte, f QueryFormatter) []byte {
+	b = append(b, '(')
+	for i, w := range q.where {
+		if i > 0 {
+			b = append(b, ' ')
+			b = w.AppendSep(b)
+			b = append(b, ' ')
+		}
+		b = w.AppendFormat(b, f)
+	}
+	b = append(b, ')')
+	return b
 }
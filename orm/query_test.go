@@ -1,6 +1,7 @@
 package orm_test
 
 import (
+	"strings"
 	"testing"
 	"unsafe"
 
@@ -9,7 +10,7 @@ import (
 
 func TestQuerySize(t *testing.T) {
 	size := int(unsafe.Sizeof(orm.Query{}))
-	wanted := 328
+	wanted := 424
 	if size != wanted {
 		t.Fatalf("got %d, wanted %d", size, wanted)
 	}
@@ -34,3 +35,16 @@ func TestQueryFormatQuery(t *testing.T) {
 		t.Fatalf("got %q, wanted %q", string(b), wanted)
 	}
 }
+
+func TestForEachUnsupported(t *testing.T) {
+	for _, fn := range []interface{}{nil, 1, func(int) error { return nil }} {
+		err := orm.ForEach(fn).Reset()
+		if err == nil || !strings.HasPrefix(err.Error(), "pg: ForEach(unsupported ") {
+			t.Errorf("got %v", err)
+		}
+	}
+
+	if err := orm.ForEach(func(*FormatModel) error { return nil }).Reset(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -32,6 +32,9 @@ func (q selectQuery) AppendQuery(b []byte, params ...interface{}) ([]byte, error
 	if q.count != "" && q.count != "*" {
 		b = append(b, q.count...)
 	} else {
+		if q.distinctOn != nil {
+			b = q.appendDistinct(b)
+		}
 		b = q.appendColumns(b)
 	}
 
@@ -42,7 +45,7 @@ func (q selectQuery) AppendQuery(b []byte, params ...interface{}) ([]byte, error
 
 	q.forEachHasOneJoin(func(j *join) {
 		b = append(b, ' ')
-		b = j.appendHasOneJoin(b)
+		b = j.appendHasOneJoin(q.Query, b)
 	})
 	if len(q.joins) > 0 {
 		for _, f := range q.joins {
@@ -97,11 +100,31 @@ func (q selectQuery) AppendQuery(b []byte, params ...interface{}) ([]byte, error
 			b = append(b, " OFFSET "...)
 			b = strconv.AppendInt(b, int64(q.offset), 10)
 		}
+
+		if q.selFor != nil {
+			b = append(b, " FOR "...)
+			b = q.selFor.AppendFormat(b, q)
+		}
 	}
 
 	return b, nil
 }
 
+func (q selectQuery) appendDistinct(b []byte) []byte {
+	b = append(b, "DISTINCT "...)
+	if len(q.distinctOn) > 0 {
+		b = append(b, "ON ("...)
+		for i, f := range q.distinctOn {
+			if i > 0 {
+				b = append(b, ", "...)
+			}
+			b = f.AppendFormat(b, q)
+		}
+		b = append(b, ") "...)
+	}
+	return b
+}
+
 func (q selectQuery) appendColumns(b []byte) []byte {
 	start := len(b)
 
@@ -1,6 +1,8 @@
 package orm
 
 import (
+	"gopkg.in/pg.v5/types"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -22,6 +24,15 @@ type HasManyModel struct {
 	SelectModelId int
 }
 
+type TagSelectModel struct {
+	Id   int
+	Tags []TagModel `pg:",many2many:tag_select_model_tags"`
+}
+
+type TagModel struct {
+	Id int
+}
+
 var _ = Describe("Select", func() {
 	It("works without db", func() {
 		q := NewQuery(nil).Where("hello = ?", "world")
@@ -66,7 +77,7 @@ var _ = Describe("Select", func() {
 	It("specifies all columns for has many", func() {
 		q := NewQuery(nil, &SelectModel{Id: 1}).Column("HasMany")
 
-		q, err := q.model.GetJoin("HasMany").manyQuery(nil)
+		q, err := q.model.GetJoin("HasMany").manyQuery(nil, "")
 		Expect(err).NotTo(HaveOccurred())
 
 		b, err := selectQuery{Query: q}.AppendQuery(nil)
@@ -74,6 +85,86 @@ var _ = Describe("Select", func() {
 		Expect(string(b)).To(Equal(`SELECT "has_many_model"."id", "has_many_model"."select_model_id" FROM "has_many_models" AS "has_many_model" WHERE (("has_many_model"."select_model_id") IN ((1)))`))
 	})
 
+	It("limits has many rows per parent with LATERAL", func() {
+		q := NewQuery(nil, &SelectModel{Id: 1}).Relation("HasMany", func(q *Query) (*Query, error) {
+			return q.Order("id DESC").Limit(5).PerParent(), nil
+		})
+
+		q, err := q.model.GetJoin("HasMany").manyQuery(nil, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT "has_many_model".* FROM (SELECT DISTINCT * FROM (VALUES (1)) AS v) AS "_parent" ("select_model_id"), LATERAL (SELECT "has_many_model"."id", "has_many_model"."select_model_id" FROM "has_many_models" AS "has_many_model" WHERE ("has_many_model"."select_model_id" = "_parent"."select_model_id") ORDER BY "id" DESC LIMIT 5) AS "has_many_model"`))
+	})
+
+	It("limits has many rows of all parents without PerParent", func() {
+		q := NewQuery(nil, &SelectModel{Id: 1}).Relation("HasMany", func(q *Query) (*Query, error) {
+			return q.Limit(5), nil
+		})
+
+		q, err := q.model.GetJoin("HasMany").manyQuery(nil, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT "has_many_model"."id", "has_many_model"."select_model_id" FROM "has_many_models" AS "has_many_model" WHERE (("has_many_model"."select_model_id") IN ((1))) LIMIT 5`))
+	})
+
+	It("limits many to many rows per parent with LATERAL", func() {
+		q := NewQuery(nil, &TagSelectModel{Id: 1}).Relation("Tags", func(q *Query) (*Query, error) {
+			return q.Limit(3).PerParent(), nil
+		})
+
+		q, err := q.model.GetJoin("Tags").m2mQuery(nil, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT "tag_model".* FROM (SELECT DISTINCT * FROM (VALUES (1)) AS v) AS "_parent" ("tag_select_model_id"), LATERAL (SELECT tag_select_model_tags.*, "tag_model"."id" FROM "tag_models" AS "tag_model" JOIN tag_select_model_tags ON tag_select_model_tags."tag_select_model_id" = "_parent"."tag_select_model_id" WHERE ("tag_model"."id" = tag_select_model_tags."tag_model_id") LIMIT 3) AS "tag_model"`))
+	})
+
+	It("supports WherePK", func() {
+		q := NewQuery(nil, &SelectModel{Id: 1}).Column("id").WherePK()
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT "id" FROM "select_models" AS "select_model" WHERE "select_model"."id" = 1`))
+	})
+
+	It("supports WherePK for slices", func() {
+		q := NewQuery(nil, &[]SelectModel{{Id: 1}, {Id: 2}}).Column("id").WherePK()
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT "id" FROM "select_models" AS "select_model" WHERE ("select_model"."id") IN ((1), (2))`))
+	})
+
+	It("supports WhereGroup", func() {
+		q := NewQuery(nil).
+			Where("active").
+			WhereGroup(func(q *Query) (*Query, error) {
+				return q.Where("role = ?", "admin").WhereOr("role = ?", "owner"), nil
+			}).
+			WhereOrGroup(func(q *Query) (*Query, error) {
+				return q.WhereIn("id IN (?)", 1, 2), nil
+			})
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT * WHERE (active) AND ((role = 'admin') OR (role = 'owner')) OR ((id IN (1,2)))`))
+	})
+
+	It("skips empty WhereGroup", func() {
+		q := NewQuery(nil).Where("active").WhereGroup(func(q *Query) (*Query, error) {
+			return q, nil
+		})
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT * WHERE (active)`))
+	})
+
 	It("supports multiple groups", func() {
 		q := NewQuery(nil).Group("one").Group("two")
 		b, err := selectQuery{Query: q}.AppendQuery(nil)
@@ -81,6 +172,83 @@ var _ = Describe("Select", func() {
 		Expect(string(b)).To(Equal(`SELECT * GROUP BY "one", "two"`))
 	})
 
+	It("selects columns from column set", func() {
+		q := NewQuery(nil, &InsertColumnSetTest{}).ColumnSet("summary")
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT "name" FROM "insert_column_set_tests" AS "insert_column_set_test"`))
+	})
+
+	It("returns an error for unknown column set", func() {
+		err := NewQuery(nil, &InsertColumnSetTest{}).ColumnSet("unknown").Select()
+		Expect(err).To(MatchError(`can't find column set=unknown in table="insert_column_set_tests"`))
+	})
+
+	It("supports DISTINCT", func() {
+		q := NewQuery(nil).Table("users").Column("name").Distinct()
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT DISTINCT "name" FROM "users"`))
+	})
+
+	It("supports DISTINCT ON", func() {
+		q := NewQuery(nil, &SelectModel{}).
+			DistinctOn("?TableAlias.has_one_id").
+			DistinctOn("lower(?)", "name").
+			OrderExpr("?TableAlias.has_one_id, ?TableAlias.id DESC")
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT DISTINCT ON ("select_model".has_one_id, lower('name')) "select_model"."id", "select_model"."name", "select_model"."has_one_id" FROM "select_models" AS "select_model" ORDER BY "select_model".has_one_id, "select_model".id DESC`))
+	})
+
+	It("supports subquery in WHERE", func() {
+		subq := NewQuery(nil).Table("users").Column("id").Where("active = ?", true)
+		q := NewQuery(nil).Table("orders").Where("user_id IN (?)", subq)
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT * FROM "orders" WHERE (user_id IN (SELECT "id" FROM "users" WHERE (active = TRUE)))`))
+	})
+
+	It("formats subquery with the formatter of the outer query", func() {
+		var f Formatter
+		f.SetParam("schema", types.F("tenant"))
+		subq := NewQuery(nil).Table("users").Column("id").Where("org = ?schema")
+
+		b := f.FormatQuery(nil, "SELECT * FROM orders WHERE user_id IN (?)", subq)
+		Expect(string(b)).To(Equal(`SELECT * FROM orders WHERE user_id IN (SELECT "id" FROM "users" WHERE (org = "tenant"))`))
+	})
+
+	It("supports subquery in FROM and JOIN", func() {
+		subq := NewQuery(nil).Table("users").Where("id = ?", 1)
+		q := NewQuery(nil).
+			TableExpr("(?) AS u", subq).
+			Join("JOIN (?) AS o ON o.user_id = u.id", NewQuery(nil).Table("orders"))
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT * FROM (SELECT * FROM "users" WHERE (id = 1)) AS u JOIN (SELECT * FROM "orders") AS o ON o.user_id = u.id`))
+	})
+
+	It("supports FOR UPDATE", func() {
+		q := NewQuery(nil).Table("jobs").Limit(1).For("UPDATE SKIP LOCKED")
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT * FROM "jobs" LIMIT 1 FOR UPDATE SKIP LOCKED`))
+	})
+
+	It("supports FOR NO KEY UPDATE OF", func() {
+		q := NewQuery(nil, &SelectModel{}).For("NO KEY UPDATE OF ?TableAlias NOWAIT")
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT "select_model"."id", "select_model"."name", "select_model"."has_one_id" FROM "select_models" AS "select_model" FOR NO KEY UPDATE OF "select_model" NOWAIT`))
+	})
+
 	It("WhereOr", func() {
 		q := NewQuery(nil).Where("1 = 1").WhereOr("1 = 2")
 		b, err := selectQuery{Query: q}.AppendQuery(nil)
@@ -98,6 +266,14 @@ var _ = Describe("Count", func() {
 		Expect(string(b)).To(Equal(`SELECT count(*)`))
 	})
 
+	It("removes FOR locking clause", func() {
+		q := NewQuery(nil).Table("jobs").Where("status = ?", "new").For("UPDATE")
+
+		b, err := q.countQuery().countSelectQuery("count(*)").AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT count(*) FROM "jobs" WHERE (status = 'new')`))
+	})
+
 	It("removes LIMIT, OFFSET, and ORDER from CTE", func() {
 		q := NewQuery(nil).
 			Column("col1", "col2").
@@ -120,6 +296,14 @@ var _ = Describe("Count", func() {
 		Expect(string(b)).To(Equal(`WITH "wrapper" AS (SELECT * GROUP BY "one") SELECT count(*) FROM "wrapper"`))
 	})
 
+	It("uses CTE when query contains DISTINCT", func() {
+		q := NewQuery(nil).Table("users").Column("name").Distinct()
+
+		b, err := q.countQuery().countSelectQuery("count(*)").AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`WITH "wrapper" AS (SELECT DISTINCT "name" FROM "users") SELECT count(*) FROM "wrapper"`))
+	})
+
 	It("includes has one joins", func() {
 		q := NewQuery(nil, &SelectModel{Id: 1}).Column("HasOne")
 
@@ -178,3 +362,71 @@ var _ = Describe("Select Order", func() {
 		}
 	})
 })
+
+type QualifiedModel struct {
+	tableName struct{} `sql:"public.qualified_models"`
+
+	Id int
+}
+
+type SchemaParamModel struct {
+	tableName struct{} `sql:"?schema.schema_param_models"`
+
+	Id int
+}
+
+var _ = Describe("Select Schema", func() {
+	It("qualifies model and has one tables", func() {
+		q := NewQuery(nil, &SelectModel{}).Column("HasOne").Schema("tenant")
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT "select_model"."id", "select_model"."name", "select_model"."has_one_id", "has_one"."id" AS "has_one__id" FROM "tenant"."select_models" AS "select_model" LEFT JOIN "tenant"."has_one_models" AS "has_one" ON "has_one"."id" = "select_model"."has_one_id"`))
+	})
+
+	It("qualifies has many tables", func() {
+		q := NewQuery(nil, &SelectModel{Id: 1}).Column("HasMany")
+
+		q, err := q.model.GetJoin("HasMany").manyQuery(nil, "tenant")
+		Expect(err).NotTo(HaveOccurred())
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT "has_many_model"."id", "has_many_model"."select_model_id" FROM "tenant"."has_many_models" AS "has_many_model" WHERE (("has_many_model"."select_model_id") IN ((1)))`))
+	})
+
+	It("does not change qualified tables", func() {
+		q := NewQuery(nil, &QualifiedModel{}).Schema("tenant")
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT "qualified_model"."id" FROM public.qualified_models AS "qualified_model"`))
+	})
+
+	It("replaces ?schema placeholder", func() {
+		q := NewQuery(nil, &SchemaParamModel{}).Schema("tenant")
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT "schema_param_model"."id" FROM "tenant".schema_param_models AS "schema_param_model"`))
+	})
+
+	It("omits ?schema placeholder when schema is empty", func() {
+		q := NewQuery(nil, &SchemaParamModel{})
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT "schema_param_model"."id" FROM schema_param_models AS "schema_param_model"`))
+	})
+
+	It("replaces ?schema placeholder with the formatter param", func() {
+		var f Formatter
+		f.SetParam("schema", types.F("tenant"))
+		q := NewQuery(nil, &SchemaParamModel{})
+		q.fmter = f
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT "schema_param_model"."id" FROM "tenant".schema_param_models AS "schema_param_model"`))
+	})
+})
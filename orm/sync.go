@@ -0,0 +1,140 @@
+package orm
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type SyncOptions struct {
+	// Delete deletes rows that are not present in the model.
+	Delete bool
+}
+
+const (
+	SyncInsert = "insert"
+	SyncUpdate = "update"
+	SyncDelete = "delete"
+)
+
+// SyncChange describes a change made by Sync.
+type SyncChange struct {
+	Op string
+	// Row is a pointer to the inserted, updated or deleted struct.
+	Row interface{}
+	// Columns contains names of the changed columns for updates.
+	Columns []string
+}
+
+func (c SyncChange) String() string {
+	if c.Op == SyncUpdate {
+		return fmt.Sprintf("%s %+v (%s)", c.Op, c.Row, strings.Join(c.Columns, ", "))
+	}
+	return fmt.Sprintf("%s %+v", c.Op, c.Row)
+}
+
+// Sync reconciles the table with the rows in the model, which must be
+// a pointer to a slice of structs with primary keys. Missing rows are
+// inserted, rows with different column values are updated and, when
+// opt.Delete is set, rows that are not in the model are deleted.
+// It does not start a transaction.
+func Sync(db DB, model interface{}, opt *SyncOptions) ([]SyncChange, error) {
+	v := reflect.ValueOf(model)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("pg: Sync(unsupported %T)", model)
+	}
+	slice := v.Elem()
+
+	elemType := indirectType(slice.Type().Elem())
+	if elemType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("pg: Sync(unsupported %T)", model)
+	}
+	table := Tables.Get(elemType)
+	if err := table.checkPKs(); err != nil {
+		return nil, err
+	}
+
+	existing := reflect.New(slice.Type())
+	q := NewQuery(db, existing.Interface()).For("UPDATE")
+	for _, pk := range table.PKs {
+		q = q.Order(pk.SQLName)
+	}
+	if err := q.Select(); err != nil {
+		return nil, err
+	}
+
+	rows := make(map[string]reflect.Value, existing.Elem().Len())
+	for i := 0; i < existing.Elem().Len(); i++ {
+		row := existing.Elem().Index(i)
+		id := modelId(nil, reflect.Indirect(row), table.PKs)
+		rows[string(id)] = row
+	}
+
+	var changes []SyncChange
+	for i := 0; i < slice.Len(); i++ {
+		row := slice.Index(i)
+		strct := reflect.Indirect(row)
+		ptr := strct.Addr().Interface()
+
+		id := string(modelId(nil, strct, table.PKs))
+		old, ok := rows[id]
+		if !ok {
+			if err := Insert(db, ptr); err != nil {
+				return nil, err
+			}
+			changes = append(changes, SyncChange{Op: SyncInsert, Row: ptr})
+			continue
+		}
+		delete(rows, id)
+
+		columns := changedColumns(table, reflect.Indirect(old), strct)
+		if len(columns) == 0 {
+			continue
+		}
+
+		_, err := NewQuery(db, ptr).Column(columns...).Update()
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, SyncChange{
+			Op:      SyncUpdate,
+			Row:     ptr,
+			Columns: columns,
+		})
+	}
+
+	if opt == nil || !opt.Delete {
+		return changes, nil
+	}
+
+	// Iterate over the existing rows to delete them in primary key order.
+	for i := 0; i < existing.Elem().Len(); i++ {
+		row := existing.Elem().Index(i)
+		strct := reflect.Indirect(row)
+		if _, ok := rows[string(modelId(nil, strct, table.PKs))]; !ok {
+			continue
+		}
+
+		ptr := strct.Addr().Interface()
+		if err := Delete(db, ptr); err != nil {
+			return nil, err
+		}
+		changes = append(changes, SyncChange{Op: SyncDelete, Row: ptr})
+	}
+
+	return changes, nil
+}
+
+func changedColumns(table *Table, old, new reflect.Value) []string {
+	var columns []string
+	for _, f := range table.Fields {
+		if f.Has(PrimaryKeyFlag) {
+			continue
+		}
+		if !bytes.Equal(f.AppendValue(nil, old, 1), f.AppendValue(nil, new, 1)) {
+			columns = append(columns, f.SQLName)
+		}
+	}
+	return columns
+}
@@ -0,0 +1,43 @@
+package orm
+
+import (
+	"reflect"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type SyncTest struct {
+	Id    int
+	Code  string
+	Name  string
+	Count int
+}
+
+var _ = Describe("Sync", func() {
+	It("returns changed columns", func() {
+		table := Tables.Get(reflect.TypeOf(SyncTest{}))
+		old := SyncTest{Id: 1, Code: "us", Name: "USA", Count: 1}
+		new := SyncTest{Id: 1, Code: "us", Name: "United States", Count: 2}
+
+		columns := changedColumns(table, reflect.ValueOf(old), reflect.ValueOf(new))
+		Expect(columns).To(Equal([]string{"name", "count"}))
+
+		columns = changedColumns(table, reflect.ValueOf(old), reflect.ValueOf(old))
+		Expect(columns).To(BeEmpty())
+	})
+
+	It("returns an error for unsupported model", func() {
+		_, err := Sync(nil, SyncTest{}, nil)
+		Expect(err).To(MatchError("pg: Sync(unsupported orm.SyncTest)"))
+	})
+
+	It("formats changes", func() {
+		change := SyncChange{
+			Op:      SyncUpdate,
+			Row:     &SyncTest{Id: 1, Name: "USA"},
+			Columns: []string{"name"},
+		}
+		Expect(change.String()).To(Equal("update &{Id:1 Code: Name:USA Count:0} (name)"))
+	})
+})
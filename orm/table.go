@@ -3,19 +3,35 @@ package orm
 import (
 	"database/sql"
 	"fmt"
+	"math/big"
+	"net"
 	"reflect"
 	"strings"
 
 	"gopkg.in/pg.v5/internal"
 	"gopkg.in/pg.v5/types"
-
-	"github.com/jinzhu/inflection"
 )
 
 var nullBool = reflect.TypeOf((*sql.NullBool)(nil)).Elem()
 var nullFloat = reflect.TypeOf((*sql.NullFloat64)(nil)).Elem()
 var nullInt = reflect.TypeOf((*sql.NullInt64)(nil)).Elem()
 var nullString = reflect.TypeOf((*sql.NullString)(nil)).Elem()
+var int64RangeType = reflect.TypeOf((*types.Int64Range)(nil)).Elem()
+var timeRangeType = reflect.TypeOf((*types.TimeRange)(nil)).Elem()
+var intervalType = reflect.TypeOf((*types.Interval)(nil)).Elem()
+var bigIntType = reflect.TypeOf((*big.Int)(nil)).Elem()
+var bigRatType = reflect.TypeOf((*big.Rat)(nil)).Elem()
+var bigFloatType = reflect.TypeOf((*big.Float)(nil)).Elem()
+var uuidType = reflect.TypeOf((*types.UUID)(nil)).Elem()
+var ipType = reflect.TypeOf((*net.IP)(nil)).Elem()
+var ipNetType = reflect.TypeOf((*net.IPNet)(nil)).Elem()
+var hardwareAddrType = reflect.TypeOf((*net.HardwareAddr)(nil)).Elem()
+var pointType = reflect.TypeOf((*types.Point)(nil)).Elem()
+var lineType = reflect.TypeOf((*types.Line)(nil)).Elem()
+var boxType = reflect.TypeOf((*types.Box)(nil)).Elem()
+var pathType = reflect.TypeOf((*types.Path)(nil)).Elem()
+var polygonType = reflect.TypeOf((*types.Polygon)(nil)).Elem()
+var circleType = reflect.TypeOf((*types.Circle)(nil)).Elem()
 
 type Table struct {
 	Type       reflect.Type
@@ -30,8 +46,15 @@ type Table struct {
 	Fields    []*Field
 	FieldsMap map[string]*Field
 
-	Methods   map[string]*Method
-	Relations map[string]*Relation
+	Methods    map[string]*Method
+	Relations  map[string]*Relation
+	ColumnSets map[string][]*Field
+	Indexes    []*Index
+
+	// Fields populated with the current time on insert and update.
+	createdAt  *Field
+	updatedAt  *Field
+	timestamps string
 
 	flags int16
 }
@@ -82,6 +105,58 @@ func (t *Table) AppendParam(dst []byte, strct reflect.Value, name string) ([]byt
 	return dst, false
 }
 
+func (t *Table) addColumnSets(field *Field, sets string) {
+	if t.ColumnSets == nil {
+		t.ColumnSets = make(map[string][]*Field)
+	}
+	for _, name := range strings.Split(sets, "|") {
+		t.ColumnSets[name] = append(t.ColumnSets[name], field)
+	}
+}
+
+// GetColumnSet returns fields that belong to the named column set.
+func (t *Table) GetColumnSet(name string) ([]*Field, error) {
+	fields, ok := t.ColumnSets[name]
+	if !ok {
+		return nil, fmt.Errorf("can't find column set=%s in table=%s", name, t.Name)
+	}
+	return fields, nil
+}
+
+// addIndex adds the field to the index with the name creating the
+// index when it does not exist. Unnamed indexes have a single field.
+func (t *Table) addIndex(name string, unique bool, field *Field) {
+	if name != "" {
+		for _, index := range t.Indexes {
+			if index.Name == name {
+				index.Columns = append(index.Columns, field.SQLName)
+				return
+			}
+		}
+	}
+	t.Indexes = append(t.Indexes, &Index{
+		Name:    name,
+		Unique:  unique,
+		Columns: []string{field.SQLName},
+	})
+}
+
+// initIndexes names the unnamed indexes after the table and the
+// columns, e.g. books_title_idx.
+func (t *Table) initIndexes() {
+	table := string(t.Name)
+	if i := strings.LastIndexByte(table, '.'); i >= 0 {
+		table = table[i+1:]
+	}
+	table = strings.Trim(table, `"`)
+
+	for _, index := range t.Indexes {
+		if index.Name == "" {
+			index.Name = table + "_" + strings.Join(index.Columns, "_") + "_idx"
+		}
+	}
+}
+
 func (t *Table) addRelation(rel *Relation) {
 	if t.Relations == nil {
 		t.Relations = make(map[string]*Relation)
@@ -106,7 +181,7 @@ func newTable(typ reflect.Type) *Table {
 		zeroStruct: reflect.Zero(typ),
 
 		TypeName:  internal.ToExported(typ.Name()),
-		Name:      types.Q(types.AppendField(nil, inflection.Plural(modelName), 1)),
+		Name:      types.Q(types.AppendField(nil, naming.TableName(typ.Name()), 1)),
 		Alias:     types.Q(types.AppendField(nil, modelName, 1)),
 		ModelName: modelName,
 
@@ -115,9 +190,18 @@ func newTable(typ reflect.Type) *Table {
 	}
 	Tables.inFlight[typ] = table
 
-	table.addFields(typ, nil)
+	table.addFields(typ, nil, "")
+	table.initTimestamps()
+	table.initIndexes()
 	typ = reflect.PtrTo(typ)
 
+	// Fields of has one relations, e.g. author__name, are copied from
+	// the joined table and are not handled by the generated code.
+	generated := typ.Implements(generatedModelType)
+	for _, f := range table.FieldsMap {
+		f.generated = generated && !strings.Contains(f.SQLName, "__")
+	}
+
 	if typ.Implements(afterQueryHookType) {
 		table.flags |= AfterQueryHookFlag
 	}
@@ -174,14 +258,24 @@ func newTable(typ reflect.Type) *Table {
 	return table
 }
 
-func (t *Table) addFields(typ reflect.Type, index []int) {
+// addFields adds the fields of the struct type. Fields of embedded
+// structs and of non-pointer struct fields with extend or prefix tag,
+// e.g.
+//
+//    Address Address `pg:",prefix:addr_"`
+//
+// are added as the fields of the table. The prefix is prepended to
+// their column names.
+func (t *Table) addFields(typ reflect.Type, index []int, prefix string) {
 	for i := 0; i < typ.NumField(); i++ {
 		f := typ.Field(i)
 
-		if f.Anonymous {
+		_, pgOpt := parseTag(f.Tag.Get("pg"))
+		fieldPrefix, hasPrefix := pgOpt.Get("prefix:")
+		_, extend := pgOpt.Get("extend")
+		if f.Anonymous || ((hasPrefix || extend) && f.Type.Kind() == reflect.Struct) {
 			embeddedTable := newTable(indirectType(f.Type))
 
-			_, pgOpt := parseTag(f.Tag.Get("pg"))
 			if _, ok := pgOpt.Get("override"); ok {
 				t.TypeName = embeddedTable.TypeName
 				t.Name = embeddedTable.Name
@@ -189,13 +283,59 @@ func (t *Table) addFields(typ reflect.Type, index []int) {
 				t.ModelName = embeddedTable.ModelName
 			}
 
-			t.addFields(embeddedTable.Type, append(index, f.Index...))
+			t.addFields(embeddedTable.Type, joinIndex(index, f.Index), prefix+fieldPrefix)
 			continue
 		}
 
-		field := t.newField(f, index)
+		field := t.newField(f, index, prefix)
 		if field != nil {
 			t.AddField(field)
+
+			if sets, ok := pgOpt.Get("set:"); ok {
+				t.addColumnSets(field, sets)
+			}
+			if _, ok := pgOpt.Get("created_at"); ok {
+				t.createdAt = field
+			}
+			if _, ok := pgOpt.Get("updated_at"); ok {
+				t.updatedAt = field
+			}
+			if name, ok := pgOpt.Get("index"); ok {
+				t.addIndex(strings.TrimPrefix(name, ":"), false, field)
+			}
+			if name, ok := pgOpt.Get("unique_index"); ok {
+				t.addIndex(strings.TrimPrefix(name, ":"), true, field)
+			}
+		}
+	}
+}
+
+// initTimestamps finds the created_at and updated_at fields, which are
+// marked with `pg:",created_at"` and `pg:",updated_at"` tags or
+// recognized by the names. By default they are populated with the clock
+// time. The tableName field tag `pg:",timestamps:server"` makes the
+// server populate them with now() like fields with `pg:",default_now"`
+// tag and `pg:",timestamps:off"` disables them.
+func (t *Table) initTimestamps() {
+	if t.timestamps == "off" {
+		t.createdAt = nil
+		t.updatedAt = nil
+		return
+	}
+
+	if t.createdAt == nil {
+		t.createdAt = t.FieldsMap["created_at"]
+	}
+	if t.updatedAt == nil {
+		t.updatedAt = t.FieldsMap["updated_at"]
+	}
+
+	if t.timestamps == "server" {
+		if t.createdAt != nil {
+			t.createdAt.flags |= DefaultNowFlag
+		}
+		if t.updatedAt != nil {
+			t.updatedAt.flags |= DefaultNowFlag
 		}
 	}
 }
@@ -211,10 +351,10 @@ func (t *Table) getField(name string) *Field {
 	if !ok {
 		return nil
 	}
-	return t.newField(f, nil)
+	return t.newField(f, nil, "")
 }
 
-func (t *Table) newField(f reflect.StructField, index []int) *Field {
+func (t *Table) newField(f reflect.StructField, index []int, prefix string) *Field {
 	sqlName, sqlOpt := parseTag(f.Tag.Get("sql"))
 
 	switch f.Name {
@@ -228,6 +368,19 @@ func (t *Table) newField(f reflect.StructField, index []int) *Field {
 		if alias, ok := sqlOpt.Get("alias:"); ok {
 			t.Alias = types.Q(alias)
 		}
+		_, pgOpt := parseTag(f.Tag.Get("pg"))
+		if s, ok := pgOpt.Get("timestamps:"); ok {
+			t.timestamps = s
+		}
+		for _, cols := range pgOpt.GetAll("index:") {
+			t.Indexes = append(t.Indexes, &Index{Columns: strings.Split(cols, "|")})
+		}
+		for _, cols := range pgOpt.GetAll("unique_index:") {
+			t.Indexes = append(t.Indexes, &Index{
+				Unique:  true,
+				Columns: strings.Split(cols, "|"),
+			})
+		}
 		return nil
 	}
 
@@ -237,8 +390,9 @@ func (t *Table) newField(f reflect.StructField, index []int) *Field {
 
 	skip := sqlName == "-"
 	if skip || sqlName == "" {
-		sqlName = internal.Underscore(f.Name)
+		sqlName = naming.ColumnName(f.Name)
 	}
+	sqlName = prefix + sqlName
 
 	if field, ok := t.FieldsMap[sqlName]; ok {
 		return field
@@ -248,15 +402,44 @@ func (t *Table) newField(f reflect.StructField, index []int) *Field {
 
 	var appender types.AppenderFunc
 	var scanner types.ScannerFunc
-	if _, ok := pgOpt.Get("array"); ok {
+	var arenaScanner types.ArenaScannerFunc
+	if name, ok := pgOpt.Get("codec:"); ok {
+		appender, scanner = types.Codec(name)
+	} else if _, ok := pgOpt.Get("array"); ok {
 		appender = types.ArrayAppender(f.Type)
 		scanner = types.ArrayScanner(f.Type)
 	} else if _, ok := pgOpt.Get("hstore"); ok {
 		appender = types.HstoreAppender(f.Type)
 		scanner = types.HstoreScanner(f.Type)
+	} else if name, ok := jsonTag(pgOpt); ok {
+		appender = types.JSONAppender(f.Type, name)
+		scanner = types.JSONScanner(f.Type, name)
+	} else if _, ok := pgOpt.Get("composite:"); ok {
+		appender = compositeAppender(f.Type)
+		scanner = compositeScanner(f.Type)
 	} else {
 		appender = types.Appender(f.Type)
 		scanner = types.Scanner(f.Type)
+		arenaScanner = types.ArenaScanner(f.Type)
+	}
+
+	var timeFlags int
+	if _, ok := pgOpt.Get("utc"); ok {
+		timeFlags |= types.TimeUTC
+	}
+	if _, ok := pgOpt.Get("usec"); ok {
+		timeFlags |= types.TimeMicroseconds
+	}
+	if timeFlags != 0 {
+		if fn := types.TimeAppender(f.Type, timeFlags); fn != nil {
+			appender = fn
+		}
+	}
+
+	if _, ok := pgOpt.Get("interval"); ok {
+		if fn := types.IntervalAppender(f.Type); fn != nil {
+			appender = fn
+		}
 	}
 
 	field := Field{
@@ -266,10 +449,11 @@ func (t *Table) newField(f reflect.StructField, index []int) *Field {
 		SQLName: sqlName,
 		ColName: types.Q(types.AppendField(nil, sqlName, 1)),
 
-		Index: append(index, f.Index...),
+		Index: joinIndex(index, f.Index),
 
-		append: appender,
-		scan:   scanner,
+		append:    appender,
+		scan:      scanner,
+		arenaScan: arenaScanner,
 
 		isEmpty: isEmptyFunc(f.Type),
 	}
@@ -277,9 +461,12 @@ func (t *Table) newField(f reflect.StructField, index []int) *Field {
 	if _, ok := sqlOpt.Get("notnull"); ok {
 		field.flags |= NotNullFlag
 	}
-	if _, ok := sqlOpt.Get("unique"); ok {
+	if _, ok := sqlOpt.Get("unique"); ok || pgOpt.Has("unique") {
 		field.flags |= UniqueFlag
 	}
+	if _, ok := pgOpt.Get("default_now"); ok {
+		field.flags |= DefaultNowFlag
+	}
 
 	if len(t.PKs) == 0 && (field.SQLName == "id" || field.SQLName == "uuid") {
 		field.flags |= PrimaryKeyFlag
@@ -291,9 +478,15 @@ func (t *Table) newField(f reflect.StructField, index []int) *Field {
 		field.flags |= ForeignKeyFlag
 	}
 
-	field.SQLType = sqlType(&field, sqlOpt)
+	field.SQLType = sqlType(&field, sqlOpt, pgOpt)
 
-	if !skip && types.IsSQLScanner(f.Type) {
+	if !skip && (types.IsSQLScanner(f.Type) || field.Type == ipNetType) {
+		return &field
+	}
+	if _, ok := pgOpt.Get("composite:"); ok && !skip {
+		return &field
+	}
+	if _, ok := jsonTag(pgOpt); ok && !skip {
 		return &field
 	}
 
@@ -322,8 +515,8 @@ func (t *Table) newField(f reflect.StructField, index []int) *Field {
 				Field:        &field,
 				JoinTable:    joinTable,
 				M2MTableName: types.Q(m2mTable),
-				BasePrefix:   internal.Underscore(basePrefix + "_"),
-				JoinPrefix:   internal.Underscore(joinPrefix + "_"),
+				BasePrefix:   naming.ColumnName(basePrefix + "_"),
+				JoinPrefix:   naming.ColumnName(joinPrefix + "_"),
 			})
 			return nil
 		}
@@ -342,7 +535,7 @@ func (t *Table) newField(f reflect.StructField, index []int) *Field {
 				Field:       &field,
 				FKs:         fks,
 				JoinTable:   joinTable,
-				BasePrefix:  internal.Underscore(basePrefix + "_"),
+				BasePrefix:  naming.ColumnName(basePrefix + "_"),
 			})
 			return nil
 		}
@@ -356,7 +549,7 @@ func (t *Table) newField(f reflect.StructField, index []int) *Field {
 			ff = ff.Copy()
 			ff.SQLName = field.SQLName + "__" + ff.SQLName
 			ff.ColName = types.Q(types.AppendField(nil, ff.SQLName, 1))
-			ff.Index = append(field.Index, ff.Index...)
+			ff.Index = joinIndex(field.Index, ff.Index)
 			t.FieldsMap[ff.SQLName] = ff
 		}
 
@@ -374,12 +567,45 @@ func (t *Table) newField(f reflect.StructField, index []int) *Field {
 	return &field
 }
 
-func sqlType(field *Field, sqlOpt tagOptions) string {
+// jsonTag returns the JSON provider name for fields with json tag,
+// e.g. `pg:",json"` or `pg:",json:jsoniter"`.
+func jsonTag(pgOpt tagOptions) (string, bool) {
+	v, ok := pgOpt.Get("json")
+	if !ok {
+		return "", false
+	}
+	if v == "" {
+		return "", true
+	}
+	if v[0] == ':' {
+		return v[1:], true
+	}
+	return "", false
+}
+
+func sqlType(field *Field, sqlOpt, pgOpt tagOptions) string {
 	if v, ok := sqlOpt.Get("type:"); ok {
 		return v
 	}
+	if _, ok := pgOpt.Get("hstore"); ok {
+		return "hstore"
+	}
+	if v, ok := pgOpt.Get("composite:"); ok {
+		return v
+	}
+	if _, ok := jsonTag(pgOpt); ok {
+		return "jsonb"
+	}
+	if _, ok := pgOpt.Get("interval"); ok {
+		return "interval"
+	}
+
+	typ := unwrapType(field.Type)
+	if name, _, ok := types.LookupEnum(typ); ok {
+		return name
+	}
 
-	switch field.Type {
+	switch typ {
 	case timeType:
 		return "timestamptz"
 	case nullBool:
@@ -390,9 +616,37 @@ func sqlType(field *Field, sqlOpt tagOptions) string {
 		return "bigint"
 	case nullString:
 		return "text"
-	}
-
-	switch field.Type.Kind() {
+	case int64RangeType:
+		return "int8range"
+	case timeRangeType:
+		return "tstzrange"
+	case intervalType:
+		return "interval"
+	case bigIntType, bigRatType, bigFloatType:
+		return "numeric"
+	case uuidType:
+		return "uuid"
+	case ipType:
+		return "inet"
+	case ipNetType:
+		return "cidr"
+	case hardwareAddrType:
+		return "macaddr"
+	case pointType:
+		return "point"
+	case lineType:
+		return "line"
+	case boxType:
+		return "box"
+	case pathType:
+		return "path"
+	case polygonType:
+		return "polygon"
+	case circleType:
+		return "circle"
+	}
+
+	switch typ.Kind() {
 	case reflect.Int8, reflect.Uint8, reflect.Int16:
 		if field.Has(PrimaryKeyFlag) {
 			return "smallserial"
@@ -421,8 +675,17 @@ func sqlType(field *Field, sqlOpt tagOptions) string {
 	case reflect.Map, reflect.Slice, reflect.Struct:
 		return "jsonb"
 	default:
-		return field.Type.Kind().String()
+		return typ.Kind().String()
+	}
+}
+
+// unwrapType returns the type embedded by wrappers like pg.NullTime,
+// which consist of a single embedded field.
+func unwrapType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Struct && typ.NumField() == 1 && typ.Field(0).Anonymous {
+		typ = typ.Field(0).Type
 	}
+	return typ
 }
 
 func foreignKeys(base, join *Table, prefix string) []*Field {
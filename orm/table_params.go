@@ -8,6 +8,10 @@ type tableParams struct {
 }
 
 func newTableParams(strct interface{}) (*tableParams, bool) {
+	if _, ok := strct.(FormatAppender); ok {
+		return nil, false
+	}
+
 	v := reflect.ValueOf(strct)
 	if !v.IsValid() {
 		return nil, false
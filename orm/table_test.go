@@ -2,6 +2,7 @@ package orm_test
 
 import (
 	"reflect"
+	"strings"
 
 	"gopkg.in/pg.v5/orm"
 
@@ -148,3 +149,151 @@ var _ = Describe("unexported types", func() {
 		Expect(rel.Type).To(Equal(orm.HasOneRelation))
 	})
 })
+
+type NestedAddress struct {
+	City string
+}
+
+type NestedAuthor struct {
+	Name    string
+	Address *NestedAddress
+}
+
+type NestedStats struct {
+	Stats struct {
+		Views int
+		Likes int
+	}
+}
+
+type NestedEmbedded struct {
+	NestedStats
+}
+
+type NestedResult struct {
+	Id     int
+	Author *NestedAuthor
+	NestedEmbedded
+}
+
+var _ = Describe("nested struct field", func() {
+	It("is scanned from prefixed columns", func() {
+		var r NestedResult
+		m, err := orm.NewModel(&r)
+		Expect(err).NotTo(HaveOccurred())
+
+		columns := map[string]string{
+			"id":                    "1",
+			"author__name":          "bob",
+			"author__address__city": "London",
+			"stats__views":          "10",
+			"stats__likes":          "20",
+		}
+		for name, value := range columns {
+			err := m.ScanColumn(0, name, []byte(value))
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		Expect(r.Id).To(Equal(1))
+		Expect(r.Author.Name).To(Equal("bob"))
+		Expect(r.Author.Address.City).To(Equal("London"))
+		Expect(r.Stats.Views).To(Equal(10))
+		Expect(r.Stats.Likes).To(Equal(20))
+	})
+})
+
+type NamingSingularModel struct {
+	Id       int
+	FullName string
+}
+
+type NamingCustomModel struct {
+	tableName struct{} `sql:"custom_table"`
+
+	Id       int
+	FullName string
+	Email    string `sql:"email_address"`
+}
+
+type NamingFuncsModel struct {
+	Id       int
+	FullName string
+}
+
+var _ = Describe("NamingStrategy", func() {
+	AfterEach(func() {
+		orm.SetNamingStrategy(nil)
+	})
+
+	It("does not pluralize table names", func() {
+		orm.SetNamingStrategy(orm.SnakeCaseSingular)
+		table := orm.Tables.Get(reflect.TypeOf(NamingSingularModel{}))
+		Expect(string(table.Name)).To(Equal(`"naming_singular_model"`))
+		Expect(table.HasField("full_name")).To(BeTrue())
+	})
+
+	It("does not override names set with tags", func() {
+		orm.SetNamingStrategy(orm.NamingFuncs{
+			Column: strings.ToLower,
+		})
+		table := orm.Tables.Get(reflect.TypeOf(NamingCustomModel{}))
+		Expect(string(table.Name)).To(Equal("custom_table"))
+		Expect(table.HasField("fullname")).To(BeTrue())
+		Expect(table.HasField("email_address")).To(BeTrue())
+	})
+
+	It("uses custom functions", func() {
+		orm.SetNamingStrategy(orm.NamingFuncs{
+			Table: func(typeName string) string {
+				return "tbl_" + strings.ToLower(typeName)
+			},
+		})
+		table := orm.Tables.Get(reflect.TypeOf(NamingFuncsModel{}))
+		Expect(string(table.Name)).To(Equal(`"tbl_namingfuncsmodel"`))
+		Expect(table.HasField("full_name")).To(BeTrue())
+	})
+})
+
+type PrefixAddress struct {
+	City string
+	Zip  string `sql:"postcode"`
+}
+
+type PrefixAudit struct {
+	CreatedBy string
+}
+
+type PrefixModel struct {
+	Id int
+	PrefixAudit
+	Home    PrefixAddress `pg:",prefix:home_"`
+	Billing PrefixAddress `pg:",extend"`
+}
+
+var _ = Describe("embedded struct with prefix", func() {
+	var table *orm.Table
+
+	BeforeEach(func() {
+		table = orm.Tables.Get(reflect.TypeOf(PrefixModel{}))
+	})
+
+	It("adds prefixed fields", func() {
+		var names []string
+		for _, f := range table.Fields {
+			names = append(names, f.SQLName)
+		}
+		Expect(names).To(Equal([]string{
+			"id", "created_by", "home_city", "home_postcode", "city", "postcode",
+		}))
+		Expect(table.PKs).To(HaveLen(1))
+	})
+
+	It("appends prefixed field values", func() {
+		strct := reflect.ValueOf(PrefixModel{
+			Home: PrefixAddress{City: "Paris"},
+		})
+		f := table.FieldsMap["home_city"]
+		Expect(f.GoName).To(Equal("City"))
+		Expect(string(f.AppendValue(nil, strct, 1))).To(Equal(`'Paris'`))
+	})
+})
@@ -23,6 +23,27 @@ func (o tagOptions) Get(name string) (string, bool) {
 	return "", false
 }
 
+// Has reports whether the option is set without a value.
+func (o tagOptions) Has(name string) bool {
+	for _, s := range strings.Split(string(o), ",") {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAll returns the values of all options with the name.
+func (o tagOptions) GetAll(name string) []string {
+	var values []string
+	for _, s := range strings.Split(string(o), ",") {
+		if strings.HasPrefix(s, name) {
+			values = append(values, s[len(name):])
+		}
+	}
+	return values
+}
+
 func parseTag(tagStr string) (string, tagOptions) {
 	tag := []byte(tagStr)
 	if idx := bytes.IndexByte(tag, ','); idx != -1 {
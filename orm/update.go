@@ -2,6 +2,7 @@ package orm
 
 import (
 	"errors"
+	"reflect"
 
 	"gopkg.in/pg.v5/internal"
 )
@@ -16,6 +17,8 @@ func Update(db DB, model interface{}) error {
 
 type updateQuery struct {
 	*Query
+	omitEmpty       bool
+	returningFields []*Field
 }
 
 var _ QueryAppender = (*updateQuery)(nil)
@@ -50,12 +53,14 @@ func (q updateQuery) AppendQuery(b []byte, params ...interface{}) ([]byte, error
 
 	if len(q.returning) > 0 {
 		b = q.appendReturning(b)
+	} else if len(q.returningFields) > 0 {
+		b = appendReturningFields(b, q.returningFields)
 	}
 
 	return b, nil
 }
 
-func (q updateQuery) mustAppendSet(b []byte) ([]byte, error) {
+func (q *updateQuery) mustAppendSet(b []byte) ([]byte, error) {
 	if len(q.set) > 0 {
 		b = q.appendSet(b)
 		return b, nil
@@ -81,9 +86,7 @@ func (q updateQuery) mustAppendSet(b []byte) ([]byte, error) {
 				b = append(b, ", "...)
 			}
 
-			b = append(b, field.ColName...)
-			b = append(b, " = "...)
-			b = field.AppendValue(b, strct, 1)
+			b = q.appendSetValue(b, table, field, strct)
 		}
 		return b, nil
 	}
@@ -93,10 +96,11 @@ func (q updateQuery) mustAppendSet(b []byte) ([]byte, error) {
 		if field.Has(PrimaryKeyFlag) {
 			continue
 		}
+		if q.omitEmpty && field != table.updatedAt && field.OmitEmpty(strct) {
+			continue
+		}
 
-		b = append(b, field.ColName...)
-		b = append(b, " = "...)
-		b = field.AppendValue(b, strct, 1)
+		b = q.appendSetValue(b, table, field, strct)
 		b = append(b, ", "...)
 	}
 	if len(b) > start {
@@ -104,3 +108,15 @@ func (q updateQuery) mustAppendSet(b []byte) ([]byte, error) {
 	}
 	return b, nil
 }
+
+// appendSetValue appends the field value or now() when updated_at is
+// populated by the server, in which case the new value is returned.
+func (q *updateQuery) appendSetValue(b []byte, table *Table, field *Field, strct reflect.Value) []byte {
+	b = append(b, field.ColName...)
+	b = append(b, " = "...)
+	if field == table.updatedAt && field.Has(DefaultNowFlag) {
+		q.returningFields = append(q.returningFields, field)
+		return append(b, "now()"...)
+	}
+	return field.AppendValue(b, strct, 1)
+}
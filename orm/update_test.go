@@ -7,6 +7,13 @@ import (
 
 type UpdateTest struct{}
 
+type UpdateModel struct {
+	Id     int
+	Name   string
+	Status string `sql:",notnull"`
+	Score  int
+}
+
 var _ = Describe("Update", func() {
 	It("supports WITH", func() {
 		q := NewQuery(nil, &UpdateTest{}).
@@ -15,8 +22,47 @@ var _ = Describe("Update", func() {
 			Table("wrapper").
 			Where("update_test.id = wrapper.id")
 
-		b, err := updateQuery{q}.AppendQuery(nil)
+		b, err := updateQuery{Query: q}.AppendQuery(nil)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(string(b)).To(Equal(`WITH "wrapper" AS (SELECT  FROM "update_tests" AS "update_test") UPDATE "update_tests" AS "update_test" SET  FROM "wrapper" WHERE (update_test.id = wrapper.id)`))
 	})
+
+	It("updates only columns in the Set", func() {
+		q := NewQuery(nil, &UpdateModel{Id: 1, Name: "hello"}).
+			Set("name = ?name").
+			Where("id = ?id")
+
+		b, err := updateQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`UPDATE "update_models" AS "update_model" SET name = 'hello' WHERE (id = 1)`))
+	})
+
+	It("updates only specified columns", func() {
+		q := NewQuery(nil, &UpdateModel{Id: 1, Name: "hello"}).
+			Column("name", "score").
+			Where("id = ?id")
+
+		b, err := updateQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`UPDATE "update_models" AS "update_model" SET "name" = 'hello', "score" = NULL WHERE (id = 1)`))
+	})
+
+	It("omits columns with zero values", func() {
+		q := NewQuery(nil, &UpdateModel{Id: 1, Name: "hello"})
+
+		b, err := updateQuery{Query: q, omitEmpty: true}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`UPDATE "update_models" AS "update_model" SET "name" = 'hello', "status" = '' WHERE "update_model"."id" = 1`))
+	})
+
+	It("supports RETURNING", func() {
+		q := NewQuery(nil, &UpdateModel{Id: 1, Name: "hello"}).
+			Set("name = ?name").
+			Where("id = ?id").
+			Returning("*")
+
+		b, err := updateQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`UPDATE "update_models" AS "update_model" SET name = 'hello' WHERE (id = 1) RETURNING *`))
+	})
 })
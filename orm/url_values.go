@@ -3,26 +3,47 @@ package orm
 import (
 	"fmt"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 
 	"gopkg.in/pg.v5/types"
 )
 
+// URLValues adds conditions and order from the URL values to the query.
+// Values with names of the model columns add conditions:
+//   - ?name=Mike or ?name__include=Mike - name = 'Mike'; several
+//     values produce name IN (...).
+//   - ?name__exclude=Mike - name != 'Mike' or name NOT IN (...).
+//   - ?id__gt=1, id__gte, id__lt and id__lte - comparisons.
+//   - ?name__like=mik% and name__ilike - LIKE and ILIKE patterns.
+//   - ?name__ieq=mike - case insensitive match.
+//   - ?name__match=(m|p).* - SIMILAR TO pattern.
+//
+// ?order=name DESC and ?sort=-name,id order by the model columns,
+// where the minus sign means descending order. Unknown columns and
+// operators are ignored.
 func URLValues(urlValues url.Values) func(*Query) (*Query, error) {
 	return func(q *Query) (*Query, error) {
-		for fieldName, values := range urlValues {
-			var operation string
-			if i := strings.Index(fieldName, "__"); i != -1 {
-				fieldName, operation = fieldName[:i], fieldName[i+2:]
+		names := make([]string, 0, len(urlValues))
+		for name := range urlValues {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		table := q.model.Table()
+		for _, name := range names {
+			fieldName, operation := name, ""
+			if i := strings.Index(name, "__"); i != -1 {
+				fieldName, operation = name[:i], name[i+2:]
 			}
 
-			if q.model.Table().HasField(fieldName) {
-				q = addOperator(q, fieldName, operation, values)
+			if table.HasField(fieldName) {
+				q = addOperator(q, fieldName, operation, urlValues[name])
 			}
 		}
 
-		return setOrder(q, urlValues), nil
+		return setOrder(q, table, urlValues), nil
 	}
 }
 
@@ -36,7 +57,9 @@ func addOperator(q *Query, fieldName, operator string, values []string) *Query {
 		q = forEachValue(q, fieldName, values, "? < ?")
 	case "lte":
 		q = forEachValue(q, fieldName, values, "? <= ?")
-	case "ieq":
+	case "like":
+		q = forEachValue(q, fieldName, values, "? LIKE ?")
+	case "ilike", "ieq":
 		q = forEachValue(q, fieldName, values, "? ILIKE ?")
 	case "match":
 		q = forEachValue(q, fieldName, values, "? SIMILAR TO ?")
@@ -64,12 +87,28 @@ func forAllValues(q *Query, fieldName string, values []string, queryTemplate, qu
 	return q
 }
 
-func setOrder(q *Query, urlValues url.Values) *Query {
+func setOrder(q *Query, table *Table, urlValues url.Values) *Query {
 	for _, order := range urlValues["order"] {
-		if order != "" {
+		field := order
+		if i := strings.IndexByte(order, ' '); i != -1 {
+			field = order[:i]
+		}
+		if table.HasField(field) {
 			q = q.Order(order)
 		}
 	}
+
+	for _, fields := range urlValues["sort"] {
+		for _, field := range strings.Split(fields, ",") {
+			dir := " ASC"
+			if strings.HasPrefix(field, "-") {
+				field, dir = field[1:], " DESC"
+			}
+			if table.HasField(field) {
+				q = q.Order(field + dir)
+			}
+		}
+	}
 	return q
 }
 
@@ -78,34 +117,28 @@ func setOrder(q *Query, urlValues url.Values) *Query {
 //   - ?page=5 - sets q.Offset((page - 1) * limit), max offset is 1000000.
 func Pager(urlValues url.Values, defaultLimit int) func(*Query) (*Query, error) {
 	return func(q *Query) (*Query, error) {
-		const maxLimit = 1000
-		const maxOffset = 1e6
-
 		limit, err := intParam(urlValues, "limit")
 		if err != nil {
 			return nil, err
 		}
 		if limit < 1 {
 			limit = defaultLimit
-		} else if limit > maxLimit {
-			return nil, fmt.Errorf("limit=%d is bigger than %d", limit, maxLimit)
-		}
-		if limit > 0 {
-			q = q.Limit(limit)
 		}
 
 		page, err := intParam(urlValues, "page")
 		if err != nil {
 			return nil, err
 		}
+
+		var offset int
 		if page > 0 {
-			offset := (page - 1) * limit
-			if offset > maxOffset {
-				return nil, fmt.Errorf("offset=%d can't bigger than %d", offset, maxOffset)
-			}
-			q = q.Offset(offset)
+			offset = (page - 1) * limit
 		}
 
+		q = q.Pager(limit, offset)
+		if q.stickyErr != nil {
+			return nil, q.stickyErr
+		}
 		return q, nil
 	}
 }
@@ -72,6 +72,30 @@ var _ = Describe("URLValues", func() {
 			url:   "http://localhost:8000/test?order=id ASC&order=name DESC",
 			query: query + ` ORDER BY "id" ASC, "name" DESC`,
 		},
+		{
+			url:   "http://localhost:8000/test?order=password DESC",
+			query: query,
+		},
+		{
+			url:   "http://localhost:8000/test?sort=-name,id,password",
+			query: query + ` ORDER BY "name" DESC, "id" ASC`,
+		},
+		{
+			url:   "http://localhost:8000/test?name__like=Mik%25",
+			query: query + ` WHERE ("name" LIKE 'Mik%')`,
+		},
+		{
+			url:   "http://localhost:8000/test?name__ilike=mik%25",
+			query: query + ` WHERE ("name" ILIKE 'mik%')`,
+		},
+		{
+			url:   "http://localhost:8000/test?name__gte=A&id__lt=10&id__gt=1",
+			query: query + ` WHERE ("id" > '1') AND ("id" < '10') AND ("name" >= 'A')`,
+		},
+		{
+			url:   "http://localhost:8000/test?name__unknown=1",
+			query: query,
+		},
 		{
 			url:   "http://localhost:8000/test?invalid_field=1",
 			query: query,
@@ -123,3 +147,23 @@ var _ = Describe("Pager", func() {
 		}
 	})
 })
+
+var _ = Describe("Query.Pager", func() {
+	It("sets limit and offset", func() {
+		q := NewQuery(nil).Table("users").Pager(10, 20)
+
+		b, err := selectQuery{Query: q}.AppendQuery(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`SELECT * FROM "users" LIMIT 10 OFFSET 20`))
+	})
+
+	It("returns an error when limit is too big", func() {
+		q := NewQuery(nil).Table("users").Pager(1001, 0)
+		Expect(q.stickyErr).To(MatchError("pg: limit=1001 is bigger than 1000"))
+	})
+
+	It("returns an error when offset is too big", func() {
+		q := NewQuery(nil).Table("users").Pager(10, 1000001)
+		Expect(q.stickyErr).To(MatchError("pg: offset=1000001 can't be bigger than 1000000"))
+	})
+})
@@ -45,6 +45,14 @@ func fieldByIndex(v reflect.Value, index []int) reflect.Value {
 	return v
 }
 
+// joinIndex returns a new index that does not share the underlying
+// array with the index or other indexes derived from it.
+func joinIndex(index, other []int) []int {
+	b := make([]int, 0, len(index)+len(other))
+	b = append(b, index...)
+	return append(b, other...)
+}
+
 func indirectNew(v reflect.Value) reflect.Value {
 	if v.Kind() == reflect.Ptr {
 		if v.IsNil() {
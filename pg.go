@@ -1,8 +1,9 @@
 package pg // import "gopkg.in/pg.v5"
 
 import (
-	"log"
+	"io"
 	"strconv"
+	"time"
 
 	"gopkg.in/pg.v5/internal"
 	"gopkg.in/pg.v5/orm"
@@ -23,17 +24,95 @@ func Scan(values ...interface{}) orm.ColumnScanner {
 	return orm.Scan(values...)
 }
 
+// ForEach returns a model that scans every row into the same struct and
+// calls fn with it, which allows processing large result sets using
+// constant memory. The fn must have the form func(*Struct) error:
+//
+//    err := db.Model(&User{}).Select(pg.ForEach(func(u *User) error {
+//        return nil
+//    }))
+func ForEach(fn interface{}) orm.Model {
+	return orm.ForEach(fn)
+}
+
 // Q replaces any placeholders found in the query.
 func Q(query string, params ...interface{}) orm.FormatAppender {
 	return orm.Q(query, params...)
 }
 
+// SafeQuery is like Q. It marks the query as a trusted SQL fragment,
+// so it is appended as is after placeholders are replaced with the
+// params, e.g.
+//
+//    db.Query(&users, "SELECT * FROM users WHERE ?", pg.SafeQuery("id = ?", id))
+//
+// The query must not contain untrusted input. Use params for values
+// and Ident for identifiers, which are always quoted.
+func SafeQuery(query string, params ...interface{}) orm.FormatAppender {
+	return orm.Q(query, params...)
+}
+
+// Named replaces :name placeholders in the query with the fields of the
+// struct or the values of the map. It can be used as a query, e.g. in
+// transactions:
+//
+//    _, err := tx.Exec(pg.Named("DELETE FROM users WHERE id = :id", user))
+func Named(query string, arg interface{}) orm.FormatAppender {
+	return orm.Named(query, arg)
+}
+
+// WithArena makes Query allocate scanned string and []byte values of
+// the model in the arena, which reduces GC pressure when many small
+// values are scanned, e.g.
+//
+//    var arena types.Arena
+//    _, err := db.Query(pg.WithArena(&arena, &events), "SELECT * FROM events")
+//    ...
+//    arena.Release() // events must not be used after Release.
+func WithArena(arena *types.Arena, values ...interface{}) interface{} {
+	return orm.WithArena(arena, values...)
+}
+
+// Idempotent marks the query as safe to execute more than once, so
+// DB.Exec and DB.Query retry it even when it fails after being sent to
+// the server and may have been applied, e.g.
+//
+//    db.Exec(pg.Idempotent("UPDATE users SET name = ? WHERE id = ?"), name, id)
+func Idempotent(query interface{}) interface{} {
+	return idempotentQuery{query: query}
+}
+
+type idempotentQuery struct {
+	query interface{}
+}
+
+func (idempotentQuery) IsIdempotent() bool {
+	return true
+}
+
 // F quotes a SQL identifier such as a table or column name replacing any
 // placeholders found in the field.
 func F(field string) types.ValueAppender {
 	return types.F(field)
 }
 
+// Ident quotes the name as a single SQL identifier, so table and column
+// names that come from user input can be safely used in queries, e.g.
+//
+//    db.Query(&users, "SELECT * FROM users ORDER BY ?", pg.Ident(column))
+//
+// Identifiers can also be bound to named params:
+//
+//    db.WithParam("TableName", pg.Ident(table)).Query(&users, "SELECT * FROM ?TableName")
+func Ident(name string) types.ValueAppender {
+	return types.Ident(name)
+}
+
+// QuoteLiteral quotes the string as a SQL string literal.
+func QuoteLiteral(s string) string {
+	return string(types.AppendString(nil, s, 1))
+}
+
 // In accepts a slice and returns a wrapper that can be used with PostgreSQL
 // IN operator:
 //
@@ -63,13 +142,101 @@ func Hstore(v interface{}) *types.Hstore {
 	return types.NewHstore(v)
 }
 
-func SetLogger(logger *log.Logger) {
-	internal.Logger = logger
+// JSON accepts a value and returns a wrapper that marshals it as JSON
+// regardless of its type, e.g. strings, byte slices and types that
+// implement driver.Valuer, which are otherwise appended as text, bytea
+// and the value returned by Value:
+//
+//    db.Exec("UPDATE users SET settings = ? WHERE id = ?", pg.JSON(settings), id)
+//
+// Scanning into the wrapper of a pointer unmarshals the value.
+func JSON(v interface{}) *types.JSON {
+	return types.NewJSON(v)
 }
 
-// SetQueryLogger sets a logger that will be used to log generated queries.
-func SetQueryLogger(logger *log.Logger) {
-	internal.QueryLogger = logger
+// ByteaReader returns a query param that streams n bytes from the reader
+// as bytea value, e.g.
+//
+//    _, err := db.Exec("INSERT INTO files (data) VALUES (?)", pg.ByteaReader(f, size))
+//
+// The reader can be read only once, so the query fails instead of
+// being retried and the param is not logged.
+func ByteaReader(r io.Reader, n int64) *types.ByteaReader {
+	return types.NewByteaReader(r, n)
+}
+
+// ByteaWriter returns a wrapper that scans bytea value into the writer, e.g.
+//
+//    _, err := db.QueryOne(pg.Scan(pg.ByteaWriter(f)), "SELECT data FROM files WHERE id = ?", id)
+func ByteaWriter(w io.Writer) *types.ByteaWriter {
+	return types.NewByteaWriter(w)
+}
+
+// RegisterCodec registers appender and scanner under the name. They are
+// used for struct fields with codec tag instead of the default ones:
+//
+//    SSN string `pg:",codec:encrypted"`
+func RegisterCodec(name string, appender types.AppenderFunc, scanner types.ScannerFunc) {
+	types.RegisterCodec(name, appender, scanner)
+}
+
+// RegisterEncryptionCodec registers a codec that transparently encrypts
+// string and []byte fields using keys from the provider:
+//
+//    SSN string `pg:",codec:pii"`
+func RegisterEncryptionCodec(name string, keys types.KeyProvider) {
+	types.RegisterEncryptionCodec(name, keys)
+}
+
+// SetJSONProvider sets the provider that marshals and unmarshals JSON
+// values instead of encoding/json. Passing nil restores encoding/json.
+func SetJSONProvider(p types.JSONProvider) {
+	types.SetJSONProvider(p)
+}
+
+// RegisterJSONProvider registers the provider under the name. It is
+// used for struct fields with json tag that refers to the name:
+//
+//    Attrs map[string]interface{} `pg:",json:jsoniter"`
+func RegisterJSONProvider(name string, p types.JSONProvider) {
+	types.RegisterJSONProvider(name, p)
+}
+
+// SetTimeFlags sets flags that control how time.Time values are
+// formatted in queries, e.g. types.TimeUTC|types.TimeMicroseconds.
+//
+// For struct fields you can use utc and usec tags:
+//
+//    CreatedAt time.Time `pg:",utc,usec"`
+func SetTimeFlags(flags int) {
+	types.SetTimeFlags(flags)
+}
+
+// SetTimeLocation sets the location of scanned time values. timestamptz
+// values are converted to the location and timestamp values without
+// time zone keep their wall clock in the location. Passing nil restores
+// the default, which keeps the offset sent by the server and uses
+// time.Local for timestamp values.
+func SetTimeLocation(loc *time.Location) {
+	types.SetTimeLocation(loc)
+}
+
+// SetInfinityTimes maps -infinity and infinity timestamps to the times
+// and back. By default scanning infinite timestamp returns an error.
+// Zero time disables the mapping of the corresponding timestamp.
+func SetInfinityTimes(negInf, posInf time.Time) {
+	types.SetInfinityTimes(negInf, posInf)
+}
+
+// SetClock sets the clock that is used to populate empty created_at and
+// updated_at fields on insert and updated_at fields on update. With a
+// custom clock now() in SELECT, INSERT, UPDATE, DELETE, WITH and VALUES
+// queries is replaced with the clock time, which makes time dependent
+// code deterministic in tests. DDL, e.g. column defaults created by
+// CreateTable, and prepared statements are not changed. Passing nil
+// restores the system clock.
+func SetClock(clock orm.Clock) {
+	orm.SetClock(clock)
 }
 
 //------------------------------------------------------------------------------
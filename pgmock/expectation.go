@@ -0,0 +1,247 @@
+package pgmock
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/pg.v5/orm"
+	"gopkg.in/pg.v5/types"
+)
+
+type callKind int
+
+const (
+	execCall callKind = iota
+	queryCall
+	copyFromCall
+	copyToCall
+	beginCall
+	commitCall
+	rollbackCall
+)
+
+func (c callKind) String() string {
+	switch c {
+	case queryCall:
+		return "Query"
+	case copyFromCall:
+		return "CopyFrom"
+	case copyToCall:
+		return "CopyTo"
+	case beginCall:
+		return "Begin"
+	case commitCall:
+		return "Commit"
+	case rollbackCall:
+		return "Rollback"
+	default:
+		return "Exec"
+	}
+}
+
+// Matcher matches the formatted query text.
+type Matcher interface {
+	Match(query string) bool
+	String() string
+}
+
+type exactMatcher string
+
+// Exact returns Matcher that matches the query equal to s ignoring
+// differences in whitespace.
+func Exact(s string) Matcher {
+	return exactMatcher(collapseSpace(s))
+}
+
+func (m exactMatcher) Match(query string) bool {
+	return collapseSpace(query) == string(m)
+}
+
+func (m exactMatcher) String() string {
+	return fmt.Sprintf("%q", string(m))
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+type regexpMatcher struct {
+	re *regexp.Regexp
+}
+
+// Regexp returns Matcher that matches the query with the regular
+// expression. It panics if the expression can't be parsed.
+func Regexp(expr string) Matcher {
+	return regexpMatcher{re: regexp.MustCompile(expr)}
+}
+
+func (m regexpMatcher) Match(query string) bool {
+	return m.re.MatchString(query)
+}
+
+func (m regexpMatcher) String() string {
+	return "regexp " + m.re.String()
+}
+
+// MatcherFunc is an adapter to use a function as Matcher.
+type MatcherFunc func(query string) bool
+
+func (f MatcherFunc) Match(query string) bool {
+	return f(query)
+}
+
+func (f MatcherFunc) String() string {
+	return "func"
+}
+
+// Any returns Matcher that matches any query.
+func Any() Matcher {
+	return MatcherFunc(func(string) bool { return true })
+}
+
+// Expectation describes the expected query and its outcome.
+type Expectation struct {
+	call    callKind
+	matcher Matcher
+
+	tag  string
+	rows *Rows
+	data []byte
+	err  error
+
+	triggered bool
+}
+
+func (e *Expectation) String() string {
+	if e.matcher == nil {
+		return e.call.String()
+	}
+	return fmt.Sprintf("%s %s", e.call, e.matcher)
+}
+
+func (e *Expectation) match(call callKind, query string) bool {
+	if e.call != call {
+		return false
+	}
+	return e.matcher == nil || e.matcher.Match(query)
+}
+
+// WillReturnResult sets the command tag of the result as it is sent by
+// PostgreSQL, e.g. "UPDATE 1" or "INSERT 0 1". By default Query returns
+// "SELECT n" and CopyFrom and CopyTo return "COPY n" where n is
+// the number of rows.
+func (e *Expectation) WillReturnResult(tag string) *Expectation {
+	e.tag = tag
+	return e
+}
+
+// WillReturnRows sets the rows that are scanned into the model passed
+// to Query.
+func (e *Expectation) WillReturnRows(rows *Rows) *Expectation {
+	e.rows = rows
+	return e
+}
+
+// WillReturnData sets the data that CopyTo writes to the writer.
+func (e *Expectation) WillReturnData(data []byte) *Expectation {
+	e.data = data
+	return e
+}
+
+// WillReturnError sets the error that is returned instead of
+// the result. The error returned by Commit or Rollback ends
+// the transaction the same way as with pg.Tx.
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+func (e *Expectation) result(rows int) *types.Result {
+	tag := e.tag
+	if tag == "" {
+		switch e.call {
+		case queryCall:
+			tag = fmt.Sprintf("SELECT %d", rows)
+		case copyFromCall, copyToCall:
+			tag = fmt.Sprintf("COPY %d", rows)
+		}
+	}
+	return types.NewResult([]byte(tag), rows)
+}
+
+// Rows are the rows returned by Query.
+type Rows struct {
+	columns []string
+	values  [][][]byte
+}
+
+// NewRows returns empty rows with the columns.
+func NewRows(columns ...string) *Rows {
+	return &Rows{
+		columns: columns,
+	}
+}
+
+// AddRow adds the row with the values of the columns. Values are
+// converted to PostgreSQL text format, nil is NULL.
+func (r *Rows) AddRow(values ...interface{}) *Rows {
+	if len(values) != len(r.columns) {
+		panic(fmt.Sprintf(
+			"pgmock: AddRow got %d values for %d columns",
+			len(values), len(r.columns),
+		))
+	}
+
+	row := make([][]byte, len(values))
+	for i, v := range values {
+		row[i] = appendValue(nil, v)
+	}
+	r.values = append(r.values, row)
+	return r
+}
+
+func appendValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case bool:
+		if v {
+			return append(b, 't')
+		}
+		return append(b, 'f')
+	default:
+		return types.Append(b, v, 0)
+	}
+}
+
+func (r *Rows) scan(model orm.Model) error {
+	var firstErr error
+	for _, row := range r.values {
+		m := model.NewModel()
+		err := scanRow(m, r.columns, row)
+		if err == nil {
+			err = model.AddModel(m)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func scanRow(scanner orm.ColumnScanner, columns []string, row [][]byte) error {
+	var firstErr error
+	for i, b := range row {
+		var err error
+		if s, ok := scanner.(orm.ColumnTypeScanner); ok {
+			err = s.ScanColumnType(i, columns[i], 0, b)
+		} else {
+			err = scanner.ScanColumn(i, columns[i], b)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,383 @@
+/*
+Package pgmock implements orm.DB interface that checks executed queries
+against registered expectations and returns canned results, so code
+that accepts orm.DB can be unit tested without PostgreSQL, e.g.
+
+    db := pgmock.New()
+    db.ExpectQuery(pgmock.Regexp(`^SELECT .* FROM "users"`)).
+        WillReturnRows(pgmock.NewRows("id", "name").AddRow(1, "admin"))
+
+    var users []User
+    err := db.Model(&users).Select()
+    ...
+    if err := db.ExpectationsWereMet(); err != nil {
+        t.Fatal(err)
+    }
+
+Queries are formatted the same way as by pg.DB, so expectations are
+written against the final SQL text. Expectations are matched in the
+order they were registered.
+
+Transactions are started with Begin or RunInTransaction and expected
+with ExpectBegin, ExpectCommit and ExpectRollback, e.g.
+
+    db.ExpectBegin()
+    db.ExpectExec(pgmock.Exact("UPDATE users SET active = FALSE"))
+    db.ExpectCommit()
+
+    err := db.RunInTransaction(func(tx *pgmock.Tx) error {
+        _, err := tx.Exec("UPDATE users SET active = FALSE")
+        return err
+    })
+*/
+package pgmock
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"gopkg.in/pg.v5/internal"
+	"gopkg.in/pg.v5/orm"
+	"gopkg.in/pg.v5/types"
+)
+
+// DB is a mock database. It is safe for concurrent use by multiple
+// goroutines.
+type DB struct {
+	fmter orm.Formatter
+
+	mu       sync.Mutex
+	expected []*Expectation
+}
+
+var _ orm.DB = (*DB)(nil)
+
+// New returns a mock database without expectations.
+func New() *DB {
+	return &DB{}
+}
+
+// SetParam sets the param that is used to format queries the same way
+// as pg.DB.WithParam does.
+func (db *DB) SetParam(param string, value interface{}) {
+	db.mu.Lock()
+	db.fmter.SetParam(param, value)
+	db.mu.Unlock()
+}
+
+// ExpectExec registers an expectation for the query executed with
+// Exec or ExecOne.
+func (db *DB) ExpectExec(m Matcher) *Expectation {
+	return db.expect(execCall, m)
+}
+
+// ExpectQuery registers an expectation for the query executed with
+// Query or QueryOne. Note that the orm executes INSERT, UPDATE and
+// DELETE with RETURNING clause using Query.
+func (db *DB) ExpectQuery(m Matcher) *Expectation {
+	return db.expect(queryCall, m)
+}
+
+// ExpectCopyFrom registers an expectation for the query executed with
+// CopyFrom. The data is read from the reader and discarded.
+func (db *DB) ExpectCopyFrom(m Matcher) *Expectation {
+	return db.expect(copyFromCall, m)
+}
+
+// ExpectCopyTo registers an expectation for the query executed with
+// CopyTo. The data set with WillReturnData is written to the writer.
+func (db *DB) ExpectCopyTo(m Matcher) *Expectation {
+	return db.expect(copyToCall, m)
+}
+
+// ExpectBegin registers an expectation for Begin.
+func (db *DB) ExpectBegin() *Expectation {
+	return db.expect(beginCall, nil)
+}
+
+// ExpectCommit registers an expectation for Tx.Commit.
+func (db *DB) ExpectCommit() *Expectation {
+	return db.expect(commitCall, nil)
+}
+
+// ExpectRollback registers an expectation for Tx.Rollback.
+func (db *DB) ExpectRollback() *Expectation {
+	return db.expect(rollbackCall, nil)
+}
+
+func (db *DB) expect(call callKind, m Matcher) *Expectation {
+	e := &Expectation{
+		call:    call,
+		matcher: m,
+	}
+	db.mu.Lock()
+	db.expected = append(db.expected, e)
+	db.mu.Unlock()
+	return e
+}
+
+// ExpectationsWereMet returns an error if some of the registered
+// expectations were not matched by a query.
+func (db *DB) ExpectationsWereMet() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var unmet []string
+	for _, e := range db.expected {
+		if !e.triggered {
+			unmet = append(unmet, e.String())
+		}
+	}
+	if len(unmet) == 0 {
+		return nil
+	}
+	return fmt.Errorf(
+		"pgmock: there are unmet expectations:\n  %s",
+		strings.Join(unmet, "\n  "),
+	)
+}
+
+// next returns the first expectation that was not triggered yet and
+// marks it as triggered if it matches the query.
+func (db *DB) next(call callKind, query string) (*Expectation, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, e := range db.expected {
+		if e.triggered {
+			continue
+		}
+		if !e.match(call, query) {
+			return nil, fmt.Errorf(
+				"pgmock: %s was not expected, next expectation is %s",
+				callString(call, query), e,
+			)
+		}
+		e.triggered = true
+		return e, nil
+	}
+	return nil, fmt.Errorf("pgmock: %s was not expected", callString(call, query))
+}
+
+func callString(call callKind, query string) string {
+	switch call {
+	case beginCall, commitCall, rollbackCall:
+		return call.String()
+	}
+	return fmt.Sprintf("%s %q", call, query)
+}
+
+func (db *DB) formatQuery(query interface{}, params ...interface{}) (string, error) {
+	var b []byte
+	var err error
+	switch query := query.(type) {
+	case orm.QueryAppender:
+		b, err = query.AppendQuery(nil, params...)
+	case string:
+		b = db.FormatQuery(nil, query, params...)
+	case orm.FormatAppender:
+		b = query.AppendFormat(nil, db)
+	default:
+		err = fmt.Errorf("pg: can't append %T", query)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Exec matches the query against the next expectation registered with
+// ExpectExec.
+func (db *DB) Exec(query interface{}, params ...interface{}) (*types.Result, error) {
+	q, err := db.formatQuery(query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := db.next(execCall, q)
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.result(0), nil
+}
+
+// ExecOne acts like Exec, but query must affect only one row. It
+// returns ErrNoRows error when query affects zero rows or ErrMultiRows
+// when query affects multiple rows.
+func (db *DB) ExecOne(query interface{}, params ...interface{}) (*types.Result, error) {
+	res, err := db.Exec(query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := internal.AssertOneRow(res.RowsAffected()); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Query matches the query against the next expectation registered with
+// ExpectQuery and scans the expected rows into the model.
+func (db *DB) Query(model, query interface{}, params ...interface{}) (*types.Result, error) {
+	return db.query(db, model, query, params...)
+}
+
+// query runs the query passing hooksDB to the AfterQuery hook.
+func (db *DB) query(
+	hooksDB orm.DB, model, query interface{}, params ...interface{},
+) (*types.Result, error) {
+	q, err := db.formatQuery(query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := db.next(queryCall, q)
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	var mod orm.Model
+	var rows int
+	if e.rows != nil {
+		mod, err = newModel(model)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.rows.scan(mod); err != nil {
+			return nil, err
+		}
+		rows = len(e.rows.values)
+	}
+
+	res := e.result(rows)
+	if rows > 0 {
+		if err := mod.AfterQuery(hooksDB); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// QueryOne acts like Query, but query must return only one row. It
+// returns ErrNoRows error when query returns zero rows or
+// ErrMultiRows when query returns multiple rows.
+func (db *DB) QueryOne(model, query interface{}, params ...interface{}) (*types.Result, error) {
+	mod, err := orm.NewModel(model)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := db.Query(mod, query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := internal.AssertOneRow(res.RowsAffected()); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// CopyFrom matches the query against the next expectation registered
+// with ExpectCopyFrom and reads the data from the reader.
+func (db *DB) CopyFrom(r io.Reader, query interface{}, params ...interface{}) (*types.Result, error) {
+	q, err := db.formatQuery(query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := db.next(copyFromCall, q)
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return e.result(bytes.Count(data, []byte{'\n'})), nil
+}
+
+// CopyTo matches the query against the next expectation registered
+// with ExpectCopyTo and writes the expected data to the writer.
+func (db *DB) CopyTo(w io.Writer, query interface{}, params ...interface{}) (*types.Result, error) {
+	q, err := db.formatQuery(query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := db.next(copyToCall, q)
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	if _, err := w.Write(e.data); err != nil {
+		return nil, err
+	}
+	return e.result(bytes.Count(e.data, []byte{'\n'})), nil
+}
+
+// Model returns new query for the model.
+func (db *DB) Model(model ...interface{}) *orm.Query {
+	return orm.NewQuery(db, model...)
+}
+
+// Select selects the model by primary key.
+func (db *DB) Select(model interface{}) error {
+	return orm.Select(db, model)
+}
+
+// Insert inserts the model updating primary keys if they are empty.
+func (db *DB) Insert(model ...interface{}) error {
+	return orm.Insert(db, model...)
+}
+
+// Update updates the model by primary key.
+func (db *DB) Update(model interface{}) error {
+	return orm.Update(db, model)
+}
+
+// Delete deletes the model by primary key.
+func (db *DB) Delete(model interface{}) error {
+	return orm.Delete(db, model)
+}
+
+func (db *DB) FormatQuery(dst []byte, query string, params ...interface{}) []byte {
+	db.mu.Lock()
+	fmter := db.fmter
+	db.mu.Unlock()
+	return fmter.Append(dst, query, params...)
+}
+
+func newModel(mod interface{}) (orm.Model, error) {
+	if mod == nil {
+		return orm.Discard{}, nil
+	}
+
+	m, ok := mod.(orm.Model)
+	if ok {
+		return m, m.Reset()
+	}
+
+	m, err := orm.NewModel(mod)
+	if err != nil {
+		return nil, err
+	}
+	return m, m.Reset()
+}
@@ -0,0 +1,230 @@
+package pgmock_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"gopkg.in/pg.v5"
+	"gopkg.in/pg.v5/orm"
+	"gopkg.in/pg.v5/pgmock"
+)
+
+type MockUser struct {
+	Id     int64
+	Name   string
+	Active bool
+}
+
+func TestSelectScansRows(t *testing.T) {
+	db := pgmock.New()
+	db.ExpectQuery(pgmock.Exact(`
+		SELECT "mock_user"."id", "mock_user"."name", "mock_user"."active"
+		FROM "mock_users" AS "mock_user"
+		WHERE (active)
+	`)).WillReturnRows(
+		pgmock.NewRows("id", "name", "active").
+			AddRow(1, "admin", true).
+			AddRow(2, nil, false),
+	)
+
+	var users []MockUser
+	err := db.Model(&users).Where("active").Select()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []MockUser{{1, "admin", true}, {2, "", false}}
+	if len(users) != len(want) || users[0] != want[0] || users[1] != want[1] {
+		t.Fatalf("got %v, wanted %v", users, want)
+	}
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueryOneNoRows(t *testing.T) {
+	db := pgmock.New()
+	db.ExpectQuery(pgmock.Regexp(`WHERE "mock_user"\."id" = 1$`)).
+		WillReturnRows(pgmock.NewRows("id"))
+
+	user := MockUser{Id: 1}
+	err := db.Select(&user)
+	if err != pg.ErrNoRows {
+		t.Fatalf("got %v, wanted ErrNoRows", err)
+	}
+}
+
+func TestInsertReturning(t *testing.T) {
+	db := pgmock.New()
+	db.ExpectQuery(pgmock.Regexp(`^INSERT INTO "mock_users" .* RETURNING "id", "active"$`)).
+		WillReturnResult("INSERT 0 1").
+		WillReturnRows(pgmock.NewRows("id", "active").AddRow(42, true))
+
+	user := MockUser{Name: "admin"}
+	if err := db.Insert(&user); err != nil {
+		t.Fatal(err)
+	}
+	if user.Id != 42 || !user.Active {
+		t.Fatalf("got %v, wanted id 42 and active", user)
+	}
+}
+
+func TestExec(t *testing.T) {
+	db := pgmock.New()
+	db.ExpectExec(pgmock.Exact(`UPDATE users SET active = FALSE WHERE id = 1`)).
+		WillReturnResult("UPDATE 2")
+
+	_, err := db.ExecOne("UPDATE users SET active = ? WHERE id = ?", false, 1)
+	if err != pg.ErrMultiRows {
+		t.Fatalf("got %v, wanted ErrMultiRows", err)
+	}
+}
+
+func TestExecParams(t *testing.T) {
+	db := pgmock.New()
+	db.SetParam("schema", pg.Q("app"))
+	db.ExpectExec(pgmock.Exact(`DELETE FROM app.users`)).
+		WillReturnResult("DELETE 3")
+
+	res, err := db.Exec("DELETE FROM ?schema.users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := res.RowsAffected(); n != 3 {
+		t.Fatalf("got %d, wanted 3", n)
+	}
+}
+
+func TestWillReturnError(t *testing.T) {
+	db := pgmock.New()
+	wanted := errors.New("connection reset")
+	db.ExpectExec(pgmock.Any()).WillReturnError(wanted)
+
+	_, err := db.Exec("SELECT 1")
+	if err != wanted {
+		t.Fatalf("got %v, wanted %v", err, wanted)
+	}
+}
+
+func TestUnexpectedQuery(t *testing.T) {
+	db := pgmock.New()
+	db.ExpectQuery(pgmock.Exact("SELECT 1"))
+	db.ExpectExec(pgmock.Exact("SELECT 2"))
+
+	_, err := db.Exec("SELECT 1")
+	if err == nil || !strings.Contains(err.Error(), `next expectation is Query "SELECT 1"`) {
+		t.Fatalf("got %v", err)
+	}
+
+	var n int
+	if _, err := db.QueryOne(pg.Scan(&n), "SELECT 1"); err != pg.ErrNoRows {
+		t.Fatalf("got %v, wanted ErrNoRows", err)
+	}
+
+	err = db.ExpectationsWereMet()
+	if err == nil || !strings.Contains(err.Error(), `Exec "SELECT 2"`) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestImplementsDB(t *testing.T) {
+	var db orm.DB = pgmock.New()
+	db.(*pgmock.DB).ExpectQuery(pgmock.MatcherFunc(func(q string) bool {
+		return strings.HasPrefix(q, "SELECT count(*)")
+	})).WillReturnRows(pgmock.NewRows("count").AddRow(7))
+
+	n, err := db.Model(&MockUser{}).Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 7 {
+		t.Fatalf("got %d, wanted 7", n)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	db := pgmock.New()
+	db.ExpectCopyFrom(pgmock.Exact("COPY users FROM STDIN"))
+	db.ExpectCopyTo(pgmock.Exact("COPY users TO STDOUT")).
+		WillReturnData([]byte("1\tadmin\n"))
+
+	res, err := db.CopyFrom(strings.NewReader("1\tadmin\n2\tuser\n"), "COPY users FROM STDIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := res.RowsAffected(); n != 2 {
+		t.Fatalf("got %d, wanted 2", n)
+	}
+
+	var buf bytes.Buffer
+	if _, err := db.CopyTo(&buf, "COPY users TO STDOUT"); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); s != "1\tadmin\n" {
+		t.Fatalf("got %q", s)
+	}
+}
+
+func TestRunInTransaction(t *testing.T) {
+	db := pgmock.New()
+	db.ExpectBegin()
+	db.ExpectExec(pgmock.Exact("UPDATE users SET active = FALSE"))
+	db.ExpectCommit()
+
+	var orig *pgmock.Tx
+	err := db.RunInTransaction(func(tx *pgmock.Tx) error {
+		orig = tx
+		_, err := tx.Exec("UPDATE users SET active = FALSE")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = orig.Exec("SELECT 1")
+	if err == nil || !strings.Contains(err.Error(), "transaction has already been committed") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	db := pgmock.New()
+	db.ExpectBegin()
+	db.ExpectExec(pgmock.Any()).WillReturnError(errors.New("deadlock detected"))
+	db.ExpectRollback()
+
+	err := db.RunInTransaction(func(tx *pgmock.Tx) error {
+		_, err := tx.Exec("DELETE FROM users")
+		return err
+	})
+	if err == nil || err.Error() != "deadlock detected" {
+		t.Fatalf("got %v", err)
+	}
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnexpectedCommit(t *testing.T) {
+	db := pgmock.New()
+	db.ExpectBegin().WillReturnError(errors.New("too many connections"))
+	if _, err := db.Begin(); err == nil || err.Error() != "too many connections" {
+		t.Fatalf("got %v", err)
+	}
+
+	db.ExpectBegin()
+	db.ExpectRollback()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tx.Commit()
+	if err == nil || err.Error() != "pgmock: Commit was not expected, next expectation is Rollback" {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package pgmock
+
+import (
+	"errors"
+	"io"
+
+	"gopkg.in/pg.v5/internal"
+	"gopkg.in/pg.v5/orm"
+	"gopkg.in/pg.v5/types"
+)
+
+var errTxDone = errors.New("pgmock: transaction has already been committed or rolled back")
+
+// Tx is a mock transaction started with DB.Begin. Queries executed in
+// the transaction are matched against the expectations of DB in the
+// same order as queries executed outside of it.
+//
+// After a call to Commit or Rollback, all operations on the transaction
+// fail with the same error as pg.Tx does.
+type Tx struct {
+	db   *DB
+	done bool
+}
+
+var _ orm.DB = (*Tx)(nil)
+
+// Begin matches the call against the next expectation registered with
+// ExpectBegin and starts a transaction.
+func (db *DB) Begin() (*Tx, error) {
+	e, err := db.next(beginCall, "")
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &Tx{db: db}, nil
+}
+
+// RunInTransaction runs a function in a transaction. If function
+// returns an error transaction is rollbacked, otherwise transaction
+// is committed.
+func (db *DB) RunInTransaction(fn func(*Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	return tx.RunInTransaction(fn)
+}
+
+// Begin returns the transaction.
+func (tx *Tx) Begin() (*Tx, error) {
+	return tx, nil
+}
+
+// RunInTransaction runs a function in the transaction. If function
+// returns an error transaction is rollbacked, otherwise transaction
+// is committed.
+func (tx *Tx) RunInTransaction(fn func(*Tx) error) error {
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Commit matches the call against the next expectation registered with
+// ExpectCommit and ends the transaction.
+func (tx *Tx) Commit() error {
+	return tx.close(commitCall)
+}
+
+// Rollback matches the call against the next expectation registered
+// with ExpectRollback and ends the transaction.
+func (tx *Tx) Rollback() error {
+	return tx.close(rollbackCall)
+}
+
+func (tx *Tx) close(call callKind) error {
+	if tx.done {
+		return errTxDone
+	}
+	tx.done = true
+
+	e, err := tx.db.next(call, "")
+	if err != nil {
+		return err
+	}
+	return e.err
+}
+
+// Exec acts like DB.Exec.
+func (tx *Tx) Exec(query interface{}, params ...interface{}) (*types.Result, error) {
+	if tx.done {
+		return nil, errTxDone
+	}
+	return tx.db.Exec(query, params...)
+}
+
+// ExecOne acts like DB.ExecOne.
+func (tx *Tx) ExecOne(query interface{}, params ...interface{}) (*types.Result, error) {
+	if tx.done {
+		return nil, errTxDone
+	}
+	return tx.db.ExecOne(query, params...)
+}
+
+// Query acts like DB.Query.
+func (tx *Tx) Query(model, query interface{}, params ...interface{}) (*types.Result, error) {
+	if tx.done {
+		return nil, errTxDone
+	}
+	return tx.db.query(tx, model, query, params...)
+}
+
+// QueryOne acts like DB.QueryOne.
+func (tx *Tx) QueryOne(model, query interface{}, params ...interface{}) (*types.Result, error) {
+	mod, err := orm.NewModel(model)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := tx.Query(mod, query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := internal.AssertOneRow(res.RowsAffected()); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// CopyFrom acts like DB.CopyFrom.
+func (tx *Tx) CopyFrom(r io.Reader, query interface{}, params ...interface{}) (*types.Result, error) {
+	if tx.done {
+		return nil, errTxDone
+	}
+	return tx.db.CopyFrom(r, query, params...)
+}
+
+// CopyTo acts like DB.CopyTo.
+func (tx *Tx) CopyTo(w io.Writer, query interface{}, params ...interface{}) (*types.Result, error) {
+	if tx.done {
+		return nil, errTxDone
+	}
+	return tx.db.CopyTo(w, query, params...)
+}
+
+// Model returns new query for the model.
+func (tx *Tx) Model(model ...interface{}) *orm.Query {
+	return orm.NewQuery(tx, model...)
+}
+
+// Select selects the model by primary key.
+func (tx *Tx) Select(model interface{}) error {
+	return orm.Select(tx, model)
+}
+
+// Insert inserts the model updating primary keys if they are empty.
+func (tx *Tx) Insert(model ...interface{}) error {
+	return orm.Insert(tx, model...)
+}
+
+// Update updates the model by primary key.
+func (tx *Tx) Update(model interface{}) error {
+	return orm.Update(tx, model)
+}
+
+// Delete deletes the model by primary key.
+func (tx *Tx) Delete(model interface{}) error {
+	return orm.Delete(tx, model)
+}
+
+func (tx *Tx) FormatQuery(dst []byte, query string, params ...interface{}) []byte {
+	return tx.db.FormatQuery(dst, query, params...)
+}
@@ -0,0 +1,618 @@
+/*
+Package pgrepl implements PostgreSQL streaming replication protocol.
+Logical replication with the pgoutput plugin streams changes of tables
+in publications, e.g. for change data capture. Physical replication
+streams raw WAL, e.g. for backup and standby tooling.
+
+	cn, err := pgrepl.Connect(&pg.Options{User: "postgres"})
+	if err != nil {
+		panic(err)
+	}
+	defer cn.Close()
+
+	err = cn.StartReplication("myslot", 0, &pgrepl.StartOptions{
+		Publications: []string{"mypub"},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	for {
+		_, msg, err := cn.Receive()
+		if err != nil {
+			panic(err)
+		}
+		if commit, ok := msg.(*pgrepl.Commit); ok {
+			cn.Ack(commit.EndLSN)
+		}
+	}
+*/
+package pgrepl
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/pg.v5"
+	"gopkg.in/pg.v5/internal"
+	"gopkg.in/pg.v5/internal/pool"
+)
+
+var errClosed = errors.New("pgrepl: connection is closed")
+
+// SystemID is the result of IDENTIFY_SYSTEM command.
+type SystemID struct {
+	SystemID string
+	Timeline int32
+	XLogPos  LSN
+	DBName   string
+}
+
+// Slot is the replication slot created with CreateSlot or
+// CreatePhysicalSlot.
+type Slot struct {
+	Name string
+	// LSN from which the slot streams changes.
+	ConsistentPoint LSN
+	// Snapshot exported by the command that can be used with SET
+	// TRANSACTION SNAPSHOT to copy existing data.
+	SnapshotName string
+}
+
+// XLogData is a chunk of WAL sent by the server.
+type XLogData struct {
+	// WAL position of the data.
+	WALStart LSN
+	// Current end of WAL on the server.
+	ServerWALEnd LSN
+	ServerTime   time.Time
+	// Raw WAL for physical replication or pgoutput message for logical
+	// replication.
+	Data []byte
+}
+
+// StartOptions are options of StartReplication and
+// StartPhysicalReplication.
+type StartOptions struct {
+	// Publications whose changes are streamed by logical replication.
+	Publications []string
+	// Timeline streamed by physical replication.
+	// Default is the current timeline of the server.
+	Timeline int32
+	// Interval of standby status updates that report received and
+	// acknowledged LSNs to the server.
+	// Default is 10 seconds.
+	StatusInterval time.Duration
+}
+
+// Conn is a logical replication connection. Receive must be called
+// from one goroutine, but Ack and SendStandbyStatus can be called
+// concurrently.
+type Conn struct {
+	db *pg.DB
+
+	cn        *pool.Conn
+	relations map[uint32]*Relation
+
+	nextTimeline      int32
+	nextTimelineStart LSN
+
+	mu        sync.Mutex // protects fields below and writes
+	closed    bool
+	received  LSN
+	confirmed LSN
+	done      chan struct{}
+}
+
+// Connect opens a logical replication connection to the database
+// using replication=database run-time parameter. Pool options are
+// ignored.
+func Connect(opt *pg.Options) (*Conn, error) {
+	return connect(opt, "database")
+}
+
+// ConnectPhysical opens a physical replication connection using
+// replication=true run-time parameter. Only replication commands can
+// be used with the connection. Pool options are ignored.
+func ConnectPhysical(opt *pg.Options) (*Conn, error) {
+	return connect(opt, "true")
+}
+
+func connect(opt *pg.Options, replication string) (*Conn, error) {
+	o := *opt
+	o.PoolSize = 1
+	o.MinIdleConns = 0
+	o.IdleTimeout = 0
+	o.MaxConnAge = 0
+	o.MaxAge = 0
+	o.ResetSessionQuery = ""
+	o.RuntimeParams = make(map[string]string, len(opt.RuntimeParams)+1)
+	for name, value := range opt.RuntimeParams {
+		o.RuntimeParams[name] = value
+	}
+	o.RuntimeParams["replication"] = replication
+
+	c := &Conn{
+		db:        pg.Connect(&o),
+		relations: make(map[uint32]*Relation),
+	}
+	// Check the connection, so bad options are reported early.
+	if _, err := c.db.Exec("IDENTIFY_SYSTEM"); err != nil {
+		c.db.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// IdentifySystem returns the system identifier, timeline and current
+// WAL position of the server.
+func (c *Conn) IdentifySystem() (*SystemID, error) {
+	var sys SystemID
+	var dbname pg.NullString
+	_, err := c.db.QueryOne(
+		pg.Scan(&sys.SystemID, &sys.Timeline, &sys.XLogPos, &dbname),
+		"IDENTIFY_SYSTEM",
+	)
+	if err != nil {
+		return nil, err
+	}
+	sys.DBName = dbname.String
+	return &sys, nil
+}
+
+// CreateSlot creates logical replication slot that uses the pgoutput
+// plugin. Temporary slots are dropped when the connection is closed.
+func (c *Conn) CreateSlot(name string, temporary bool) (*Slot, error) {
+	query := "CREATE_REPLICATION_SLOT ? LOGICAL pgoutput"
+	if temporary {
+		query = "CREATE_REPLICATION_SLOT ? TEMPORARY LOGICAL pgoutput"
+	}
+
+	var slot Slot
+	var snapshot, plugin pg.NullString
+	_, err := c.db.QueryOne(
+		pg.Scan(&slot.Name, &slot.ConsistentPoint, &snapshot, &plugin),
+		query, pg.F(name),
+	)
+	if err != nil {
+		return nil, err
+	}
+	slot.SnapshotName = snapshot.String
+	return &slot, nil
+}
+
+// CreatePhysicalSlot creates physical replication slot. When
+// reserveWAL is set, WAL is reserved immediately instead of when the
+// slot is first used by StartPhysicalReplication.
+func (c *Conn) CreatePhysicalSlot(name string, temporary, reserveWAL bool) (*Slot, error) {
+	query := "CREATE_REPLICATION_SLOT ?"
+	if temporary {
+		query += " TEMPORARY"
+	}
+	query += " PHYSICAL"
+	if reserveWAL {
+		query += " RESERVE_WAL"
+	}
+
+	var slot Slot
+	var snapshot, plugin pg.NullString
+	_, err := c.db.QueryOne(
+		pg.Scan(&slot.Name, &slot.ConsistentPoint, &snapshot, &plugin),
+		query, pg.F(name),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &slot, nil
+}
+
+// TimelineHistory returns the name and content of the timeline history
+// file for the timeline.
+func (c *Conn) TimelineHistory(timeline int32) (filename string, content []byte, err error) {
+	_, err = c.db.QueryOne(pg.Scan(&filename, &content), "TIMELINE_HISTORY ?", timeline)
+	return filename, content, err
+}
+
+// DropSlot drops the replication slot.
+func (c *Conn) DropSlot(name string) error {
+	_, err := c.db.Exec("DROP_REPLICATION_SLOT ?", pg.F(name))
+	return err
+}
+
+// StartReplication starts streaming changes from the logical slot
+// beginning at the LSN. Zero LSN starts from the last position
+// confirmed by Ack. After the replication is started the connection
+// can only be used to receive messages.
+func (c *Conn) StartReplication(slot string, start LSN, opt *StartOptions) error {
+	if opt == nil || len(opt.Publications) == 0 {
+		return errors.New("pgrepl: at least one publication is required")
+	}
+
+	pubs := make([]string, len(opt.Publications))
+	for i, pub := range opt.Publications {
+		pubs[i] = `"` + strings.Replace(pub, `"`, `""`, -1) + `"`
+	}
+	b := c.db.FormatQuery(nil,
+		"START_REPLICATION SLOT ? LOGICAL ? (proto_version '1', publication_names ?)",
+		pg.F(slot), pg.Q(start.String()), strings.Join(pubs, ","),
+	)
+	return c.start(b, start, opt)
+}
+
+// StartPhysicalReplication starts streaming WAL beginning at the LSN
+// using the slot, which can be empty to stream without a slot. Use
+// ReceiveXLogData to receive the WAL.
+func (c *Conn) StartPhysicalReplication(slot string, start LSN, opt *StartOptions) error {
+	if opt == nil {
+		opt = &StartOptions{}
+	}
+
+	b := []byte("START_REPLICATION ")
+	if slot != "" {
+		b = c.db.FormatQuery(b, "SLOT ? ", pg.F(slot))
+	}
+	b = append(b, "PHYSICAL "...)
+	b = append(b, start.String()...)
+	if opt.Timeline > 0 {
+		b = append(b, " TIMELINE "...)
+		b = strconv.AppendInt(b, int64(opt.Timeline), 10)
+	}
+	return c.start(b, start, opt)
+}
+
+func (c *Conn) start(query []byte, start LSN, opt *StartOptions) error {
+	if c.cn != nil {
+		return errors.New("pgrepl: replication is already started")
+	}
+
+	cn, err := c.db.RawConn()
+	if err != nil {
+		return err
+	}
+	// Server sends keepalives, so reads don't need a timeout.
+	cn.SetReadWriteTimeout(0, 0)
+	c.cn = cn
+	c.received = start
+	c.confirmed = start
+
+	cn.Wr.StartMessage('Q')
+	cn.Wr.WriteBytes(query)
+	cn.Wr.FinishMessage()
+	if err := cn.FlushWriter(); err != nil {
+		return c.fail(err)
+	}
+
+	for {
+		typ, msg, err := c.readMessage()
+		if err != nil {
+			return c.fail(err)
+		}
+		switch typ {
+		case 'W': // CopyBothResponse
+			interval := opt.StatusInterval
+			if interval == 0 {
+				interval = 10 * time.Second
+			}
+			c.done = make(chan struct{})
+			go c.sendStatus(interval, c.done)
+			return nil
+		case 'E':
+			return c.fail(parseError(msg))
+		case 'N', 'S':
+		default:
+			return c.fail(fmt.Errorf("pgrepl: unexpected message %q", typ))
+		}
+	}
+}
+
+func (c *Conn) sendStatus(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.SendStandbyStatus(false); err != nil {
+				if err != errClosed {
+					internal.Logf("pgrepl: sending standby status failed: %s", err)
+				}
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// Receive waits for the next pgoutput message and returns it with
+// the WAL position of the change. Keepalive messages are handled
+// internally and standby status updates are sent in the background
+// every status interval. It returns io.EOF when the server ends the
+// replication.
+func (c *Conn) Receive() (LSN, Message, error) {
+	xld, err := c.ReceiveXLogData()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	msg, err := Decode(xld.Data)
+	if err != nil {
+		return 0, nil, err
+	}
+	if rel, ok := msg.(*Relation); ok {
+		c.relations[rel.ID] = rel
+	}
+	return xld.WALStart, msg, nil
+}
+
+// ReceiveXLogData is like Receive, but returns XLogData without
+// decoding it, which is used by physical replication. When the server
+// ends streaming of the timeline, it returns io.EOF and the next
+// timeline is returned by NextTimeline. The connection can't be used
+// after the streaming ends.
+func (c *Conn) ReceiveXLogData() (*XLogData, error) {
+	if c.cn == nil {
+		return nil, errors.New("pgrepl: replication is not started")
+	}
+
+	for {
+		typ, b, err := c.readMessage()
+		if err != nil {
+			if c.isClosed() {
+				return nil, errClosed
+			}
+			return nil, err
+		}
+
+		switch typ {
+		case 'd': // CopyData
+		case 'c': // CopyDone
+			if err := c.endCopy(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		case 'E':
+			return nil, parseError(b)
+		case 'N', 'S':
+			continue
+		default:
+			return nil, fmt.Errorf("pgrepl: unexpected message %q", typ)
+		}
+		if len(b) == 0 {
+			return nil, errors.New("pgrepl: empty CopyData message")
+		}
+
+		switch b[0] {
+		case 'w': // XLogData
+			if len(b) < 25 {
+				return nil, errors.New("pgrepl: XLogData message is too short")
+			}
+			xld := &XLogData{
+				WALStart:     LSN(binary.BigEndian.Uint64(b[1:])),
+				ServerWALEnd: LSN(binary.BigEndian.Uint64(b[9:])),
+				ServerTime:   pgTime(int64(binary.BigEndian.Uint64(b[17:]))),
+				Data:         make([]byte, len(b)-25),
+			}
+			copy(xld.Data, b[25:])
+			c.setReceived(xld.WALStart + LSN(len(xld.Data)))
+			return xld, nil
+		case 'k': // Primary keepalive
+			if len(b) < 18 {
+				return nil, errors.New("pgrepl: keepalive message is too short")
+			}
+			c.setReceived(LSN(binary.BigEndian.Uint64(b[1:])))
+			if b[17] == 1 {
+				if err := c.SendStandbyStatus(false); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, fmt.Errorf("pgrepl: unexpected CopyData message %q", b[0])
+		}
+	}
+}
+
+// endCopy responds to CopyDone sent by the server and reads the next
+// timeline that is sent when physical replication reaches the end of
+// the timeline.
+func (c *Conn) endCopy() error {
+	c.mu.Lock()
+	_, err := c.cn.Write([]byte{'c', 0, 0, 0, 4})
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for {
+		typ, b, err := c.readMessage()
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case 'D': // DataRow with next_tli and next_tli_startpos
+			cols := parseDataRow(b)
+			if len(cols) == 2 {
+				tli, _ := strconv.ParseInt(cols[0], 10, 32)
+				c.nextTimeline = int32(tli)
+				c.nextTimelineStart, _ = ParseLSN(cols[1])
+			}
+		case 'E':
+			if firstErr == nil {
+				firstErr = parseError(b)
+			}
+		case 'Z':
+			return firstErr
+		}
+	}
+}
+
+// NextTimeline returns the timeline and its start position that the
+// server switched to when ReceiveXLogData returned io.EOF.
+func (c *Conn) NextTimeline() (int32, LSN) {
+	return c.nextTimeline, c.nextTimelineStart
+}
+
+// Relation returns the relation with the id received before, which
+// describes columns of Insert, Update and Delete messages.
+func (c *Conn) Relation(id uint32) (*Relation, bool) {
+	rel, ok := c.relations[id]
+	return rel, ok
+}
+
+// Ack confirms that changes up to the LSN are processed, so the server
+// can remove WAL that is no longer needed by the slot. It is reported
+// with the next standby status update.
+func (c *Conn) Ack(lsn LSN) {
+	c.mu.Lock()
+	if lsn > c.confirmed {
+		c.confirmed = lsn
+	}
+	c.mu.Unlock()
+}
+
+// ConfirmedLSN returns the last LSN passed to Ack.
+func (c *Conn) ConfirmedLSN() LSN {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.confirmed
+}
+
+func (c *Conn) setReceived(lsn LSN) {
+	c.mu.Lock()
+	if lsn > c.received {
+		c.received = lsn
+	}
+	c.mu.Unlock()
+}
+
+// SendStandbyStatus sends standby status update with received LSN as
+// the write position and acknowledged LSN as the flush and apply
+// positions. When replyRequested is set the server responds with a
+// keepalive.
+func (c *Conn) SendStandbyStatus(replyRequested bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return errClosed
+	}
+	if c.cn == nil {
+		return errors.New("pgrepl: replication is not started")
+	}
+
+	now := time.Now()
+	b := make([]byte, 0, 39)
+	b = append(b, 'd', 0, 0, 0, 38, 'r')
+	b = appendUint64(b, uint64(c.received))
+	b = appendUint64(b, uint64(c.confirmed))
+	b = appendUint64(b, uint64(c.confirmed))
+	b = appendUint64(b, uint64(pgTimestamp(now)))
+	if replyRequested {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+
+	_, err := c.cn.Write(b)
+	return err
+}
+
+// Close closes the connection. Temporary slots are dropped.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return errClosed
+	}
+	c.closed = true
+	cn := c.cn
+	if c.done != nil {
+		close(c.done)
+	}
+	c.mu.Unlock()
+
+	if cn != nil {
+		_ = c.db.CloseRawConn(cn, errClosed)
+	}
+	return c.db.Close()
+}
+
+func (c *Conn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// fail closes the connection that can't be used for replication.
+func (c *Conn) fail(err error) error {
+	_ = c.db.CloseRawConn(c.cn, err)
+	c.cn = nil
+	return err
+}
+
+func (c *Conn) readMessage() (byte, []byte, error) {
+	typ, err := c.cn.Rd.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	b, err := c.cn.ReadN(4)
+	if err != nil {
+		return 0, nil, err
+	}
+	n := int(binary.BigEndian.Uint32(b)) - 4
+	if n < 0 {
+		return 0, nil, fmt.Errorf("pgrepl: invalid message length %d", n)
+	}
+	b, err = c.cn.ReadN(n)
+	if err != nil {
+		return 0, nil, err
+	}
+	return typ, b, nil
+}
+
+func parseDataRow(b []byte) []string {
+	if len(b) < 2 {
+		return nil
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	b = b[2:]
+	cols := make([]string, 0, n)
+	for i := 0; i < n && len(b) >= 4; i++ {
+		l := int(int32(binary.BigEndian.Uint32(b)))
+		b = b[4:]
+		if l < 0 || l > len(b) {
+			cols = append(cols, "")
+			continue
+		}
+		cols = append(cols, string(b[:l]))
+		b = b[l:]
+	}
+	return cols
+}
+
+func parseError(b []byte) error {
+	m := make(map[byte]string)
+	for len(b) > 0 && b[0] != 0 {
+		k := b[0]
+		b = b[1:]
+		ind := strings.IndexByte(string(b), 0)
+		if ind == -1 {
+			break
+		}
+		m[k] = string(b[:ind])
+		b = b[ind+1:]
+	}
+	return internal.NewPGError(m)
+}
+
+func appendUint64(b []byte, n uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], n)
+	return append(b, buf[:]...)
+}
@@ -0,0 +1,245 @@
+package pgrepl_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/pg.v5"
+	"gopkg.in/pg.v5/pgrepl"
+)
+
+// fakeServer accepts the startup message, answers queries with
+// CommandComplete and streams messages after START_REPLICATION. The
+// first byte of each stream item is the message type. Standby status
+// updates are sent to status. CopyDone is answered with the next
+// timeline.
+func fakeServer(cn net.Conn, startup, query chan<- string, stream [][]byte, status chan<- []byte) {
+	defer cn.Close()
+
+	writeMsg := func(c byte, b []byte) {
+		msg := []byte{c, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(msg[1:], uint32(len(b)+4))
+		cn.Write(append(msg, b...))
+	}
+	readN := func(n int) []byte {
+		b := make([]byte, n)
+		if _, err := io.ReadFull(cn, b); err != nil {
+			return nil
+		}
+		return b
+	}
+
+	b := readN(4)
+	if b == nil {
+		return
+	}
+	startup <- string(readN(int(binary.BigEndian.Uint32(b)) - 4))
+	writeMsg('R', []byte{0, 0, 0, 0})
+	writeMsg('Z', []byte{'I'})
+
+	for {
+		hdr := readN(5)
+		if hdr == nil || hdr[0] == 'X' {
+			return
+		}
+		body := readN(int(binary.BigEndian.Uint32(hdr[1:])) - 4)
+		switch hdr[0] {
+		case 'Q':
+			q := strings.TrimRight(string(body), "\x00")
+			if !strings.HasPrefix(q, "START_REPLICATION") {
+				writeMsg('C', []byte("SELECT 1\x00"))
+				writeMsg('Z', []byte{'I'})
+				continue
+			}
+			query <- q
+			writeMsg('W', []byte{0, 0, 0})
+			for _, b := range stream {
+				writeMsg(b[0], b[1:])
+			}
+		case 'c':
+			writeMsg('T', nil)
+			writeMsg('D', []byte("\x00\x02\x00\x00\x00\x012\x00\x00\x00\x090/3000000"))
+			writeMsg('C', []byte("SELECT 1\x00"))
+			writeMsg('C', []byte("START_STREAMING\x00"))
+			writeMsg('Z', []byte{'I'})
+		case 'd':
+			status <- body
+		}
+	}
+}
+
+func TestReplication(t *testing.T) {
+	startup := make(chan string, 1)
+	query := make(chan string, 1)
+	status := make(chan []byte, 10)
+	stream := [][]byte{
+		append(msgBuilder{'d', 'w'}.int64(0x100).int64(0x200).int64(0),
+			msgBuilder{'B'}.int64(0x180).int64(commitTime).int32(42)...),
+		msgBuilder{'d', 'k'}.int64(0x300).int64(0).byte(1),
+	}
+
+	cn, err := pgrepl.Connect(&pg.Options{
+		User:     "postgres",
+		Database: "postgres",
+		Dialer: func(network, addr string) (net.Conn, error) {
+			client, server := net.Pipe()
+			go fakeServer(server, startup, query, stream, status)
+			return client, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cn.Close()
+
+	if s := <-startup; !strings.Contains(s, "replication\x00database\x00") {
+		t.Fatalf("startup message %q does not request replication", s)
+	}
+
+	err = cn.StartReplication("my_slot", 0x100, &pgrepl.StartOptions{
+		Publications:   []string{"pub1", `pub"2`},
+		StatusInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := `START_REPLICATION SLOT "my_slot" LOGICAL 0/100 (proto_version '1', publication_names '"pub1","pub""2"')`
+	if q := <-query; q != wanted {
+		t.Fatalf("got %q, wanted %q", q, wanted)
+	}
+
+	lsn, msg, err := cn.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lsn != 0x100 {
+		t.Errorf("got LSN %s, wanted 0/100", lsn)
+	}
+	if begin, ok := msg.(*pgrepl.Begin); !ok || begin.XID != 42 {
+		t.Errorf("got %#v, wanted Begin", msg)
+	}
+
+	cn.Ack(0x180)
+	if got := cn.ConfirmedLSN(); got != 0x180 {
+		t.Errorf("got confirmed LSN %s, wanted 0/180", got)
+	}
+
+	// The keepalive requests a reply.
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := cn.Receive()
+		done <- err
+	}()
+
+	select {
+	case b := <-status:
+		if len(b) != 34 || b[0] != 'r' {
+			t.Fatalf("got %q, wanted standby status update", b)
+		}
+		wantedLSNs := msgBuilder{}.int64(0x300).int64(0x180).int64(0x180)
+		if !bytes.Equal(b[1:25], wantedLSNs) {
+			t.Errorf("got LSNs %x, wanted %x", b[1:25], []byte(wantedLSNs))
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("standby status update is not sent")
+	}
+
+	if err := cn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("Receive must fail after Close")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Receive is not aborted by Close")
+	}
+}
+
+func TestStartReplicationRequiresPublication(t *testing.T) {
+	cn, err := pgrepl.Connect(&pg.Options{
+		User:     "postgres",
+		Database: "postgres",
+		Dialer: func(network, addr string) (net.Conn, error) {
+			client, server := net.Pipe()
+			go fakeServer(server, make(chan string, 1), nil, nil, nil)
+			return client, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cn.Close()
+
+	if err := cn.StartReplication("my_slot", 0, nil); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestPhysicalReplication(t *testing.T) {
+	startup := make(chan string, 1)
+	query := make(chan string, 1)
+	stream := [][]byte{
+		append(msgBuilder{'d', 'w'}.int64(0x2000000).int64(0x2000100).int64(commitTime),
+			"wal"...),
+		msgBuilder{'d', 'k'}.int64(0x2000100).int64(0).byte(0),
+		{'c'},
+	}
+
+	cn, err := pgrepl.ConnectPhysical(&pg.Options{
+		User: "postgres",
+		Dialer: func(network, addr string) (net.Conn, error) {
+			client, server := net.Pipe()
+			go fakeServer(server, startup, query, stream, nil)
+			return client, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cn.Close()
+
+	if s := <-startup; !strings.Contains(s, "replication\x00true\x00") {
+		t.Fatalf("startup message %q does not request physical replication", s)
+	}
+
+	err = cn.StartPhysicalReplication("my_slot", 0x2000000, &pgrepl.StartOptions{
+		Timeline:       1,
+		StatusInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := `START_REPLICATION SLOT "my_slot" PHYSICAL 0/2000000 TIMELINE 1`
+	if q := <-query; q != wanted {
+		t.Fatalf("got %q, wanted %q", q, wanted)
+	}
+
+	xld, err := cn.ReceiveXLogData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xld.WALStart != 0x2000000 || xld.ServerWALEnd != 0x2000100 {
+		t.Errorf("got WAL start %s and end %s", xld.WALStart, xld.ServerWALEnd)
+	}
+	if string(xld.Data) != "wal" {
+		t.Errorf("got data %q, wanted %q", xld.Data, "wal")
+	}
+	if !xld.ServerTime.Equal(time.Date(2017, time.January, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got server time %s", xld.ServerTime)
+	}
+
+	if _, err := cn.ReceiveXLogData(); err != io.EOF {
+		t.Fatalf("got %v, wanted io.EOF", err)
+	}
+	tli, start := cn.NextTimeline()
+	if tli != 2 || start != 0x3000000 {
+		t.Errorf("got next timeline %d at %s, wanted 2 at 0/3000000", tli, start)
+	}
+}
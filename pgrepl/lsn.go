@@ -0,0 +1,58 @@
+package pgrepl
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/pg.v5/internal"
+	"gopkg.in/pg.v5/types"
+)
+
+// LSN is a PostgreSQL write-ahead log location (pg_lsn type),
+// e.g. 16/B374D848. LSNs can be compared with regular operators.
+type LSN uint64
+
+var _ sql.Scanner = (*LSN)(nil)
+var _ types.ValueAppender = (*LSN)(nil)
+
+// ParseLSN parses LSN in the form XXX/XXX.
+func ParseLSN(s string) (LSN, error) {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '/' {
+			continue
+		}
+
+		hi, err := strconv.ParseUint(s[:i], 16, 32)
+		if err != nil {
+			return 0, fmt.Errorf("pgrepl: can't parse LSN: %q", s)
+		}
+		lo, err := strconv.ParseUint(s[i+1:], 16, 32)
+		if err != nil {
+			return 0, fmt.Errorf("pgrepl: can't parse LSN: %q", s)
+		}
+		return LSN(hi<<32 | lo), nil
+	}
+	return 0, fmt.Errorf("pgrepl: can't parse LSN: %q", s)
+}
+
+func (lsn LSN) String() string {
+	return fmt.Sprintf("%X/%X", uint64(lsn)>>32, uint32(lsn))
+}
+
+func (lsn LSN) AppendValue(b []byte, quote int) ([]byte, error) {
+	return types.AppendString(b, lsn.String(), quote), nil
+}
+
+func (lsn *LSN) Scan(b interface{}) error {
+	if b == nil {
+		*lsn = 0
+		return nil
+	}
+	v, err := ParseLSN(internal.BytesToString(b.([]byte)))
+	if err != nil {
+		return err
+	}
+	*lsn = v
+	return nil
+}
@@ -0,0 +1,25 @@
+package pgrepl_test
+
+import (
+	"testing"
+
+	"gopkg.in/pg.v5/pgrepl"
+)
+
+func TestParseLSN(t *testing.T) {
+	lsn, err := pgrepl.ParseLSN("16/B374D848")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lsn != 0x16B374D848 {
+		t.Fatalf("got %x, wanted 16B374D848", uint64(lsn))
+	}
+	if s := lsn.String(); s != "16/B374D848" {
+		t.Fatalf("got %q, wanted 16/B374D848", s)
+	}
+
+	_, err = pgrepl.ParseLSN("16B374D848")
+	if err == nil || err.Error() != `pgrepl: can't parse LSN: "16B374D848"` {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -0,0 +1,352 @@
+package pgrepl
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Message is a logical replication message sent by the pgoutput
+// plugin: *Begin, *Commit, *Origin, *Relation, *Type, *Insert,
+// *Update, *Delete or *Truncate.
+type Message interface {
+	messageType() byte
+}
+
+// Begin starts a transaction. Changes of the transaction follow until
+// Commit.
+type Begin struct {
+	// LSN of the commit record of the transaction.
+	FinalLSN   LSN
+	CommitTime time.Time
+	XID        uint32
+}
+
+// Commit ends the transaction started with Begin.
+type Commit struct {
+	Flags     uint8
+	CommitLSN LSN
+	// LSN that should be acknowledged with Conn.Ack once the
+	// transaction is processed.
+	EndLSN     LSN
+	CommitTime time.Time
+}
+
+// Origin is sent after Begin for transactions that were replicated
+// from another node.
+type Origin struct {
+	CommitLSN LSN
+	Name      string
+}
+
+// Relation describes the table that is referenced by following
+// Insert, Update, Delete and Truncate messages. It is sent before the
+// first change of the table and after the table is altered.
+type Relation struct {
+	ID        uint32
+	Namespace string
+	Name      string
+	// Replica identity setting of the table: 'd' for default (primary
+	// key), 'n' for nothing, 'f' for all columns and 'i' for index.
+	ReplicaIdentity byte
+	Columns         []RelationColumn
+}
+
+// RelationColumn describes a column of the Relation.
+type RelationColumn struct {
+	// Whether the column is part of the replica identity.
+	Key          bool
+	Name         string
+	TypeOID      uint32
+	TypeModifier int32
+}
+
+// Type describes a custom data type used by a Relation column.
+type Type struct {
+	ID        uint32
+	Namespace string
+	Name      string
+}
+
+// Insert is a new row.
+type Insert struct {
+	RelationID uint32
+	New        []TupleColumn
+}
+
+// Update is a changed row. Old is only sent when the replica identity
+// changed (OldIsKey) or for tables with REPLICA IDENTITY FULL.
+type Update struct {
+	RelationID uint32
+	OldIsKey   bool
+	Old        []TupleColumn
+	New        []TupleColumn
+}
+
+// Delete is a deleted row. Old contains the replica identity columns
+// (OldIsKey) or all columns for tables with REPLICA IDENTITY FULL.
+type Delete struct {
+	RelationID uint32
+	OldIsKey   bool
+	Old        []TupleColumn
+}
+
+// Truncate truncates the relations.
+type Truncate struct {
+	// Bit 1 is set for CASCADE and bit 2 for RESTART IDENTITY.
+	Options     uint8
+	RelationIDs []uint32
+}
+
+// TupleColumn is a column value of the row in text format.
+type TupleColumn struct {
+	// 'n' for NULL, 'u' for unchanged TOASTed value that is not sent
+	// and 't' for text value.
+	Kind byte
+	Data []byte
+}
+
+// IsNull reports whether the value is NULL.
+func (c TupleColumn) IsNull() bool {
+	return c.Kind == 'n'
+}
+
+// IsUnchanged reports whether the value is unchanged TOASTed value
+// that is not sent by the server.
+func (c TupleColumn) IsUnchanged() bool {
+	return c.Kind == 'u'
+}
+
+func (*Begin) messageType() byte    { return 'B' }
+func (*Commit) messageType() byte   { return 'C' }
+func (*Origin) messageType() byte   { return 'O' }
+func (*Relation) messageType() byte { return 'R' }
+func (*Type) messageType() byte     { return 'Y' }
+func (*Insert) messageType() byte   { return 'I' }
+func (*Update) messageType() byte   { return 'U' }
+func (*Delete) messageType() byte   { return 'D' }
+func (*Truncate) messageType() byte { return 'T' }
+
+// Decode decodes pgoutput message. Returned messages reference b, so
+// b must not be modified.
+func Decode(b []byte) (Message, error) {
+	if len(b) == 0 {
+		return nil, errors.New("pgrepl: empty message")
+	}
+	d := decoder{b: b[1:]}
+
+	var msg Message
+	switch b[0] {
+	case 'B':
+		msg = &Begin{
+			FinalLSN:   d.lsn(),
+			CommitTime: d.time(),
+			XID:        d.uint32(),
+		}
+	case 'C':
+		msg = &Commit{
+			Flags:      d.uint8(),
+			CommitLSN:  d.lsn(),
+			EndLSN:     d.lsn(),
+			CommitTime: d.time(),
+		}
+	case 'O':
+		msg = &Origin{
+			CommitLSN: d.lsn(),
+			Name:      d.string(),
+		}
+	case 'R':
+		rel := &Relation{
+			ID:              d.uint32(),
+			Namespace:       d.string(),
+			Name:            d.string(),
+			ReplicaIdentity: d.uint8(),
+		}
+		n := d.int16()
+		for i := 0; i < n && d.err == nil; i++ {
+			rel.Columns = append(rel.Columns, RelationColumn{
+				Key:          d.uint8()&1 != 0,
+				Name:         d.string(),
+				TypeOID:      d.uint32(),
+				TypeModifier: int32(d.uint32()),
+			})
+		}
+		msg = rel
+	case 'Y':
+		msg = &Type{
+			ID:        d.uint32(),
+			Namespace: d.string(),
+			Name:      d.string(),
+		}
+	case 'I':
+		ins := &Insert{RelationID: d.uint32()}
+		if d.expect('N') {
+			ins.New = d.tuple()
+		}
+		msg = ins
+	case 'U':
+		upd := &Update{RelationID: d.uint32()}
+		switch d.uint8() {
+		case 'K':
+			upd.OldIsKey = true
+			upd.Old = d.tuple()
+			d.expect('N')
+		case 'O':
+			upd.Old = d.tuple()
+			d.expect('N')
+		case 'N':
+		default:
+			d.fail()
+		}
+		upd.New = d.tuple()
+		msg = upd
+	case 'D':
+		del := &Delete{RelationID: d.uint32()}
+		switch d.uint8() {
+		case 'K':
+			del.OldIsKey = true
+		case 'O':
+		default:
+			d.fail()
+		}
+		del.Old = d.tuple()
+		msg = del
+	case 'T':
+		n := int(d.uint32())
+		trunc := &Truncate{Options: d.uint8()}
+		for i := 0; i < n && d.err == nil; i++ {
+			trunc.RelationIDs = append(trunc.RelationIDs, d.uint32())
+		}
+		msg = trunc
+	default:
+		return nil, fmt.Errorf("pgrepl: unknown message type %q", b[0])
+	}
+
+	if d.err != nil {
+		return nil, fmt.Errorf("pgrepl: can't decode message %q: %s", b[0], d.err)
+	}
+	return msg, nil
+}
+
+// pgEpoch is the epoch of PostgreSQL timestamps.
+var pgEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func pgTime(us int64) time.Time {
+	return pgEpoch.Add(time.Duration(us) * time.Microsecond)
+}
+
+func pgTimestamp(tm time.Time) int64 {
+	return int64(tm.Sub(pgEpoch) / time.Microsecond)
+}
+
+var errShortMessage = errors.New("message is too short")
+
+// decoder reads message fields. Reading past the end of the message
+// sets err and returns zero values.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = errors.New("malformed message")
+	}
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if len(d.b) < n {
+		d.err = errShortMessage
+		return nil
+	}
+	b := d.b[:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *decoder) uint8() uint8 {
+	b := d.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) int16() int {
+	b := d.next(2)
+	if b == nil {
+		return 0
+	}
+	return int(int16(binary.BigEndian.Uint16(b)))
+}
+
+func (d *decoder) uint32() uint32 {
+	b := d.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (d *decoder) uint64() uint64 {
+	b := d.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+func (d *decoder) lsn() LSN {
+	return LSN(d.uint64())
+}
+
+func (d *decoder) time() time.Time {
+	return pgTime(int64(d.uint64()))
+}
+
+func (d *decoder) string() string {
+	if d.err != nil {
+		return ""
+	}
+	for i, c := range d.b {
+		if c == 0 {
+			s := string(d.b[:i])
+			d.b = d.b[i+1:]
+			return s
+		}
+	}
+	d.err = errShortMessage
+	return ""
+}
+
+func (d *decoder) expect(c byte) bool {
+	if d.uint8() != c {
+		d.fail()
+		return false
+	}
+	return true
+}
+
+func (d *decoder) tuple() []TupleColumn {
+	n := d.int16()
+	if d.err != nil {
+		return nil
+	}
+	cols := make([]TupleColumn, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		col := TupleColumn{Kind: d.uint8()}
+		switch col.Kind {
+		case 'n', 'u':
+		case 't':
+			col.Data = d.next(int(d.uint32()))
+		default:
+			d.fail()
+		}
+		cols = append(cols, col)
+	}
+	return cols
+}
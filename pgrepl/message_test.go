@@ -0,0 +1,151 @@
+package pgrepl_test
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/pg.v5/pgrepl"
+)
+
+type msgBuilder []byte
+
+func (b msgBuilder) byte(c byte) msgBuilder {
+	return append(b, c)
+}
+
+func (b msgBuilder) int16(n int16) msgBuilder {
+	return append(b, byte(n>>8), byte(n))
+}
+
+func (b msgBuilder) int32(n uint32) msgBuilder {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], n)
+	return append(b, buf[:]...)
+}
+
+func (b msgBuilder) int64(n uint64) msgBuilder {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], n)
+	return append(b, buf[:]...)
+}
+
+func (b msgBuilder) string(s string) msgBuilder {
+	return append(append(b, s...), 0)
+}
+
+func (b msgBuilder) text(s string) msgBuilder {
+	return append(b.byte('t').int32(uint32(len(s))), s...)
+}
+
+// 2017-01-02 00:00:00 UTC in microseconds since 2000-01-01.
+const commitTime = 536630400 * 1000000
+
+func TestDecode(t *testing.T) {
+	tm := time.Date(2017, time.January, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		b   msgBuilder
+		msg pgrepl.Message
+	}{{
+		msgBuilder{'B'}.int64(0x16B374D848).int64(commitTime).int32(42),
+		&pgrepl.Begin{FinalLSN: 0x16B374D848, CommitTime: tm, XID: 42},
+	}, {
+		msgBuilder{'C'}.byte(0).int64(100).int64(200).int64(commitTime),
+		&pgrepl.Commit{CommitLSN: 100, EndLSN: 200, CommitTime: tm},
+	}, {
+		msgBuilder{'O'}.int64(100).string("node1"),
+		&pgrepl.Origin{CommitLSN: 100, Name: "node1"},
+	}, {
+		msgBuilder{'R'}.int32(16384).string("public").string("users").byte('d').int16(2).
+			byte(1).string("id").int32(23).int32(0xffffffff).
+			byte(0).string("name").int32(25).int32(0xffffffff),
+		&pgrepl.Relation{
+			ID:              16384,
+			Namespace:       "public",
+			Name:            "users",
+			ReplicaIdentity: 'd',
+			Columns: []pgrepl.RelationColumn{
+				{Key: true, Name: "id", TypeOID: 23, TypeModifier: -1},
+				{Name: "name", TypeOID: 25, TypeModifier: -1},
+			},
+		},
+	}, {
+		msgBuilder{'Y'}.int32(16390).string("public").string("mood"),
+		&pgrepl.Type{ID: 16390, Namespace: "public", Name: "mood"},
+	}, {
+		msgBuilder{'I'}.int32(16384).byte('N').int16(3).text("1").byte('n').byte('u'),
+		&pgrepl.Insert{
+			RelationID: 16384,
+			New: []pgrepl.TupleColumn{
+				{Kind: 't', Data: []byte("1")},
+				{Kind: 'n'},
+				{Kind: 'u'},
+			},
+		},
+	}, {
+		msgBuilder{'U'}.int32(16384).byte('N').int16(1).text("2"),
+		&pgrepl.Update{
+			RelationID: 16384,
+			New:        []pgrepl.TupleColumn{{Kind: 't', Data: []byte("2")}},
+		},
+	}, {
+		msgBuilder{'U'}.int32(16384).byte('K').int16(1).text("1").byte('N').int16(1).text("2"),
+		&pgrepl.Update{
+			RelationID: 16384,
+			OldIsKey:   true,
+			Old:        []pgrepl.TupleColumn{{Kind: 't', Data: []byte("1")}},
+			New:        []pgrepl.TupleColumn{{Kind: 't', Data: []byte("2")}},
+		},
+	}, {
+		msgBuilder{'D'}.int32(16384).byte('O').int16(1).text("1"),
+		&pgrepl.Delete{
+			RelationID: 16384,
+			Old:        []pgrepl.TupleColumn{{Kind: 't', Data: []byte("1")}},
+		},
+	}, {
+		msgBuilder{'T'}.int32(2).byte(1).int32(16384).int32(16385),
+		&pgrepl.Truncate{Options: 1, RelationIDs: []uint32{16384, 16385}},
+	}}
+
+	for _, test := range tests {
+		msg, err := pgrepl.Decode(test.b)
+		if err != nil {
+			t.Errorf("%q: %s", test.b[0], err)
+			continue
+		}
+		if !reflect.DeepEqual(msg, test.msg) {
+			t.Errorf("%q: got %#v, wanted %#v", test.b[0], msg, test.msg)
+		}
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	tests := []msgBuilder{
+		nil,
+		msgBuilder{'B'}.int64(1),
+		msgBuilder{'R'}.int32(1).string("public"),
+		msgBuilder{'I'}.int32(1).byte('X'),
+		msgBuilder{'I'}.int32(1).byte('N').int16(1).byte('t').int32(10),
+		msgBuilder{'D'}.int32(1).byte('N').int16(0),
+		msgBuilder{'Z'},
+	}
+	for _, b := range tests {
+		if msg, err := pgrepl.Decode(b); err == nil {
+			t.Errorf("%q: got %#v, wanted an error", []byte(b), msg)
+		}
+	}
+}
+
+func TestTupleColumn(t *testing.T) {
+	if !(pgrepl.TupleColumn{Kind: 'n'}).IsNull() {
+		t.Errorf("'n' column must be NULL")
+	}
+	if !(pgrepl.TupleColumn{Kind: 'u'}).IsUnchanged() {
+		t.Errorf("'u' column must be unchanged")
+	}
+	if col := (pgrepl.TupleColumn{Kind: 't'}); col.IsNull() || col.IsUnchanged() {
+		t.Errorf("'t' column must have a value")
+	}
+}
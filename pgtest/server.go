@@ -0,0 +1,666 @@
+/*
+Package pgtest implements a fake PostgreSQL server that speaks enough
+of the frontend/backend protocol to test the client deterministically:
+startup, authentication, simple queries with scripted rows, errors,
+delays, cancel requests and disconnects, e.g.
+
+    srv := pgtest.NewServer(nil)
+    defer srv.Close()
+
+    srv.Handle("SELECT 1",
+        &pgtest.Response{Disconnect: true},
+        &pgtest.Response{Columns: []string{"?column?"}, Rows: [][]interface{}{{1}}},
+    )
+
+    db := pg.Connect(&pg.Options{
+        Dialer:     srv.Dial,
+        MaxRetries: 1,
+    })
+
+    // The query is retried after the disconnect, because it is idempotent.
+    _, err := db.Exec(pg.Idempotent("SELECT 1"))
+
+The extended query protocol, e.g. prepared statements, is not
+supported and is answered with an error. Protocol options (_pq_.*)
+sent in the startup message are rejected with NegotiateProtocolVersion.
+*/
+package pgtest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/pg.v5/types"
+)
+
+const (
+	protocolVersion   = 196608
+	sslRequestCode    = 80877103
+	cancelRequestCode = 80877102
+)
+
+var errClosed = errors.New("pgtest: server is closed")
+
+type Options struct {
+	// Password enables cleartext password authentication. Clients
+	// with a different password get the 28P01 error.
+	Password string
+	// Params are sent to the client with ParameterStatus messages
+	// after authentication.
+	Params map[string]string
+}
+
+// Response is the scripted response to a query. The messages are sent
+// in the protocol order: Error or RowDescription with Columns, DataRow
+// for each of the Rows and CommandComplete with Tag.
+type Response struct {
+	Columns []string
+	// Rows are converted to PostgreSQL text format, nil is NULL.
+	Rows [][]interface{}
+	// Tag is the command tag, e.g. "UPDATE 1". The default is
+	// "SELECT n" where n is the number of rows.
+	Tag string
+
+	// Error is sent instead of the rows.
+	Error *Error
+	// Delay is the time to wait before responding. The query is
+	// canceled with the 57014 error when the client sends a cancel
+	// request during the delay.
+	Delay time.Duration
+	// Disconnect closes the connection after the rows are sent
+	// without completing the command.
+	Disconnect bool
+}
+
+// Error is sent with ErrorResponse message.
+type Error struct {
+	// Severity defaults to ERROR.
+	Severity string
+	Code     string
+	Message  string
+}
+
+func (e *Error) appendFields(b []byte) []byte {
+	severity := e.Severity
+	if severity == "" {
+		severity = "ERROR"
+	}
+	b = appendField(b, 'S', severity)
+	b = appendField(b, 'C', e.Code)
+	b = appendField(b, 'M', e.Message)
+	return append(b, 0)
+}
+
+func appendField(b []byte, c byte, s string) []byte {
+	b = append(b, c)
+	b = append(b, s...)
+	return append(b, 0)
+}
+
+// HandlerFunc returns the response to the query. Nil is answered with
+// an error.
+type HandlerFunc func(query string) *Response
+
+type handler struct {
+	responses []*Response
+	n         int
+}
+
+func (h *handler) next() *Response {
+	i := h.n
+	if i >= len(h.responses) {
+		i = len(h.responses) - 1
+	}
+	h.n++
+	return h.responses[i]
+}
+
+// Server is a fake PostgreSQL server. It is safe for concurrent use by
+// multiple goroutines.
+type Server struct {
+	opt Options
+	ln  net.Listener
+
+	mu       sync.Mutex
+	handlers map[string]*handler
+	fallback HandlerFunc
+	queries  []string
+	startups []map[string]string
+	conns    map[*conn]struct{}
+	lastPid  int32
+	closed   bool
+	done     chan struct{}
+
+	wg sync.WaitGroup
+}
+
+// NewServer starts the server listening on a random local TCP port.
+// Options can be nil.
+func NewServer(opt *Options) *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("pgtest: failed to listen: %s", err))
+	}
+
+	srv := &Server{
+		ln:       ln,
+		handlers: make(map[string]*handler),
+		conns:    make(map[*conn]struct{}),
+		done:     make(chan struct{}),
+	}
+	if opt != nil {
+		srv.opt = *opt
+	}
+
+	srv.wg.Add(1)
+	go srv.accept()
+	return srv
+}
+
+// Addr returns the address of the server that can be used as
+// pg.Options.Addr.
+func (srv *Server) Addr() string {
+	return srv.ln.Addr().String()
+}
+
+// Dial connects to the server ignoring the network and addr, so it can
+// be used as pg.Options.Dialer. The connection is buffered by the
+// kernel, so the server and the client don't block each other when
+// both write, e.g. when the client terminates the connection after
+// a read timeout.
+func (srv *Server) Dial(network, addr string) (net.Conn, error) {
+	return net.Dial("tcp", srv.Addr())
+}
+
+// Handle sets the responses to the query. The responses are used in
+// order and the last one is repeated.
+func (srv *Server) Handle(query string, responses ...*Response) {
+	if len(responses) == 0 {
+		panic("pgtest: Handle requires at least one response")
+	}
+	srv.mu.Lock()
+	srv.handlers[query] = &handler{responses: responses}
+	srv.mu.Unlock()
+}
+
+// HandleFunc sets the function that is called for the queries without
+// responses set with Handle.
+func (srv *Server) HandleFunc(fn HandlerFunc) {
+	srv.mu.Lock()
+	srv.fallback = fn
+	srv.mu.Unlock()
+}
+
+// Queries returns the queries received by the server.
+func (srv *Server) Queries() []string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return append([]string(nil), srv.queries...)
+}
+
+// StartupParams returns the params of the startup messages received by
+// the server, one per client connection.
+func (srv *Server) StartupParams() []map[string]string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return append([]map[string]string(nil), srv.startups...)
+}
+
+// CloseConns closes the client connections as if the server was
+// restarted, but new connections are still accepted.
+func (srv *Server) CloseConns() {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for c := range srv.conns {
+		c.cn.Close()
+	}
+}
+
+// Close stops the listener, closes client connections and waits until
+// they are done.
+func (srv *Server) Close() error {
+	srv.mu.Lock()
+	if srv.closed {
+		srv.mu.Unlock()
+		return errClosed
+	}
+	srv.closed = true
+	close(srv.done)
+	for c := range srv.conns {
+		c.cn.Close()
+	}
+	srv.mu.Unlock()
+
+	err := srv.ln.Close()
+	srv.wg.Wait()
+	return err
+}
+
+func (srv *Server) accept() {
+	defer srv.wg.Done()
+	for {
+		cn, err := srv.ln.Accept()
+		if err != nil {
+			return
+		}
+		if err := srv.ServeConn(cn); err != nil {
+			return
+		}
+	}
+}
+
+// ServeConn serves the connection accepted by another listener, e.g.
+// Unix socket, in a new goroutine. It returns an error and closes
+// the connection if the server is closed.
+func (srv *Server) ServeConn(cn net.Conn) error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if srv.closed {
+		cn.Close()
+		return errClosed
+	}
+
+	srv.lastPid++
+	c := &conn{
+		srv:       srv,
+		cn:        cn,
+		rd:        bufio.NewReader(cn),
+		wr:        bufio.NewWriter(cn),
+		pid:       srv.lastPid,
+		secretKey: srv.lastPid * 7919,
+		cancel:    make(chan struct{}, 1),
+	}
+	srv.conns[c] = struct{}{}
+
+	srv.wg.Add(1)
+	go func() {
+		defer srv.wg.Done()
+		c.serve()
+
+		srv.mu.Lock()
+		delete(srv.conns, c)
+		srv.mu.Unlock()
+	}()
+	return nil
+}
+
+func (srv *Server) response(query string) *Response {
+	srv.mu.Lock()
+	srv.queries = append(srv.queries, query)
+	h, ok := srv.handlers[query]
+	var resp *Response
+	if ok {
+		resp = h.next()
+	}
+	fallback := srv.fallback
+	srv.mu.Unlock()
+
+	if resp != nil {
+		return resp
+	}
+	if fallback != nil {
+		if resp := fallback(query); resp != nil {
+			return resp
+		}
+	}
+	return &Response{
+		Error: &Error{
+			Code:    "XX000",
+			Message: fmt.Sprintf("pgtest: unexpected query %q", query),
+		},
+	}
+}
+
+func (srv *Server) cancelQuery(pid, secretKey int32) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	for c := range srv.conns {
+		if c.pid == pid && c.secretKey == secretKey {
+			select {
+			case c.cancel <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+type conn struct {
+	srv *Server
+	cn  net.Conn
+	rd  *bufio.Reader
+	wr  *bufio.Writer
+
+	pid       int32
+	secretKey int32
+	cancel    chan struct{}
+}
+
+func (c *conn) serve() {
+	defer c.cn.Close()
+
+	if !c.startup() {
+		return
+	}
+
+	var extended bool
+	for {
+		typ, err := c.rd.ReadByte()
+		if err != nil {
+			return
+		}
+		b, err := c.readMsg()
+		if err != nil {
+			return
+		}
+
+		switch typ {
+		case 'Q':
+			resp := c.srv.response(cstring(b))
+			if !c.respond(resp) {
+				return
+			}
+		case 'S':
+			if extended {
+				c.writeError(&Error{
+					Code:    "0A000",
+					Message: "pgtest: extended query protocol is not supported",
+				})
+				extended = false
+			}
+			c.writeMsg('Z', []byte{'I'})
+		case 'X':
+			return
+		default:
+			extended = true
+		}
+
+		if err := c.wr.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// startup reads the startup message and authenticates the client.
+// It reports whether the connection is ready for queries.
+func (c *conn) startup() bool {
+	for {
+		b, err := c.readMsg()
+		if err != nil || len(b) < 4 {
+			return false
+		}
+
+		switch code := binary.BigEndian.Uint32(b); code {
+		case sslRequestCode:
+			if _, err := c.cn.Write([]byte{'N'}); err != nil {
+				return false
+			}
+		case cancelRequestCode:
+			if len(b) == 12 {
+				c.srv.cancelQuery(
+					int32(binary.BigEndian.Uint32(b[4:])),
+					int32(binary.BigEndian.Uint32(b[8:])),
+				)
+			}
+			return false
+		case protocolVersion:
+			params := startupParams(b[4:])
+			c.srv.mu.Lock()
+			c.srv.startups = append(c.srv.startups, params)
+			c.srv.mu.Unlock()
+
+			if opts := protocolOptions(params); len(opts) > 0 {
+				c.writeMsg('v', appendNegotiateProtocolVersion(nil, opts))
+			}
+			if !c.authenticate(params) {
+				return false
+			}
+			return c.wr.Flush() == nil
+		default:
+			c.writeError(&Error{
+				Severity: "FATAL",
+				Code:     "0A000",
+				Message:  fmt.Sprintf("unsupported frontend protocol %d", code),
+			})
+			c.wr.Flush()
+			return false
+		}
+	}
+}
+
+func (c *conn) authenticate(params map[string]string) bool {
+	if password := c.srv.opt.Password; password != "" {
+		c.writeMsg('R', appendInt32(nil, 3))
+		if err := c.wr.Flush(); err != nil {
+			return false
+		}
+
+		typ, err := c.rd.ReadByte()
+		if err != nil {
+			return false
+		}
+		b, err := c.readMsg()
+		if err != nil {
+			return false
+		}
+		if typ != 'p' || cstring(b) != password {
+			c.writeError(&Error{
+				Severity: "FATAL",
+				Code:     "28P01",
+				Message: fmt.Sprintf(
+					"password authentication failed for user %q", params["user"],
+				),
+			})
+			c.wr.Flush()
+			return false
+		}
+	}
+
+	c.writeMsg('R', appendInt32(nil, 0))
+
+	names := make([]string, 0, len(c.srv.opt.Params))
+	for name := range c.srv.opt.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b := append([]byte(name), 0)
+		b = append(b, c.srv.opt.Params[name]...)
+		c.writeMsg('S', append(b, 0))
+	}
+
+	b := appendInt32(nil, c.pid)
+	c.writeMsg('K', appendInt32(b, c.secretKey))
+	c.writeMsg('Z', []byte{'I'})
+	return true
+}
+
+// respond writes the response to the simple query. It reports whether
+// the connection should be kept open.
+func (c *conn) respond(resp *Response) bool {
+	// Cancel requests sent before the query are ignored.
+	select {
+	case <-c.cancel:
+	default:
+	}
+
+	if resp.Delay > 0 {
+		t := time.NewTimer(resp.Delay)
+		select {
+		case <-t.C:
+		case <-c.srv.done:
+			t.Stop()
+			return false
+		case <-c.cancel:
+			t.Stop()
+			c.writeError(&Error{
+				Code:    "57014",
+				Message: "canceling statement due to user request",
+			})
+			c.writeMsg('Z', []byte{'I'})
+			return true
+		}
+	}
+
+	if resp.Error != nil {
+		c.writeError(resp.Error)
+		c.writeMsg('Z', []byte{'I'})
+		return !resp.Disconnect
+	}
+
+	if resp.Columns != nil {
+		c.writeMsg('T', appendRowDescription(nil, resp.Columns))
+	}
+	for _, row := range resp.Rows {
+		c.writeMsg('D', appendDataRow(nil, row))
+	}
+	if resp.Disconnect {
+		c.wr.Flush()
+		return false
+	}
+
+	tag := resp.Tag
+	if tag == "" {
+		tag = fmt.Sprintf("SELECT %d", len(resp.Rows))
+	}
+	c.writeMsg('C', append([]byte(tag), 0))
+	c.writeMsg('Z', []byte{'I'})
+	return true
+}
+
+// readMsg reads the length prefixed message body.
+func (c *conn) readMsg() ([]byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(c.rd, hdr[:]); err != nil {
+		return nil, err
+	}
+	n := int(binary.BigEndian.Uint32(hdr[:])) - 4
+	if n < 0 {
+		return nil, fmt.Errorf("pgtest: invalid message length %d", n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(c.rd, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (c *conn) writeMsg(typ byte, b []byte) {
+	c.wr.WriteByte(typ)
+	c.wr.Write(appendInt32(nil, int32(len(b)+4)))
+	c.wr.Write(b)
+}
+
+func (c *conn) writeError(e *Error) {
+	c.writeMsg('E', e.appendFields(nil))
+}
+
+func appendInt16(b []byte, n int16) []byte {
+	return append(b, byte(n>>8), byte(n))
+}
+
+func appendInt32(b []byte, n int32) []byte {
+	return append(b, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func appendRowDescription(b []byte, columns []string) []byte {
+	b = appendInt16(b, int16(len(columns)))
+	for _, col := range columns {
+		b = append(b, col...)
+		b = append(b, 0)
+		b = appendInt32(b, 0)  // table oid
+		b = appendInt16(b, 0)  // attribute number
+		b = appendInt32(b, 25) // text type oid
+		b = appendInt16(b, -1) // type size
+		b = appendInt32(b, -1) // type modifier
+		b = appendInt16(b, 0)  // text format
+	}
+	return b
+}
+
+func appendDataRow(b []byte, row []interface{}) []byte {
+	b = appendInt16(b, int16(len(row)))
+	for _, v := range row {
+		if v == nil {
+			b = appendInt32(b, -1)
+			continue
+		}
+		value := appendValue(nil, v)
+		b = appendInt32(b, int32(len(value)))
+		b = append(b, value...)
+	}
+	return b
+}
+
+func appendValue(b []byte, v interface{}) []byte {
+	if v, ok := v.(bool); ok {
+		if v {
+			return append(b, 't')
+		}
+		return append(b, 'f')
+	}
+	return types.Append(b, v, 0)
+}
+
+// protocolOptions returns the sorted names of the protocol options,
+// which are not supported by the server.
+func protocolOptions(params map[string]string) []string {
+	var opts []string
+	for name := range params {
+		if strings.HasPrefix(name, "_pq_.") {
+			opts = append(opts, name)
+		}
+	}
+	sort.Strings(opts)
+	return opts
+}
+
+func appendNegotiateProtocolVersion(b []byte, opts []string) []byte {
+	b = appendInt32(b, 0) // newest supported minor version
+	b = appendInt32(b, int32(len(opts)))
+	for _, opt := range opts {
+		b = append(b, opt...)
+		b = append(b, 0)
+	}
+	return b
+}
+
+func startupParams(b []byte) map[string]string {
+	params := make(map[string]string)
+	for {
+		name := cstring(b)
+		if name == "" {
+			return params
+		}
+		if len(name) == len(b) {
+			return params
+		}
+		b = b[len(name)+1:]
+		value := cstring(b)
+		if len(value) < len(b) {
+			b = b[len(value)+1:]
+		} else {
+			b = nil
+		}
+		params[name] = value
+	}
+}
+
+// cstring returns the null-terminated string at the start of b.
+func cstring(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,312 @@
+package pgtest_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/pg.v5"
+	"gopkg.in/pg.v5/pgtest"
+)
+
+func connect(srv *pgtest.Server, opt *pg.Options) *pg.DB {
+	if opt == nil {
+		opt = new(pg.Options)
+	}
+	opt.User = "postgres"
+	opt.Dialer = srv.Dial
+	return pg.Connect(opt)
+}
+
+func errorCode(err error) string {
+	if err, ok := err.(pg.Error); ok {
+		return err.Field('C')
+	}
+	return ""
+}
+
+type TestUser struct {
+	Id   int
+	Name string
+}
+
+func TestRows(t *testing.T) {
+	srv := pgtest.NewServer(nil)
+	defer srv.Close()
+
+	srv.Handle("SELECT id, name FROM users", &pgtest.Response{
+		Columns: []string{"id", "name"},
+		Rows:    [][]interface{}{{1, "admin"}, {2, nil}},
+	})
+
+	db := connect(srv, nil)
+	defer db.Close()
+
+	var users []TestUser
+	res, err := db.Query(&users, "SELECT id, name FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := res.RowsReturned(); n != 2 {
+		t.Fatalf("got %d rows, wanted 2", n)
+	}
+	if len(users) != 2 || users[0] != (TestUser{1, "admin"}) || users[1] != (TestUser{2, ""}) {
+		t.Fatalf("got %v", users)
+	}
+}
+
+func TestError(t *testing.T) {
+	srv := pgtest.NewServer(nil)
+	defer srv.Close()
+
+	srv.Handle("INSERT INTO users VALUES (1)", &pgtest.Response{
+		Error: &pgtest.Error{
+			Code:    "23505",
+			Message: "duplicate key value violates unique constraint",
+		},
+	})
+
+	db := connect(srv, nil)
+	defer db.Close()
+
+	_, err := db.Exec("INSERT INTO users VALUES (1)")
+	if pgErr, ok := err.(pg.Error); !ok || !pgErr.IntegrityViolation() {
+		t.Fatalf("got %v, wanted integrity violation", err)
+	}
+
+	// The connection is still usable after the error.
+	_, err = db.Exec("SELECT 1")
+	if err == nil || !strings.Contains(err.Error(), `unexpected query "SELECT 1"`) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestRetryAfterDisconnect(t *testing.T) {
+	srv := pgtest.NewServer(nil)
+	defer srv.Close()
+
+	srv.Handle("SELECT 1",
+		&pgtest.Response{Disconnect: true},
+		&pgtest.Response{Columns: []string{"?column?"}, Rows: [][]interface{}{{1}}},
+	)
+
+	db := connect(srv, &pg.Options{MaxRetries: 1})
+	defer db.Close()
+
+	// The query was sent before the disconnect, so it is retried only
+	// because it is idempotent.
+	var n int
+	if _, err := db.QueryOne(pg.Scan(&n), pg.Idempotent("SELECT 1")); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("got %d, wanted 1", n)
+	}
+	if queries := srv.Queries(); len(queries) != 2 {
+		t.Fatalf("got %q, wanted 2 queries", queries)
+	}
+}
+
+func TestNoRetryAfterReadTimeout(t *testing.T) {
+	srv := pgtest.NewServer(nil)
+	defer srv.Close()
+
+	srv.Handle("INSERT INTO users VALUES (1)", &pgtest.Response{
+		Delay: 100 * time.Millisecond,
+		Tag:   "INSERT 0 1",
+	})
+
+	db := connect(srv, &pg.Options{
+		MaxRetries:  2,
+		ReadTimeout: 10 * time.Millisecond,
+	})
+	defer db.Close()
+
+	_, err := db.Exec("INSERT INTO users VALUES (1)")
+	if err == nil {
+		t.Fatal("got nil error")
+	}
+	// The server may have executed the query, so it is not sent again.
+	if queries := srv.Queries(); len(queries) != 1 {
+		t.Fatalf("got %q, wanted 1 query", queries)
+	}
+}
+
+func TestMidStreamDisconnect(t *testing.T) {
+	srv := pgtest.NewServer(nil)
+	defer srv.Close()
+
+	srv.Handle("SELECT id FROM users", &pgtest.Response{
+		Columns:    []string{"id"},
+		Rows:       [][]interface{}{{1}, {2}},
+		Disconnect: true,
+	})
+
+	db := connect(srv, &pg.Options{MaxRetries: 1})
+	defer db.Close()
+
+	var ids []int
+	_, err := db.Query(pg.Scan(&ids), "SELECT id FROM users")
+	if err == nil {
+		t.Fatal("got nil error")
+	}
+	// The rows were partially read, so the query is not retried.
+	if queries := srv.Queries(); len(queries) != 1 {
+		t.Fatalf("got %q, wanted 1 query", queries)
+	}
+}
+
+func TestPassword(t *testing.T) {
+	srv := pgtest.NewServer(&pgtest.Options{Password: "secret"})
+	defer srv.Close()
+
+	srv.Handle("SELECT 1", &pgtest.Response{Tag: "SELECT 1"})
+
+	db := connect(srv, &pg.Options{Password: "secret"})
+	defer db.Close()
+	if _, err := db.Exec("SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	db = connect(srv, &pg.Options{Password: "wrong"})
+	defer db.Close()
+	_, err := db.Exec("SELECT 1")
+	if code := errorCode(err); code != "28P01" {
+		t.Fatalf("got %v, wanted 28P01", err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	srv := pgtest.NewServer(nil)
+	defer srv.Close()
+
+	srv.HandleFunc(func(query string) *pgtest.Response {
+		return &pgtest.Response{Delay: time.Minute}
+	})
+
+	db := connect(srv, nil)
+	defer db.Close()
+
+	var users []TestUser
+	err := db.Model(&users).Timeout(10 * time.Millisecond).Select()
+	if code := errorCode(err); code != "57014" {
+		t.Fatalf("got %v, wanted 57014", err)
+	}
+}
+
+func TestCancelAfterCompletion(t *testing.T) {
+	srv := pgtest.NewServer(nil)
+	defer srv.Close()
+
+	srv.HandleFunc(func(query string) *pgtest.Response {
+		// The cancel request arrives before the response is sent, so
+		// the server ignores it.
+		time.Sleep(50 * time.Millisecond)
+		return &pgtest.Response{Tag: "SELECT 0"}
+	})
+
+	db := connect(srv, nil)
+	defer db.Close()
+
+	var users []TestUser
+	err := db.Model(&users).Timeout(10 * time.Millisecond).Select()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The cancel request could still cancel the next statement, so
+	// the connection is not reused.
+	if n := db.PoolStats().TotalConns; n != 0 {
+		t.Fatalf("got %d conns, wanted 0", n)
+	}
+}
+
+func TestAddr(t *testing.T) {
+	srv := pgtest.NewServer(&pgtest.Options{
+		Params: map[string]string{"server_version": "9.6.0"},
+	})
+	defer srv.Close()
+
+	srv.Handle("SELECT 1", &pgtest.Response{Tag: "SELECT 1"})
+
+	db := pg.Connect(&pg.Options{
+		Addr: srv.Addr(),
+		User: "postgres",
+	})
+	defer db.Close()
+
+	if _, err := db.Exec("SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtendedProtocol(t *testing.T) {
+	srv := pgtest.NewServer(nil)
+	defer srv.Close()
+
+	db := connect(srv, nil)
+	defer db.Close()
+
+	_, err := db.Prepare("SELECT $1")
+	if code := errorCode(err); code != "0A000" {
+		t.Fatalf("got %v, wanted 0A000", err)
+	}
+}
+
+func TestStartupParams(t *testing.T) {
+	srv := pgtest.NewServer(nil)
+	defer srv.Close()
+
+	srv.Handle("SELECT 1", &pgtest.Response{Tag: "SELECT 1"})
+
+	db := connect(srv, &pg.Options{
+		ProtocolExtensions: map[string]string{"foo": "bar"},
+	})
+	defer db.Close()
+
+	// Both connections are used at the same time, so the second one
+	// is dialed.
+	for i := 0; i < 2; i++ {
+		cn, err := db.Conn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cn.Release()
+		if _, err := cn.Exec("SELECT 1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	params := srv.StartupParams()
+	if len(params) != 2 {
+		t.Fatalf("got %d startup messages, wanted 2", len(params))
+	}
+	if v := params[0]["_pq_.foo"]; v != "bar" {
+		t.Fatalf("got %q, wanted bar", v)
+	}
+}
+
+func TestCloseConns(t *testing.T) {
+	srv := pgtest.NewServer(nil)
+	defer srv.Close()
+
+	srv.Handle("SELECT 1", &pgtest.Response{Tag: "SELECT 1"})
+
+	db := connect(srv, nil)
+	defer db.Close()
+
+	cn, err := db.Conn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cn.Release()
+
+	srv.CloseConns()
+	if _, err := cn.Exec("SELECT 1"); err == nil {
+		t.Fatal("got nil error")
+	}
+
+	// New connections are still accepted.
+	if _, err := db.Exec("SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+}
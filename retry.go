@@ -0,0 +1,136 @@
+package pg
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"gopkg.in/pg.v5/internal"
+)
+
+// retryBudgetMax is the number of retries that can be accumulated in
+// the retry budget.
+const retryBudgetMax = 10
+
+// RetryPolicy configures retries of failed queries. The policy is
+// shared by all queries of the DB, so it must not be modified after
+// the DB is created.
+type RetryPolicy struct {
+	// Maximum number of retries of a query.
+	MaxRetries int
+
+	// Backoff before the first retry, which doubles with every retry
+	// up to MaxBackoff.
+	// Default is 250 milliseconds.
+	MinBackoff time.Duration
+	// Default is no limit.
+	MaxBackoff time.Duration
+	// Jitter randomizes backoff by up to the fraction of it, e.g. 0.2
+	// sleeps 80-120% of the backoff.
+	// Default is no jitter.
+	Jitter float64
+
+	// Budget limits retries to the ratio of queries, e.g. 0.1 allows
+	// one retry per 10 queries, so retries don't overload a failing
+	// server. Up to 10 retries can be saved in the budget.
+	// Default is no limit.
+	Budget float64
+
+	// Retryable reports whether the failed query can be retried. Safe
+	// failures happened before the query was sent to the server, e.g.
+	// while connecting or writing the query, or the query is marked
+	// with Idempotent. Other failures, including timeouts while waiting
+	// for the response, are ambiguous, because the query may have been
+	// executed.
+	// Default retries serialization failures, statement timeouts when
+	// RetryStatementTimeout is set and safe network errors.
+	Retryable func(err error, safe bool) bool
+
+	// spent is the number of retries taken from the budget, so the zero
+	// policy starts with the full budget and needs no initialization.
+	mu    sync.Mutex
+	spent float64
+}
+
+// deposit adds the budget of a query.
+func (p *RetryPolicy) deposit() {
+	if p.Budget <= 0 {
+		return
+	}
+	p.mu.Lock()
+	p.spent -= p.Budget
+	if p.spent < 0 {
+		p.spent = 0
+	}
+	p.mu.Unlock()
+}
+
+// withdraw takes a retry from the budget.
+func (p *RetryPolicy) withdraw() bool {
+	if p.Budget <= 0 {
+		return true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if retryBudgetMax-p.spent < 1 {
+		return false
+	}
+	p.spent++
+	return true
+}
+
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	d := p.MinBackoff
+	if d == 0 {
+		d = internal.RetryBackoff
+	}
+	for i := 0; i < retry && (p.MaxBackoff == 0 || d < p.MaxBackoff); i++ {
+		if d > math.MaxInt64/2 {
+			d = math.MaxInt64
+			break
+		}
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		f := float64(d) + (rand.Float64()*2-1)*p.Jitter*float64(d)
+		if f >= math.MaxInt64 {
+			return math.MaxInt64
+		}
+		d = time.Duration(f)
+	}
+	return d
+}
+
+// retry reports whether the query that failed on the attempt should be
+// retried and sleeps before the retry. It stops sleeping and gives up
+// when the context of the DB is done.
+func (db *DB) retry(attempt int, err error, safe bool) bool {
+	if err == nil {
+		return false
+	}
+	p := db.opt.RetryPolicy
+	if attempt >= p.MaxRetries {
+		return false
+	}
+
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = db.shouldRetry
+	}
+	if !retryable(err, safe) || !p.withdraw() {
+		return false
+	}
+
+	t := time.NewTimer(p.backoff(attempt))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-db.ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,95 @@
+package pg
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/pg.v5/internal"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 50 * time.Millisecond,
+	}
+
+	wanted := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		50 * time.Millisecond,
+		50 * time.Millisecond,
+	}
+	for i, w := range wanted {
+		if d := p.backoff(i); d != w {
+			t.Fatalf("backoff(%d) = %s, wanted %s", i, d, w)
+		}
+	}
+
+	p.Jitter = 0.2
+	for i := 0; i < 100; i++ {
+		d := p.backoff(0)
+		if d < 8*time.Millisecond || d > 12*time.Millisecond {
+			t.Fatalf("backoff with jitter = %s, wanted 8ms-12ms", d)
+		}
+	}
+}
+
+func TestRetryPolicyBackoffLimit(t *testing.T) {
+	p := &RetryPolicy{Jitter: 0.5}
+	if d := p.backoff(0); d < internal.RetryBackoff/2 || d > internal.RetryBackoff*3/2 {
+		t.Fatalf("got %s, wanted default backoff with jitter", d)
+	}
+	for _, retry := range []int{35, 63, 100} {
+		if d := p.backoff(retry); d <= 0 {
+			t.Fatalf("backoff(%d) = %s, wanted positive", retry, d)
+		}
+	}
+}
+
+func TestRetryPolicyBudget(t *testing.T) {
+	p := &RetryPolicy{Budget: 0.5}
+
+	for i := 0; i < retryBudgetMax; i++ {
+		if !p.withdraw() {
+			t.Fatalf("withdraw %d failed", i)
+		}
+	}
+	if p.withdraw() {
+		t.Fatal("withdraw from empty budget succeeded")
+	}
+
+	p.deposit()
+	if p.withdraw() {
+		t.Fatal("withdraw of half a retry succeeded")
+	}
+	p.deposit()
+	p.deposit()
+	if !p.withdraw() {
+		t.Fatal("withdraw after deposits failed")
+	}
+
+	for i := 0; i < 100; i++ {
+		p.deposit()
+	}
+	if p.spent != 0 {
+		t.Fatalf("got %v spent retries, wanted 0", p.spent)
+	}
+}
+
+func TestRetryPolicySharedBudget(t *testing.T) {
+	p := &RetryPolicy{Budget: 0.1}
+	db1 := Connect(&Options{RetryPolicy: p})
+	defer db1.Close()
+
+	if !p.withdraw() {
+		t.Fatal("withdraw failed")
+	}
+
+	// Connecting with the same policy doesn't refill the budget.
+	db2 := Connect(&Options{RetryPolicy: p})
+	defer db2.Close()
+	if p.spent != 1 {
+		t.Fatalf("got %v spent retries, wanted 1", p.spent)
+	}
+}
@@ -1,8 +1,11 @@
 package pg
 
 import (
+	"encoding/hex"
+	"fmt"
+	"math"
 	"sync"
-	"time"
+	"sync/atomic"
 
 	"gopkg.in/pg.v5/internal"
 	"gopkg.in/pg.v5/internal/pool"
@@ -10,18 +13,31 @@ import (
 	"gopkg.in/pg.v5/types"
 )
 
+// closedStmts is incremented when a statement is closed, so
+// connections only look for statements to deallocate after a change.
+var closedStmts uint32
+
 // Stmt is a prepared statement. Stmt is safe for concurrent use by
 // multiple goroutines.
+//
+// Statements created with DB.Prepare are not bound to a connection.
+// They are prepared on demand on whichever connection from the pool
+// executes them and are prepared again after the connection is lost
+// or the statement is deallocated, e.g. by DISCARD ALL. Statements
+// created with Tx.Prepare use the connection of the transaction.
 type Stmt struct {
 	db *DB
+	q  string
 
-	mu   sync.Mutex
-	_cn  *pool.Conn
-	inTx bool
+	mu         sync.Mutex
+	paramTypes []int32
+	columns    []pool.Column
+	closed     int32
 
-	q       string
-	name    string
-	columns [][]byte
+	// Connection and name of the statement in the transaction.
+	inTx bool
+	_cn  *pool.Conn
+	name string
 
 	stickyErr error
 }
@@ -34,45 +50,142 @@ func (db *DB) Prepare(q string) (*Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
-	return prepare(db, cn, q)
+
+	stmt := &Stmt{
+		db: db,
+		q:  q,
+	}
+	_, err = stmt.prepare(cn)
+	err = db.freeConn(cn, err)
+	if err != nil {
+		return nil, err
+	}
+	return stmt, nil
 }
 
-func (stmt *Stmt) conn() (*pool.Conn, error) {
-	if stmt._cn == nil {
-		if stmt.stickyErr != nil {
-			return nil, stmt.stickyErr
-		}
-		return nil, errStmtClosed
+// prepare returns the name of the statement prepared on the pooled
+// connection and prepares it when needed.
+func (stmt *Stmt) prepare(cn *pool.Conn) (string, error) {
+	if name, ok := cn.Stmts[stmt]; ok {
+		return name, nil
 	}
 
-	stmt._cn.SetReadWriteTimeout(stmt.db.opt.ReadTimeout, stmt.db.opt.WriteTimeout)
-	return stmt._cn, nil
-}
+	name, paramTypes, columns, err := prepareConn(cn, stmt.q)
+	if err != nil {
+		return "", err
+	}
+	if cn.Stmts == nil {
+		cn.Stmts = make(map[interface{}]string)
+	}
+	cn.Stmts[stmt] = name
 
-func (stmt *Stmt) exec(params ...interface{}) (*types.Result, error) {
 	stmt.mu.Lock()
-	defer stmt.mu.Unlock()
+	stmt.paramTypes = paramTypes
+	stmt.columns = columns
+	stmt.mu.Unlock()
+
+	return name, nil
+}
+
+// withConn calls fn with the connection and the name of the statement
+// prepared on it. It reports whether the failure is safe to retry,
+// which is never the case in a transaction.
+func (stmt *Stmt) withConn(fn func(cn *pool.Conn, name string, desc stmtDesc) error) (bool, error) {
+	if stmt.inTx {
+		stmt.mu.Lock()
+		defer stmt.mu.Unlock()
+
+		if stmt._cn == nil {
+			if stmt.stickyErr != nil {
+				return false, stmt.stickyErr
+			}
+			return false, errStmtClosed
+		}
+		stmt._cn.SetReadWriteTimeout(stmt.db.opt.ReadTimeout, stmt.db.opt.WriteTimeout)
+		return false, fn(stmt._cn, stmt.name, stmtDesc{stmt.paramTypes, stmt.columns})
+	}
+
+	if stmt.isClosed() {
+		return false, errStmtClosed
+	}
 
-	cn, err := stmt.conn()
+	cn, err := stmt.db.conn()
 	if err != nil {
-		return nil, err
+		return true, err
+	}
+
+	// Preparing does not execute the statement, so only failures after
+	// the statement is flushed are ambiguous.
+	name, err := stmt.prepare(cn)
+	flushes := cn.Flushes()
+	if err == nil {
+		err = fn(cn, name, stmt.desc())
+		if isStmtNotExist(err) {
+			// The statement was deallocated, e.g. by DISCARD ALL.
+			delete(cn.Stmts, stmt)
+			name, err = stmt.prepare(cn)
+			flushes = cn.Flushes()
+			if err == nil {
+				err = fn(cn, name, stmt.desc())
+			}
+		}
 	}
-	return extQuery(cn, stmt.name, params...)
+	safe := cn.Flushes() == flushes
+	err = stmt.db.freeConn(cn, err)
+	return safe, err
+}
+
+// stmtDesc describes params and result columns of the statement.
+type stmtDesc struct {
+	paramTypes []int32
+	columns    []pool.Column
+}
+
+func (stmt *Stmt) desc() stmtDesc {
+	stmt.mu.Lock()
+	desc := stmtDesc{stmt.paramTypes, stmt.columns}
+	stmt.mu.Unlock()
+	return desc
+}
+
+// ParamTypes returns data type OIDs of the statement params as
+// described by the server. Params are converted to these types, e.g.
+// []byte is sent as text to a json param and as a UUID string to a
+// uuid param.
+func (stmt *Stmt) ParamTypes() []int32 {
+	return stmt.desc().paramTypes
+}
+
+func (stmt *Stmt) isClosed() bool {
+	return atomic.LoadInt32(&stmt.closed) == 1
+}
+
+func isStmtNotExist(err error) bool {
+	pgErr, ok := err.(Error)
+	return ok && pgErr.Field('C') == "26000" // invalid_sql_statement_name
+}
+
+func (stmt *Stmt) exec(params ...interface{}) (res *types.Result, safe bool, err error) {
+	safe, err = stmt.withConn(func(cn *pool.Conn, name string, desc stmtDesc) (err error) {
+		if qlog := newStmtLog(stmt.db, stmt.q, params); qlog != nil {
+			defer func() { qlog.done(cn, err) }()
+		}
+		res, err = extQuery(cn, name, desc.paramTypes, params...)
+		return err
+	})
+	return res, safe, err
 }
 
 // Exec executes a prepared statement with the given parameters.
 func (stmt *Stmt) Exec(params ...interface{}) (res *types.Result, err error) {
-	for i := 0; i < 3; i++ {
-		res, err = stmt.exec(params...)
+	stmt.db.opt.RetryPolicy.deposit()
+	for i := 0; ; i++ {
+		var safe bool
+		res, safe, err = stmt.exec(params...)
 
-		if i >= stmt.db.opt.MaxRetries {
+		if !stmt.db.retry(i, err, safe) {
 			break
 		}
-		if !stmt.db.shouldRetry(err) {
-			break
-		}
-
-		time.Sleep(internal.RetryBackoff << uint(i))
 	}
 	if err != nil {
 		stmt.setErr(err)
@@ -95,42 +208,38 @@ func (stmt *Stmt) ExecOne(params ...interface{}) (*types.Result, error) {
 	return res, nil
 }
 
-func (stmt *Stmt) query(model interface{}, params ...interface{}) (*types.Result, error) {
-	stmt.mu.Lock()
-	defer stmt.mu.Unlock()
-
-	cn, err := stmt.conn()
-	if err != nil {
-		return nil, err
-	}
-
-	res, mod, err := extQueryData(cn, stmt.name, model, stmt.columns, params...)
+func (stmt *Stmt) query(model interface{}, params ...interface{}) (res *types.Result, safe bool, err error) {
+	var mod orm.Model
+	safe, err = stmt.withConn(func(cn *pool.Conn, name string, desc stmtDesc) (err error) {
+		if qlog := newStmtLog(stmt.db, stmt.q, params); qlog != nil {
+			defer func() { qlog.done(cn, err) }()
+		}
+		res, mod, err = extQueryData(cn, name, model, desc, params...)
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return nil, safe, err
 	}
 
 	if res.RowsReturned() > 0 && mod != nil {
 		if err = mod.AfterQuery(stmt.db); err != nil {
-			return res, err
+			return res, false, err
 		}
 	}
 
-	return res, nil
+	return res, false, nil
 }
 
 // Query executes a prepared query statement with the given parameters.
 func (stmt *Stmt) Query(model interface{}, params ...interface{}) (res *types.Result, err error) {
-	for i := 0; i < 3; i++ {
-		res, err = stmt.query(model, params...)
+	stmt.db.opt.RetryPolicy.deposit()
+	for i := 0; ; i++ {
+		var safe bool
+		res, safe, err = stmt.query(model, params...)
 
-		if i >= stmt.db.opt.MaxRetries {
-			break
-		}
-		if !stmt.db.shouldRetry(err) {
+		if !stmt.db.retry(i, err, safe) {
 			break
 		}
-
-		time.Sleep(internal.RetryBackoff << uint(i))
 	}
 	if err != nil {
 		stmt.setErr(err)
@@ -159,13 +268,25 @@ func (stmt *Stmt) QueryOne(model interface{}, params ...interface{}) (*types.Res
 }
 
 func (stmt *Stmt) setErr(e error) {
+	stmt.mu.Lock()
 	if stmt.stickyErr == nil {
 		stmt.stickyErr = e
 	}
+	stmt.mu.Unlock()
 }
 
-// Close closes the statement.
+// Close closes the statement. Statements created with DB.Prepare are
+// deallocated on each connection the next time it is taken from the
+// pool.
 func (stmt *Stmt) Close() error {
+	if !stmt.inTx {
+		if !atomic.CompareAndSwapInt32(&stmt.closed, 0, 1) {
+			return errStmtClosed
+		}
+		atomic.AddUint32(&closedStmts, 1)
+		return nil
+	}
+
 	stmt.mu.Lock()
 	defer stmt.mu.Unlock()
 
@@ -174,41 +295,71 @@ func (stmt *Stmt) Close() error {
 	}
 
 	err := closeStmt(stmt._cn, stmt.name)
-	if !stmt.inTx {
-		_ = stmt.db.freeConn(stmt._cn, err)
-	}
 	stmt._cn = nil
 	return err
 }
 
-func prepare(db *DB, cn *pool.Conn, q string) (*Stmt, error) {
-	name := cn.NextId()
-	writeParseDescribeSyncMsg(cn.Wr, name, q)
-	if err := cn.FlushWriter(); err != nil {
-		db.freeConn(cn, err)
-		return nil, err
+// closeStmts deallocates statements that were closed since the
+// connection was last used.
+func closeStmts(cn *pool.Conn) error {
+	if len(cn.Stmts) == 0 {
+		return nil
+	}
+	gen := atomic.LoadUint32(&closedStmts)
+	if cn.StmtsGen == gen {
+		return nil
+	}
+	for key, name := range cn.Stmts {
+		if !key.(*Stmt).isClosed() {
+			continue
+		}
+		if err := closeStmt(cn, name); err != nil {
+			return err
+		}
+		delete(cn.Stmts, key)
 	}
+	cn.StmtsGen = gen
+	return nil
+}
 
-	columns, err := readParseDescribeSync(cn)
+func prepare(db *DB, cn *pool.Conn, q string) (*Stmt, error) {
+	name, paramTypes, columns, err := prepareConn(cn, q)
 	if err != nil {
-		db.freeConn(cn, err)
 		return nil, err
 	}
 
 	stmt := &Stmt{
-		db:      db,
-		_cn:     cn,
-		q:       q,
-		name:    name,
-		columns: columns,
+		db:         db,
+		q:          q,
+		_cn:        cn,
+		name:       name,
+		paramTypes: paramTypes,
+		columns:    columns,
 	}
 	return stmt, nil
 }
 
-func extQuery(cn *pool.Conn, name string, params ...interface{}) (*types.Result, error) {
-	if err := writeBindExecuteMsg(cn.Wr, name, params...); err != nil {
+func prepareConn(cn *pool.Conn, q string) (string, []int32, []pool.Column, error) {
+	name := cn.NextId()
+	writeParseDescribeSyncMsg(cn.Wr, name, q)
+	if err := cn.FlushWriter(); err != nil {
+		return "", nil, nil, err
+	}
+
+	paramTypes, columns, err := readParseDescribeSync(cn)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return name, paramTypes, columns, nil
+}
+
+func extQuery(
+	cn *pool.Conn, name string, paramTypes []int32, params ...interface{},
+) (*types.Result, error) {
+	if err := writeBindExecuteMsg(cn.Wr, name, paramTypes, params...); err != nil {
 		return nil, err
 	}
+	cn.Queries++
 	if err := cn.FlushWriter(); err != nil {
 		return nil, err
 	}
@@ -216,15 +367,16 @@ func extQuery(cn *pool.Conn, name string, params ...interface{}) (*types.Result,
 }
 
 func extQueryData(
-	cn *pool.Conn, name string, model interface{}, columns [][]byte, params ...interface{},
+	cn *pool.Conn, name string, model interface{}, desc stmtDesc, params ...interface{},
 ) (*types.Result, orm.Model, error) {
-	if err := writeBindExecuteMsg(cn.Wr, name, params...); err != nil {
+	if err := writeBindExecuteMsg(cn.Wr, name, desc.paramTypes, params...); err != nil {
 		return nil, nil, err
 	}
+	cn.Queries++
 	if err := cn.FlushWriter(); err != nil {
 		return nil, nil, err
 	}
-	return readExtQueryData(cn, model, columns)
+	return readExtQueryData(cn, model, desc.columns)
 }
 
 func closeStmt(cn *pool.Conn, name string) error {
@@ -235,3 +387,80 @@ func closeStmt(cn *pool.Conn, name string) error {
 	}
 	return readCloseCompleteMsg(cn)
 }
+
+// PostgreSQL data type OIDs of params that are converted.
+const (
+	pgBytea   = 17
+	pgText    = 25
+	pgOID     = 26
+	pgJSON    = 114
+	pgXML     = 142
+	pgBpchar  = 1042
+	pgVarchar = 1043
+	pgUUID    = 2950
+	pgJSONB   = 3802
+)
+
+// appendParam appends the param in text format converting it to the
+// data type when the default format is not accepted by the type.
+func appendParam(b []byte, param interface{}, oid int32) ([]byte, error) {
+	switch oid {
+	case pgBytea:
+		if s, ok := param.(string); ok {
+			return types.Append(b, []byte(s), 0), nil
+		}
+	case pgText, pgJSON, pgXML, pgBpchar, pgVarchar, pgJSONB:
+		if v, ok := param.([]byte); ok && v != nil {
+			return types.AppendString(b, string(v), 0), nil
+		}
+	case pgUUID:
+		switch v := param.(type) {
+		case [16]byte:
+			return appendUUID(b, v[:]), nil
+		case []byte:
+			if v == nil {
+				break
+			}
+			if len(v) != 16 {
+				return nil, fmt.Errorf("pg: can't use %d bytes as uuid", len(v))
+			}
+			return appendUUID(b, v), nil
+		}
+	case pgOID:
+		var n int64
+		switch v := param.(type) {
+		case int:
+			n = int64(v)
+		case int32:
+			n = int64(v)
+		case int64:
+			n = v
+		case uint64:
+			if v > math.MaxUint32 {
+				return nil, fmt.Errorf("pg: %d is out of range for oid", v)
+			}
+		case uint:
+			if uint64(v) > math.MaxUint32 {
+				return nil, fmt.Errorf("pg: %d is out of range for oid", v)
+			}
+		}
+		if n < 0 || n > math.MaxUint32 {
+			return nil, fmt.Errorf("pg: %d is out of range for oid", n)
+		}
+	}
+	return types.Append(b, param, 0), nil
+}
+
+func appendUUID(b []byte, u []byte) []byte {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return append(b, buf[:]...)
+}
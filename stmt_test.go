@@ -0,0 +1,98 @@
+package pg
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/pg.v5/internal/pool"
+)
+
+const pgInt4 = 23
+
+func TestAppendParam(t *testing.T) {
+	uuid := [16]byte{
+		0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3,
+		0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00,
+	}
+
+	tests := []struct {
+		param  interface{}
+		oid    int32
+		wanted string
+	}{
+		{"\\x41", pgBytea, `\x5c783431`},
+		{[]byte(`{"a":1}`), pgJSONB, `{"a":1}`},
+		{[]byte("text"), pgText, "text"},
+		{[]byte("bytes"), pgBytea, `\x6279746573`},
+		{uuid, pgUUID, "123e4567-e89b-12d3-a456-426614174000"},
+		{uuid[:], pgUUID, "123e4567-e89b-12d3-a456-426614174000"},
+		{"123e4567-e89b-12d3-a456-426614174000", pgUUID, "123e4567-e89b-12d3-a456-426614174000"},
+		{int64(4294967295), pgOID, "4294967295"},
+		{42, pgInt4, "42"},
+	}
+	for _, test := range tests {
+		b, err := appendParam(nil, test.param, test.oid)
+		if err != nil {
+			t.Fatalf("%v: %s", test.param, err)
+		}
+		if string(b) != test.wanted {
+			t.Errorf("%v as %d: got %q, wanted %q", test.param, test.oid, b, test.wanted)
+		}
+	}
+
+	errTests := []struct {
+		param interface{}
+		oid   int32
+	}{
+		{[]byte{1, 2, 3}, pgUUID},
+		{int64(-1), pgOID},
+		{int64(4294967296), pgOID},
+		{uint64(4294967296), pgOID},
+	}
+	for _, test := range errTests {
+		if _, err := appendParam(nil, test.param, test.oid); err == nil {
+			t.Errorf("%v as %d: expected an error", test.param, test.oid)
+		}
+	}
+}
+
+func TestWriteBindExecuteMsgChecksParams(t *testing.T) {
+	buf := pool.NewWriteBuffer()
+	err := writeBindExecuteMsg(buf, "1", []int32{pgInt4, pgInt4}, 1)
+	if err == nil || err.Error() != "pg: statement expects 2 params, got 1" {
+		t.Errorf("got %v", err)
+	}
+
+	err = writeBindExecuteMsg(buf, "1", []int32{pgOID}, -1)
+	if err == nil || !strings.HasPrefix(err.Error(), "pg: param $1: ") {
+		t.Errorf("got %v", err)
+	}
+	if len(buf.Bytes) != 0 {
+		t.Errorf("buffer is not reset: %q", buf.Bytes)
+	}
+
+	if err := writeBindExecuteMsg(buf, "1", nil, 1, 2); err != nil {
+		t.Errorf("params must not be checked without types, got %v", err)
+	}
+}
+
+func TestParseLastInsertId(t *testing.T) {
+	tests := []struct {
+		row    []byte
+		wanted int64
+		ok     bool
+	}{
+		{[]byte{0, 1, 0, 0, 0, 2, '4', '2'}, 42, true},
+		{[]byte{0, 2, 0, 0, 0, 1, '7', 0, 0, 0, 1, 'x'}, 7, true},
+		{[]byte{0, 1, 0xff, 0xff, 0xff, 0xff}, 0, false},
+		{[]byte{0, 1, 0, 0, 0, 3, 'a', 'b', 'c'}, 0, false},
+		{[]byte{0, 1, 0, 0, 0, 21, '1', '2', '3', '4', '5', '6', '7', '8', '9', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', '0', '1'}, 0, false},
+		{[]byte{0, 0}, 0, false},
+	}
+	for _, test := range tests {
+		n, ok := parseLastInsertId(appendIdColumn(nil, test.row))
+		if n != test.wanted || ok != test.ok {
+			t.Errorf("%v: got %d, %v, wanted %d, %v", test.row, n, ok, test.wanted, test.ok)
+		}
+	}
+}
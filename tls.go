@@ -0,0 +1,175 @@
+package pg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+)
+
+// SSL modes that have the same meaning as libpq sslmode.
+const (
+	// SSLDisable only tries a non-SSL connection.
+	SSLDisable = "disable"
+	// SSLAllow first tries a non-SSL connection and then an SSL
+	// connection when the server rejects the first one.
+	SSLAllow = "allow"
+	// SSLPrefer first tries an SSL connection and falls back to a
+	// non-SSL connection when the server does not support SSL.
+	SSLPrefer = "prefer"
+	// SSLRequire requires an SSL connection. The server certificate is
+	// verified like in SSLVerifyCA when SSLRootCert is set.
+	SSLRequire = "require"
+	// SSLVerifyCA requires an SSL connection and verifies that the
+	// server certificate is signed by a trusted CA.
+	SSLVerifyCA = "verify-ca"
+	// SSLVerifyFull is like SSLVerifyCA, but also verifies that the
+	// server host name matches the certificate.
+	SSLVerifyFull = "verify-full"
+)
+
+// newTLSConfig returns TLS config for the SSL mode and certificate
+// options. Root certificates are loaded from SSLRootCert file or system
+// roots are used when it is empty. It returns nil config for the
+// disable mode.
+func newTLSConfig(opt *Options) (*tls.Config, error) {
+	mode := opt.SSLMode
+	if mode == SSLRequire && opt.SSLRootCert != "" {
+		mode = SSLVerifyCA
+	}
+
+	var conf *tls.Config
+	switch mode {
+	case SSLDisable:
+		return nil, nil
+	case SSLAllow, SSLPrefer, SSLRequire:
+		conf = &tls.Config{InsecureSkipVerify: true}
+	case SSLVerifyCA, SSLVerifyFull:
+		roots, err := loadRootCerts(opt.SSLRootCert)
+		if err != nil {
+			return nil, err
+		}
+
+		if mode == SSLVerifyFull {
+			host, _, err := net.SplitHostPort(opt.Addr)
+			if err != nil {
+				host = opt.Addr
+			}
+			conf = &tls.Config{
+				ServerName: host,
+				RootCAs:    roots,
+			}
+		} else {
+			conf, err = verifyCATLSConfig(roots)
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("pg: sslmode '%v' is not supported", mode)
+	}
+
+	if opt.SSLCert != "" || opt.SSLKey != "" {
+		cert, err := loadClientCert(opt.SSLCert, opt.SSLKey, opt.SSLPassword)
+		if err != nil {
+			return nil, err
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return conf, nil
+}
+
+func loadRootCerts(file string) (*x509.CertPool, error) {
+	if file == "" {
+		return nil, nil
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("pg: no certificates found in %s", file)
+	}
+	return roots, nil
+}
+
+// loadClientCert loads PEM encoded certificate and key. Encrypted keys
+// are decrypted using the password.
+func loadClientCert(certFile, keyFile, password string) (tls.Certificate, error) {
+	if certFile == "" || keyFile == "" {
+		return tls.Certificate{}, errors.New("pg: both SSLCert and SSLKey are required")
+	}
+
+	certPEM, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	keyPEM, err = decryptKey(keyPEM, password)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+func decryptKey(keyPEM []byte, password string) ([]byte, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("pg: no PEM data found in SSLKey")
+	}
+
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		return nil, errors.New("pg: PKCS#8 encrypted keys are not supported; use a key encrypted with a PEM cipher")
+	}
+	// Keys encrypted by openssl, e.g. with -aes256, have the
+	// Proc-Type header and are decrypted by x509.DecryptPEMBlock.
+	if !x509.IsEncryptedPEMBlock(block) {
+		return keyPEM, nil
+	}
+	if password == "" {
+		return nil, errors.New("pg: SSLKey is encrypted and SSLPassword is not set")
+	}
+
+	der, err := x509.DecryptPEMBlock(block, []byte(password))
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  block.Type,
+		Bytes: der,
+	}), nil
+}
+
+func verifyCertChain(rawCerts [][]byte, roots *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return errors.New("pg: server did not send a certificate")
+	}
+
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, b := range rawCerts {
+		cert, err := x509.ParseCertificate(b)
+		if err != nil {
+			return err
+		}
+		certs[i] = cert
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(opts)
+	return err
+}
@@ -0,0 +1,16 @@
+//go:build !go1.8
+// +build !go1.8
+
+package pg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+)
+
+// verifyCATLSConfig returns an error, because tls.Config can't verify
+// the chain without the host name before Go 1.8.
+func verifyCATLSConfig(roots *x509.CertPool) (*tls.Config, error) {
+	return nil, errors.New("pg: sslmode 'verify-ca' requires Go 1.8 or newer")
+}
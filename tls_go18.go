@@ -0,0 +1,21 @@
+//go:build go1.8
+// +build go1.8
+
+package pg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+)
+
+// verifyCATLSConfig returns TLS config for the verify-ca mode, which
+// checks the chain, but not the host name, which is not supported by
+// tls.Config directly.
+func verifyCATLSConfig(roots *x509.CertPool) (*tls.Config, error) {
+	return &tls.Config{
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyCertChain(rawCerts, roots)
+		},
+	}, nil
+}
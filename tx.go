@@ -94,17 +94,22 @@ func (tx *Tx) conn() (*pool.Conn, error) {
 	return cn, nil
 }
 
-func (tx *Tx) freeConn(cn *pool.Conn, err error) {
+func (tx *Tx) freeConn(cn *pool.Conn, err error) error {
 	if tx.db.opt.DisableTransaction {
-		_ = tx.db.freeConn(cn, err)
+		return tx.db.freeConn(cn, err)
 	}
+	if isBadConn(err, false) {
+		// The connection is removed when the transaction is closed.
+		return newConnError(cn, err, true)
+	}
+	return err
 }
 
 // Stmt returns a transaction-specific prepared statement from an existing statement.
 func (tx *Tx) Stmt(stmt *Stmt) *Stmt {
 	stmt, err := tx.Prepare(stmt.q)
 	if err != nil {
-		return &Stmt{stickyErr: err}
+		return &Stmt{inTx: true, stickyErr: err}
 	}
 	return stmt
 }
@@ -122,7 +127,7 @@ func (tx *Tx) Prepare(q string) (*Stmt, error) {
 	}
 
 	stmt, err := prepare(tx.db, cn, q)
-	tx.freeConn(cn, err)
+	err = tx.freeConn(cn, err)
 	if err != nil {
 		return nil, err
 	}
@@ -141,7 +146,7 @@ func (tx *Tx) Exec(query interface{}, params ...interface{}) (*types.Result, err
 	}
 
 	res, err := tx.db.simpleQuery(cn, query, params...)
-	tx.freeConn(cn, err)
+	err = tx.freeConn(cn, err)
 	return res, err
 }
 
@@ -168,7 +173,7 @@ func (tx *Tx) Query(model interface{}, query interface{}, params ...interface{})
 	}
 
 	res, mod, err := tx.db.simpleQueryData(cn, model, query, params...)
-	tx.freeConn(cn, err)
+	err = tx.freeConn(cn, err)
 	if err != nil {
 		return nil, err
 	}
@@ -230,11 +235,51 @@ func (tx *Tx) Delete(model interface{}) error {
 // CreateTable creates table for the model. It recognizes following field tags:
 //   - notnull - sets NOT NULL constraint.
 //   - unique - sets UNIQUE constraint.
+//
+// Indexes are created by CreateIndexes.
 func (tx *Tx) CreateTable(model interface{}, opt *orm.CreateTableOptions) error {
 	_, err := orm.CreateTable(tx, model, opt)
 	return err
 }
 
+// CreateIndexes creates the indexes of the model. See DB.CreateIndexes.
+func (tx *Tx) CreateIndexes(model interface{}, opt *orm.CreateIndexOptions) error {
+	return orm.CreateIndexes(tx, model, opt)
+}
+
+// CreateExtension creates the extension, e.g.
+//
+//    err := tx.CreateExtension("hstore", &orm.CreateExtensionOptions{IfNotExists: true})
+func (tx *Tx) CreateExtension(name string, opt *orm.CreateExtensionOptions) error {
+	_, err := orm.CreateExtension(tx, name, opt)
+	return err
+}
+
+// CreateEnum creates enum type with the values. See DB.CreateEnum.
+func (tx *Tx) CreateEnum(name string, values ...string) error {
+	_, err := orm.CreateEnum(tx, name, values)
+	return err
+}
+
+// AddEnumValue adds the value to enum type. Before PostgreSQL 12
+// it fails inside a transaction. See DB.AddEnumValue.
+func (tx *Tx) AddEnumValue(name, value string, opt *orm.AddEnumValueOptions) error {
+	_, err := orm.AddEnumValue(tx, name, value, opt)
+	return err
+}
+
+// CreateSchema creates extensions, enums, tables and indexes for the models.
+// See DB.CreateSchema.
+func (tx *Tx) CreateSchema(models ...interface{}) error {
+	return orm.CreateSchema(tx, models, nil)
+}
+
+// Sync reconciles the table with reference rows in the model.
+// See DB.Sync.
+func (tx *Tx) Sync(model interface{}, opt *orm.SyncOptions) ([]orm.SyncChange, error) {
+	return orm.Sync(tx, model, opt)
+}
+
 func (tx *Tx) FormatQuery(dst []byte, query string, params ...interface{}) []byte {
 	return tx.db.FormatQuery(dst, query, params...)
 }
@@ -280,20 +325,62 @@ func (tx *Tx) close(lastErr error) error {
 	}
 	tx.stmts = nil
 
-	err := tx.db.freeConn(tx.cn, lastErr)
+	err := tx.db.releaseConn(tx.cn, tx.db.opt.ResetSessionQuery, lastErr)
 	tx.cn = nil
 
 	return err
 }
 
 // CopyFrom copies data from reader to a table.
-func (tx *Tx) CopyFrom(r io.Reader, query string, params ...interface{}) (*types.Result, error) {
+func (tx *Tx) CopyFrom(r io.Reader, query interface{}, params ...interface{}) (*types.Result, error) {
+	cn, err := tx.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := tx.db.copyFrom(cn, r, nil, query, params...)
+	err = tx.freeConn(cn, err)
+	return res, err
+}
+
+// CopyFromWithOptions is like CopyFrom, but reports progress and limits
+// the rate of copied data using the options.
+func (tx *Tx) CopyFromWithOptions(
+	r io.Reader, opt *CopyOptions, query interface{}, params ...interface{},
+) (*types.Result, error) {
+	cn, err := tx.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := tx.db.copyFrom(cn, r, opt, query, params...)
+	err = tx.freeConn(cn, err)
+	return res, err
+}
+
+// CopyTo copies data from a table to writer.
+func (tx *Tx) CopyTo(w io.Writer, query interface{}, params ...interface{}) (*types.Result, error) {
+	cn, err := tx.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := tx.db.copyTo(cn, w, nil, query, params...)
+	err = tx.freeConn(cn, err)
+	return res, err
+}
+
+// CopyToWithOptions is like CopyTo, but reports progress and limits the
+// rate of copied data using the options.
+func (tx *Tx) CopyToWithOptions(
+	w io.Writer, opt *CopyOptions, query interface{}, params ...interface{},
+) (*types.Result, error) {
 	cn, err := tx.conn()
 	if err != nil {
 		return nil, err
 	}
 
-	res, err := tx.db.copyFrom(cn, r, query, params...)
-	tx.freeConn(cn, err)
+	res, err := tx.db.copyTo(cn, w, opt, query, params...)
+	err = tx.freeConn(cn, err)
 	return res, err
 }
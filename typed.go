@@ -0,0 +1,130 @@
+//go:build go1.18
+// +build go1.18
+
+package pg
+
+import "gopkg.in/pg.v5/orm"
+
+// TypedQuery is a Query for the models of type T, which returns the
+// selected models instead of scanning them into a value passed by
+// pointer, e.g.
+//
+//    users, err := pg.NewQuery[User](db).Where("active").Order("id").Select()
+//
+// Query returns the underlying orm.Query for the methods that are not
+// wrapped.
+type TypedQuery[T any] struct {
+	q      *orm.Query
+	models []T
+}
+
+// NewQuery returns a TypedQuery for the models of type T, which must
+// be a struct type. The db can be DB or Tx.
+func NewQuery[T any](db orm.DB) *TypedQuery[T] {
+	tq := new(TypedQuery[T])
+	tq.q = orm.NewQuery(db, &tq.models)
+	return tq
+}
+
+// Query returns the underlying query.
+func (tq *TypedQuery[T]) Query() *orm.Query {
+	return tq.q
+}
+
+func (tq *TypedQuery[T]) Column(columns ...string) *TypedQuery[T] {
+	tq.q = tq.q.Column(columns...)
+	return tq
+}
+
+func (tq *TypedQuery[T]) Relation(name string, apply func(*orm.Query) (*orm.Query, error)) *TypedQuery[T] {
+	tq.q = tq.q.Relation(name, apply)
+	return tq
+}
+
+func (tq *TypedQuery[T]) Where(where string, params ...interface{}) *TypedQuery[T] {
+	tq.q = tq.q.Where(where, params...)
+	return tq
+}
+
+func (tq *TypedQuery[T]) WhereOr(where string, params ...interface{}) *TypedQuery[T] {
+	tq.q = tq.q.WhereOr(where, params...)
+	return tq
+}
+
+func (tq *TypedQuery[T]) WhereIn(where string, params ...interface{}) *TypedQuery[T] {
+	tq.q = tq.q.WhereIn(where, params...)
+	return tq
+}
+
+func (tq *TypedQuery[T]) Order(orders ...string) *TypedQuery[T] {
+	tq.q = tq.q.Order(orders...)
+	return tq
+}
+
+func (tq *TypedQuery[T]) Limit(n int) *TypedQuery[T] {
+	tq.q = tq.q.Limit(n)
+	return tq
+}
+
+func (tq *TypedQuery[T]) Offset(n int) *TypedQuery[T] {
+	tq.q = tq.q.Offset(n)
+	return tq
+}
+
+// Apply calls the fn passing the underlying query as an argument.
+func (tq *TypedQuery[T]) Apply(fn func(*orm.Query) (*orm.Query, error)) *TypedQuery[T] {
+	tq.q = tq.q.Apply(fn)
+	return tq
+}
+
+// Select selects the models.
+func (tq *TypedQuery[T]) Select() ([]T, error) {
+	tq.models = nil
+	if err := tq.q.Select(); err != nil {
+		return nil, err
+	}
+	return tq.models, nil
+}
+
+// First selects the first model ordered by primary key. It returns
+// ErrNoRows when there are no models.
+func (tq *TypedQuery[T]) First() (*T, error) {
+	tq.models = nil
+	if err := tq.q.First(); err != nil {
+		return nil, err
+	}
+	return tq.one()
+}
+
+// Last selects the last model ordered by primary key. It returns
+// ErrNoRows when there are no models.
+func (tq *TypedQuery[T]) Last() (*T, error) {
+	tq.models = nil
+	if err := tq.q.Last(); err != nil {
+		return nil, err
+	}
+	return tq.one()
+}
+
+func (tq *TypedQuery[T]) one() (*T, error) {
+	if len(tq.models) == 0 {
+		return nil, ErrNoRows
+	}
+	return &tq.models[0], nil
+}
+
+// Count returns the number of models matching the query.
+func (tq *TypedQuery[T]) Count() (int, error) {
+	return tq.q.Count()
+}
+
+// SelectAndCount selects the models and the number of models matching
+// the query ignoring limit and offset.
+func (tq *TypedQuery[T]) SelectAndCount() ([]T, int, error) {
+	tq.models = nil
+	count, err := tq.q.SelectAndCount()
+	if err != nil {
+		return nil, 0, err
+	}
+	return tq.models, count, nil
+}
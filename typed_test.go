@@ -0,0 +1,61 @@
+//go:build go1.18
+// +build go1.18
+
+package pg_test
+
+import (
+	"bytes"
+
+	"gopkg.in/pg.v5"
+	"gopkg.in/pg.v5/pgtest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type TypedUser struct {
+	Id   int
+	Name string
+}
+
+var _ = Describe("TypedQuery", func() {
+	var buf bytes.Buffer
+	var srv *pgtest.Server
+	var db *pg.DB
+
+	BeforeEach(func() {
+		srv = newFakeServer()
+		buf.Reset()
+		db = pg.Connect(&pg.Options{
+			User:      "postgres",
+			Database:  "postgres",
+			TraceWire: &buf,
+			Dialer:    srv.Dial,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).NotTo(HaveOccurred())
+		Expect(srv.Close()).NotTo(HaveOccurred())
+	})
+
+	It("selects models", func() {
+		users, err := pg.NewQuery[TypedUser](db).
+			Where("name = ?", "admin").
+			Order("id").
+			Limit(10).
+			Select()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(users).To(BeEmpty())
+		Expect(buf.String()).To(ContainSubstring(
+			`Query "SELECT \"typed_user\".\"id\", \"typed_user\".\"name\" FROM \"typed_users\" AS \"typed_user\" WHERE (name = 'admin') ORDER BY \"id\" LIMIT 10"`,
+		))
+	})
+
+	It("returns ErrNoRows when first model does not exist", func() {
+		user, err := pg.NewQuery[TypedUser](db).First()
+		Expect(err).To(Equal(pg.ErrNoRows))
+		Expect(user).To(BeNil())
+		Expect(buf.String()).To(ContainSubstring(`ORDER BY \"typed_user\".\"id\" LIMIT 1"`))
+	})
+})
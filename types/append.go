@@ -3,6 +3,7 @@ package types
 import (
 	"database/sql/driver"
 	"encoding/hex"
+	"math/big"
 	"reflect"
 	"strconv"
 	"time"
@@ -44,6 +45,12 @@ func Append(b []byte, v interface{}, quote int) []byte {
 		return AppendTime(b, v, quote)
 	case []byte:
 		return appendBytes(b, v, quote)
+	case *big.Int:
+		return AppendBigInt(b, v)
+	case *big.Rat:
+		return AppendBigRat(b, v)
+	case *big.Float:
+		return AppendBigFloat(b, v)
 	case ValueAppender:
 		return appendAppender(b, v, quote)
 	case driver.Valuer:
@@ -61,11 +68,30 @@ func AppendError(b []byte, err error) []byte {
 }
 
 func AppendNull(b []byte, quote int) []byte {
-	if quote == 1 {
-		return append(b, "NULL"...)
-	} else {
+	if quote == 0 {
 		return nil
 	}
+	return append(b, "NULL"...)
+}
+
+// elemQuote returns quote for values nested in array and hstore literals.
+// Quote 2 is used when the literal is enclosed in single quotes and quote 3
+// when it is not, e.g. for bind params, so single quotes are not doubled.
+func elemQuote(quote int) int {
+	if quote == 0 || quote == 3 {
+		return 3
+	}
+	return 2
+}
+
+// AppendBool appends v as TRUE or FALSE.
+func AppendBool(dst []byte, v bool) []byte {
+	return appendBool(dst, v)
+}
+
+// AppendFloat appends v in the format used for float fields.
+func AppendFloat(dst []byte, v float64) []byte {
+	return appendFloat(dst, v)
 }
 
 func appendBool(dst []byte, v bool) []byte {
@@ -80,7 +106,7 @@ func appendFloat(dst []byte, v float64) []byte {
 }
 
 func AppendString(b []byte, s string, quote int) []byte {
-	if quote == 2 {
+	if quote >= 2 {
 		b = append(b, '"')
 	} else if quote == 1 {
 		b = append(b, '\'')
@@ -93,14 +119,14 @@ func AppendString(b []byte, s string, quote int) []byte {
 			continue
 		}
 
-		if quote >= 1 {
+		if quote == 1 || quote == 2 {
 			if c == '\'' {
 				b = append(b, '\'', '\'')
 				continue
 			}
 		}
 
-		if quote == 2 {
+		if quote >= 2 {
 			if c == '"' {
 				b = append(b, '\\', '"')
 				continue
@@ -154,9 +180,9 @@ func AppendStringStringMap(b []byte, m map[string]string, quote int) []byte {
 	}
 
 	for key, value := range m {
-		b = AppendString(b, key, 2)
+		b = AppendString(b, key, elemQuote(quote))
 		b = append(b, '=', '>')
-		b = AppendString(b, value, 2)
+		b = AppendString(b, value, elemQuote(quote))
 		b = append(b, ',')
 	}
 	if len(m) > 0 {
@@ -6,6 +6,7 @@ import (
 )
 
 var stringType = reflect.TypeOf((*string)(nil)).Elem()
+var stringPtrType = reflect.TypeOf((*string)(nil))
 var sliceStringType = reflect.TypeOf([]string(nil))
 
 var intType = reflect.TypeOf((*int)(nil)).Elem()
@@ -44,7 +45,7 @@ func ArrayAppender(typ reflect.Type) AppenderFunc {
 		b = append(b, '{')
 		for i := 0; i < v.Len(); i++ {
 			elem := v.Index(i)
-			b = appendElem(b, elem, 2)
+			b = appendElem(b, elem, elemQuote(quote))
 			b = append(b, ',')
 		}
 		if v.Len() > 0 {
@@ -77,7 +78,7 @@ func appendSliceString(b []byte, ss []string, quote int) []byte {
 
 	b = append(b, '{')
 	for _, s := range ss {
-		b = AppendString(b, s, 2)
+		b = AppendString(b, s, elemQuote(quote))
 		b = append(b, ',')
 	}
 	if len(ss) > 0 {
@@ -0,0 +1,99 @@
+package types_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/pg.v5/types"
+)
+
+type arrayElem struct {
+	s string
+}
+
+var _ types.ValueAppender = arrayElem{}
+
+func (e arrayElem) AppendValue(b []byte, quote int) ([]byte, error) {
+	return types.AppendString(b, "elem:"+e.s, quote), nil
+}
+
+func (e *arrayElem) Scan(src interface{}) error {
+	if src == nil {
+		e.s = ""
+		return nil
+	}
+	e.s = strings.TrimPrefix(string(src.([]byte)), "elem:")
+	return nil
+}
+
+func TestAppendArray(t *testing.T) {
+	s := "it's"
+	n := 42
+
+	tests := []struct {
+		v      interface{}
+		quote  int
+		wanted string
+	}{
+		{types.NewArray([]string{"it's", `"\`}), 1, `'{"it''s","\"\\"}'`},
+		{types.NewArray([]string{"it's", `"\`}), 0, `{"it's","\"\\"}`},
+		{types.NewArray([][]string{{"it's"}}), 1, `'{{"it''s"}}'`},
+		{types.NewArray([][]string{{"it's"}}), 0, `{{"it's"}}`},
+		{types.NewArray([]*string{nil, &s}), 1, `'{NULL,"it''s"}'`},
+		{types.NewArray([]*string{nil, &s}), 0, `{NULL,"it's"}`},
+		{types.NewArray([][]int{{1, 2}, {3, 4}}), 1, `'{{1,2},{3,4}}'`},
+		{types.NewArray([][]int64{{1}, {2}}), 0, `{{1},{2}}`},
+		{types.NewArray([]*int{&n, nil}), 1, `'{42,NULL}'`},
+		{types.NewArray([]arrayElem{{"a"}, {"it's"}}), 1, `'{"elem:a","elem:it''s"}'`},
+		{types.NewArray([]*arrayElem{{"a"}, nil}), 0, `{"elem:a",NULL}`},
+		{types.NewHstore(map[string]string{"it's": "ok"}), 0, `"it's"=>"ok"`},
+	}
+	for _, test := range tests {
+		got := types.Append(nil, test.v, test.quote)
+		if string(got) != test.wanted {
+			t.Errorf("got %q, wanted %q (quote=%d)", got, test.wanted, test.quote)
+		}
+	}
+}
+
+func TestScanArray(t *testing.T) {
+	s := "it's"
+	n := 42
+
+	tests := []struct {
+		src    string
+		dst    interface{}
+		wanted interface{}
+	}{
+		{`{{1,2},{3,4}}`, new([][]int), [][]int{{1, 2}, {3, 4}}},
+		{`{{1},{NULL}}`, new([][]int64), [][]int64{{1}, {0}}},
+		{`{{"it's","b c"},{d,NULL}}`, new([][]string), [][]string{{"it's", "b c"}, {"d", ""}}},
+		{`{{{1}},{{2}}}`, new([][][]int), [][][]int{{{1}}, {{2}}}},
+		{`{42,NULL}`, new([]*int), []*int{&n, nil}},
+		{`{NULL,"it's"}`, new([]*string), []*string{nil, &s}},
+		{`{elem:a,"elem:b c",NULL}`, new([]arrayElem), []arrayElem{{"a"}, {"b c"}, {""}}},
+		{`{elem:a,NULL}`, new([]*arrayElem), []*arrayElem{{"a"}, nil}},
+	}
+	for _, test := range tests {
+		err := types.NewArray(test.dst).Scan([]byte(test.src))
+		if err != nil {
+			t.Errorf("Scan(%q) failed: %s", test.src, err)
+			continue
+		}
+		got := reflect.ValueOf(test.dst).Elem().Interface()
+		if !reflect.DeepEqual(got, test.wanted) {
+			t.Errorf("Scan(%q): got %#v, wanted %#v", test.src, got, test.wanted)
+		}
+	}
+}
+
+func TestScanArrayNull(t *testing.T) {
+	ints := [][]int{{1}}
+	if err := types.NewArray(&ints).Scan(nil); err != nil {
+		t.Fatal(err)
+	}
+	if ints != nil {
+		t.Errorf("got %v, wanted nil", ints)
+	}
+}
@@ -10,6 +10,23 @@ func AppendFieldBytes(b []byte, field []byte, quote int) []byte {
 	return appendField(b, parser.New(field), quote)
 }
 
+// AppendIdent appends the name as a single identifier doubling any
+// quotes in it. Unlike AppendField, the name is quoted regardless of
+// quote, so it is never appended as is.
+func AppendIdent(b []byte, name string, quote int) []byte {
+	b = append(b, '"')
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; c {
+		case '"':
+			b = append(b, '"', '"')
+		case '\000':
+		default:
+			b = append(b, c)
+		}
+	}
+	return append(b, '"')
+}
+
 func appendField(b []byte, p *parser.Parser, quote int) []byte {
 	var quoted bool
 	for p.Valid() {
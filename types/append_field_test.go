@@ -35,3 +35,14 @@ func TestAppendField(t *testing.T) {
 		}
 	}
 }
+
+func TestAppendIdent(t *testing.T) {
+	name := `name"; DROP TABLE users; --`
+	wanted := `"name""; DROP TABLE users; --"`
+	for _, quote := range []int{0, 1} {
+		got := types.AppendIdent(nil, name, quote)
+		if string(got) != wanted {
+			t.Errorf("got %q, wanted %q (quote=%d)", got, wanted, quote)
+		}
+	}
+}
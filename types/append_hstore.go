@@ -6,11 +6,15 @@ import (
 )
 
 var mapStringStringType = reflect.TypeOf(map[string]string(nil))
+var mapStringStringPtrType = reflect.TypeOf(map[string]*string(nil))
 
 func HstoreAppender(typ reflect.Type) AppenderFunc {
 	if typ.Key() == stringType && typ.Elem() == stringType {
 		return appendMapStringStringValue
 	}
+	if typ.Key() == stringType && typ.Elem() == stringPtrType {
+		return appendMapStringStringPtrValue
+	}
 	return func(b []byte, v reflect.Value, quote int) []byte {
 		err := fmt.Errorf("pg.Hstore(unsupported %s)", v.Type())
 		return AppendError(b, err)
@@ -27,9 +31,9 @@ func appendMapStringString(b []byte, m map[string]string, quote int) []byte {
 	}
 
 	for key, value := range m {
-		b = AppendString(b, key, 2)
+		b = AppendString(b, key, elemQuote(quote))
 		b = append(b, '=', '>')
-		b = AppendString(b, value, 2)
+		b = AppendString(b, value, elemQuote(quote))
 		b = append(b, ',')
 	}
 	if len(m) > 0 {
@@ -47,3 +51,38 @@ func appendMapStringStringValue(b []byte, v reflect.Value, quote int) []byte {
 	m := v.Convert(mapStringStringType).Interface().(map[string]string)
 	return appendMapStringString(b, m, quote)
 }
+
+func appendMapStringStringPtr(b []byte, m map[string]*string, quote int) []byte {
+	if m == nil {
+		return AppendNull(b, quote)
+	}
+
+	if quote == 1 {
+		b = append(b, '\'')
+	}
+
+	for key, value := range m {
+		b = AppendString(b, key, elemQuote(quote))
+		b = append(b, '=', '>')
+		if value == nil {
+			b = AppendNull(b, elemQuote(quote))
+		} else {
+			b = AppendString(b, *value, elemQuote(quote))
+		}
+		b = append(b, ',')
+	}
+	if len(m) > 0 {
+		b = b[:len(b)-1] // Strip trailing comma.
+	}
+
+	if quote == 1 {
+		b = append(b, '\'')
+	}
+
+	return b
+}
+
+func appendMapStringStringPtrValue(b []byte, v reflect.Value, quote int) []byte {
+	m := v.Convert(mapStringStringPtrType).Interface().(map[string]*string)
+	return appendMapStringStringPtr(b, m, quote)
+}
@@ -2,7 +2,6 @@ package types
 
 import (
 	"database/sql/driver"
-	"encoding/json"
 	"reflect"
 	"strconv"
 	"time"
@@ -54,6 +53,28 @@ func appender(typ reflect.Type, pgArray bool) AppenderFunc {
 		return appendTimeValue
 	}
 
+	if fn := numericAppender(typ); fn != nil {
+		return fn
+	}
+
+	if fn := netAppender(typ); fn != nil {
+		return fn
+	}
+
+	if fn := enumAppender(typ); fn != nil {
+		return fn
+	}
+
+	if typ == jsonRawMessageType {
+		return appendJSONRawMessageValue
+	}
+
+	if typ.Kind() == reflect.Ptr && (typ.Elem().Implements(appenderType) ||
+		typ.Elem().Implements(driverValuerType)) {
+		// Nil pointer to a value receiver is appended as NULL.
+		return ptrAppenderFunc(typ)
+	}
+
 	if typ.Implements(appenderType) {
 		return appendAppenderValue
 	}
@@ -134,14 +155,6 @@ func appendStructValue(b []byte, v reflect.Value, quote int) []byte {
 	return appendJSONValue(b, v, quote)
 }
 
-func appendJSONValue(b []byte, v reflect.Value, quote int) []byte {
-	bytes, err := json.Marshal(v.Interface())
-	if err != nil {
-		return AppendError(b, err)
-	}
-	return AppendJSONB(b, bytes, quote)
-}
-
 func appendTimeValue(b []byte, v reflect.Value, quote int) []byte {
 	tm := v.Interface().(time.Time)
 	return AppendTime(b, tm, quote)
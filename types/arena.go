@@ -0,0 +1,133 @@
+package types
+
+import (
+	"encoding/hex"
+	"reflect"
+	"sync"
+
+	"gopkg.in/pg.v5/internal"
+)
+
+const (
+	arenaChunkSize = 64 << 10
+	// Values larger than arenaMaxValue are allocated on the heap, so
+	// they don't waste the rest of the chunk.
+	arenaMaxValue = arenaChunkSize / 8
+)
+
+var arenaChunks = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, arenaChunkSize)
+		return &b
+	},
+}
+
+// Arena allocates scanned string and []byte values in large chunks of
+// memory that are reused after Release, which reduces GC pressure when
+// millions of small values are scanned. Values scanned into the arena
+// must not be used after Release. Arena is not safe for concurrent use.
+// The zero value is ready to use.
+type Arena struct {
+	chunks []*[]byte
+	cur    []byte
+}
+
+// Alloc returns a slice of n bytes allocated in the arena.
+func (a *Arena) Alloc(n int) []byte {
+	if n > arenaMaxValue {
+		return make([]byte, n)
+	}
+	if len(a.cur)+n > cap(a.cur) {
+		chunk := arenaChunks.Get().(*[]byte)
+		a.chunks = append(a.chunks, chunk)
+		a.cur = (*chunk)[:0]
+	}
+	start := len(a.cur)
+	a.cur = a.cur[:start+n]
+	return a.cur[start : start+n : start+n]
+}
+
+// Bytes returns a copy of b allocated in the arena.
+func (a *Arena) Bytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	dst := a.Alloc(len(b))
+	copy(dst, b)
+	return dst
+}
+
+// String returns b as a string allocated in the arena.
+func (a *Arena) String(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return internal.BytesToString(a.Bytes(b))
+}
+
+// Release returns the memory of the arena to the pool of chunks, so it
+// can be reused by other arenas. Values allocated in the arena must
+// not be used afterwards.
+func (a *Arena) Release() {
+	for _, chunk := range a.chunks {
+		*chunk = (*chunk)[:0]
+		arenaChunks.Put(chunk)
+	}
+	a.chunks = a.chunks[:0]
+	a.cur = nil
+}
+
+// ArenaScannerFunc scans the value into v allocating memory in the arena.
+type ArenaScannerFunc func(v reflect.Value, b []byte, a *Arena) error
+
+// ArenaScanner returns the scanner that allocates values in the arena
+// for string and []byte types that are scanned by the default scanners
+// and nil for other types.
+func ArenaScanner(typ reflect.Type) ArenaScannerFunc {
+	if typ == timeType || typ == durationType || typ == jsonRawMessageType || IsSQLScanner(typ) {
+		return nil
+	}
+	if numericScanner(typ) != nil || netScanner(typ) != nil || enumScanner(typ) != nil {
+		return nil
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return scanStringArena
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return scanBytesArena
+		}
+	}
+	return nil
+}
+
+func scanStringArena(v reflect.Value, b []byte, a *Arena) error {
+	if !v.CanSet() {
+		return internal.Errorf("pg: Scan(non-pointer %s)", v.Type())
+	}
+	v.SetString(a.String(b))
+	return nil
+}
+
+func scanBytesArena(v reflect.Value, b []byte, a *Arena) error {
+	if !v.CanSet() {
+		return internal.Errorf("pg: Scan(non-pointer %s)", v.Type())
+	}
+	if b == nil {
+		v.SetBytes(nil)
+		return nil
+	}
+	// Only hex format is supported.
+	if len(b) < 2 || b[0] != '\\' || b[1] != 'x' {
+		return internal.Errorf("pg: can't parse bytes: %q", b)
+	}
+
+	b = b[2:] // Trim off "\\x".
+	tmp := a.Alloc(hex.DecodedLen(len(b)))
+	if _, err := hex.Decode(tmp, b); err != nil {
+		return err
+	}
+	v.SetBytes(tmp)
+	return nil
+}
@@ -0,0 +1,85 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestArena(t *testing.T) {
+	var a Arena
+
+	s := a.String([]byte("hello"))
+	b := a.Bytes([]byte("world"))
+	if s != "hello" || string(b) != "world" {
+		t.Fatalf("got %q and %q", s, b)
+	}
+	if len(a.chunks) != 1 {
+		t.Fatalf("got %d chunks, wanted 1", len(a.chunks))
+	}
+	if cap(b) != len(b) {
+		t.Fatalf("appending to the value can overwrite the arena: cap=%d", cap(b))
+	}
+
+	if a.Bytes(nil) != nil {
+		t.Fatal("nil is not preserved")
+	}
+
+	large := a.Alloc(arenaMaxValue + 1)
+	if len(large) != arenaMaxValue+1 || len(a.chunks) != 1 {
+		t.Fatalf("large value is allocated in the arena")
+	}
+
+	for i := 0; i < arenaChunkSize/100+1; i++ {
+		a.Alloc(100)
+	}
+	if len(a.chunks) != 2 {
+		t.Fatalf("got %d chunks, wanted 2", len(a.chunks))
+	}
+
+	a.Release()
+	if len(a.chunks) != 0 || a.cur != nil {
+		t.Fatal("arena is not released")
+	}
+}
+
+func TestArenaScanner(t *testing.T) {
+	type MyString string
+
+	tests := []struct {
+		typ reflect.Type
+		ok  bool
+	}{
+		{reflect.TypeOf(""), true},
+		{reflect.TypeOf(MyString("")), true},
+		{reflect.TypeOf([]byte(nil)), true},
+		{reflect.TypeOf(0), false},
+		{reflect.TypeOf(time.Time{}), false},
+		{jsonRawMessageType, false},
+		{reflect.TypeOf([]string(nil)), false},
+	}
+	for _, test := range tests {
+		if ok := ArenaScanner(test.typ) != nil; ok != test.ok {
+			t.Errorf("%s: got %v, wanted %v", test.typ, ok, test.ok)
+		}
+	}
+
+	var a Arena
+	var s string
+	var b []byte
+	if err := ArenaScanner(reflect.TypeOf(s))(reflect.ValueOf(&s).Elem(), []byte("foo"), &a); err != nil {
+		t.Fatal(err)
+	}
+	if err := ArenaScanner(reflect.TypeOf(b))(reflect.ValueOf(&b).Elem(), []byte(`\x0102`), &a); err != nil {
+		t.Fatal(err)
+	}
+	if s != "foo" || !reflect.DeepEqual(b, []byte{1, 2}) {
+		t.Fatalf("got %q and %v", s, b)
+	}
+
+	// Escape format is not supported.
+	err := ArenaScanner(reflect.TypeOf(b))(reflect.ValueOf(&b).Elem(), []byte(`ab\000`), &a)
+	if err == nil {
+		t.Fatal("got nil error for escape format")
+	}
+}
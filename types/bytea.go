@@ -0,0 +1,136 @@
+package types
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const byteaChunkSize = 32 * 1024
+
+// ByteaReader is a query param that streams bytea value from the reader
+// directly into the query without buffering it in a []byte.
+type ByteaReader struct {
+	r    io.Reader
+	n    int64
+	read bool
+}
+
+var _ ValueAppender = (*ByteaReader)(nil)
+
+// NewByteaReader returns a query param that reads exactly n bytes from
+// the reader. The reader is consumed when the query is formatted, so
+// the param can be appended only once: appending it again, e.g. when
+// the query is retried, returns an error instead of an empty value.
+func NewByteaReader(r io.Reader, n int64) *ByteaReader {
+	return &ByteaReader{
+		r: r,
+		n: n,
+	}
+}
+
+func (r *ByteaReader) AppendValue(b []byte, quote int) ([]byte, error) {
+	if r.r == nil {
+		return AppendNull(b, quote), nil
+	}
+	if r.read {
+		return nil, errors.New("pg: ByteaReader is already read and can't be appended again")
+	}
+	r.read = true
+
+	if quote == 1 {
+		b = append(b, '\'')
+	}
+	b = append(b, "\\x"...)
+
+	b = growBytes(b, 2*r.n+1)
+	buf := make([]byte, byteaChunkSize)
+	for remaining := r.n; remaining > 0; {
+		chunk := buf
+		if remaining < int64(len(chunk)) {
+			chunk = chunk[:remaining]
+		}
+		if _, err := io.ReadFull(r.r, chunk); err != nil {
+			return nil, fmt.Errorf("pg: can't read bytea value: %s", err)
+		}
+
+		start := len(b)
+		b = b[:start+hex.EncodedLen(len(chunk))]
+		hex.Encode(b[start:], chunk)
+		remaining -= int64(len(chunk))
+	}
+
+	if quote == 1 {
+		b = append(b, '\'')
+	}
+	return b, nil
+}
+
+func growBytes(b []byte, n int64) []byte {
+	if int64(cap(b)-len(b)) >= n {
+		return b
+	}
+	bb := make([]byte, len(b), int64(len(b))+n)
+	copy(bb, b)
+	return bb
+}
+
+// ByteaWriter scans bytea value into the writer. The value is decoded
+// in chunks straight from the connection read buffer so no []byte of
+// the value size is allocated. NULL writes nothing.
+type ByteaWriter struct {
+	w io.Writer
+	n int64
+}
+
+var _ sql.Scanner = (*ByteaWriter)(nil)
+
+func NewByteaWriter(w io.Writer) *ByteaWriter {
+	return &ByteaWriter{
+		w: w,
+	}
+}
+
+// Written returns the number of bytes written by the last Scan.
+func (w *ByteaWriter) Written() int64 {
+	return w.n
+}
+
+func (w *ByteaWriter) Scan(src interface{}) error {
+	w.n = 0
+	if src == nil {
+		return nil
+	}
+
+	b, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("pg: can't scan %T into ByteaWriter", src)
+	}
+	if len(b) < 2 || b[0] != '\\' || b[1] != 'x' {
+		return fmt.Errorf("pg: can't parse bytea value: %.16q", b)
+	}
+	b = b[2:] // Trim off "\\x".
+
+	buf := make([]byte, byteaChunkSize)
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > hex.EncodedLen(len(buf)) {
+			chunk = chunk[:hex.EncodedLen(len(buf))]
+		}
+
+		n, err := hex.Decode(buf, chunk)
+		if err != nil {
+			return err
+		}
+		n, err = w.w.Write(buf[:n])
+		w.n += int64(n)
+		if err != nil {
+			return err
+		}
+
+		b = b[len(chunk):]
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package types_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gopkg.in/pg.v5/types"
+)
+
+func TestAppendByteaReader(t *testing.T) {
+	got := types.Append(nil, types.NewByteaReader(strings.NewReader("hello world"), 5), 1)
+	if wanted := `'\x68656c6c6f'`; string(got) != wanted {
+		t.Errorf("got %q, wanted %q", got, wanted)
+	}
+
+	r := types.NewByteaReader(strings.NewReader("hello"), 5)
+	types.Append(nil, r, 1)
+	got = types.Append(nil, r, 1)
+	if wanted := `?!(pg: ByteaReader is already read and can't be appended again)`; string(got) != wanted {
+		t.Errorf("got %q, wanted %q", got, wanted)
+	}
+
+	got = types.Append(nil, types.NewByteaReader(strings.NewReader("hi"), 5), 1)
+	if wanted := `?!(pg: can't read bytea value: unexpected EOF)`; string(got) != wanted {
+		t.Errorf("got %q, wanted %q", got, wanted)
+	}
+}
+
+func TestByteaRoundtrip(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 10000)
+
+	b := types.Append(nil, types.NewByteaReader(bytes.NewReader(data), int64(len(data))), 0)
+	if !bytes.Equal(b, types.Append(nil, data, 0)) {
+		t.Fatal("ByteaReader and []byte are appended differently")
+	}
+
+	var buf bytes.Buffer
+	w := types.NewByteaWriter(&buf)
+	if err := types.Scan(w, b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("got %d bytes, wanted %d", buf.Len(), len(data))
+	}
+	if w.Written() != int64(len(data)) {
+		t.Errorf("got %d, wanted %d", w.Written(), len(data))
+	}
+}
+
+func TestScanByteaWriterError(t *testing.T) {
+	var buf bytes.Buffer
+	err := types.Scan(types.NewByteaWriter(&buf), []byte("hello"))
+	if wanted := `pg: can't parse bytea value: "hello"`; err == nil || err.Error() != wanted {
+		t.Errorf("got %v, wanted %q", err, wanted)
+	}
+}
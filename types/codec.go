@@ -0,0 +1,50 @@
+package types
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+type codec struct {
+	append AppenderFunc
+	scan   ScannerFunc
+}
+
+var codecs = struct {
+	sync.RWMutex
+	m map[string]codec
+}{
+	m: make(map[string]codec),
+}
+
+// RegisterCodec registers appender and scanner under the name so they can
+// be used for struct fields with codec tag, e.g. `pg:",codec:name"`.
+func RegisterCodec(name string, appender AppenderFunc, scanner ScannerFunc) {
+	codecs.Lock()
+	codecs.m[name] = codec{
+		append: appender,
+		scan:   scanner,
+	}
+	codecs.Unlock()
+}
+
+// Codec returns appender and scanner registered under the name.
+// If there is no such codec returned funcs report an error.
+func Codec(name string) (AppenderFunc, ScannerFunc) {
+	codecs.RLock()
+	c, ok := codecs.m[name]
+	codecs.RUnlock()
+	if ok {
+		return c.append, c.scan
+	}
+
+	err := fmt.Errorf("pg: codec=%s is not registered", name)
+	appender := func(b []byte, _ reflect.Value, _ int) []byte {
+		return AppendError(b, err)
+	}
+	scanner := func(_ reflect.Value, _ []byte) error {
+		return err
+	}
+	return appender, scanner
+}
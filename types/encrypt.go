@@ -0,0 +1,156 @@
+package types
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"gopkg.in/pg.v5/internal"
+)
+
+// KeyProvider provides keys for column encryption, e.g. by fetching
+// them from a key management service. Keys must be 16, 24 or 32 bytes
+// long to select AES-128, AES-192 or AES-256.
+type KeyProvider interface {
+	// CurrentKey returns the key and its id that are used to encrypt
+	// new values.
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key by id that is used to decrypt values.
+	Key(id string) ([]byte, error)
+}
+
+// RegisterEncryptionCodec registers a codec that encrypts string and
+// []byte fields using AES-GCM before they are appended to the query and
+// decrypts them after they are scanned. Encrypted values are stored as
+// text in the form "key_id$base64(nonce|ciphertext)" so keys can be
+// rotated without re-encrypting existing rows.
+func RegisterEncryptionCodec(name string, keys KeyProvider) {
+	RegisterCodec(name, encryptionAppender(keys), encryptionScanner(keys))
+}
+
+func encryptionAppender(keys KeyProvider) AppenderFunc {
+	return func(b []byte, v reflect.Value, quote int) []byte {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return AppendNull(b, quote)
+			}
+			v = v.Elem()
+		}
+
+		var plaintext []byte
+		switch {
+		case v.Kind() == reflect.String:
+			plaintext = []byte(v.String())
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			plaintext = v.Bytes()
+		default:
+			err := fmt.Errorf("pg: can't encrypt %s", v.Type())
+			return AppendError(b, err)
+		}
+
+		s, err := encrypt(keys, plaintext)
+		if err != nil {
+			return AppendError(b, err)
+		}
+		return AppendString(b, s, quote)
+	}
+}
+
+func encryptionScanner(keys KeyProvider) ScannerFunc {
+	return func(v reflect.Value, b []byte) error {
+		if !v.CanSet() {
+			return fmt.Errorf("pg: Scan(nonsettable %s)", v.Type())
+		}
+
+		if b == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+
+		plaintext, err := decrypt(keys, internal.BytesToString(b))
+		if err != nil {
+			return err
+		}
+
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+
+		switch {
+		case v.Kind() == reflect.String:
+			v.SetString(string(plaintext))
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			v.SetBytes(plaintext)
+		default:
+			return fmt.Errorf("pg: can't decrypt into %s", v.Type())
+		}
+		return nil
+	}
+}
+
+func encrypt(keys KeyProvider, plaintext []byte) (string, error) {
+	id, key, err := keys.CurrentKey()
+	if err != nil {
+		return "", err
+	}
+	if strings.IndexByte(id, '$') != -1 {
+		return "", fmt.Errorf("pg: key id=%q must not contain '$'", id)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	b := gcm.Seal(nonce, nonce, plaintext, nil)
+	return id + "$" + base64.StdEncoding.EncodeToString(b), nil
+}
+
+func decrypt(keys KeyProvider, s string) ([]byte, error) {
+	ind := strings.IndexByte(s, '$')
+	if ind == -1 {
+		return nil, internal.Errorf("pg: can't parse encrypted value")
+	}
+
+	key, err := keys.Key(s[:ind])
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := base64.StdEncoding.DecodeString(s[ind+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < gcm.NonceSize() {
+		return nil, internal.Errorf("pg: can't parse encrypted value")
+	}
+
+	nonce, ciphertext := b[:gcm.NonceSize()], b[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,92 @@
+package types_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/pg.v5/types"
+)
+
+type testKeys struct {
+	current string
+	keys    map[string][]byte
+}
+
+func (k *testKeys) CurrentKey() (string, []byte, error) {
+	return k.current, k.keys[k.current], nil
+}
+
+func (k *testKeys) Key(id string) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, errors.New("unknown key")
+	}
+	return key, nil
+}
+
+func TestEncryptionCodec(t *testing.T) {
+	keys := &testKeys{
+		current: "k1",
+		keys: map[string][]byte{
+			"k1": []byte("0123456789abcdef"),
+			"k2": []byte("0123456789abcdef0123456789abcdef"),
+		},
+	}
+	types.RegisterEncryptionCodec("encryption_test", keys)
+	appender, scanner := types.Codec("encryption_test")
+
+	src := "secret"
+	b := appender(nil, reflect.ValueOf(src), 0)
+	if !strings.HasPrefix(string(b), "k1$") {
+		t.Fatalf("got %q, wanted k1$ prefix", b)
+	}
+	if strings.Contains(string(b), src) {
+		t.Fatalf("got %q, wanted encrypted value", b)
+	}
+
+	// Values encrypted with the old key are still readable after rotation.
+	keys.current = "k2"
+
+	var dst string
+	if err := scanner(reflect.ValueOf(&dst).Elem(), b); err != nil {
+		t.Fatal(err)
+	}
+	if dst != src {
+		t.Fatalf("got %q, wanted %q", dst, src)
+	}
+
+	var bs []byte
+	b = appender(nil, reflect.ValueOf([]byte(src)), 1)
+	b = b[1 : len(b)-1] // Trim quotes.
+	if err := scanner(reflect.ValueOf(&bs).Elem(), b); err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != src {
+		t.Fatalf("got %q, wanted %q", bs, src)
+	}
+}
+
+func TestEncryptionCodecNilPointer(t *testing.T) {
+	keys := &testKeys{
+		current: "k1",
+		keys:    map[string][]byte{"k1": []byte("0123456789abcdef")},
+	}
+	types.RegisterEncryptionCodec("encryption_nil_test", keys)
+	appender, scanner := types.Codec("encryption_nil_test")
+
+	var src *string
+	b := appender(nil, reflect.ValueOf(&src).Elem(), 1)
+	if string(b) != "NULL" {
+		t.Fatalf("got %q, wanted NULL", b)
+	}
+
+	dst := new(string)
+	if err := scanner(reflect.ValueOf(&dst).Elem(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if dst != nil {
+		t.Fatalf("got %q, wanted nil", *dst)
+	}
+}
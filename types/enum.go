@@ -0,0 +1,114 @@
+package types
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"gopkg.in/pg.v5/internal"
+)
+
+type enum struct {
+	name   string
+	labels []string
+	known  map[string]struct{}
+}
+
+var enums = struct {
+	sync.RWMutex
+	m map[reflect.Type]*enum
+}{
+	m: make(map[reflect.Type]*enum),
+}
+
+// RegisterEnum registers string based Go type as PostgreSQL enum with
+// the name and labels. Values of the type are appended and scanned only
+// when they are one of the labels; otherwise an error is returned. Empty
+// value is appended as NULL. Types must be registered before they are
+// used, e.g. in init:
+//
+//    type Mood string
+//
+//    func init() {
+//        types.RegisterEnum("mood", Mood(""), "sad", "ok", "happy")
+//    }
+func RegisterEnum(name string, v interface{}, labels ...string) {
+	typ := reflect.TypeOf(v)
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.String {
+		panic(fmt.Errorf("pg: RegisterEnum(unsupported %T)", v))
+	}
+
+	e := &enum{
+		name:   name,
+		labels: labels,
+		known:  make(map[string]struct{}, len(labels)),
+	}
+	for _, label := range labels {
+		e.known[label] = struct{}{}
+	}
+
+	enums.Lock()
+	enums.m[typ] = e
+	enums.Unlock()
+}
+
+// LookupEnum returns the enum name and labels registered for the type.
+func LookupEnum(typ reflect.Type) (name string, labels []string, ok bool) {
+	e := lookupEnum(typ)
+	if e == nil {
+		return "", nil, false
+	}
+	return e.name, e.labels, true
+}
+
+func lookupEnum(typ reflect.Type) *enum {
+	enums.RLock()
+	e := enums.m[typ]
+	enums.RUnlock()
+	return e
+}
+
+func enumAppender(typ reflect.Type) AppenderFunc {
+	e := lookupEnum(typ)
+	if e == nil {
+		return nil
+	}
+	return func(b []byte, v reflect.Value, quote int) []byte {
+		s := v.String()
+		if s == "" {
+			return AppendNull(b, quote)
+		}
+		if _, ok := e.known[s]; !ok {
+			return AppendError(b, e.unknownLabel(s))
+		}
+		return AppendString(b, s, quote)
+	}
+}
+
+func enumScanner(typ reflect.Type) ScannerFunc {
+	e := lookupEnum(typ)
+	if e == nil {
+		return nil
+	}
+	return func(v reflect.Value, b []byte) error {
+		if !v.CanSet() {
+			return internal.Errorf("pg: Scan(non-pointer %s)", v.Type())
+		}
+		if b == nil {
+			v.SetString("")
+			return nil
+		}
+		if _, ok := e.known[internal.BytesToString(b)]; !ok {
+			return e.unknownLabel(string(b))
+		}
+		v.SetString(string(b))
+		return nil
+	}
+}
+
+func (e *enum) unknownLabel(label string) error {
+	return fmt.Errorf("pg: unknown label %q for enum %q", label, e.name)
+}
@@ -0,0 +1,73 @@
+package types_test
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/pg.v5/types"
+)
+
+type Mood string
+
+func init() {
+	types.RegisterEnum("mood", Mood(""), "sad", "ok", "it's fine")
+}
+
+func TestAppendEnum(t *testing.T) {
+	happy := Mood("happy")
+
+	tests := []struct {
+		v      interface{}
+		quote  int
+		wanted string
+	}{
+		{Mood("sad"), 1, `'sad'`},
+		{Mood("it's fine"), 1, `'it''s fine'`},
+		{Mood(""), 1, `NULL`},
+		{&happy, 1, `?!(pg: unknown label "happy" for enum "mood")`},
+		{types.NewArray([]Mood{"sad", "ok"}), 1, `'{"sad","ok"}'`},
+	}
+	for _, test := range tests {
+		got := types.Append(nil, test.v, test.quote)
+		if string(got) != test.wanted {
+			t.Errorf("got %q, wanted %q (quote=%d)", got, test.wanted, test.quote)
+		}
+	}
+}
+
+func TestScanEnum(t *testing.T) {
+	var mood Mood
+	if err := types.Scan(&mood, []byte("ok")); err != nil {
+		t.Fatal(err)
+	}
+	if mood != "ok" {
+		t.Errorf("got %q, wanted %q", mood, "ok")
+	}
+
+	if err := types.Scan(&mood, nil); err != nil {
+		t.Fatal(err)
+	}
+	if mood != "" {
+		t.Errorf("got %q, wanted empty string", mood)
+	}
+
+	err := types.Scan(&mood, []byte("happy"))
+	wanted := `pg: unknown label "happy" for enum "mood"`
+	if err == nil || err.Error() != wanted {
+		t.Errorf("got %v, wanted %q", err, wanted)
+	}
+}
+
+func TestLookupEnum(t *testing.T) {
+	name, labels, ok := types.LookupEnum(reflect.TypeOf(Mood("")))
+	if !ok {
+		t.Fatal("enum is not registered")
+	}
+	if name != "mood" || !reflect.DeepEqual(labels, []string{"sad", "ok", "it's fine"}) {
+		t.Errorf("got %q %q", name, labels)
+	}
+
+	if _, _, ok := types.LookupEnum(reflect.TypeOf("")); ok {
+		t.Error("string is registered as enum")
+	}
+}
@@ -0,0 +1,279 @@
+package types
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/pg.v5/internal"
+)
+
+// Point represents PostgreSQL point type.
+type Point struct {
+	X, Y float64
+}
+
+var _ ValueAppender = (*Point)(nil)
+var _ sql.Scanner = (*Point)(nil)
+
+func (p Point) AppendValue(b []byte, quote int) ([]byte, error) {
+	return appendGeo(b, p.appendText(nil), quote), nil
+}
+
+func (p Point) appendText(b []byte) []byte {
+	b = append(b, '(')
+	b = appendFloat(b, p.X)
+	b = append(b, ',')
+	b = appendFloat(b, p.Y)
+	return append(b, ')')
+}
+
+func (p *Point) Scan(src interface{}) error {
+	fs, err := scanGeo(src, "Point")
+	if err != nil || fs == nil {
+		*p = Point{}
+		return err
+	}
+	if len(fs) != 2 {
+		return fmt.Errorf("pg: can't parse Point: %q", src)
+	}
+	*p = Point{X: fs[0], Y: fs[1]}
+	return nil
+}
+
+// Line represents PostgreSQL line type, i.e. infinite line
+// defined by the equation Ax + By + C = 0.
+type Line struct {
+	A, B, C float64
+}
+
+var _ ValueAppender = (*Line)(nil)
+var _ sql.Scanner = (*Line)(nil)
+
+func (l Line) AppendValue(b []byte, quote int) ([]byte, error) {
+	text := make([]byte, 0, 32)
+	text = append(text, '{')
+	text = appendFloat(text, l.A)
+	text = append(text, ',')
+	text = appendFloat(text, l.B)
+	text = append(text, ',')
+	text = appendFloat(text, l.C)
+	text = append(text, '}')
+	return appendGeo(b, text, quote), nil
+}
+
+func (l *Line) Scan(src interface{}) error {
+	fs, err := scanGeo(src, "Line")
+	if err != nil || fs == nil {
+		*l = Line{}
+		return err
+	}
+	if len(fs) != 3 {
+		return fmt.Errorf("pg: can't parse Line: %q", src)
+	}
+	*l = Line{A: fs[0], B: fs[1], C: fs[2]}
+	return nil
+}
+
+// Box represents PostgreSQL box type. PostgreSQL reorders the corners
+// so High is the upper right corner and Low is the lower left corner.
+type Box struct {
+	High, Low Point
+}
+
+var _ ValueAppender = (*Box)(nil)
+var _ sql.Scanner = (*Box)(nil)
+
+func (box Box) AppendValue(b []byte, quote int) ([]byte, error) {
+	text := make([]byte, 0, 64)
+	text = box.High.appendText(text)
+	text = append(text, ',')
+	text = box.Low.appendText(text)
+	return appendGeo(b, text, quote), nil
+}
+
+func (box *Box) Scan(src interface{}) error {
+	fs, err := scanGeo(src, "Box")
+	if err != nil || fs == nil {
+		*box = Box{}
+		return err
+	}
+	if len(fs) != 4 {
+		return fmt.Errorf("pg: can't parse Box: %q", src)
+	}
+	*box = Box{
+		High: Point{X: fs[0], Y: fs[1]},
+		Low:  Point{X: fs[2], Y: fs[3]},
+	}
+	return nil
+}
+
+// Path represents PostgreSQL path type. Closed path is appended as
+// (p1,...,pn) and open path as [p1,...,pn]. Nil Points are appended
+// as NULL.
+type Path struct {
+	Points []Point
+	Closed bool
+}
+
+var _ ValueAppender = (*Path)(nil)
+var _ sql.Scanner = (*Path)(nil)
+
+func (p Path) AppendValue(b []byte, quote int) ([]byte, error) {
+	if p.Points == nil {
+		return AppendNull(b, quote), nil
+	}
+	if p.Closed {
+		return appendGeo(b, appendPoints(nil, p.Points, '(', ')'), quote), nil
+	}
+	return appendGeo(b, appendPoints(nil, p.Points, '[', ']'), quote), nil
+}
+
+func (p *Path) Scan(src interface{}) error {
+	fs, err := scanGeo(src, "Path")
+	if err != nil || fs == nil {
+		*p = Path{}
+		return err
+	}
+	points, err := pointsFromFloats(fs, src, "Path")
+	if err != nil {
+		return err
+	}
+	b := src.([]byte)
+	*p = Path{
+		Points: points,
+		Closed: len(b) > 0 && b[0] != '[',
+	}
+	return nil
+}
+
+// Polygon represents PostgreSQL polygon type. Nil Points are appended
+// as NULL.
+type Polygon struct {
+	Points []Point
+}
+
+var _ ValueAppender = (*Polygon)(nil)
+var _ sql.Scanner = (*Polygon)(nil)
+
+func (p Polygon) AppendValue(b []byte, quote int) ([]byte, error) {
+	if p.Points == nil {
+		return AppendNull(b, quote), nil
+	}
+	return appendGeo(b, appendPoints(nil, p.Points, '(', ')'), quote), nil
+}
+
+func (p *Polygon) Scan(src interface{}) error {
+	fs, err := scanGeo(src, "Polygon")
+	if err != nil || fs == nil {
+		*p = Polygon{}
+		return err
+	}
+	points, err := pointsFromFloats(fs, src, "Polygon")
+	if err != nil {
+		return err
+	}
+	*p = Polygon{Points: points}
+	return nil
+}
+
+// Circle represents PostgreSQL circle type.
+type Circle struct {
+	Center Point
+	Radius float64
+}
+
+var _ ValueAppender = (*Circle)(nil)
+var _ sql.Scanner = (*Circle)(nil)
+
+func (c Circle) AppendValue(b []byte, quote int) ([]byte, error) {
+	text := make([]byte, 0, 48)
+	text = append(text, '<')
+	text = c.Center.appendText(text)
+	text = append(text, ',')
+	text = appendFloat(text, c.Radius)
+	text = append(text, '>')
+	return appendGeo(b, text, quote), nil
+}
+
+func (c *Circle) Scan(src interface{}) error {
+	fs, err := scanGeo(src, "Circle")
+	if err != nil || fs == nil {
+		*c = Circle{}
+		return err
+	}
+	if len(fs) != 3 {
+		return fmt.Errorf("pg: can't parse Circle: %q", src)
+	}
+	*c = Circle{
+		Center: Point{X: fs[0], Y: fs[1]},
+		Radius: fs[2],
+	}
+	return nil
+}
+
+func appendGeo(b, text []byte, quote int) []byte {
+	return AppendString(b, internal.BytesToString(text), quote)
+}
+
+func appendPoints(b []byte, points []Point, open, close byte) []byte {
+	b = append(b, open)
+	for i, p := range points {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = p.appendText(b)
+	}
+	return append(b, close)
+}
+
+func pointsFromFloats(fs []float64, src interface{}, typeName string) ([]Point, error) {
+	if len(fs)%2 != 0 {
+		return nil, fmt.Errorf("pg: can't parse %s: %q", typeName, src)
+	}
+	points := make([]Point, len(fs)/2)
+	for i := range points {
+		points[i] = Point{X: fs[2*i], Y: fs[2*i+1]}
+	}
+	return points, nil
+}
+
+// scanGeo returns numbers of geometric value in the order they appear
+// ignoring parentheses, brackets and commas. It returns nil for NULL.
+func scanGeo(src interface{}, typeName string) ([]float64, error) {
+	if src == nil {
+		return nil, nil
+	}
+	b, ok := src.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("pg: can't scan %T into %s", src, typeName)
+	}
+
+	fs := make([]float64, 0, 4)
+	for i := 0; i < len(b); {
+		if isGeoSep(b[i]) {
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(b) && !isGeoSep(b[j]) {
+			j++
+		}
+		f, err := strconv.ParseFloat(internal.BytesToString(b[i:j]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("pg: can't parse %s: %q", typeName, b)
+		}
+		fs = append(fs, f)
+		i = j
+	}
+	return fs, nil
+}
+
+func isGeoSep(c byte) bool {
+	switch c {
+	case '(', ')', '[', ']', '<', '>', '{', '}', ',', ' ':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package types_test
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/pg.v5/types"
+)
+
+func TestAppendGeo(t *testing.T) {
+	tests := []struct {
+		v      interface{}
+		quote  int
+		wanted string
+	}{
+		{types.Point{X: 1.5, Y: -2}, 1, `'(1.5,-2)'`},
+		{types.Point{X: 1.5, Y: -2}, 0, `(1.5,-2)`},
+		{types.Line{A: 1, B: -1, C: 0}, 1, `'{1,-1,0}'`},
+		{types.Box{High: types.Point{X: 2, Y: 2}, Low: types.Point{X: 0, Y: 0}}, 1, `'(2,2),(0,0)'`},
+		{types.Path{Points: []types.Point{{X: 0, Y: 0}, {X: 1, Y: 1}}}, 1, `'[(0,0),(1,1)]'`},
+		{types.Path{Points: []types.Point{{X: 0, Y: 0}, {X: 1, Y: 1}}, Closed: true}, 1, `'((0,0),(1,1))'`},
+		{types.Path{}, 1, `NULL`},
+		{types.Polygon{Points: []types.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}}}, 1, `'((0,0),(1,0),(0,1))'`},
+		{types.Polygon{}, 1, `NULL`},
+		{types.Circle{Center: types.Point{X: 1, Y: 2}, Radius: 3}, 1, `'<(1,2),3>'`},
+		{types.NewArray([]types.Point{{X: 1, Y: 2}, {X: 3, Y: 4}}), 1, `'{"(1,2)","(3,4)"}'`},
+	}
+	for _, test := range tests {
+		got := types.Append(nil, test.v, test.quote)
+		if string(got) != test.wanted {
+			t.Errorf("got %q, wanted %q (quote=%d)", got, test.wanted, test.quote)
+		}
+	}
+}
+
+func TestScanGeo(t *testing.T) {
+	tests := []struct {
+		src    string
+		dst    interface{}
+		wanted interface{}
+	}{
+		{`(1.5,-2)`, new(types.Point), types.Point{X: 1.5, Y: -2}},
+		{`(1e+20,0)`, new(types.Point), types.Point{X: 1e20, Y: 0}},
+		{`{1,-1,0}`, new(types.Line), types.Line{A: 1, B: -1, C: 0}},
+		{`(2,2),(0,0)`, new(types.Box), types.Box{High: types.Point{X: 2, Y: 2}}},
+		{`[(0,0),(1,1)]`, new(types.Path), types.Path{Points: []types.Point{{X: 0, Y: 0}, {X: 1, Y: 1}}}},
+		{`((0,0),(1,1))`, new(types.Path), types.Path{Points: []types.Point{{X: 0, Y: 0}, {X: 1, Y: 1}}, Closed: true}},
+		{`((0,0),(1,0),(0,1))`, new(types.Polygon), types.Polygon{Points: []types.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}}}},
+		{`<(1,2),3>`, new(types.Circle), types.Circle{Center: types.Point{X: 1, Y: 2}, Radius: 3}},
+	}
+	for _, test := range tests {
+		if err := types.Scan(test.dst, []byte(test.src)); err != nil {
+			t.Errorf("Scan(%q) failed: %s", test.src, err)
+			continue
+		}
+		got := reflect.ValueOf(test.dst).Elem().Interface()
+		if !reflect.DeepEqual(got, test.wanted) {
+			t.Errorf("Scan(%q): got %#v, wanted %#v", test.src, got, test.wanted)
+		}
+	}
+}
+
+func TestScanGeoError(t *testing.T) {
+	var p types.Point
+	err := types.Scan(&p, []byte(`(1,2,3)`))
+	if err == nil || err.Error() != `pg: can't parse Point: "(1,2,3)"` {
+		t.Errorf("got %v", err)
+	}
+
+	var poly types.Polygon
+	err = types.Scan(&poly, []byte(`((0,0),(foo,1))`))
+	if err == nil || err.Error() != `pg: can't parse Polygon: "((0,0),(foo,1))"` {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestScanGeoNull(t *testing.T) {
+	path := types.Path{Points: []types.Point{{X: 1, Y: 1}}}
+	if err := types.Scan(&path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if path.Points != nil {
+		t.Errorf("got %v, wanted nil points", path.Points)
+	}
+}
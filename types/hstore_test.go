@@ -0,0 +1,66 @@
+package types_test
+
+import (
+	"testing"
+
+	"gopkg.in/pg.v5/types"
+)
+
+func TestAppendHstoreStringPtr(t *testing.T) {
+	s := `it's "ok"`
+
+	tests := []struct {
+		v      interface{}
+		quote  int
+		wanted string
+	}{
+		{types.NewHstore(map[string]*string{"k": &s}), 1, `'"k"=>"it''s \"ok\""'`},
+		{types.NewHstore(map[string]*string{"k": &s}), 0, `"k"=>"it's \"ok\""`},
+		{types.NewHstore(map[string]*string{"k": nil}), 1, `'"k"=>NULL'`},
+		{types.NewHstore(map[string]*string{"k": nil}), 0, `"k"=>NULL`},
+		{types.NewHstore(map[string]*string(nil)), 1, `NULL`},
+	}
+	for _, test := range tests {
+		got := types.Append(nil, test.v, test.quote)
+		if string(got) != test.wanted {
+			t.Errorf("got %q, wanted %q (quote=%d)", got, test.wanted, test.quote)
+		}
+	}
+}
+
+func TestScanHstoreStringPtr(t *testing.T) {
+	var m map[string]*string
+	err := types.NewHstore(&m).Scan([]byte(`"a"=>"it's", "b"=>NULL, "c"=>""`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m) != 3 {
+		t.Fatalf("got %d elements, wanted 3", len(m))
+	}
+	if m["a"] == nil || *m["a"] != "it's" {
+		t.Fatalf(`got %v, wanted "it's"`, m["a"])
+	}
+	if m["b"] != nil {
+		t.Fatalf("got %q, wanted nil", *m["b"])
+	}
+	if m["c"] == nil || *m["c"] != "" {
+		t.Fatalf(`got %v, wanted ""`, m["c"])
+	}
+}
+
+func TestScanHstoreString(t *testing.T) {
+	var m map[string]string
+	err := types.NewHstore(&m).Scan([]byte(`"a"=>"", "b"=>NULL`))
+	if err == nil {
+		t.Fatalf("got nil error, wanted unexpected NULL error")
+	}
+
+	err = types.NewHstore(&m).Scan([]byte(`"a"=>""`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := m["a"]; !ok || v != "" {
+		t.Fatalf(`got %q, wanted ""`, v)
+	}
+}
@@ -25,3 +25,16 @@ var _ ValueAppender = F("")
 func (f F) AppendValue(dst []byte, quote int) ([]byte, error) {
 	return AppendField(dst, string(f), quote), nil
 }
+
+//------------------------------------------------------------------------------
+
+// Ident represents a single SQL identifier that is quoted as a whole,
+// so unlike F it can safely hold any name including the one with dots
+// and quotes, e.g. a column name that comes from user input.
+type Ident string
+
+var _ ValueAppender = Ident("")
+
+func (id Ident) AppendValue(dst []byte, quote int) ([]byte, error) {
+	return AppendIdent(dst, string(id), quote), nil
+}
@@ -0,0 +1,259 @@
+package types
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/pg.v5/internal"
+)
+
+var durationType = reflect.TypeOf((*time.Duration)(nil)).Elem()
+
+const microsecondsPerDay = 24 * 60 * 60 * 1000000
+
+// Interval represents PostgreSQL interval type. Months and days are
+// stored separately from time, because their length varies.
+type Interval struct {
+	Months       int32
+	Days         int32
+	Microseconds int64
+}
+
+var _ ValueAppender = (*Interval)(nil)
+var _ sql.Scanner = (*Interval)(nil)
+
+// NewInterval returns interval that has the duration.
+func NewInterval(d time.Duration) Interval {
+	return Interval{Microseconds: int64(d / time.Microsecond)}
+}
+
+// Duration converts interval to time.Duration assuming that a day has
+// 24 hours. It returns an error when interval has months or does not
+// fit into time.Duration.
+func (i Interval) Duration() (time.Duration, error) {
+	if i.Months != 0 {
+		return 0, fmt.Errorf("pg: interval with months can't be converted to time.Duration")
+	}
+
+	f := float64(i.Days)*microsecondsPerDay + float64(i.Microseconds)
+	if math.Abs(f) > math.MaxInt64/float64(time.Microsecond) {
+		return 0, fmt.Errorf("pg: interval is out of time.Duration range")
+	}
+
+	us := int64(i.Days)*microsecondsPerDay + i.Microseconds
+	return time.Duration(us) * time.Microsecond, nil
+}
+
+func (i Interval) AppendValue(b []byte, quote int) ([]byte, error) {
+	return AppendInterval(b, i, quote), nil
+}
+
+func (i *Interval) Scan(src interface{}) error {
+	if src == nil {
+		*i = Interval{}
+		return nil
+	}
+
+	b, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("pg: can't scan %T into Interval", src)
+	}
+
+	v, err := ParseInterval(b)
+	if err != nil {
+		return err
+	}
+	*i = v
+	return nil
+}
+
+// AppendInterval appends interval in the format
+// "1 mons 2 days 03:04:05.000006".
+func AppendInterval(b []byte, i Interval, quote int) []byte {
+	if quote == 1 {
+		b = append(b, '\'')
+	}
+
+	b = strconv.AppendInt(b, int64(i.Months), 10)
+	b = append(b, " mons "...)
+	b = strconv.AppendInt(b, int64(i.Days), 10)
+	b = append(b, " days "...)
+
+	us := i.Microseconds
+	if us < 0 {
+		b = append(b, '-')
+		us = -us
+	}
+	b = strconv.AppendInt(b, us/3600000000, 10)
+	b = append(b, ':')
+	b = appendPadded(b, us/60000000%60, 2)
+	b = append(b, ':')
+	b = appendPadded(b, us/1000000%60, 2)
+	b = append(b, '.')
+	b = appendPadded(b, us%1000000, 6)
+
+	if quote == 1 {
+		b = append(b, '\'')
+	}
+	return b
+}
+
+func appendPadded(b []byte, n int64, width int) []byte {
+	s := strconv.FormatInt(n, 10)
+	for i := len(s); i < width; i++ {
+		b = append(b, '0')
+	}
+	return append(b, s...)
+}
+
+// ParseInterval parses interval in the default postgres IntervalStyle,
+// e.g. "1 year 2 mons -3 days +04:05:06.789".
+func ParseInterval(b []byte) (Interval, error) {
+	var i Interval
+
+	fields := strings.Fields(internal.BytesToString(b))
+	for j := 0; j < len(fields); j++ {
+		field := fields[j]
+
+		if strings.IndexByte(field, ':') != -1 {
+			us, err := parseIntervalTime(field)
+			if err != nil {
+				return Interval{}, fmt.Errorf("pg: can't parse interval: %q", b)
+			}
+			i.Microseconds += us
+			continue
+		}
+
+		if j+1 >= len(fields) {
+			return Interval{}, fmt.Errorf("pg: can't parse interval: %q", b)
+		}
+
+		n, err := strconv.ParseInt(field, 10, 32)
+		if err != nil {
+			return Interval{}, fmt.Errorf("pg: can't parse interval: %q", b)
+		}
+
+		j++
+		switch fields[j] {
+		case "year", "years":
+			i.Months += int32(n) * 12
+		case "mon", "mons":
+			i.Months += int32(n)
+		case "day", "days":
+			i.Days += int32(n)
+		default:
+			return Interval{}, fmt.Errorf("pg: can't parse interval: %q", b)
+		}
+	}
+
+	return i, nil
+}
+
+// parseIntervalTime parses time part like "-04:05:06.789" and returns
+// it in microseconds.
+func parseIntervalTime(s string) (int64, error) {
+	var neg bool
+	switch s[0] {
+	case '-':
+		neg = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("pg: can't parse interval time: %q", s)
+	}
+
+	hours, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	minutes, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	sec := parts[2]
+	var frac string
+	if ind := strings.IndexByte(sec, '.'); ind != -1 {
+		sec, frac = sec[:ind], sec[ind+1:]
+	}
+	seconds, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	var us int64
+	if frac != "" {
+		if len(frac) > 6 {
+			frac = frac[:6]
+		}
+		frac += strings.Repeat("0", 6-len(frac))
+		us, err = strconv.ParseInt(frac, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	us += ((hours*60+minutes)*60 + seconds) * 1000000
+	if neg {
+		us = -us
+	}
+	return us, nil
+}
+
+// IntervalAppender returns appender that appends time.Duration as
+// interval. It returns nil for other types.
+func IntervalAppender(typ reflect.Type) AppenderFunc {
+	switch typ {
+	case durationType:
+		return appendDurationValue
+	case reflect.PtrTo(durationType):
+		return func(b []byte, v reflect.Value, quote int) []byte {
+			if v.IsNil() {
+				return AppendNull(b, quote)
+			}
+			return appendDurationValue(b, v.Elem(), quote)
+		}
+	}
+	return nil
+}
+
+func appendDurationValue(b []byte, v reflect.Value, quote int) []byte {
+	return AppendInterval(b, NewInterval(time.Duration(v.Int())), quote)
+}
+
+// scanDurationValue scans integer (nanoseconds) or interval
+// into time.Duration.
+func scanDurationValue(v reflect.Value, b []byte) error {
+	if !v.CanSet() {
+		return internal.Errorf("pg: Scan(non-pointer %s)", v.Type())
+	}
+	if b == nil {
+		v.SetInt(0)
+		return nil
+	}
+
+	if n, err := strconv.ParseInt(internal.BytesToString(b), 10, 64); err == nil {
+		v.SetInt(n)
+		return nil
+	}
+
+	i, err := ParseInterval(b)
+	if err != nil {
+		return err
+	}
+	d, err := i.Duration()
+	if err != nil {
+		return err
+	}
+	v.SetInt(int64(d))
+	return nil
+}
@@ -0,0 +1,86 @@
+package types_test
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/pg.v5/types"
+)
+
+var intervalTests = []struct {
+	s      string
+	wanted types.Interval
+}{
+	{`00:00:00`, types.Interval{}},
+	{`1 day`, types.Interval{Days: 1}},
+	{`1 year 2 mons 3 days 04:05:06.789`, types.Interval{
+		Months:       14,
+		Days:         3,
+		Microseconds: 14706789000,
+	}},
+	{`-1 days +02:03:00`, types.Interval{Days: -1, Microseconds: 7380000000}},
+	{`-00:00:01.5`, types.Interval{Microseconds: -1500000}},
+	{`100:00:00.000001`, types.Interval{Microseconds: 360000000001}},
+}
+
+func TestParseInterval(t *testing.T) {
+	for _, test := range intervalTests {
+		got, err := types.ParseInterval([]byte(test.s))
+		if err != nil {
+			t.Fatalf("%s: %s", test.s, err)
+		}
+		if got != test.wanted {
+			t.Errorf("%s: got %+v, wanted %+v", test.s, got, test.wanted)
+		}
+	}
+
+	if _, err := types.ParseInterval([]byte(`1 fortnight`)); err == nil {
+		t.Errorf("got nil error, wanted parse error")
+	}
+}
+
+func TestAppendInterval(t *testing.T) {
+	tests := []struct {
+		v      interface{}
+		quote  int
+		wanted string
+	}{
+		{types.Interval{Months: 14, Days: 3, Microseconds: 14706789000}, 1,
+			`'14 mons 3 days 4:05:06.789000'`},
+		{types.Interval{Days: -1, Microseconds: -1500000}, 0,
+			`0 mons -1 days -0:00:01.500000`},
+		{types.NewInterval(100 * time.Hour), 1,
+			`'0 mons 0 days 100:00:00.000000'`},
+	}
+	for _, test := range tests {
+		got := types.Append(nil, test.v, test.quote)
+		if string(got) != test.wanted {
+			t.Errorf("got %q, wanted %q (quote=%d)", got, test.wanted, test.quote)
+		}
+	}
+}
+
+func TestScanDuration(t *testing.T) {
+	tests := []struct {
+		s      string
+		wanted time.Duration
+	}{
+		{`1000`, 1000},
+		{`1 day 01:00:00`, 25 * time.Hour},
+		{`-00:00:01.5`, -1500 * time.Millisecond},
+	}
+	for _, test := range tests {
+		var d time.Duration
+		if err := types.Scan(&d, []byte(test.s)); err != nil {
+			t.Fatalf("%s: %s", test.s, err)
+		}
+		if d != test.wanted {
+			t.Errorf("%s: got %s, wanted %s", test.s, d, test.wanted)
+		}
+	}
+
+	var d time.Duration
+	if err := types.Scan(&d, []byte(`1 mon`)); err == nil {
+		t.Errorf("got nil error, wanted months error")
+	}
+}
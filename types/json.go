@@ -0,0 +1,166 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"gopkg.in/pg.v5/internal"
+)
+
+var jsonRawMessageType = reflect.TypeOf((*json.RawMessage)(nil)).Elem()
+
+// JSONProvider marshals and unmarshals values of json and jsonb columns.
+// It allows replacing encoding/json with a faster implementation, e.g.
+// jsoniter.ConfigCompatibleWithStandardLibrary satisfies it.
+type JSONProvider interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type stdJSONProvider struct{}
+
+func (stdJSONProvider) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (stdJSONProvider) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+var jsonProviders = struct {
+	sync.RWMutex
+	def JSONProvider
+	m   map[string]JSONProvider
+}{
+	def: stdJSONProvider{},
+	m:   make(map[string]JSONProvider),
+}
+
+// SetJSONProvider sets the provider that is used for structs, maps and
+// slices that are stored as JSON by default and for fields with json tag.
+// Passing nil restores encoding/json.
+func SetJSONProvider(p JSONProvider) {
+	if p == nil {
+		p = stdJSONProvider{}
+	}
+	jsonProviders.Lock()
+	jsonProviders.def = p
+	jsonProviders.Unlock()
+}
+
+// RegisterJSONProvider registers the provider under the name so it can
+// be used for struct fields with json tag, e.g. `pg:",json:name"`.
+func RegisterJSONProvider(name string, p JSONProvider) {
+	jsonProviders.Lock()
+	jsonProviders.m[name] = p
+	jsonProviders.Unlock()
+}
+
+func jsonProvider(name string) (JSONProvider, error) {
+	jsonProviders.RLock()
+	defer jsonProviders.RUnlock()
+	if name == "" {
+		return jsonProviders.def, nil
+	}
+	p, ok := jsonProviders.m[name]
+	if !ok {
+		return nil, fmt.Errorf("pg: json provider=%s is not registered", name)
+	}
+	return p, nil
+}
+
+// MarshalJSON marshals the value using the default JSON provider.
+func MarshalJSON(v interface{}) ([]byte, error) {
+	p, _ := jsonProvider("")
+	return p.Marshal(v)
+}
+
+// UnmarshalJSON unmarshals the data using the default JSON provider.
+func UnmarshalJSON(data []byte, v interface{}) error {
+	p, _ := jsonProvider("")
+	return p.Unmarshal(data, v)
+}
+
+// JSONAppender returns appender that marshals values of the type using
+// the JSON provider registered under the name or the default provider
+// when the name is empty. It is used for fields with json tag.
+func JSONAppender(typ reflect.Type, provider string) AppenderFunc {
+	return func(b []byte, v reflect.Value, quote int) []byte {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return AppendNull(b, quote)
+		}
+		p, err := jsonProvider(provider)
+		if err != nil {
+			return AppendError(b, err)
+		}
+		return appendJSON(b, p, v, quote)
+	}
+}
+
+// JSONScanner returns scanner that unmarshals values of the type using
+// the JSON provider registered under the name or the default provider
+// when the name is empty. It is used for fields with json tag.
+func JSONScanner(typ reflect.Type, provider string) ScannerFunc {
+	return func(v reflect.Value, b []byte) error {
+		p, err := jsonProvider(provider)
+		if err != nil {
+			return err
+		}
+		return scanJSON(p, v, b)
+	}
+}
+
+func appendJSONValue(b []byte, v reflect.Value, quote int) []byte {
+	p, _ := jsonProvider("")
+	return appendJSON(b, p, v, quote)
+}
+
+func appendJSON(b []byte, p JSONProvider, v reflect.Value, quote int) []byte {
+	bytes, err := p.Marshal(v.Interface())
+	if err != nil {
+		return AppendError(b, err)
+	}
+	return AppendJSONB(b, bytes, quote)
+}
+
+func scanJSONValue(v reflect.Value, b []byte) error {
+	p, _ := jsonProvider("")
+	return scanJSON(p, v, b)
+}
+
+// scanJSON resets the value before unmarshaling so maps and structs
+// that are reused don't keep keys and fields from the previous row.
+func scanJSON(p JSONProvider, v reflect.Value, b []byte) error {
+	if !v.CanSet() {
+		return internal.Errorf("pg: Scan(non-pointer %s)", v.Type())
+	}
+	v.Set(reflect.Zero(v.Type()))
+	if b == nil {
+		return nil
+	}
+	return p.Unmarshal(b, v.Addr().Interface())
+}
+
+func appendJSONRawMessageValue(b []byte, v reflect.Value, quote int) []byte {
+	bytes := v.Bytes()
+	if bytes == nil {
+		return AppendNull(b, quote)
+	}
+	return AppendJSONB(b, bytes, quote)
+}
+
+// scanJSONRawMessageValue copies the data, because it is only valid
+// until the next row is read.
+func scanJSONRawMessageValue(v reflect.Value, b []byte) error {
+	if !v.CanSet() {
+		return internal.Errorf("pg: Scan(non-pointer %s)", v.Type())
+	}
+	if b == nil {
+		v.SetBytes(nil)
+		return nil
+	}
+	v.SetBytes(append(make([]byte, 0, len(b)), b...))
+	return nil
+}
@@ -0,0 +1,145 @@
+package types_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/pg.v5/types"
+)
+
+// upperJSON marshals values with encoding/json and upper cases the result.
+type upperJSON struct{}
+
+func (upperJSON) Marshal(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	return bytes.ToUpper(b), err
+}
+
+func (upperJSON) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(bytes.ToLower(data), v)
+}
+
+func init() {
+	types.RegisterJSONProvider("upper", upperJSON{})
+}
+
+func TestJSONRawMessage(t *testing.T) {
+	got := types.Append(nil, json.RawMessage(`{"it's":1}`), 1)
+	if wanted := `'{"it''s":1}'`; string(got) != wanted {
+		t.Errorf("got %q, wanted %q", got, wanted)
+	}
+
+	got = types.Append(nil, json.RawMessage(nil), 1)
+	if wanted := `NULL`; string(got) != wanted {
+		t.Errorf("got %q, wanted %q", got, wanted)
+	}
+
+	src := []byte(`{"foo":"bar"}`)
+	var msg json.RawMessage
+	if err := types.Scan(&msg, src); err != nil {
+		t.Fatal(err)
+	}
+	src[2] = 'x'
+	if string(msg) != `{"foo":"bar"}` {
+		t.Errorf("got %q, wanted copy of the data", msg)
+	}
+}
+
+func TestScanJSONResetsValue(t *testing.T) {
+	m := map[string]interface{}{"old": true}
+	if err := types.Scan(&m, []byte(`{"new":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, map[string]interface{}{"new": float64(1)}) {
+		t.Errorf("got %v", m)
+	}
+
+	type nested struct {
+		A, B string
+	}
+	s := nested{A: "a", B: "b"}
+	if err := types.Scan(&s, []byte(`{"B":"c"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if s != (nested{B: "c"}) {
+		t.Errorf("got %+v", s)
+	}
+}
+
+func TestJSONProvider(t *testing.T) {
+	typ := reflect.TypeOf(map[string]string{})
+
+	v := reflect.ValueOf(map[string]string{"foo": "bar"})
+	got := types.JSONAppender(typ, "upper")(nil, v, 1)
+	if wanted := `'{"FOO":"BAR"}'`; string(got) != wanted {
+		t.Errorf("got %q, wanted %q", got, wanted)
+	}
+
+	var m map[string]string
+	err := types.JSONScanner(typ, "upper")(reflect.ValueOf(&m).Elem(), []byte(`{"FOO":"BAR"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, map[string]string{"foo": "bar"}) {
+		t.Errorf("got %v", m)
+	}
+
+	got = types.JSONAppender(typ, "unknown")(nil, v, 1)
+	if wanted := `?!(pg: json provider=unknown is not registered)`; string(got) != wanted {
+		t.Errorf("got %q, wanted %q", got, wanted)
+	}
+}
+
+func TestSetJSONProvider(t *testing.T) {
+	types.SetJSONProvider(upperJSON{})
+	got := types.Append(nil, map[string]string{"foo": "bar"}, 1)
+	types.SetJSONProvider(nil)
+
+	if wanted := `'{"FOO":"BAR"}'`; string(got) != wanted {
+		t.Errorf("got %q, wanted %q", got, wanted)
+	}
+
+	got = types.Append(nil, map[string]string{"foo": "bar"}, 1)
+	if !strings.Contains(string(got), "foo") {
+		t.Errorf("got %q, wanted encoding/json output", got)
+	}
+}
+
+func TestJSONWrapper(t *testing.T) {
+	tests := []struct {
+		v      interface{}
+		wanted string
+	}{
+		{"it's", `'"it''s"'`},
+		{[]byte("foo"), `'"Zm9v"'`},
+		{map[string]int{"foo": 1}, `'{"foo":1}'`},
+		{nil, `NULL`},
+		{(*int)(nil), `NULL`},
+	}
+	for _, test := range tests {
+		got := types.Append(nil, types.NewJSON(test.v), 1)
+		if string(got) != test.wanted {
+			t.Errorf("got %q, wanted %q", got, test.wanted)
+		}
+	}
+
+	var m map[string]int
+	if err := types.NewJSON(&m).Scan([]byte(`{"foo":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, map[string]int{"foo": 1}) {
+		t.Fatalf("got %v", m)
+	}
+	if err := types.NewJSON(&m).Scan(nil); err != nil {
+		t.Fatal(err)
+	}
+	if m != nil {
+		t.Fatalf("got %v, wanted nil", m)
+	}
+	if err := types.NewJSON(m).Scan([]byte(`{}`)); err == nil {
+		t.Fatal("expected an error for non-pointer")
+	}
+}
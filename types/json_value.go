@@ -0,0 +1,53 @@
+package types
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// JSON marshals and unmarshals the value as JSON regardless of its
+// type, e.g. strings and byte slices are not appended as text and bytea.
+type JSON struct {
+	v reflect.Value
+}
+
+var _ ValueAppender = (*JSON)(nil)
+var _ sql.Scanner = (*JSON)(nil)
+
+func NewJSON(vi interface{}) *JSON {
+	return &JSON{
+		v: reflect.ValueOf(vi),
+	}
+}
+
+func (j *JSON) Value() interface{} {
+	if j.v.IsValid() {
+		return j.v.Interface()
+	}
+	return nil
+}
+
+func (j *JSON) AppendValue(b []byte, quote int) ([]byte, error) {
+	if !j.v.IsValid() || (j.v.Kind() == reflect.Ptr && j.v.IsNil()) {
+		return AppendNull(b, quote), nil
+	}
+	bytes, err := MarshalJSON(j.v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return AppendJSONB(b, bytes, quote), nil
+}
+
+func (j *JSON) Scan(b interface{}) error {
+	if !j.v.IsValid() {
+		return fmt.Errorf("pg: Scan(JSON(nil))")
+	}
+	if j.v.Kind() != reflect.Ptr || j.v.IsNil() {
+		return fmt.Errorf("pg: Scan(JSON(non-pointer %s))", j.v.Type())
+	}
+	if b == nil {
+		return scanJSONValue(j.v.Elem(), nil)
+	}
+	return scanJSONValue(j.v.Elem(), b.([]byte))
+}
@@ -0,0 +1,141 @@
+package types
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+
+	"gopkg.in/pg.v5/internal"
+)
+
+var (
+	ipType           = reflect.TypeOf((*net.IP)(nil)).Elem()
+	ipNetType        = reflect.TypeOf((*net.IPNet)(nil)).Elem()
+	hardwareAddrType = reflect.TypeOf((*net.HardwareAddr)(nil)).Elem()
+)
+
+func netAppender(typ reflect.Type) AppenderFunc {
+	switch typ {
+	case ipType:
+		return appendIPValue
+	case ipNetType:
+		return appendIPNetValue
+	case hardwareAddrType:
+		return appendHardwareAddrValue
+	}
+	return nil
+}
+
+func appendIPValue(b []byte, v reflect.Value, quote int) []byte {
+	ip := v.Interface().(net.IP)
+	if ip == nil {
+		return AppendNull(b, quote)
+	}
+	return AppendString(b, ip.String(), quote)
+}
+
+func appendIPNetValue(b []byte, v reflect.Value, quote int) []byte {
+	ipnet := v.Interface().(net.IPNet)
+	return AppendString(b, ipnet.String(), quote)
+}
+
+func appendHardwareAddrValue(b []byte, v reflect.Value, quote int) []byte {
+	addr := v.Interface().(net.HardwareAddr)
+	if addr == nil {
+		return AppendNull(b, quote)
+	}
+	return AppendString(b, addr.String(), quote)
+}
+
+func netScanner(typ reflect.Type) ScannerFunc {
+	switch typ {
+	case ipType:
+		return scanIPValue
+	case ipNetType:
+		return scanIPNetValue
+	case hardwareAddrType:
+		return scanHardwareAddrValue
+	}
+	return nil
+}
+
+func scanIPValue(v reflect.Value, b []byte) error {
+	if !v.CanSet() {
+		return internal.Errorf("pg: Scan(non-pointer %s)", v.Type())
+	}
+	if b == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	ipnet, err := ParseIPNet(internal.BytesToString(b))
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(ipnet.IP))
+	return nil
+}
+
+func scanIPNetValue(v reflect.Value, b []byte) error {
+	if !v.CanSet() {
+		return internal.Errorf("pg: Scan(non-pointer %s)", v.Type())
+	}
+	if b == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	ipnet, err := ParseIPNet(internal.BytesToString(b))
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(*ipnet))
+	return nil
+}
+
+func scanHardwareAddrValue(v reflect.Value, b []byte) error {
+	if !v.CanSet() {
+		return internal.Errorf("pg: Scan(non-pointer %s)", v.Type())
+	}
+	if b == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	addr, err := net.ParseMAC(internal.BytesToString(b))
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(addr))
+	return nil
+}
+
+// ParseIPNet parses inet or cidr value. Unlike net.ParseCIDR it keeps
+// host bits of the address, e.g. 192.168.0.1/24, and accepts addresses
+// without mask, which are returned with a full mask.
+func ParseIPNet(s string) (*net.IPNet, error) {
+	ind := strings.IndexByte(s, '/')
+	if ind == -1 {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("pg: can't parse IP address: %q", s)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+			bits = 8 * net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	ip, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, err
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	ipnet.IP = ip
+	return ipnet, nil
+}
@@ -0,0 +1,79 @@
+package types_test
+
+import (
+	"net"
+	"testing"
+
+	"gopkg.in/pg.v5/types"
+)
+
+func TestAppendNet(t *testing.T) {
+	_, ipnet, _ := net.ParseCIDR("192.168.0.0/24")
+	mac, _ := net.ParseMAC("08:00:2b:01:02:03")
+
+	tests := []struct {
+		v      interface{}
+		quote  int
+		wanted string
+	}{
+		{net.ParseIP("192.168.0.1"), 1, `'192.168.0.1'`},
+		{net.ParseIP("2001:db8::1"), 0, `2001:db8::1`},
+		{net.IP(nil), 1, `NULL`},
+		{ipnet, 1, `'192.168.0.0/24'`},
+		{*ipnet, 1, `'192.168.0.0/24'`},
+		{mac, 1, `'08:00:2b:01:02:03'`},
+		{types.NewArray([]net.IP{net.ParseIP("10.0.0.1"), nil}), 1, `'{"10.0.0.1",NULL}'`},
+	}
+	for _, test := range tests {
+		got := types.Append(nil, test.v, test.quote)
+		if string(got) != test.wanted {
+			t.Errorf("got %q, wanted %q (quote=%d)", got, test.wanted, test.quote)
+		}
+	}
+}
+
+func TestScanNet(t *testing.T) {
+	var ip net.IP
+	if err := types.Scan(&ip, []byte("192.168.0.1")); err != nil {
+		t.Fatal(err)
+	}
+	if !ip.Equal(net.ParseIP("192.168.0.1")) {
+		t.Errorf("got %s, wanted 192.168.0.1", ip)
+	}
+
+	var ipnet *net.IPNet
+	if err := types.Scan(&ipnet, []byte("192.168.0.1/24")); err != nil {
+		t.Fatal(err)
+	}
+	if ipnet.String() != "192.168.0.1/24" {
+		t.Errorf("got %s, wanted 192.168.0.1/24", ipnet)
+	}
+
+	var ipnet2 net.IPNet
+	if err := types.Scan(&ipnet2, []byte("2001:db8::1")); err != nil {
+		t.Fatal(err)
+	}
+	if ipnet2.String() != "2001:db8::1/128" {
+		t.Errorf("got %s, wanted 2001:db8::1/128", &ipnet2)
+	}
+
+	var mac net.HardwareAddr
+	if err := types.Scan(&mac, []byte("08:00:2b:01:02:03")); err != nil {
+		t.Fatal(err)
+	}
+	if mac.String() != "08:00:2b:01:02:03" {
+		t.Errorf("got %s, wanted 08:00:2b:01:02:03", mac)
+	}
+
+	var ips []net.IP
+	if err := types.NewArray(&ips).Scan([]byte(`{10.0.0.1,NULL,::1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 3 || !ips[0].Equal(net.ParseIP("10.0.0.1")) || ips[1] != nil || !ips[2].Equal(net.IPv6loopback) {
+		t.Errorf("got %v, wanted [10.0.0.1 <nil> ::1]", ips)
+	}
+
+	if err := types.Scan(&ip, []byte("not an ip")); err == nil {
+		t.Errorf("got nil error, wanted parse error")
+	}
+}
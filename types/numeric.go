@@ -0,0 +1,175 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"gopkg.in/pg.v5/internal"
+)
+
+// Arbitrary precision numeric values are supported using big.Int,
+// big.Rat and big.Float types. Decimal types from other libraries are
+// supported when they implement sql.Scanner and driver.Valuer.
+
+var (
+	bigIntType   = reflect.TypeOf((*big.Int)(nil)).Elem()
+	bigRatType   = reflect.TypeOf((*big.Rat)(nil)).Elem()
+	bigFloatType = reflect.TypeOf((*big.Float)(nil)).Elem()
+)
+
+var (
+	bigTwo  = big.NewInt(2)
+	bigFive = big.NewInt(5)
+)
+
+// maxRatScale is the number of digits after the decimal point used for
+// rationals that can't be represented exactly as decimals, e.g. 1/3.
+const maxRatScale = 100
+
+func numericAppender(typ reflect.Type) AppenderFunc {
+	switch typ {
+	case bigIntType:
+		return func(b []byte, v reflect.Value, quote int) []byte {
+			return AppendBigInt(b, addr(v).Interface().(*big.Int))
+		}
+	case bigRatType:
+		return func(b []byte, v reflect.Value, quote int) []byte {
+			return AppendBigRat(b, addr(v).Interface().(*big.Rat))
+		}
+	case bigFloatType:
+		return func(b []byte, v reflect.Value, quote int) []byte {
+			return AppendBigFloat(b, addr(v).Interface().(*big.Float))
+		}
+	}
+	return nil
+}
+
+// addr returns a pointer to the value, copying the value when it is
+// not addressable.
+func addr(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v.Addr()
+	}
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	return ptr
+}
+
+func AppendBigInt(b []byte, n *big.Int) []byte {
+	return n.Append(b, 10)
+}
+
+// AppendBigRat appends the rational as a decimal number. Rationals that
+// don't have exact decimal representation are rounded to 100 digits
+// after the decimal point.
+func AppendBigRat(b []byte, r *big.Rat) []byte {
+	if r.IsInt() {
+		return r.Num().Append(b, 10)
+	}
+	return append(b, r.FloatString(ratScale(r))...)
+}
+
+// ratScale returns the number of digits after the decimal point
+// that are needed to represent the rational exactly.
+func ratScale(r *big.Rat) int {
+	den := new(big.Int).Set(r.Denom())
+	rem := new(big.Int)
+
+	var twos, fives int
+	for {
+		q, m := new(big.Int).QuoRem(den, bigTwo, rem)
+		if m.Sign() != 0 {
+			break
+		}
+		den = q
+		twos++
+	}
+	for {
+		q, m := new(big.Int).QuoRem(den, bigFive, rem)
+		if m.Sign() != 0 {
+			break
+		}
+		den = q
+		fives++
+	}
+
+	if den.Cmp(big.NewInt(1)) != 0 {
+		return maxRatScale
+	}
+	if twos > fives {
+		return twos
+	}
+	return fives
+}
+
+func AppendBigFloat(b []byte, f *big.Float) []byte {
+	if f.IsInf() {
+		return AppendError(b, fmt.Errorf("pg: can't append infinite big.Float"))
+	}
+	return f.Append(b, 'f', -1)
+}
+
+func numericScanner(typ reflect.Type) ScannerFunc {
+	switch typ {
+	case bigIntType:
+		return scanBigIntValue
+	case bigRatType:
+		return scanBigRatValue
+	case bigFloatType:
+		return scanBigFloatValue
+	}
+	return nil
+}
+
+func scanBigIntValue(v reflect.Value, b []byte) error {
+	if !v.CanSet() {
+		return internal.Errorf("pg: Scan(non-pointer %s)", v.Type())
+	}
+	n := v.Addr().Interface().(*big.Int)
+	if b == nil {
+		n.SetInt64(0)
+		return nil
+	}
+	if _, ok := n.SetString(internal.BytesToString(b), 10); !ok {
+		return fmt.Errorf("pg: can't parse big.Int: %q", b)
+	}
+	return nil
+}
+
+func scanBigRatValue(v reflect.Value, b []byte) error {
+	if !v.CanSet() {
+		return internal.Errorf("pg: Scan(non-pointer %s)", v.Type())
+	}
+	r := v.Addr().Interface().(*big.Rat)
+	if b == nil {
+		r.SetInt64(0)
+		return nil
+	}
+	if _, ok := r.SetString(internal.BytesToString(b)); !ok {
+		return fmt.Errorf("pg: can't parse big.Rat: %q", b)
+	}
+	return nil
+}
+
+// scanBigFloatValue parses numeric into big.Float. When the float does
+// not have precision set, it uses precision that is enough to represent
+// all decimal digits of the value.
+func scanBigFloatValue(v reflect.Value, b []byte) error {
+	if !v.CanSet() {
+		return internal.Errorf("pg: Scan(non-pointer %s)", v.Type())
+	}
+	f := v.Addr().Interface().(*big.Float)
+	if b == nil {
+		f.SetInt64(0)
+		return nil
+	}
+	if f.Prec() == 0 {
+		// log2(10) < 3.33
+		f.SetPrec(uint(len(b))*10/3 + 64)
+	}
+	if _, ok := f.SetString(internal.BytesToString(b)); !ok {
+		return fmt.Errorf("pg: can't parse big.Float: %q", b)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package types_test
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"gopkg.in/pg.v5/types"
+)
+
+func TestAppendNumeric(t *testing.T) {
+	n, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	f, _, _ := big.ParseFloat("0.1", 10, 200, big.ToNearestEven)
+
+	tests := []struct {
+		v      interface{}
+		wanted string
+	}{
+		{n, `123456789012345678901234567890`},
+		{*n, `123456789012345678901234567890`},
+		{big.NewRat(1, 8), `0.125`},
+		{big.NewRat(-3, 40), `-0.075`},
+		{big.NewRat(10, 2), `5`},
+		{big.NewRat(1, 3), "0." + strings.Repeat("3", 100)},
+		{f, `0.1`},
+	}
+	for _, test := range tests {
+		got := types.Append(nil, test.v, 1)
+		if string(got) != test.wanted {
+			t.Errorf("got %q, wanted %q", got, test.wanted)
+		}
+	}
+}
+
+func TestScanNumeric(t *testing.T) {
+	const s = "12345678901234567890.123456789012345678901"
+
+	var r big.Rat
+	if err := types.Scan(&r, []byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(types.Append(nil, &r, 1)); got != s {
+		t.Errorf("got %q, wanted %q", got, s)
+	}
+
+	var f big.Float
+	if err := types.Scan(&f, []byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Text('f', 21); got != s {
+		t.Errorf("got %q, wanted %q", got, s)
+	}
+
+	var n *big.Int
+	if err := types.Scan(&n, []byte("-98765432109876543210")); err != nil {
+		t.Fatal(err)
+	}
+	if got := n.String(); got != "-98765432109876543210" {
+		t.Errorf("got %q, wanted -98765432109876543210", got)
+	}
+
+	if err := types.Scan(&r, []byte("NaN")); err == nil {
+		t.Errorf("got nil error, wanted parse error")
+	}
+}
@@ -0,0 +1,359 @@
+package types
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gopkg.in/pg.v5/internal"
+)
+
+var pgEmptyRange = []byte("empty")
+
+// RangeBounds describes bounds of a PostgreSQL range. Infinite bound
+// means that the range is unbounded on that side and the bound value
+// is ignored.
+type RangeBounds struct {
+	LowerInclusive bool
+	UpperInclusive bool
+	LowerInfinite  bool
+	UpperInfinite  bool
+	Empty          bool
+}
+
+// Int64Range represents int4range and int8range types.
+// It can be used as a query param, e.g.
+//
+//    db.Model(&reservations).Where("seats && ?", r).Select()
+type Int64Range struct {
+	Lower int64
+	Upper int64
+	RangeBounds
+}
+
+var _ ValueAppender = (*Int64Range)(nil)
+var _ sql.Scanner = (*Int64Range)(nil)
+
+// NewInt64Range returns range [lower, upper).
+func NewInt64Range(lower, upper int64) Int64Range {
+	return Int64Range{
+		Lower:       lower,
+		Upper:       upper,
+		RangeBounds: RangeBounds{LowerInclusive: true},
+	}
+}
+
+// Contains reports whether the value is within the range like
+// PostgreSQL range @> element operator does.
+func (r Int64Range) Contains(v int64) bool {
+	return r.Overlaps(Int64Range{
+		Lower: v,
+		Upper: v,
+		RangeBounds: RangeBounds{
+			LowerInclusive: true,
+			UpperInclusive: true,
+		},
+	})
+}
+
+// Overlaps reports whether ranges have points in common like
+// PostgreSQL && operator does.
+func (r Int64Range) Overlaps(other Int64Range) bool {
+	return overlaps(
+		r.RangeBounds, other.RangeBounds,
+		cmpInt64(r.Lower, other.Upper), cmpInt64(other.Lower, r.Upper),
+	)
+}
+
+func (r Int64Range) AppendValue(b []byte, quote int) ([]byte, error) {
+	return appendRange(b, quote, r.RangeBounds, func(b []byte, lower bool) []byte {
+		if lower {
+			return strconv.AppendInt(b, r.Lower, 10)
+		}
+		return strconv.AppendInt(b, r.Upper, 10)
+	}), nil
+}
+
+func (r *Int64Range) Scan(src interface{}) error {
+	if src == nil {
+		*r = Int64Range{}
+		return nil
+	}
+
+	b, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("pg: can't scan %T into Int64Range", src)
+	}
+
+	lower, upper, bounds, err := parseRange(b)
+	if err != nil {
+		return err
+	}
+
+	*r = Int64Range{RangeBounds: bounds}
+	if bounds.Empty {
+		return nil
+	}
+	if !bounds.LowerInfinite {
+		r.Lower, err = strconv.ParseInt(internal.BytesToString(lower), 10, 64)
+		if err != nil {
+			return err
+		}
+	}
+	if !bounds.UpperInfinite {
+		r.Upper, err = strconv.ParseInt(internal.BytesToString(upper), 10, 64)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TimeRange represents tstzrange, tsrange and daterange types.
+type TimeRange struct {
+	Lower time.Time
+	Upper time.Time
+	RangeBounds
+}
+
+var _ ValueAppender = (*TimeRange)(nil)
+var _ sql.Scanner = (*TimeRange)(nil)
+
+// NewTimeRange returns range [lower, upper).
+func NewTimeRange(lower, upper time.Time) TimeRange {
+	return TimeRange{
+		Lower:       lower,
+		Upper:       upper,
+		RangeBounds: RangeBounds{LowerInclusive: true},
+	}
+}
+
+// Contains reports whether the time is within the range like
+// PostgreSQL range @> element operator does.
+func (r TimeRange) Contains(tm time.Time) bool {
+	return r.Overlaps(TimeRange{
+		Lower: tm,
+		Upper: tm,
+		RangeBounds: RangeBounds{
+			LowerInclusive: true,
+			UpperInclusive: true,
+		},
+	})
+}
+
+// Overlaps reports whether ranges have points in common like
+// PostgreSQL && operator does.
+func (r TimeRange) Overlaps(other TimeRange) bool {
+	return overlaps(
+		r.RangeBounds, other.RangeBounds,
+		cmpTime(r.Lower, other.Upper), cmpTime(other.Lower, r.Upper),
+	)
+}
+
+func (r TimeRange) AppendValue(b []byte, quote int) ([]byte, error) {
+	return appendRange(b, quote, r.RangeBounds, func(b []byte, lower bool) []byte {
+		b = append(b, '"')
+		if lower {
+			b = AppendTime(b, r.Lower, 0)
+		} else {
+			b = AppendTime(b, r.Upper, 0)
+		}
+		return append(b, '"')
+	}), nil
+}
+
+func (r *TimeRange) Scan(src interface{}) error {
+	if src == nil {
+		*r = TimeRange{}
+		return nil
+	}
+
+	b, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("pg: can't scan %T into TimeRange", src)
+	}
+
+	lower, upper, bounds, err := parseRange(b)
+	if err != nil {
+		return err
+	}
+
+	*r = TimeRange{RangeBounds: bounds}
+	if bounds.Empty {
+		return nil
+	}
+	if bytes.Equal(lower, []byte("-infinity")) {
+		r.LowerInfinite = true
+	}
+	if bytes.Equal(upper, []byte("infinity")) {
+		r.UpperInfinite = true
+	}
+
+	if !r.LowerInfinite {
+		r.Lower, err = ParseTime(lower)
+		if err != nil {
+			return err
+		}
+	}
+	if !r.UpperInfinite {
+		r.Upper, err = ParseTime(upper)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// overlaps reports whether ranges overlap using results of comparing
+// lower bound of every range with upper bound of the other range.
+func overlaps(r1, r2 RangeBounds, cmpLower1Upper2, cmpLower2Upper1 int) bool {
+	if r1.Empty || r2.Empty {
+		return false
+	}
+	return boundsOrdered(r1.LowerInfinite || r2.UpperInfinite, cmpLower1Upper2,
+		r1.LowerInclusive && r2.UpperInclusive) &&
+		boundsOrdered(r2.LowerInfinite || r1.UpperInfinite, cmpLower2Upper1,
+			r2.LowerInclusive && r1.UpperInclusive)
+}
+
+// boundsOrdered reports whether lower bound precedes upper bound.
+func boundsOrdered(infinite bool, cmp int, inclusive bool) bool {
+	return infinite || cmp < 0 || cmp == 0 && inclusive
+}
+
+func cmpInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func cmpTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func appendRange(
+	b []byte, quote int, bounds RangeBounds, appendBound func([]byte, bool) []byte,
+) []byte {
+	if quote == 1 {
+		b = append(b, '\'')
+	}
+
+	if bounds.Empty {
+		b = append(b, pgEmptyRange...)
+	} else {
+		if bounds.LowerInclusive && !bounds.LowerInfinite {
+			b = append(b, '[')
+		} else {
+			b = append(b, '(')
+		}
+		if !bounds.LowerInfinite {
+			b = appendBound(b, true)
+		}
+		b = append(b, ',')
+		if !bounds.UpperInfinite {
+			b = appendBound(b, false)
+		}
+		if bounds.UpperInclusive && !bounds.UpperInfinite {
+			b = append(b, ']')
+		} else {
+			b = append(b, ')')
+		}
+	}
+
+	if quote == 1 {
+		b = append(b, '\'')
+	}
+	return b
+}
+
+func parseRange(b []byte) (lower, upper []byte, bounds RangeBounds, err error) {
+	if bytes.Equal(b, pgEmptyRange) {
+		bounds.Empty = true
+		return nil, nil, bounds, nil
+	}
+
+	if len(b) < 3 {
+		return nil, nil, bounds, fmt.Errorf("pg: can't parse range: %q", b)
+	}
+
+	switch b[0] {
+	case '[':
+		bounds.LowerInclusive = true
+	case '(':
+	default:
+		return nil, nil, bounds, fmt.Errorf("pg: can't parse range: %q", b)
+	}
+
+	switch b[len(b)-1] {
+	case ']':
+		bounds.UpperInclusive = true
+	case ')':
+	default:
+		return nil, nil, bounds, fmt.Errorf("pg: can't parse range: %q", b)
+	}
+
+	b = b[1 : len(b)-1]
+	lower, b = readRangeBound(b)
+	if len(b) == 0 || b[0] != ',' {
+		return nil, nil, bounds, fmt.Errorf("pg: can't parse range bounds")
+	}
+	upper, b = readRangeBound(b[1:])
+	if len(b) != 0 {
+		return nil, nil, bounds, fmt.Errorf("pg: can't parse range bounds")
+	}
+
+	bounds.LowerInfinite = lower == nil
+	bounds.UpperInfinite = upper == nil
+	return lower, upper, bounds, nil
+}
+
+// readRangeBound reads the bound, which is nil when it is omitted,
+// and returns remaining bytes.
+func readRangeBound(b []byte) (bound []byte, rest []byte) {
+	if len(b) == 0 || b[0] == ',' {
+		return nil, b
+	}
+
+	if b[0] != '"' {
+		ind := bytes.IndexByte(b, ',')
+		if ind == -1 {
+			return b, nil
+		}
+		return b[:ind], b[ind:]
+	}
+
+	bound = []byte{}
+	for i := 1; i < len(b); i++ {
+		switch c := b[i]; c {
+		case '\\':
+			if i+1 < len(b) {
+				i++
+				bound = append(bound, b[i])
+			}
+		case '"':
+			if i+1 < len(b) && b[i+1] == '"' {
+				i++
+				bound = append(bound, '"')
+				continue
+			}
+			return bound, b[i+1:]
+		default:
+			bound = append(bound, c)
+		}
+	}
+	return bound, nil
+}
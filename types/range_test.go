@@ -0,0 +1,126 @@
+package types_test
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/pg.v5/types"
+)
+
+func TestAppendRange(t *testing.T) {
+	tm := time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		v      interface{}
+		quote  int
+		wanted string
+	}{
+		{types.NewInt64Range(1, 5), 1, `'[1,5)'`},
+		{types.NewInt64Range(1, 5), 0, `[1,5)`},
+		{types.Int64Range{Upper: 5, RangeBounds: types.RangeBounds{
+			LowerInfinite:  true,
+			UpperInclusive: true,
+		}}, 1, `'(,5]'`},
+		{types.Int64Range{RangeBounds: types.RangeBounds{Empty: true}}, 1, `'empty'`},
+		{types.TimeRange{Lower: tm, RangeBounds: types.RangeBounds{
+			LowerInclusive: true,
+			UpperInfinite:  true,
+		}}, 1, `'["2017-01-02 03:04:05+00:00:00",)'`},
+	}
+	for _, test := range tests {
+		got := types.Append(nil, test.v, test.quote)
+		if string(got) != test.wanted {
+			t.Errorf("got %q, wanted %q (quote=%d)", got, test.wanted, test.quote)
+		}
+	}
+}
+
+func TestScanInt64Range(t *testing.T) {
+	tests := []struct {
+		s      string
+		wanted types.Int64Range
+	}{
+		{`[1,5)`, types.NewInt64Range(1, 5)},
+		{`(,5]`, types.Int64Range{Upper: 5, RangeBounds: types.RangeBounds{
+			LowerInfinite:  true,
+			UpperInclusive: true,
+		}}},
+		{`[-3,)`, types.Int64Range{Lower: -3, RangeBounds: types.RangeBounds{
+			LowerInclusive: true,
+			UpperInfinite:  true,
+		}}},
+		{`empty`, types.Int64Range{RangeBounds: types.RangeBounds{Empty: true}}},
+	}
+	for _, test := range tests {
+		var r types.Int64Range
+		if err := r.Scan([]byte(test.s)); err != nil {
+			t.Fatalf("%s: %s", test.s, err)
+		}
+		if r != test.wanted {
+			t.Errorf("%s: got %+v, wanted %+v", test.s, r, test.wanted)
+		}
+	}
+
+	var r types.Int64Range
+	if err := r.Scan([]byte(`1,5`)); err == nil {
+		t.Errorf("got nil error, wanted parse error")
+	}
+}
+
+func TestScanTimeRange(t *testing.T) {
+	var r types.TimeRange
+	err := r.Scan([]byte(`["2017-01-02 03:04:05+00","2017-01-03 00:00:00+00")`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.LowerInclusive || r.UpperInclusive {
+		t.Errorf("got %+v, wanted [) bounds", r.RangeBounds)
+	}
+	if want := time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC); !r.Lower.Equal(want) {
+		t.Errorf("got %s, wanted %s", r.Lower, want)
+	}
+
+	err = r.Scan([]byte(`[2017-01-02,infinity)`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.UpperInfinite || r.LowerInfinite {
+		t.Errorf("got %+v, wanted infinite upper bound", r.RangeBounds)
+	}
+	if want := time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC); !r.Lower.Equal(want) {
+		t.Errorf("got %s, wanted %s", r.Lower, want)
+	}
+}
+
+func TestRangeContainsOverlaps(t *testing.T) {
+	r := types.NewInt64Range(1, 5)
+	if !r.Contains(1) || !r.Contains(4) || r.Contains(5) || r.Contains(0) {
+		t.Errorf("Contains does not respect [1,5) bounds")
+	}
+
+	tests := []struct {
+		other  types.Int64Range
+		wanted bool
+	}{
+		{types.NewInt64Range(4, 10), true},
+		{types.NewInt64Range(5, 10), false},
+		{types.NewInt64Range(-5, 2), true},
+		{types.NewInt64Range(-5, 1), false},
+		{types.Int64Range{Upper: 1, RangeBounds: types.RangeBounds{
+			LowerInfinite:  true,
+			UpperInclusive: true,
+		}}, true},
+		{types.Int64Range{RangeBounds: types.RangeBounds{Empty: true}}, false},
+	}
+	for _, test := range tests {
+		if got := r.Overlaps(test.other); got != test.wanted {
+			t.Errorf("%+v overlaps %+v: got %v, wanted %v", r, test.other, got, test.wanted)
+		}
+	}
+
+	tm := time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC)
+	tr := types.NewTimeRange(tm, tm.Add(time.Hour))
+	if !tr.Contains(tm) || tr.Contains(tm.Add(time.Hour)) {
+		t.Errorf("Contains does not respect time range bounds")
+	}
+}
@@ -7,37 +7,122 @@ import (
 	"gopkg.in/pg.v5/internal"
 )
 
+// Column describes a column of the rows returned by the query.
+type Column struct {
+	Name         string
+	TableOID     int32 // OID of the table or 0
+	ColumnNum    int16 // attribute number of the column or 0
+	DataType     int32 // OID of the data type
+	DataTypeSize int16 // negative values denote variable-width types
+	TypeModifier int32
+	Format       int16 // 0 for text and 1 for binary
+}
+
 // A Result summarizes an executed SQL command.
 type Result struct {
+	command  string
 	affected int
 	returned int
+	columns  []Column
+
+	lastInsertId    int64
+	hasLastInsertId bool
 }
 
+// NewResult parses the command tag b of the CommandComplete message,
+// e.g. "INSERT 0 5", and returns the result of the command that
+// returned the number of rows.
 func NewResult(b []byte, returned int) *Result {
 	res := Result{
 		affected: -1,
 		returned: returned,
 	}
-	ind := bytes.LastIndexByte(b, ' ')
-	if ind == -1 {
+
+	b = bytes.TrimSuffix(b, []byte{0})
+	fields := bytes.Fields(b)
+	if len(fields) == 0 {
+		return &res
+	}
+
+	verb := internal.BytesToString(fields[0])
+	switch {
+	case verb == "INSERT" && len(fields) == 3:
+		// INSERT oid rows
+	case len(fields) == 2 && isCountVerb(verb):
+	default:
+		res.command = string(b)
 		return &res
 	}
-	s := internal.BytesToString(b[ind+1 : len(b)-1])
-	affected, err := strconv.Atoi(s)
+
+	res.command = string(fields[0])
+	affected, err := strconv.Atoi(internal.BytesToString(fields[len(fields)-1]))
 	if err == nil {
 		res.affected = affected
 	}
 	return &res
 }
 
-// RowsAffected returns the number of rows affected by SELECT, INSERT, UPDATE,
-// or DELETE queries. It returns -1 when query can't possibly affect any rows,
-// e.g. in case of CREATE or SHOW queries.
+func isCountVerb(verb string) bool {
+	switch verb {
+	case "SELECT", "UPDATE", "DELETE", "MERGE", "MOVE", "FETCH", "COPY":
+		return true
+	}
+	return false
+}
+
+// Command returns the command of the SQL statement, e.g. INSERT or
+// CREATE TABLE, without the number of rows.
+func (r Result) Command() string {
+	return r.command
+}
+
+// RowsAffected returns the number of rows affected by the command as
+// reported by the server, e.g. rows inserted by INSERT or selected by
+// SELECT, which may differ from RowsReturned, e.g. for INSERT without
+// RETURNING. It returns -1 when the command can't possibly affect any
+// rows, e.g. in case of CREATE or SHOW queries.
 func (r Result) RowsAffected() int {
 	return r.affected
 }
 
-// RowsReturned returns the number of rows returned by the query.
+// RowsReturned returns the number of rows sent to the client by the
+// query, e.g. rows of SELECT or of INSERT ... RETURNING.
 func (r Result) RowsReturned() int {
 	return r.returned
 }
+
+// LastInsertId returns the value of the first column of the last row
+// returned by INSERT ... RETURNING executed with Exec or ExecOne, e.g.
+//
+//    res, err := db.Exec("INSERT INTO users (name) VALUES (?) RETURNING id", name)
+//    id, err := res.LastInsertId()
+//
+// PostgreSQL does not report the ids of inserted rows otherwise, so an
+// error is returned when the query is not INSERT, does not return rows
+// or the first column is not an integer.
+func (r Result) LastInsertId() (int64, error) {
+	if r.command != "INSERT" {
+		return 0, internal.Errorf("pg: LastInsertId is not supported by %s", r.command)
+	}
+	if !r.hasLastInsertId {
+		return 0, internal.Errorf("pg: LastInsertId requires INSERT ... RETURNING of integer id")
+	}
+	return r.lastInsertId, nil
+}
+
+// SetLastInsertId sets the id returned by LastInsertId.
+func (r *Result) SetLastInsertId(id int64) {
+	r.lastInsertId = id
+	r.hasLastInsertId = true
+}
+
+// Columns returns the description of the columns returned by the query.
+// It is only available for queries that scan rows, e.g. Query and QueryOne.
+func (r Result) Columns() []Column {
+	return r.columns
+}
+
+// SetColumns sets columns returned by Columns.
+func (r *Result) SetColumns(columns []Column) {
+	r.columns = columns
+}
@@ -0,0 +1,55 @@
+package types_test
+
+import (
+	"testing"
+
+	"gopkg.in/pg.v5/types"
+)
+
+func TestNewResult(t *testing.T) {
+	tests := []struct {
+		tag      string
+		command  string
+		affected int
+	}{
+		{"INSERT 0 5\x00", "INSERT", 5},
+		{"SELECT 3\x00", "SELECT", 3},
+		{"UPDATE 0\x00", "UPDATE", 0},
+		{"DELETE 12\x00", "DELETE", 12},
+		{"COPY 7\x00", "COPY", 7},
+		{"CREATE TABLE\x00", "CREATE TABLE", -1},
+		{"SHOW\x00", "SHOW", -1},
+		{"\x00", "", -1},
+	}
+	for _, test := range tests {
+		res := types.NewResult([]byte(test.tag), 0)
+		if res.Command() != test.command {
+			t.Errorf("%q: got command %q, wanted %q", test.tag, res.Command(), test.command)
+		}
+		if res.RowsAffected() != test.affected {
+			t.Errorf("%q: got %d rows affected, wanted %d", test.tag, res.RowsAffected(), test.affected)
+		}
+	}
+}
+
+func TestResultLastInsertId(t *testing.T) {
+	res := types.NewResult([]byte("INSERT 0 1\x00"), 1)
+	if _, err := res.LastInsertId(); err == nil {
+		t.Fatal("expected error without returned id")
+	}
+
+	res.SetLastInsertId(42)
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 42 {
+		t.Fatalf("got %d, wanted 42", id)
+	}
+
+	res = types.NewResult([]byte("UPDATE 1\x00"), 1)
+	res.SetLastInsertId(42)
+	if _, err := res.LastInsertId(); err == nil {
+		t.Fatal("expected error for UPDATE")
+	}
+}
@@ -63,6 +63,45 @@ func Scan(v interface{}, b []byte) error {
 	return ScanValue(vv, b)
 }
 
+// ScanInt64 parses the column value like the scanner of int fields.
+// NULL is parsed as 0. It is used by the code generated with pggen.
+func ScanInt64(b []byte) (int64, error) {
+	if b == nil {
+		return 0, nil
+	}
+	return strconv.ParseInt(internal.BytesToString(b), 10, 64)
+}
+
+// ScanUint64 parses the column value like the scanner of uint fields.
+func ScanUint64(b []byte) (uint64, error) {
+	if b == nil {
+		return 0, nil
+	}
+	return strconv.ParseUint(internal.BytesToString(b), 10, 64)
+}
+
+// ScanFloat64 parses the column value like the scanner of float fields.
+func ScanFloat64(b []byte) (float64, error) {
+	if b == nil {
+		return 0, nil
+	}
+	return strconv.ParseFloat(internal.BytesToString(b), 64)
+}
+
+// ScanBool parses the column value like the scanner of bool fields.
+func ScanBool(b []byte) bool {
+	return len(b) == 1 && (b[0] == 't' || b[0] == '1')
+}
+
+// ScanTime parses the column value like the scanner of time.Time
+// fields. NULL is parsed as the zero time.
+func ScanTime(b []byte) (time.Time, error) {
+	if b == nil {
+		return time.Time{}, nil
+	}
+	return ParseTime(b)
+}
+
 func scanSQLScanner(scanner sql.Scanner, b []byte) error {
 	if b == nil {
 		return scanner.Scan(nil)
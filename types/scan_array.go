@@ -29,7 +29,7 @@ func ArrayScanner(typ reflect.Type) ScannerFunc {
 		}
 		if b == nil {
 			if !v.IsNil() {
-				v.Set(reflect.New(v.Type()))
+				v.Set(reflect.Zero(v.Type()))
 			}
 			return nil
 		}
@@ -42,7 +42,7 @@ func ArrayScanner(typ reflect.Type) ScannerFunc {
 			if err != nil {
 				return err
 			}
-			elemValue := internal.SliceNextElem(v)
+			elemValue := sliceNextElem(v)
 			if err := scanElem(elemValue, elem); err != nil {
 				return err
 			}
@@ -51,6 +51,17 @@ func ArrayScanner(typ reflect.Type) ScannerFunc {
 	}
 }
 
+// sliceNextElem is like internal.SliceNextElem, but it does not
+// allocate pointer elements so NULL elements are scanned as nil.
+func sliceNextElem(v reflect.Value) reflect.Value {
+	if v.Len() < v.Cap() {
+		v.Set(v.Slice(0, v.Len()+1))
+		return v.Index(v.Len() - 1)
+	}
+	v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+	return v.Index(v.Len() - 1)
+}
+
 func scanSliceStringValue(v reflect.Value, b []byte) error {
 	if !v.CanSet() {
 		return internal.Errorf("pg: Scan(non-pointer %s)", v.Type())
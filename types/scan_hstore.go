@@ -12,6 +12,9 @@ func HstoreScanner(typ reflect.Type) ScannerFunc {
 	if typ.Key() == stringType && typ.Elem() == stringType {
 		return scanMapStringStringValue
 	}
+	if typ.Key() == stringType && typ.Elem() == stringPtrType {
+		return scanMapStringStringPtrValue
+	}
 	return func(v reflect.Value, b []byte) error {
 		return fmt.Errorf("pg.Hstore(unsupported %s)", v.Type())
 	}
@@ -57,3 +60,43 @@ func scanMapStringStringValue(v reflect.Value, b []byte) error {
 	v.Set(reflect.ValueOf(m))
 	return nil
 }
+
+func scanMapStringStringPtr(b []byte) (map[string]*string, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	p := parser.NewHstoreParser(b)
+	m := make(map[string]*string)
+	for p.Valid() {
+		key, err := p.NextKey()
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := p.NextValue()
+		if err != nil {
+			return nil, err
+		}
+
+		if value == nil {
+			m[string(key)] = nil
+		} else {
+			s := string(value)
+			m[string(key)] = &s
+		}
+	}
+	return m, nil
+}
+
+func scanMapStringStringPtrValue(v reflect.Value, b []byte) error {
+	if !v.CanSet() {
+		return internal.Errorf("pg: Scan(non-pointer %s)", v.Type())
+	}
+	m, err := scanMapStringStringPtr(b)
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(m).Convert(v.Type()))
+	return nil
+}
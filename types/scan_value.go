@@ -3,7 +3,6 @@ package types
 import (
 	"database/sql"
 	"database/sql/driver"
-	"encoding/json"
 	"reflect"
 	"strconv"
 	"time"
@@ -63,6 +62,25 @@ func scanner(typ reflect.Type, pgArray bool) ScannerFunc {
 	if typ == timeType {
 		return scanTimeValue
 	}
+	if typ == durationType {
+		return scanDurationValue
+	}
+
+	if fn := numericScanner(typ); fn != nil {
+		return fn
+	}
+
+	if fn := netScanner(typ); fn != nil {
+		return fn
+	}
+
+	if fn := enumScanner(typ); fn != nil {
+		return fn
+	}
+
+	if typ == jsonRawMessageType {
+		return scanJSONRawMessageValue
+	}
 
 	if typ.Implements(scannerType) {
 		return scanSQLScannerValue
@@ -213,17 +231,6 @@ func scanStringValue(v reflect.Value, b []byte) error {
 	return nil
 }
 
-func scanJSONValue(v reflect.Value, b []byte) error {
-	if !v.CanSet() {
-		return internal.Errorf("pg: Scan(non-pointer %s)", v.Type())
-	}
-	if b == nil {
-		v.Set(reflect.New(v.Type()).Elem())
-		return nil
-	}
-	return json.Unmarshal(b, v.Addr().Interface())
-}
-
 var zeroTimeValue = reflect.ValueOf(time.Time{})
 
 func scanTimeValue(v reflect.Value, b []byte) error {
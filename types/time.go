@@ -1,6 +1,11 @@
 package types
 
-import "time"
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
 
 const (
 	dateFormat         = "2006-01-02"
@@ -11,7 +16,98 @@ const (
 	timestamptzFormat3 = "2006-01-02 15:04:05.999999999-07"
 )
 
+const (
+	// TimeUTC converts time to UTC before it is appended.
+	TimeUTC = 1 << iota
+	// TimeMicroseconds truncates time to microseconds, which is
+	// the precision of PostgreSQL timestamps.
+	TimeMicroseconds
+)
+
+var timeFlags int32
+
+// SetTimeFlags sets flags that control how time.Time values are appended
+// to queries, e.g. TimeUTC|TimeMicroseconds. Flags set on individual
+// struct fields are combined with these flags. It is safe to call
+// SetTimeFlags while queries are being formatted.
+func SetTimeFlags(flags int) {
+	atomic.StoreInt32(&timeFlags, int32(flags))
+}
+
+var timeLocation atomic.Value // *time.Location
+
+func getTimeLocation() *time.Location {
+	loc, _ := timeLocation.Load().(*time.Location)
+	return loc
+}
+
+// SetTimeLocation sets the location of scanned time values. timestamptz
+// values are converted to the location. timestamp values, which don't
+// have a time zone, are naive: their wall clock is kept and interpreted
+// in the location. By default timestamptz values keep the offset sent
+// by the server and timestamp values are interpreted in time.Local.
+// Passing nil restores the default. It is safe to call SetTimeLocation
+// while values are being scanned.
+func SetTimeLocation(loc *time.Location) {
+	timeLocation.Store(loc)
+}
+
+// infinityTimes maps -infinity and infinity timestamps to neg and pos
+// when they are not zero.
+type infinityTimes struct {
+	neg, pos time.Time
+}
+
+var infinity atomic.Value // *infinityTimes
+
+func getInfinityTimes() *infinityTimes {
+	if inf, ok := infinity.Load().(*infinityTimes); ok {
+		return inf
+	}
+	return &infinityTimes{}
+}
+
+// SetInfinityTimes maps -infinity and infinity timestamps to the times
+// when they are scanned and the times back to -infinity and infinity
+// when they are appended, e.g.
+//
+//    types.SetInfinityTimes(
+//        time.Date(-4713, time.November, 24, 0, 0, 0, 0, time.UTC),
+//        time.Date(294276, time.December, 31, 23, 59, 59, 999999000, time.UTC),
+//    )
+//
+// By default, scanning infinite timestamp returns an error. Zero time
+// disables the mapping of -infinity or infinity, so zero time.Time is
+// never appended as infinite timestamp.
+func SetInfinityTimes(negInf, posInf time.Time) {
+	infinity.Store(&infinityTimes{neg: negInf, pos: posInf})
+}
+
+func parseInfinity(b []byte) (time.Time, bool, error) {
+	inf := getInfinityTimes()
+	var tm time.Time
+	switch string(b) {
+	case "infinity":
+		tm = inf.pos
+	case "-infinity":
+		tm = inf.neg
+	default:
+		return time.Time{}, false, nil
+	}
+	if tm.IsZero() {
+		return time.Time{}, true, fmt.Errorf(
+			"pg: can't scan %s timestamp; use SetInfinityTimes to map it to time", b)
+	}
+	return tm, true, nil
+}
+
 func ParseTime(b []byte) (time.Time, error) {
+	if len(b) > 0 && (b[0] == 'i' || b[0] == '-') {
+		if tm, ok, err := parseInfinity(b); ok {
+			return tm, err
+		}
+	}
+
 	switch l := len(b); {
 	case l <= len(dateFormat):
 		return time.Parse(dateFormat, string(b))
@@ -19,25 +115,80 @@ func ParseTime(b []byte) (time.Time, error) {
 		return time.Parse(timeFormat, string(b))
 	default:
 		if c := b[len(b)-9]; c == '+' || c == '-' {
-			return time.Parse(timestamptzFormat, string(b))
+			return parseTimestamptz(timestamptzFormat, b)
 		}
 		if c := b[len(b)-6]; c == '+' || c == '-' {
-			return time.Parse(timestamptzFormat2, string(b))
+			return parseTimestamptz(timestamptzFormat2, b)
 		}
 		if c := b[len(b)-3]; c == '+' || c == '-' {
-			return time.Parse(timestamptzFormat3, string(b))
+			return parseTimestamptz(timestamptzFormat3, b)
 		}
-		return time.ParseInLocation(timestampFormat, string(b), time.Local)
+		loc := getTimeLocation()
+		if loc == nil {
+			loc = time.Local
+		}
+		return time.ParseInLocation(timestampFormat, string(b), loc)
+	}
+}
+
+func parseTimestamptz(format string, b []byte) (time.Time, error) {
+	tm, err := time.Parse(format, string(b))
+	if err != nil {
+		return tm, err
+	}
+	if loc := getTimeLocation(); loc != nil {
+		tm = tm.In(loc)
 	}
+	return tm, nil
 }
 
 func AppendTime(b []byte, tm time.Time, quote int) []byte {
+	return AppendTimeFlags(b, tm, quote, 0)
+}
+
+// AppendTimeFlags appends time using the flags combined with the flags
+// set by SetTimeFlags.
+func AppendTimeFlags(b []byte, tm time.Time, quote int, flags int) []byte {
+	flags |= int(atomic.LoadInt32(&timeFlags))
+	if flags&TimeUTC != 0 {
+		tm = tm.UTC()
+	}
+	if flags&TimeMicroseconds != 0 {
+		tm = tm.Truncate(time.Microsecond)
+	}
+
 	if quote == 1 {
 		b = append(b, '\'')
 	}
-	b = tm.AppendFormat(b, timestamptzFormat)
+	inf := getInfinityTimes()
+	if !inf.pos.IsZero() && tm.Equal(inf.pos) {
+		b = append(b, "infinity"...)
+	} else if !inf.neg.IsZero() && tm.Equal(inf.neg) {
+		b = append(b, "-infinity"...)
+	} else {
+		b = tm.AppendFormat(b, timestamptzFormat)
+	}
 	if quote == 1 {
 		b = append(b, '\'')
 	}
 	return b
 }
+
+// TimeAppender returns appender for time.Time or *time.Time type
+// that uses the flags. It returns nil for other types.
+func TimeAppender(typ reflect.Type, flags int) AppenderFunc {
+	switch typ {
+	case timeType:
+		return func(b []byte, v reflect.Value, quote int) []byte {
+			return AppendTimeFlags(b, v.Interface().(time.Time), quote, flags)
+		}
+	case reflect.PtrTo(timeType):
+		return func(b []byte, v reflect.Value, quote int) []byte {
+			if v.IsNil() {
+				return AppendNull(b, quote)
+			}
+			return AppendTimeFlags(b, v.Elem().Interface().(time.Time), quote, flags)
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package types_test
 
 import (
 	"testing"
+	"time"
 
 	"gopkg.in/pg.v5/types"
 )
@@ -11,3 +12,161 @@ func BenchmarkParseTime(b *testing.B) {
 		types.ParseTime([]byte("2001-02-03 04:05:06+07"))
 	}
 }
+
+func TestAppendTimeFlags(t *testing.T) {
+	loc := time.FixedZone("", 3*3600)
+	tm := time.Date(2001, time.February, 3, 4, 5, 6, 123456789, loc)
+
+	tests := []struct {
+		flags  int
+		wanted string
+	}{
+		{0, "'2001-02-03 04:05:06.123456789+03:00:00'"},
+		{types.TimeUTC, "'2001-02-03 01:05:06.123456789+00:00:00'"},
+		{types.TimeMicroseconds, "'2001-02-03 04:05:06.123456+03:00:00'"},
+		{types.TimeUTC | types.TimeMicroseconds, "'2001-02-03 01:05:06.123456+00:00:00'"},
+	}
+	for _, test := range tests {
+		got := types.AppendTimeFlags(nil, tm, 1, test.flags)
+		if string(got) != test.wanted {
+			t.Errorf("got %q, wanted %q (flags=%d)", got, test.wanted, test.flags)
+		}
+	}
+}
+
+func TestSetTimeFlagsConcurrently(t *testing.T) {
+	defer types.SetTimeFlags(0)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			types.SetTimeFlags(types.TimeUTC)
+		}
+	}()
+
+	tm := time.Date(2001, time.February, 3, 4, 5, 6, 0, time.UTC)
+	for i := 0; i < 100; i++ {
+		types.AppendTimeFlags(nil, tm, 1, 0)
+	}
+	<-done
+}
+
+func TestSetTimeLocationConcurrently(t *testing.T) {
+	defer types.SetTimeLocation(nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			types.SetTimeLocation(time.UTC)
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		if _, err := types.ParseTime([]byte("2001-02-03 04:05:06")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
+
+func TestSetTimeLocation(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	types.SetTimeLocation(loc)
+	defer types.SetTimeLocation(nil)
+
+	tm, err := types.ParseTime([]byte("2001-02-03 04:05:06+00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tm.Location() != loc {
+		t.Errorf("got %s, wanted %s", tm.Location(), loc)
+	}
+	if wanted := time.Date(2001, time.February, 3, 4, 5, 6, 0, time.UTC); !tm.Equal(wanted) {
+		t.Errorf("got %s, wanted %s", tm, wanted)
+	}
+
+	tm, err = types.ParseTime([]byte("2001-02-03 04:05:06"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wanted := time.Date(2001, time.February, 3, 4, 5, 6, 0, loc); !tm.Equal(wanted) || tm.Location() != loc {
+		t.Errorf("got %s, wanted %s", tm, wanted)
+	}
+}
+
+func TestParseNaiveTime(t *testing.T) {
+	tm, err := types.ParseTime([]byte("2001-02-03 04:05:06.123"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tm.Location() != time.Local {
+		t.Errorf("got %s, wanted Local", tm.Location())
+	}
+	if tm.Hour() != 4 || tm.Nanosecond() != 123000000 {
+		t.Errorf("wall clock is changed: %s", tm)
+	}
+}
+
+func TestInfinityTime(t *testing.T) {
+	_, err := types.ParseTime([]byte("infinity"))
+	if wanted := "pg: can't scan infinity timestamp; use SetInfinityTimes to map it to time"; err == nil || err.Error() != wanted {
+		t.Errorf("got %v, wanted %q", err, wanted)
+	}
+
+	negInf := time.Date(-4713, time.November, 24, 0, 0, 0, 0, time.UTC)
+	posInf := time.Date(294276, time.December, 31, 23, 59, 59, 999999000, time.UTC)
+	types.SetInfinityTimes(negInf, posInf)
+	defer types.SetInfinityTimes(time.Time{}, time.Time{})
+
+	tests := []struct {
+		s  string
+		tm time.Time
+	}{
+		{"infinity", posInf},
+		{"-infinity", negInf},
+	}
+	for _, test := range tests {
+		tm, err := types.ParseTime([]byte(test.s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !tm.Equal(test.tm) {
+			t.Errorf("got %s, wanted %s", tm, test.tm)
+		}
+
+		got := types.AppendTime(nil, tm, 1)
+		if wanted := "'" + test.s + "'"; string(got) != wanted {
+			t.Errorf("got %q, wanted %q", got, wanted)
+		}
+	}
+
+	tm, err := types.ParseTime([]byte("2001-02-03 04:05:06+00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := types.AppendTime(nil, tm, 0); string(got) != "2001-02-03 04:05:06+00:00:00" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestInfinityTimeOneSide(t *testing.T) {
+	posInf := time.Date(294276, time.December, 31, 23, 59, 59, 999999000, time.UTC)
+	types.SetInfinityTimes(time.Time{}, posInf)
+	defer types.SetInfinityTimes(time.Time{}, time.Time{})
+
+	if got := types.AppendTime(nil, time.Time{}, 1); string(got) != "'0001-01-01 00:00:00+00:00:00'" {
+		t.Errorf("got %q", got)
+	}
+	if got := types.AppendTime(nil, posInf, 1); string(got) != "'infinity'" {
+		t.Errorf("got %q, wanted 'infinity'", got)
+	}
+	if _, err := types.ParseTime([]byte("-infinity")); err == nil {
+		t.Error("got nil error for -infinity")
+	}
+}
@@ -0,0 +1,120 @@
+package types
+
+import (
+	"database/sql"
+	"encoding"
+	"encoding/hex"
+	"fmt"
+)
+
+// UUID represents PostgreSQL uuid type. Other UUID types, e.g.
+// github.com/google/uuid, are supported as well, because they
+// implement sql.Scanner and driver.Valuer.
+type UUID [16]byte
+
+var _ ValueAppender = (*UUID)(nil)
+var _ sql.Scanner = (*UUID)(nil)
+var _ encoding.TextMarshaler = (*UUID)(nil)
+var _ encoding.TextUnmarshaler = (*UUID)(nil)
+
+// ParseUUID parses UUID in the canonical form
+// xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx, in the form without hyphens
+// or in the 16 bytes binary form.
+func ParseUUID(b []byte) (UUID, error) {
+	var u UUID
+	switch len(b) {
+	case 16:
+		copy(u[:], b)
+		return u, nil
+	case 32:
+		if _, err := hex.Decode(u[:], b); err != nil {
+			return u, fmt.Errorf("pg: can't parse UUID: %q", b)
+		}
+		return u, nil
+	case 36:
+		if b[8] != '-' || b[13] != '-' || b[18] != '-' || b[23] != '-' {
+			return u, fmt.Errorf("pg: can't parse UUID: %q", b)
+		}
+		var h [32]byte
+		copy(h[0:8], b[0:8])
+		copy(h[8:12], b[9:13])
+		copy(h[12:16], b[14:18])
+		copy(h[16:20], b[19:23])
+		copy(h[20:32], b[24:36])
+		if _, err := hex.Decode(u[:], h[:]); err != nil {
+			return u, fmt.Errorf("pg: can't parse UUID: %q", b)
+		}
+		return u, nil
+	default:
+		return u, fmt.Errorf("pg: can't parse UUID: %q", b)
+	}
+}
+
+func (u UUID) String() string {
+	return string(u.appendString(nil))
+}
+
+func (u UUID) appendString(b []byte) []byte {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:36], u[10:16])
+	return append(b, buf[:]...)
+}
+
+func (u UUID) AppendValue(b []byte, quote int) ([]byte, error) {
+	if quote == 1 {
+		b = append(b, '\'')
+	}
+	b = u.appendString(b)
+	if quote == 1 {
+		b = append(b, '\'')
+	}
+	return b, nil
+}
+
+func (u *UUID) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*u = UUID{}
+		return nil
+	case []byte:
+		v, err := ParseUUID(src)
+		if err != nil {
+			return err
+		}
+		*u = v
+		return nil
+	case string:
+		v, err := ParseUUID([]byte(src))
+		if err != nil {
+			return err
+		}
+		*u = v
+		return nil
+	default:
+		return fmt.Errorf("pg: can't scan %T into UUID", src)
+	}
+}
+
+func (u UUID) MarshalText() ([]byte, error) {
+	return u.appendString(nil), nil
+}
+
+func (u *UUID) UnmarshalText(b []byte) error {
+	if len(b) == 16 {
+		return fmt.Errorf("pg: can't parse UUID: %q", b)
+	}
+	v, err := ParseUUID(b)
+	if err != nil {
+		return err
+	}
+	*u = v
+	return nil
+}
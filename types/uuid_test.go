@@ -0,0 +1,83 @@
+package types_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/pg.v5/types"
+)
+
+const uuidString = "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"
+
+var uuidBytes = types.UUID{
+	0xa0, 0xee, 0xbc, 0x99, 0x9c, 0x0b, 0x4e, 0xf8,
+	0xbb, 0x6d, 0x6b, 0xb9, 0xbd, 0x38, 0x0a, 0x11,
+}
+
+func TestParseUUID(t *testing.T) {
+	tests := []string{
+		uuidString,
+		"A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11",
+		"a0eebc999c0b4ef8bb6d6bb9bd380a11",
+		string(uuidBytes[:]),
+	}
+	for _, s := range tests {
+		u, err := types.ParseUUID([]byte(s))
+		if err != nil {
+			t.Fatalf("%q: %s", s, err)
+		}
+		if u != uuidBytes {
+			t.Errorf("%q: got %s, wanted %s", s, u, uuidString)
+		}
+	}
+
+	for _, s := range []string{"", "a0eebc99-9c0b-4ef8-bb6d", "a0eebc99x9c0b-4ef8-bb6d-6bb9bd380a11"} {
+		if _, err := types.ParseUUID([]byte(s)); err == nil {
+			t.Errorf("%q: got nil error, wanted parse error", s)
+		}
+	}
+}
+
+func TestAppendUUID(t *testing.T) {
+	if got := string(types.Append(nil, uuidBytes, 1)); got != "'"+uuidString+"'" {
+		t.Errorf("got %q, wanted %q", got, "'"+uuidString+"'")
+	}
+	if got := string(types.Append(nil, &uuidBytes, 0)); got != uuidString {
+		t.Errorf("got %q, wanted %q", got, uuidString)
+	}
+}
+
+func TestScanUUID(t *testing.T) {
+	var u types.UUID
+	if err := types.Scan(&u, []byte(uuidString)); err != nil {
+		t.Fatal(err)
+	}
+	if u != uuidBytes {
+		t.Errorf("got %s, wanted %s", u, uuidString)
+	}
+
+	if err := types.Scan(&u, nil); err != nil {
+		t.Fatal(err)
+	}
+	if u != (types.UUID{}) {
+		t.Errorf("got %s, wanted zero UUID", u)
+	}
+}
+
+func TestUUIDJSON(t *testing.T) {
+	b, err := json.Marshal(uuidBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `"`+uuidString+`"` {
+		t.Errorf("got %s, wanted %q", b, uuidString)
+	}
+
+	var u types.UUID
+	if err := json.Unmarshal(b, &u); err != nil {
+		t.Fatal(err)
+	}
+	if u != uuidBytes {
+		t.Errorf("got %s, wanted %s", u, uuidString)
+	}
+}
@@ -0,0 +1,381 @@
+package pg
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/pg.v5/internal/pool"
+)
+
+const (
+	sslRequestCode    = 80877103
+	cancelRequestCode = 80877102
+
+	// Only the beginning of long messages is decoded.
+	maxTraceBody = 1024
+)
+
+var traceMu sync.Mutex
+
+func (db *DB) traceConn(cn *pool.Conn) {
+	if db.opt.TraceWire == nil {
+		return
+	}
+	t := &wireTracer{
+		w:       db.opt.TraceWire,
+		cn:      cn,
+		startup: true,
+	}
+	cn.SetTrace(t.trace)
+}
+
+// wireTracer writes protocol messages sent (F) and received (B) over
+// the connection, e.g.
+//
+//    2017-01-02 15:04:05.000000 pid=1234 F 13 Query "SELECT 1"
+//    2017-01-02 15:04:05.000310 pid=1234 B 5 ReadyForQuery I
+//
+// Messages written by the client are always complete, but messages read
+// from the server are split across reads and are reassembled.
+type wireTracer struct {
+	w  io.Writer
+	cn *pool.Conn
+
+	startup     bool // StartupMessage is not sent yet
+	sslResponse bool // server is about to respond to SSLRequest
+
+	hdr  []byte // header of the backend message
+	body []byte // beginning of the backend message body
+	left int    // number of body bytes that are not read yet
+}
+
+func (t *wireTracer) trace(frontend bool, b []byte) {
+	if frontend {
+		t.frontend(b)
+	} else {
+		t.backend(b)
+	}
+}
+
+func (t *wireTracer) frontend(b []byte) {
+	for len(b) > 0 {
+		if t.startup {
+			if len(b) < 8 {
+				t.write('F', len(b), "incomplete message")
+				return
+			}
+			n := int(binary.BigEndian.Uint32(b))
+			if n < 8 || n > len(b) {
+				t.write('F', len(b), "incomplete message")
+				return
+			}
+			t.write('F', n, t.startupSummary(b[4:n]))
+			b = b[n:]
+			continue
+		}
+
+		if len(b) < 5 {
+			t.write('F', len(b), "incomplete message")
+			return
+		}
+		n := int(binary.BigEndian.Uint32(b[1:]))
+		if n < 4 || n+1 > len(b) {
+			t.write('F', len(b), "incomplete message")
+			return
+		}
+		t.write('F', n, frontendSummary(b[0], traceBody(b[5:n+1])))
+		b = b[n+1:]
+	}
+}
+
+func (t *wireTracer) startupSummary(b traceBuf) string {
+	switch code := b.int32(); code {
+	case sslRequestCode:
+		t.sslResponse = true
+		return "SSLRequest"
+	case cancelRequestCode:
+		return "CancelRequest " + strconv.Itoa(b.int32())
+	default:
+		t.startup = false
+		var params []string
+		for len(b) > 0 {
+			name := b.string()
+			if name == "" {
+				break
+			}
+			params = append(params, name+"="+strconv.Quote(b.string()))
+		}
+		return fmt.Sprintf("StartupMessage %d.%d %s", code>>16, code&0xffff, strings.Join(params, " "))
+	}
+}
+
+func (t *wireTracer) backend(b []byte) {
+	for len(b) > 0 {
+		if t.sslResponse {
+			t.sslResponse = false
+			t.write('B', 1, "SSLResponse "+string(b[0]))
+			b = b[1:]
+			continue
+		}
+
+		if len(t.hdr) < 5 {
+			n := 5 - len(t.hdr)
+			if n > len(b) {
+				n = len(b)
+			}
+			t.hdr = append(t.hdr, b[:n]...)
+			b = b[n:]
+			if len(t.hdr) < 5 {
+				return
+			}
+			t.left = int(binary.BigEndian.Uint32(t.hdr[1:])) - 4
+			if t.left < 0 {
+				t.left = 0
+			}
+			t.body = t.body[:0]
+		}
+
+		n := t.left
+		if n > len(b) {
+			n = len(b)
+		}
+		if keep := maxTraceBody - len(t.body); keep > 0 {
+			if keep > n {
+				keep = n
+			}
+			t.body = append(t.body, b[:keep]...)
+		}
+		t.left -= n
+		b = b[n:]
+		if t.left > 0 {
+			return
+		}
+
+		t.write('B', int(binary.BigEndian.Uint32(t.hdr[1:])), backendSummary(t.hdr[0], t.body))
+		t.hdr = t.hdr[:0]
+	}
+}
+
+func (t *wireTracer) write(dir byte, n int, msg string) {
+	b := make([]byte, 0, 64+len(msg))
+	b = time.Now().AppendFormat(b, "2006-01-02 15:04:05.000000")
+	b = append(b, " pid="...)
+	b = strconv.AppendInt(b, int64(t.cn.ProcessId), 10)
+	b = append(b, ' ', dir, ' ')
+	b = strconv.AppendInt(b, int64(n), 10)
+	b = append(b, ' ')
+	b = append(b, msg...)
+	b = append(b, '\n')
+
+	traceMu.Lock()
+	t.w.Write(b)
+	traceMu.Unlock()
+}
+
+func traceBody(b []byte) traceBuf {
+	if len(b) > maxTraceBody {
+		b = b[:maxTraceBody]
+	}
+	return b
+}
+
+func frontendSummary(c byte, b traceBuf) string {
+	switch c {
+	case queryMsg:
+		return "Query " + strconv.Quote(b.string())
+	case parseMsg:
+		name := b.string()
+		return fmt.Sprintf("Parse %q %q", name, b.string())
+	case bindMsg:
+		portal := b.string()
+		return fmt.Sprintf("Bind %q %q", portal, b.string())
+	case describeMsg:
+		kind := b.byte()
+		return fmt.Sprintf("Describe %c %q", kind, b.string())
+	case executeMsg:
+		portal := b.string()
+		return fmt.Sprintf("Execute %q %d", portal, b.int32())
+	case closeMsg:
+		kind := b.byte()
+		return fmt.Sprintf("Close %c %q", kind, b.string())
+	case syncMsg:
+		return "Sync"
+	case flushMsg:
+		return "Flush"
+	case terminateMsg:
+		return "Terminate"
+	case passwordMessageMsg:
+		return "PasswordMessage"
+	case copyDataMsg:
+		return "CopyData"
+	case copyDoneMsg:
+		return "CopyDone"
+	case 'f':
+		return "CopyFail " + strconv.Quote(b.string())
+	}
+	return fmt.Sprintf("Unknown %q", c)
+}
+
+func backendSummary(c byte, b traceBuf) string {
+	switch c {
+	case authenticationOKMsg:
+		switch code := b.int32(); code {
+		case authenticationOK:
+			return "AuthenticationOk"
+		case authenticationCleartextPassword:
+			return "AuthenticationCleartextPassword"
+		case authenticationMD5Password:
+			return "AuthenticationMD5Password"
+		case authenticationSASL:
+			var mechanisms []string
+			for len(b) > 0 {
+				s := b.string()
+				if s == "" {
+					break
+				}
+				mechanisms = append(mechanisms, s)
+			}
+			return "AuthenticationSASL " + strings.Join(mechanisms, " ")
+		case 11:
+			return "AuthenticationSASLContinue"
+		case 12:
+			return "AuthenticationSASLFinal"
+		default:
+			return "Authentication " + strconv.Itoa(code)
+		}
+	case parameterStatusMsg:
+		name := b.string()
+		return fmt.Sprintf("ParameterStatus %s %q", name, b.string())
+	case backendKeyDataMsg:
+		return "BackendKeyData " + strconv.Itoa(b.int32())
+	case negotiateProtocolVersionMsg:
+		return "NegotiateProtocolVersion " + strconv.Itoa(b.int32())
+	case readyForQueryMsg:
+		return fmt.Sprintf("ReadyForQuery %c", b.byte())
+	case rowDescriptionMsg:
+		n := b.int16()
+		names := make([]string, 0, n)
+		for i := 0; i < n && len(b) > 0; i++ {
+			names = append(names, strconv.Quote(b.string()))
+			b.skip(18)
+		}
+		return fmt.Sprintf("RowDescription %d [%s]", n, strings.Join(names, " "))
+	case dataRowMsg:
+		return "DataRow " + strconv.Itoa(b.int16())
+	case commandCompleteMsg:
+		return "CommandComplete " + strconv.Quote(b.string())
+	case errorResponseMsg:
+		return "ErrorResponse " + traceError(b)
+	case noticeResponseMsg:
+		return "NoticeResponse " + traceError(b)
+	case parseCompleteMsg:
+		return "ParseComplete"
+	case bindCompleteMsg:
+		return "BindComplete"
+	case closeCompleteMsg:
+		return "CloseComplete"
+	case noDataMsg:
+		return "NoData"
+	case 's':
+		return "PortalSuspended"
+	case emptyQueryResponseMsg:
+		return "EmptyQueryResponse"
+	case parameterDescriptionMsg:
+		return "ParameterDescription " + strconv.Itoa(b.int16())
+	case notificationResponseMsg:
+		pid := b.int32()
+		channel := b.string()
+		return fmt.Sprintf("NotificationResponse %d %q %q", pid, channel, b.string())
+	case copyInResponseMsg:
+		return "CopyInResponse"
+	case copyOutResponseMsg:
+		return "CopyOutResponse"
+	case 'W':
+		return "CopyBothResponse"
+	case copyDataMsg:
+		return "CopyData"
+	case copyDoneMsg:
+		return "CopyDone"
+	}
+	return fmt.Sprintf("Unknown %q", c)
+}
+
+// traceError formats severity, code and message fields of
+// ErrorResponse and NoticeResponse.
+func traceError(b traceBuf) string {
+	var severity, code, msg string
+	for len(b) > 0 {
+		c := b.byte()
+		if c == 0 {
+			break
+		}
+		s := b.string()
+		switch c {
+		case 'S':
+			severity = s
+		case 'C':
+			code = s
+		case 'M':
+			msg = s
+		}
+	}
+	return fmt.Sprintf("%s %s %q", severity, code, msg)
+}
+
+// traceBuf decodes message fields. Messages can be truncated, so
+// missing fields are returned as zero values.
+type traceBuf []byte
+
+func (b *traceBuf) byte() byte {
+	if len(*b) < 1 {
+		return 0
+	}
+	c := (*b)[0]
+	*b = (*b)[1:]
+	return c
+}
+
+func (b *traceBuf) int16() int {
+	if len(*b) < 2 {
+		*b = nil
+		return 0
+	}
+	n := int(int16(binary.BigEndian.Uint16(*b)))
+	*b = (*b)[2:]
+	return n
+}
+
+func (b *traceBuf) int32() int {
+	if len(*b) < 4 {
+		*b = nil
+		return 0
+	}
+	n := int(int32(binary.BigEndian.Uint32(*b)))
+	*b = (*b)[4:]
+	return n
+}
+
+func (b *traceBuf) string() string {
+	for i, c := range *b {
+		if c == 0 {
+			s := string((*b)[:i])
+			*b = (*b)[i+1:]
+			return s
+		}
+	}
+	s := string(*b)
+	*b = nil
+	return s
+}
+
+func (b *traceBuf) skip(n int) {
+	if n > len(*b) {
+		n = len(*b)
+	}
+	*b = (*b)[n:]
+}
@@ -0,0 +1,51 @@
+package pg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gopkg.in/pg.v5/internal/pool"
+)
+
+func TestWireTracer(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := &wireTracer{
+		w:       &buf,
+		cn:      new(pool.Conn),
+		startup: true,
+	}
+
+	wr := pool.NewWriteBuffer()
+	writeSSLMsg(wr)
+	tracer.trace(true, wr.Bytes)
+	tracer.trace(false, []byte("S"))
+
+	wr.Reset()
+	writePasswordMsg(wr, "secret")
+	tracer.startup = false
+	tracer.trace(true, wr.Bytes)
+
+	msg := []byte("E\x00\x00\x00\x32SERROR\x00C42P01\x00Mrelation \"foo\" does not exist\x00\x00Z\x00\x00\x00\x05I")
+	for i := range msg {
+		tracer.trace(false, msg[i:i+1])
+	}
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		got = append(got, strings.SplitN(line, " ", 4)[3])
+	}
+	wanted := []string{
+		`F 8 SSLRequest`,
+		`B 1 SSLResponse S`,
+		`F 11 PasswordMessage`,
+		`B 50 ErrorResponse ERROR 42P01 "relation \"foo\" does not exist"`,
+		`B 5 ReadyForQuery I`,
+	}
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("got\n%s\nwanted\n%s", strings.Join(got, "\n"), strings.Join(wanted, "\n"))
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Fatalf("password is traced")
+	}
+}